@@ -0,0 +1,84 @@
+package azemailsender
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// DefaultAttachmentSizeLimit is the total size Azure Communication Services accepts
+// for a message's attachments, measured after base64 encoding
+const DefaultAttachmentSizeLimit = 10 * 1024 * 1024
+
+// AttachmentSize is the encoded size of one attachment
+type AttachmentSize struct {
+	Name string
+	Size int64
+}
+
+// AttachmentLimitError reports attachments that push a message over the size limit
+type AttachmentLimitError struct {
+	Limit int64
+	Total int64
+
+	// Offending lists the attachments from the one that crossed the limit onwards
+	Offending []AttachmentSize
+}
+
+func (e *AttachmentLimitError) Error() string {
+	names := make([]string, len(e.Offending))
+	for i, attachment := range e.Offending {
+		names[i] = fmt.Sprintf("%s (%d bytes)", attachment.Name, attachment.Size)
+	}
+	return fmt.Sprintf("attachments total %d bytes, exceeding the %d byte limit: %s", e.Total, e.Limit, strings.Join(names, ", "))
+}
+
+// attachmentSize returns the encoded size of an attachment. The size of reader
+// attachments is only known when the reader can report it through Stat
+func attachmentSize(attachment EmailAttachment) int64 {
+	if attachment.ContentInBase64 != "" {
+		return int64(len(attachment.ContentInBase64))
+	}
+	if stater, ok := attachment.Reader.(interface{ Stat() (fs.FileInfo, error) }); ok {
+		if info, err := stater.Stat(); err == nil {
+			return int64(base64.StdEncoding.EncodedLen(int(info.Size())))
+		}
+	}
+	return 0
+}
+
+// AttachmentSizeLimit returns the total encoded attachment size the client
+// accepts, or zero when the check is disabled
+func (c *Client) AttachmentSizeLimit() int64 {
+	switch {
+	case c.options.AttachmentSizeLimit < 0:
+		return 0
+	case c.options.AttachmentSizeLimit == 0:
+		return DefaultAttachmentSizeLimit
+	default:
+		return c.options.AttachmentSizeLimit
+	}
+}
+
+// checkAttachmentBudget returns an AttachmentLimitError if the attachments exceed limit
+func checkAttachmentBudget(attachments []EmailAttachment, limit int64) error {
+	if limit <= 0 {
+		return nil
+	}
+
+	var total int64
+	var offending []AttachmentSize
+	for _, attachment := range attachments {
+		size := attachmentSize(attachment)
+		total += size
+		if total > limit {
+			offending = append(offending, AttachmentSize{Name: attachment.Name, Size: size})
+		}
+	}
+
+	if len(offending) == 0 {
+		return nil
+	}
+	return &AttachmentLimitError{Limit: limit, Total: total, Offending: offending}
+}
@@ -0,0 +1,172 @@
+package azemailsender
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// Defaults for AttachmentFromURL
+const (
+	DefaultAttachmentMaxSize = 10 << 20
+	DefaultAttachmentTimeout = 30 * time.Second
+)
+
+// maxAttachmentRedirects limits the redirects followed by an attachment download
+const maxAttachmentRedirects = 10
+
+// ErrAttachmentTooLarge is returned when a downloaded attachment exceeds the size limit
+var ErrAttachmentTooLarge = errors.New("attachment exceeds size limit")
+
+// URLAttachmentOptions configures AttachmentFromURL
+type URLAttachmentOptions struct {
+	// Name overrides the attachment name derived from the URL path or Content-Disposition
+	Name string
+
+	// ContentType overrides the type reported by the server
+	ContentType string
+
+	// MaxSize limits the downloaded size in bytes. Zero uses DefaultAttachmentMaxSize
+	MaxSize int64
+
+	// Timeout bounds the download. Zero uses DefaultAttachmentTimeout
+	Timeout time.Duration
+
+	// AllowedSchemes lists the accepted URL schemes. Empty allows only https
+	AllowedSchemes []string
+
+	// HTTPClient performs the download. Nil uses a client with default settings.
+	// Redirects are checked against AllowedSchemes before its own CheckRedirect runs
+	HTTPClient *http.Client
+}
+
+// AttachmentFromURL downloads rawURL and returns it as an attachment, e.g. for a blob SAS URL
+func AttachmentFromURL(ctx context.Context, rawURL string, options *URLAttachmentOptions) (*EmailAttachment, error) {
+	if options == nil {
+		options = &URLAttachmentOptions{}
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attachment URL: %w", err)
+	}
+	if !schemeAllowed(parsed.Scheme, options.AllowedSchemes) {
+		return nil, fmt.Errorf("attachment URL scheme %q is not allowed", parsed.Scheme)
+	}
+
+	maxSize := options.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultAttachmentMaxSize
+	}
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = DefaultAttachmentTimeout
+	}
+	httpClient := attachmentClient(options.HTTPClient, options.AllowedSchemes)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", parsed.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attachment request: %w", err)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download attachment: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("failed to download attachment: %s", resp.Status)
+	}
+	if resp.ContentLength > maxSize {
+		return nil, fmt.Errorf("%w: %d bytes (limit %d)", ErrAttachmentTooLarge, resp.ContentLength, maxSize)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	if int64(len(content)) > maxSize {
+		return nil, fmt.Errorf("%w: limit %d bytes", ErrAttachmentTooLarge, maxSize)
+	}
+
+	name := options.Name
+	if name == "" {
+		name = attachmentName(resp, parsed)
+	}
+
+	contentType := options.ContentType
+	if contentType == "" {
+		contentType = mediaType(resp.Header.Get("Content-Type"))
+	}
+	if contentType == "" || contentType == defaultContentType {
+		contentType = DetectContentType(name, content)
+	}
+
+	return &EmailAttachment{
+		Name:            name,
+		ContentType:     contentType,
+		ContentInBase64: base64.StdEncoding.EncodeToString(content),
+	}, nil
+}
+
+// attachmentClient returns a copy of client, or a default client when it is nil,
+// that only follows redirects to allowed schemes, so an allowed URL cannot
+// redirect the download to another one
+func attachmentClient(client *http.Client, allowedSchemes []string) *http.Client {
+	checked := &http.Client{}
+	if client != nil {
+		*checked = *client
+	}
+	next := checked.CheckRedirect
+	checked.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !schemeAllowed(req.URL.Scheme, allowedSchemes) {
+			return fmt.Errorf("attachment redirect to scheme %q is not allowed", req.URL.Scheme)
+		}
+		if next != nil {
+			return next(req, via)
+		}
+		if len(via) >= maxAttachmentRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxAttachmentRedirects)
+		}
+		return nil
+	}
+	return checked
+}
+
+// schemeAllowed reports whether scheme is in allowed, defaulting to https only
+func schemeAllowed(scheme string, allowed []string) bool {
+	if len(allowed) == 0 {
+		allowed = []string{"https"}
+	}
+	for _, candidate := range allowed {
+		if strings.EqualFold(scheme, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// attachmentName picks a file name from Content-Disposition or the last URL path segment
+func attachmentName(resp *http.Response, u *url.URL) string {
+	if disposition := resp.Header.Get("Content-Disposition"); disposition != "" {
+		if _, params, err := mime.ParseMediaType(disposition); err == nil && params["filename"] != "" {
+			return path.Base(params["filename"])
+		}
+	}
+	if name := path.Base(u.Path); name != "." && name != "/" && name != "" {
+		return name
+	}
+	return "attachment"
+}
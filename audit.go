@@ -0,0 +1,188 @@
+package azemailsender
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Audit results recorded in AuditEntry.Result
+const (
+	AuditResultSent   = "sent"
+	AuditResultFailed = "failed"
+)
+
+// AuditEntry is a single line of the audit log
+type AuditEntry struct {
+	Timestamp       time.Time `json:"timestamp"`
+	Sender          string    `json:"sender"`
+	RecipientsHash  string    `json:"recipientsHash"`
+	RecipientCount  int       `json:"recipientCount"`
+	Subject         string    `json:"subject"`
+	MessageID       string    `json:"messageId,omitempty"`
+	ClientRequestID string    `json:"clientRequestId,omitempty"`
+	Result          string    `json:"result"`
+	Error           string    `json:"error,omitempty"`
+}
+
+// auditLockSuffix names the lock file next to an audit log, held while it is
+// appended to, rotated or pruned, so other processes don't lose entries
+const auditLockSuffix = ".lock"
+
+// auditLog appends JSON lines to a file and rotates it by size
+type auditLog struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	maxAge     time.Duration
+}
+
+// newAuditLog creates an audit log writer from options
+func newAuditLog(options *AuditLogOptions) *auditLog {
+	return &auditLog{
+		path:       options.Path,
+		maxSize:    options.MaxSize,
+		maxBackups: options.MaxBackups,
+		maxAge:     options.MaxAge,
+	}
+}
+
+// write appends an entry, rotating the file first if it would exceed the size limit
+func (a *auditLog) write(entry *AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	unlock, err := lockFile(a.path + auditLockSuffix)
+	if err != nil {
+		return fmt.Errorf("failed to write audit log %s: %w", a.path, err)
+	}
+	defer unlock()
+
+	if a.maxSize > 0 {
+		if info, err := os.Stat(a.path); err == nil && info.Size()+int64(len(line)) > a.maxSize {
+			if err := a.rotate(); err != nil {
+				return err
+			}
+		}
+	}
+
+	file, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log %s: %w", a.path, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit log %s: %w", a.path, err)
+	}
+	return nil
+}
+
+// rotate shifts path.N to path.N+1 and moves the current file to path.1
+func (a *auditLog) rotate() error {
+	if a.maxBackups > 0 {
+		os.Remove(fmt.Sprintf("%s.%d", a.path, a.maxBackups))
+	}
+
+	// Find the highest existing backup so nothing is overwritten
+	last := 1
+	for {
+		if _, err := os.Stat(fmt.Sprintf("%s.%d", a.path, last)); err != nil {
+			break
+		}
+		last++
+	}
+
+	for i := last; i > 1; i-- {
+		if err := os.Rename(fmt.Sprintf("%s.%d", a.path, i-1), fmt.Sprintf("%s.%d", a.path, i)); err != nil {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+	}
+
+	if err := os.Rename(a.path, a.path+".1"); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+
+	if a.maxAge > 0 {
+		a.removeExpired(time.Now().Add(-a.maxAge))
+	}
+	return nil
+}
+
+// removeExpired deletes the rotated files last written before cutoff. Higher numbers
+// are older, so everything from the first expired file on goes
+func (a *auditLog) removeExpired(cutoff time.Time) {
+	expired := false
+	for i := 1; ; i++ {
+		backup := fmt.Sprintf("%s.%d", a.path, i)
+		info, err := os.Stat(backup)
+		if err != nil {
+			return
+		}
+		if expired = expired || info.ModTime().Before(cutoff); expired {
+			os.Remove(backup)
+		}
+	}
+}
+
+// newAuditEntry builds an audit entry for a send attempt
+func newAuditEntry(message *EmailMessage, requestID string, response *SendResponse, sendErr error) *AuditEntry {
+	var addresses []string
+	for _, list := range [][]EmailAddress{message.Recipients.To, message.Recipients.Cc, message.Recipients.Bcc} {
+		for _, addr := range list {
+			addresses = append(addresses, strings.ToLower(addr.Address))
+		}
+	}
+
+	entry := &AuditEntry{
+		Timestamp:       time.Now().UTC(),
+		Sender:          message.SenderAddress,
+		RecipientsHash:  hashRecipients(addresses),
+		RecipientCount:  len(addresses),
+		Subject:         message.Content.Subject,
+		ClientRequestID: requestID,
+		Result:          AuditResultSent,
+	}
+
+	if response != nil {
+		entry.MessageID = response.ID
+	}
+	if sendErr != nil {
+		entry.Result = AuditResultFailed
+		entry.Error = sendErr.Error()
+	}
+
+	return entry
+}
+
+// hashRecipients returns a stable SHA-256 hash of the recipient addresses
+func hashRecipients(addresses []string) string {
+	sorted := append([]string(nil), addresses...)
+	sort.Strings(sorted)
+
+	sum := sha256.Sum256([]byte(strings.Join(sorted, ",")))
+	return hex.EncodeToString(sum[:])
+}
+
+// recordAudit writes an audit entry if auditing is enabled
+func (c *Client) recordAudit(message *EmailMessage, requestID string, response *SendResponse, sendErr error) {
+	if c.audit == nil {
+		return
+	}
+
+	if err := c.audit.write(newAuditEntry(message, requestID, response, sendErr)); err != nil {
+		c.logger.Printf("[WARN] Failed to write audit log: %v", err)
+	}
+}
@@ -0,0 +1,256 @@
+// Package azfunc serves email sends from Azure Functions custom handlers.
+//
+// A custom handler is an HTTP server started by the Functions host. HTTPHandler
+// serves HTTP triggers forwarded with enableForwardingHttpRequest, and
+// QueueHandler serves queue triggers, which the host posts to /<FunctionName>.
+// Both accept a message in the JSON format written by azemailsender.SaveMessage,
+// or a bare API payload:
+//
+//	client := azemailsender.NewClient(os.Getenv("ACS_ENDPOINT"), os.Getenv("ACS_ACCESS_KEY"), nil)
+//	mux := http.NewServeMux()
+//	mux.Handle("/api/send", azfunc.HTTPHandler(client, nil))
+//	mux.Handle("/send-queued", azfunc.QueueHandler(client, nil))
+//	log.Fatal(http.ListenAndServe(azfunc.Addr(), mux))
+package azfunc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/groovy-sky/azemailsender"
+)
+
+// DefaultMaxBodySize is the largest request accepted by default, enough for a
+// message with attachments up to the service limit once base64 encoded
+const DefaultMaxBodySize = 16 << 20
+
+// Options configures the handlers
+type Options struct {
+	// Wait waits for each message to reach a final status before answering
+	Wait bool
+
+	// WaitOptions configures waiting; nil uses the client defaults
+	WaitOptions *azemailsender.WaitOptions
+
+	// Binding is the name of the queue trigger binding in function.json. When
+	// empty, the only input binding of the invocation is used
+	Binding string
+
+	// MaxBodySize limits the size of requests; 0 uses DefaultMaxBodySize
+	MaxBodySize int64
+}
+
+// Result is the JSON answer of HTTPHandler to a sent message, and the return
+// value of a queue invocation
+type Result struct {
+	ID              string                    `json:"id"`
+	Status          azemailsender.EmailStatus `json:"status"`
+	ClientRequestID string                    `json:"clientRequestId,omitempty"`
+	RequestID       string                    `json:"requestId,omitempty"`
+
+	// WaitError is why waiting for the final status failed; the message was
+	// accepted, so it is not reported as a failed send
+	WaitError string `json:"waitError,omitempty"`
+}
+
+// errorResult is the JSON answer of HTTPHandler to a request that failed
+type errorResult struct {
+	Error     string `json:"error"`
+	Retryable bool   `json:"retryable"`
+}
+
+// invokeRequest is the payload the Functions host posts for a non-HTTP trigger
+type invokeRequest struct {
+	Data     map[string]json.RawMessage `json:"Data"`
+	Metadata map[string]json.RawMessage `json:"Metadata"`
+}
+
+// invokeResponse is the payload a custom handler answers a non-HTTP trigger with
+type invokeResponse struct {
+	Outputs     map[string]interface{} `json:"Outputs"`
+	Logs        []string               `json:"Logs"`
+	ReturnValue interface{}            `json:"ReturnValue"`
+}
+
+// Addr returns the address the Functions host expects the custom handler to
+// listen on, from FUNCTIONS_CUSTOMHANDLER_PORT, or ":8080" outside the host
+func Addr() string {
+	if port := os.Getenv("FUNCTIONS_CUSTOMHANDLER_PORT"); port != "" {
+		return ":" + port
+	}
+	return ":8080"
+}
+
+// HTTPHandler returns a handler sending the message posted as the request body.
+// It answers 202 with a Result once the message is accepted, or 200 when
+// waiting for the final status. Invalid messages get 400, errors worth retrying
+// 503 and other errors from the service 502, each with a JSON error
+func HTTPHandler(client *azemailsender.Client, options *Options) http.Handler {
+	if options == nil {
+		options = &Options{}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeJSON(w, http.StatusMethodNotAllowed, &errorResult{Error: "use POST"})
+			return
+		}
+
+		message, err := azemailsender.LoadMessage(http.MaxBytesReader(w, r.Body, options.maxBodySize()))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, &errorResult{Error: err.Error()})
+			return
+		}
+
+		result, err := send(r.Context(), client, options, message)
+		if err != nil {
+			writeJSON(w, statusCode(err), &errorResult{Error: err.Error(), Retryable: azemailsender.IsRetryable(err)})
+			return
+		}
+
+		status := http.StatusAccepted
+		if options.Wait {
+			status = http.StatusOK
+		}
+		writeJSON(w, status, result)
+	})
+}
+
+// QueueHandler returns a handler sending the message of a queue trigger
+// invocation. Failed sends answer 500 so the host retries the message and
+// moves it to the poison queue once its dequeue count is exhausted. Retries
+// of a queue message share one client request ID, the queue message ID
+func QueueHandler(client *azemailsender.Client, options *Options) http.Handler {
+	if options == nil {
+		options = &Options{}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := &invokeResponse{Outputs: map[string]interface{}{}, Logs: []string{}}
+		fail := func(err error) {
+			response.Logs = append(response.Logs, "Failed to send message: "+err.Error())
+			writeJSON(w, http.StatusInternalServerError, response)
+		}
+
+		var invocation invokeRequest
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, options.maxBodySize()))
+		if err := decoder.Decode(&invocation); err != nil {
+			fail(fmt.Errorf("failed to decode invocation: %w", err))
+			return
+		}
+
+		message, err := invocation.message(options.Binding)
+		if err != nil {
+			fail(err)
+			return
+		}
+		if message.ClientRequestID == "" {
+			var id string
+			if json.Unmarshal(invocation.Metadata["Id"], &id) == nil {
+				message.ClientRequestID = id
+			}
+		}
+
+		result, err := send(r.Context(), client, options, message)
+		if err != nil {
+			fail(err)
+			return
+		}
+
+		response.Logs = append(response.Logs, fmt.Sprintf("Sent message %s (%s)", result.ID, result.Status))
+		if result.WaitError != "" {
+			response.Logs = append(response.Logs, "Failed to wait for the final status: "+result.WaitError)
+		}
+		response.ReturnValue = result
+		writeJSON(w, http.StatusOK, response)
+	})
+}
+
+// message decodes the message of the queue trigger binding. The host passes
+// queue messages holding JSON as a string, so both strings and objects are accepted
+func (i *invokeRequest) message(binding string) (*azemailsender.EmailMessage, error) {
+	if binding == "" {
+		if len(i.Data) != 1 {
+			names := make([]string, 0, len(i.Data))
+			for name := range i.Data {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("expected one input binding, got %d (%s): set Options.Binding", len(i.Data), strings.Join(names, ", "))
+		}
+		for name := range i.Data {
+			binding = name
+		}
+	}
+
+	data, ok := i.Data[binding]
+	if !ok {
+		return nil, fmt.Errorf("no input binding %q in invocation", binding)
+	}
+	var text string
+	if json.Unmarshal(data, &text) == nil {
+		data = []byte(text)
+	}
+	return azemailsender.LoadMessage(bytes.NewReader(data))
+}
+
+// send sends message, waiting for its final status when options ask for it
+func send(ctx context.Context, client *azemailsender.Client, options *Options, message *azemailsender.EmailMessage) (*Result, error) {
+	response, err := client.SendWithContext(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		ID:              response.ID,
+		Status:          response.Status,
+		ClientRequestID: response.ClientRequestID,
+		RequestID:       response.RequestID,
+	}
+	// Transports other than the API answer with the final status right away
+	if !options.Wait || response.Status.IsFinal() {
+		return result, nil
+	}
+
+	waited, err := client.Wait(ctx, response.ID, options.WaitOptions)
+	if err != nil {
+		result.WaitError = err.Error()
+	} else if waited.FinalStatus != "" {
+		result.Status = waited.FinalStatus
+	}
+	return result, nil
+}
+
+// statusCode maps a send error to the HTTP status answered by HTTPHandler
+func statusCode(err error) int {
+	var apiErr *azemailsender.APIError
+	switch {
+	case azemailsender.IsRetryable(err):
+		return http.StatusServiceUnavailable
+	case errors.As(err, &apiErr) && apiErr.StatusCode != http.StatusBadRequest:
+		// The service refused the request for a reason the caller can't fix, such as the credentials
+		return http.StatusBadGateway
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func (o *Options) maxBodySize() int64 {
+	if o.MaxBodySize > 0 {
+		return o.MaxBodySize
+	}
+	return DefaultMaxBodySize
+}
+
+// writeJSON answers with value as JSON
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,93 @@
+package azemailsender
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultBatchConcurrency is the number of concurrent sends used by SendBatch by default
+const DefaultBatchConcurrency = 4
+
+// BatchOptions configures SendBatch
+type BatchOptions struct {
+	// MaxConcurrency limits the number of sends in flight. Zero uses DefaultBatchConcurrency
+	MaxConcurrency int
+
+	// MaxRequestsPerSecond limits the send rate across all workers. Zero means unlimited
+	MaxRequestsPerSecond float64
+
+	// PauseBetween is the minimum delay between the start of consecutive sends
+	PauseBetween time.Duration
+}
+
+// BatchResult is the outcome of one message of a batch
+type BatchResult struct {
+	// Index is the position of the message in the input, starting at 0
+	Index int
+
+	Message  *EmailMessage
+	Response *SendResponse
+	Err      error
+}
+
+// SendBatch sends the messages received from the channel concurrently and streams one
+// result per message, in completion order. The result channel is closed once the input
+// channel is closed and all sends have finished. The caller must drain the result channel
+func (c *Client) SendBatch(ctx context.Context, messages <-chan *EmailMessage, options *BatchOptions) <-chan BatchResult {
+	if options == nil {
+		options = &BatchOptions{}
+	}
+
+	concurrency := options.MaxConcurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+
+	if c.options.Debug {
+		c.logger.Printf("[DEBUG] Sending batch with %d workers (max %.1f requests/s, pause %v)", concurrency, options.MaxRequestsPerSecond, options.PauseBetween)
+	}
+
+	type job struct {
+		index   int
+		message *EmailMessage
+	}
+
+	limiter := newRateLimiter(options.MaxRequestsPerSecond, options.PauseBetween)
+	jobs := make(chan job)
+	results := make(chan BatchResult)
+
+	// Number the messages in arrival order
+	go func() {
+		defer close(jobs)
+		index := 0
+		for message := range messages {
+			jobs <- job{index: index, message: message}
+			index++
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				result := BatchResult{Index: j.index, Message: j.message}
+				if err := limiter.wait(ctx); err != nil {
+					result.Err = err
+				} else {
+					result.Response, result.Err = c.SendWithContext(ctx, j.message)
+				}
+				results <- result
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
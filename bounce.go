@@ -0,0 +1,221 @@
+package azemailsender
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DeliveryReportEventType is the Event Grid event type of email delivery reports
+const DeliveryReportEventType = "Microsoft.Communication.EmailDeliveryReportReceived"
+
+// Delivery report statuses sent by Event Grid
+const (
+	DeliveryStatusDelivered    = "Delivered"
+	DeliveryStatusExpanded     = "Expanded"
+	DeliveryStatusBounced      = "Bounced"
+	DeliveryStatusSuppressed   = "Suppressed"
+	DeliveryStatusFilteredSpam = "FilteredSpam"
+	DeliveryStatusQuarantined  = "Quarantined"
+	DeliveryStatusFailed       = "Failed"
+)
+
+// DeliveryReport is the data of an EmailDeliveryReportReceived Event Grid event
+type DeliveryReport struct {
+	Sender                   string                `json:"sender"`
+	Recipient                string                `json:"recipient"`
+	MessageID                string                `json:"messageId"`
+	Status                   string                `json:"status"`
+	DeliveryStatusDetails    DeliveryStatusDetails `json:"deliveryStatusDetails"`
+	DeliveryAttemptTimestamp time.Time             `json:"deliveryAttemptTimestamp"`
+}
+
+// DeliveryStatusDetails holds the remote server's response for a delivery report
+type DeliveryStatusDetails struct {
+	StatusMessage string `json:"statusMessage"`
+}
+
+// FailureClass categorizes why an email was not delivered
+type FailureClass string
+
+const (
+	FailureNone       FailureClass = ""
+	FailureHardBounce FailureClass = "HardBounce"
+	FailureSoftBounce FailureClass = "SoftBounce"
+	FailureSpamBlock  FailureClass = "SpamBlock"
+	FailureQuota      FailureClass = "Quota"
+	FailureSuppressed FailureClass = "Suppressed"
+	FailureUnknown    FailureClass = "Unknown"
+)
+
+// FailureClassification is the classified outcome of a delivery report or failed status
+type FailureClassification struct {
+	Class FailureClass
+
+	// Permanent is set when retrying the recipient will not help
+	Permanent bool
+
+	// Recipient is the affected address, when known
+	Recipient string
+
+	// Reason is the message the classification was based on
+	Reason string
+
+	// Token is the plus-address token of the report's sender, when it has one
+	Token string
+}
+
+// SuppressionStore is a suppression list that can record new suppressions
+type SuppressionStore interface {
+	SuppressionList
+	Add(address, reason string)
+}
+
+// eventEnvelope covers both the Event Grid and CloudEvents schemas
+type eventEnvelope struct {
+	EventType string          `json:"eventType"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// ParseDeliveryReports reads delivery reports from an Event Grid or CloudEvents payload,
+// which may be a single event or an array of events. Other event types are skipped
+func ParseDeliveryReports(r io.Reader) ([]*DeliveryReport, error) {
+	payload, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read events: %w", err)
+	}
+
+	var events []eventEnvelope
+	trimmed := strings.TrimSpace(string(payload))
+	if strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal(payload, &events); err != nil {
+			return nil, fmt.Errorf("failed to parse events: %w", err)
+		}
+	} else {
+		var event eventEnvelope
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("failed to parse event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	var reports []*DeliveryReport
+	for _, event := range events {
+		if event.EventType != DeliveryReportEventType && event.Type != DeliveryReportEventType {
+			continue
+		}
+
+		var report DeliveryReport
+		if err := json.Unmarshal(event.Data, &report); err != nil {
+			return nil, fmt.Errorf("failed to parse delivery report: %w", err)
+		}
+		reports = append(reports, &report)
+	}
+
+	return reports, nil
+}
+
+// enhancedStatusCode matches RFC 3463 enhanced status codes such as 5.1.1
+var enhancedStatusCode = regexp.MustCompile(`\b([245])\.(\d{1,3})\.(\d{1,3})\b`)
+
+// ClassifyDeliveryReport classifies a delivery report
+func ClassifyDeliveryReport(report *DeliveryReport) *FailureClassification {
+	classification := classifyMessage(report.DeliveryStatusDetails.StatusMessage)
+	classification.Recipient = report.Recipient
+	classification.Token, _ = report.ReturnToken()
+	if classification.Recipient == "" {
+		// A VERP sender names the recipient the report is about
+		classification.Recipient, _ = ParseVERP(report.Sender)
+	}
+
+	switch report.Status {
+	case DeliveryStatusDelivered, DeliveryStatusExpanded:
+		return &FailureClassification{Class: FailureNone, Recipient: classification.Recipient, Token: classification.Token}
+	case DeliveryStatusSuppressed:
+		classification.Class = FailureSuppressed
+		classification.Permanent = true
+	case DeliveryStatusFilteredSpam, DeliveryStatusQuarantined:
+		classification.Class = FailureSpamBlock
+		classification.Permanent = false
+	case DeliveryStatusBounced:
+		// A bounce without a recognizable code is treated as permanent
+		if classification.Class == FailureUnknown {
+			classification.Class = FailureHardBounce
+			classification.Permanent = true
+		}
+	}
+
+	return classification
+}
+
+// ClassifyStatus classifies a failed status response. It returns FailureNone for other statuses
+func ClassifyStatus(status *StatusResponse) *FailureClassification {
+	if status.Status != StatusFailed {
+		return &FailureClassification{Class: FailureNone}
+	}
+	if status.Error == nil {
+		return &FailureClassification{Class: FailureUnknown}
+	}
+
+	code := strings.ToLower(status.Error.Code)
+	if strings.Contains(code, "toomanyrequests") || strings.Contains(code, "quota") || strings.Contains(code, "throttl") {
+		return &FailureClassification{Class: FailureQuota, Reason: status.Error.Message}
+	}
+	return classifyMessage(status.Error.Code + " " + status.Error.Message)
+}
+
+// classifyMessage classifies a remote server or service error message
+func classifyMessage(message string) *FailureClassification {
+	classification := &FailureClassification{Class: FailureUnknown, Reason: strings.TrimSpace(message)}
+	lower := strings.ToLower(message)
+
+	switch {
+	case strings.Contains(lower, "quota") || strings.Contains(lower, "rate limit") || strings.Contains(lower, "throttl"):
+		classification.Class = FailureQuota
+		return classification
+	case strings.Contains(lower, "spam") || strings.Contains(lower, "blocked") || strings.Contains(lower, "blacklist") || strings.Contains(lower, "blocklist"):
+		classification.Class = FailureSpamBlock
+		return classification
+	}
+
+	match := enhancedStatusCode.FindStringSubmatch(message)
+	if match == nil {
+		return classification
+	}
+
+	class, subject := match[1], match[2]
+	switch {
+	case class == "2":
+		classification.Class = FailureNone
+	case subject == "7":
+		// X.7.X are security and policy rejections
+		classification.Class = FailureSpamBlock
+	case class == "4" || (subject == "2" && match[3] == "2"):
+		// Transient failures and full mailboxes (X.2.2) may succeed later
+		classification.Class = FailureSoftBounce
+	default:
+		classification.Class = FailureHardBounce
+		classification.Permanent = true
+	}
+	return classification
+}
+
+// ProcessDeliveryReport classifies a delivery report and records permanently failed
+// recipients in store when it is not nil
+func ProcessDeliveryReport(report *DeliveryReport, store SuppressionStore) *FailureClassification {
+	classification := ClassifyDeliveryReport(report)
+
+	if store != nil && classification.Permanent && classification.Class != FailureSuppressed && classification.Recipient != "" {
+		reason := string(classification.Class)
+		if classification.Reason != "" {
+			reason += ": " + classification.Reason
+		}
+		store.Add(classification.Recipient, strings.ReplaceAll(reason, "\n", " "))
+	}
+
+	return classification
+}
@@ -1,7 +1,10 @@
 package azemailsender
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
 	"strings"
 )
 
@@ -9,6 +12,8 @@ import (
 type MessageBuilder struct {
 	client  *Client
 	message *EmailMessage
+	// fromSet records that From was called, so category defaults keep the sender
+	fromSet bool
 }
 
 // NewMessage creates a new message builder
@@ -18,16 +23,48 @@ func (c *Client) NewMessage() *MessageBuilder {
 	}
 	
 	return &MessageBuilder{
-		client: c,
-		message: &EmailMessage{
-			Recipients: EmailRecipients{
-				To:  make([]EmailAddress, 0),
-				Cc:  make([]EmailAddress, 0),
-				Bcc: make([]EmailAddress, 0),
-			},
-			ReplyTo: make([]EmailAddress, 0),
+		client:  c,
+		message: c.newEmailMessage(),
+	}
+}
+
+// newEmailMessage returns an empty message preset with the client defaults
+func (c *Client) newEmailMessage() *EmailMessage {
+	return &EmailMessage{
+		SenderAddress: c.options.DefaultFrom,
+		Recipients: EmailRecipients{
+			To:  make([]EmailAddress, 0),
+			Cc:  make([]EmailAddress, 0),
+			Bcc: make([]EmailAddress, 0),
 		},
+		ReplyTo: make([]EmailAddress, 0),
+	}
+}
+
+// Clone returns an independent copy of the builder, so a partially configured builder
+// can serve as a prototype. Reader attachments are shared and can still be sent only once
+func (b *MessageBuilder) Clone() *MessageBuilder {
+	if b.client.options.Debug {
+		b.client.logger.Printf("[DEBUG] Cloning message builder")
+	}
+	
+	return &MessageBuilder{
+		client:  b.client,
+		message: cloneMessage(b.message),
+		fromSet: b.fromSet,
+	}
+}
+
+// Reset discards everything set on the builder, as if it was just created by NewMessage.
+// Messages built earlier are not affected
+func (b *MessageBuilder) Reset() *MessageBuilder {
+	if b.client.options.Debug {
+		b.client.logger.Printf("[DEBUG] Resetting message builder")
 	}
+	
+	b.message = b.client.newEmailMessage()
+	b.fromSet = false
+	return b
 }
 
 // From sets the sender address for the email
@@ -37,6 +74,7 @@ func (b *MessageBuilder) From(address string) *MessageBuilder {
 	}
 	
 	b.message.SenderAddress = address
+	b.fromSet = true
 	return b
 }
 
@@ -146,6 +184,133 @@ func (b *MessageBuilder) HTML(content string) *MessageBuilder {
 	return b
 }
 
+// Attach adds a file attachment to the email. An empty contentType is detected from the name and content
+func (b *MessageBuilder) Attach(name, contentType string, content []byte) *MessageBuilder {
+	if contentType == "" {
+		contentType = DetectContentType(name, content)
+	}
+	
+	if b.client.options.Debug {
+		b.client.logger.Printf("[DEBUG] Adding attachment: %s (%s, %d bytes)", name, contentType, len(content))
+	}
+	
+	b.message.Attachments = append(b.message.Attachments, EmailAttachment{
+		Name:            name,
+		ContentType:     contentType,
+		ContentInBase64: base64.StdEncoding.EncodeToString(content),
+	})
+	return b
+}
+
+// AddAttachment adds a prepared attachment, such as one returned by AttachmentFromURL
+func (b *MessageBuilder) AddAttachment(attachment EmailAttachment) *MessageBuilder {
+	if b.client.options.Debug {
+		b.client.logger.Printf("[DEBUG] Adding attachment: %s (%s)", attachment.Name, attachment.ContentType)
+	}
+	
+	b.message.Attachments = append(b.message.Attachments, attachment)
+	return b
+}
+
+// AttachmentSize returns the total encoded size of the attachments added so far
+func (b *MessageBuilder) AttachmentSize() int64 {
+	var total int64
+	for _, attachment := range b.message.Attachments {
+		total += attachmentSize(attachment)
+	}
+	return total
+}
+
+// AttachReader adds an attachment whose content is read from r and encoded only when the email is sent
+func (b *MessageBuilder) AttachReader(name, contentType string, r io.Reader) *MessageBuilder {
+	if contentType == "" {
+		contentType, r = sniffReader(name, r)
+	}
+	
+	if b.client.options.Debug {
+		b.client.logger.Printf("[DEBUG] Adding streamed attachment: %s (%s)", name, contentType)
+	}
+	
+	b.message.Attachments = append(b.message.Attachments, EmailAttachment{
+		Name:        name,
+		ContentType: contentType,
+		Reader:      r,
+	})
+	return b
+}
+
+// AttachInline adds an inline attachment that the HTML content references as cid:<contentID>
+func (b *MessageBuilder) AttachInline(contentID, name, contentType string, content []byte) *MessageBuilder {
+	if contentType == "" {
+		contentType = DetectContentType(name, content)
+	}
+	
+	if b.client.options.Debug {
+		b.client.logger.Printf("[DEBUG] Adding inline attachment: %s as cid:%s (%s, %d bytes)", name, contentID, contentType, len(content))
+	}
+	
+	b.message.Attachments = append(b.message.Attachments, EmailAttachment{
+		Name:            name,
+		ContentType:     contentType,
+		ContentInBase64: base64.StdEncoding.EncodeToString(content),
+		ContentID:       contentID,
+	})
+	return b
+}
+
+// Header adds a custom header to the email
+func (b *MessageBuilder) Header(name, value string) *MessageBuilder {
+	if b.client.options.Debug {
+		b.client.logger.Printf("[DEBUG] Setting header: %s", name)
+	}
+	
+	if b.message.Headers == nil {
+		b.message.Headers = make(map[string]string)
+	}
+	b.message.Headers[name] = value
+	return b
+}
+
+// DisableTracking disables user engagement tracking for the email
+func (b *MessageBuilder) DisableTracking() *MessageBuilder {
+	if b.client.options.Debug {
+		b.client.logger.Printf("[DEBUG] Disabling user engagement tracking")
+	}
+	
+	b.message.UserEngagementTrackingDisabled = true
+	return b
+}
+
+// APIVersion overrides the client API version for this email
+func (b *MessageBuilder) APIVersion(version string) *MessageBuilder {
+	if b.client.options.Debug {
+		b.client.logger.Printf("[DEBUG] Setting API version override: %s", version)
+	}
+	
+	b.message.APIVersion = version
+	return b
+}
+
+// CheckSuppression sets how suppressed recipients are handled for this email
+func (b *MessageBuilder) CheckSuppression(mode SuppressionMode) *MessageBuilder {
+	if b.client.options.Debug {
+		b.client.logger.Printf("[DEBUG] Setting suppression check mode: %d", mode)
+	}
+	
+	b.message.SuppressionCheck = mode
+	return b
+}
+
+// ClientRequestID sets the x-ms-client-request-id used to correlate this send with service logs
+func (b *MessageBuilder) ClientRequestID(id string) *MessageBuilder {
+	if b.client.options.Debug {
+		b.client.logger.Printf("[DEBUG] Setting client request ID: %s", id)
+	}
+	
+	b.message.ClientRequestID = id
+	return b
+}
+
 // AddMultipleRecipients adds multiple recipients to the specified field
 func (b *MessageBuilder) AddMultipleRecipients(recipientType string, addresses []string) *MessageBuilder {
 	if b.client.options.Debug {
@@ -169,55 +334,88 @@ func (b *MessageBuilder) AddMultipleRecipients(recipientType string, addresses [
 	return b
 }
 
-// Validate validates the email message before building
+// Validate validates the email message before building. All problems are reported
+// together as a joined error, so they can be inspected with errors.Is and errors.As
 func (b *MessageBuilder) Validate() error {
 	if b.client.options.Debug {
 		b.client.logger.Printf("[DEBUG] Validating email message")
 	}
 	
-	var errors []string
+	var problems []error
 	
 	// Check sender address
 	if b.message.SenderAddress == "" {
-		errors = append(errors, "sender address is required")
+		problems = append(problems, ErrMissingSender)
+	} else if !isValidEmail(b.message.SenderAddress) {
+		problems = append(problems, &InvalidAddressError{Field: "sender", Address: b.message.SenderAddress})
 	}
 	
 	// Check subject
 	if b.message.Content.Subject == "" {
-		errors = append(errors, "subject is required")
+		problems = append(problems, ErrMissingSubject)
 	}
 	
 	// Check content
 	if b.message.Content.PlainText == "" && b.message.Content.Html == "" {
-		errors = append(errors, "either plain text or HTML content is required")
+		problems = append(problems, ErrMissingContent)
 	}
 	
 	// Check recipients
 	if len(b.message.Recipients.To) == 0 && len(b.message.Recipients.Cc) == 0 && len(b.message.Recipients.Bcc) == 0 {
-		errors = append(errors, "at least one recipient is required")
+		problems = append(problems, ErrNoRecipients)
 	}
 	
 	// Validate email addresses
-	allRecipients := append(append(b.message.Recipients.To, b.message.Recipients.Cc...), b.message.Recipients.Bcc...)
-	for _, recipient := range allRecipients {
-		if !isValidEmail(recipient.Address) {
-			errors = append(errors, fmt.Sprintf("invalid email address: %s", recipient.Address))
+	for _, list := range []struct {
+		field      string
+		recipients []EmailAddress
+	}{
+		{"to", b.message.Recipients.To},
+		{"cc", b.message.Recipients.Cc},
+		{"bcc", b.message.Recipients.Bcc},
+		{"reply-to", b.message.ReplyTo},
+	} {
+		for _, recipient := range list.recipients {
+			if !isValidEmail(recipient.Address) {
+				problems = append(problems, &InvalidAddressError{Field: list.field, Address: recipient.Address})
+			}
 		}
 	}
 	
-	// Validate sender email
-	if !isValidEmail(b.message.SenderAddress) {
-		errors = append(errors, fmt.Sprintf("invalid sender email address: %s", b.message.SenderAddress))
+	// Validate custom headers
+	problems = append(problems, validateHeaders(b.message.Headers)...)
+	if err := checkPriority(b.message.Headers); err != nil {
+		problems = append(problems, err)
 	}
 	
-	if len(errors) > 0 {
+	// Validate attachments
+	for _, attachment := range b.message.Attachments {
+		if attachment.Name == "" {
+			problems = append(problems, errors.New("attachment name is required"))
+		}
+		if attachment.ContentType == "" {
+			problems = append(problems, fmt.Errorf("content type is required for attachment: %s", attachment.Name))
+		}
+	}
+	
+	// Check the total attachment size
+	if err := checkAttachmentBudget(b.message.Attachments, b.client.AttachmentSizeLimit()); err != nil {
+		problems = append(problems, err)
+	}
+	
+	// Check features against the API version
+	if err := checkFeatures(b.message, b.client.messageAPIVersion(b.message)); err != nil {
+		problems = append(problems, err)
+	}
+	
+	if len(problems) > 0 {
 		if b.client.options.Debug {
-			b.client.logger.Printf("[DEBUG] Validation failed with %d errors:", len(errors))
-			for _, err := range errors {
-				b.client.logger.Printf("[DEBUG]   - %s", err)
+			b.client.logger.Printf("[DEBUG] Validation failed with %d errors:", len(problems))
+			for _, err := range problems {
+				b.client.logger.Printf("[DEBUG]   - %v", err)
 			}
 		}
-		return fmt.Errorf("validation failed: %s", strings.Join(errors, "; "))
+		return fmt.Errorf("validation failed: %w", errors.Join(problems...))
 	}
 	
 	if b.client.options.Debug {
@@ -233,6 +431,16 @@ func (b *MessageBuilder) Build() (*EmailMessage, error) {
 		b.client.logger.Printf("[DEBUG] Building email message")
 	}
 	
+	b.applyCategory()
+	
+	// Fall back to the client default reply-to when none was set
+	if len(b.message.ReplyTo) == 0 && b.client.options.DefaultReplyTo != "" {
+		if b.client.options.Debug {
+			b.client.logger.Printf("[DEBUG] Using default reply-to address: %s", b.client.options.DefaultReplyTo)
+		}
+		b.message.ReplyTo = append(b.message.ReplyTo, EmailAddress{Address: b.client.options.DefaultReplyTo})
+	}
+	
 	if err := b.Validate(); err != nil {
 		return nil, err
 	}
@@ -0,0 +1,252 @@
+package azemailsender
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redacted replaces secret values in captured exchanges
+const redacted = "[REDACTED]"
+
+// CapturedExchange is a sanitized request/response pair of a failed send
+type CapturedExchange struct {
+	Timestamp       time.Time         `json:"timestamp"`
+	ClientRequestID string            `json:"clientRequestId,omitempty"`
+	Duration        string            `json:"duration"`
+	Error           string            `json:"error"`
+	Request         CapturedRequest   `json:"request"`
+	Response        *CapturedResponse `json:"response,omitempty"`
+}
+
+// CapturedRequest is the sanitized request part of a captured exchange
+type CapturedRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// CapturedResponse is the response part of a captured exchange
+type CapturedResponse struct {
+	StatusCode int               `json:"statusCode"`
+	Headers    map[string]string `json:"headers"`
+	Body       json.RawMessage   `json:"body,omitempty"`
+}
+
+// captureWriter stores captured exchanges in a directory or a zip support bundle
+type captureWriter struct {
+	mu   sync.Mutex
+	path string
+}
+
+// newCaptureWriter creates a capture writer for a directory or .zip path
+func newCaptureWriter(path string) *captureWriter {
+	return &captureWriter{path: path}
+}
+
+// isBundle reports whether exchanges are written into a zip file
+func (w *captureWriter) isBundle() bool {
+	return strings.EqualFold(filepath.Ext(w.path), ".zip")
+}
+
+// write stores one exchange as a JSON document
+func (w *captureWriter) write(exchange *CapturedExchange) error {
+	data, err := json.MarshalIndent(exchange, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal captured exchange: %w", err)
+	}
+
+	name := exchange.Timestamp.UTC().Format(captureTimeLayout)
+	if exchange.ClientRequestID != "" {
+		name += "-" + exchange.ClientRequestID
+	}
+	name += ".json"
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.isBundle() {
+		return w.appendToBundle(name, data)
+	}
+
+	if err := os.MkdirAll(w.path, 0o700); err != nil {
+		return fmt.Errorf("failed to create capture directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(w.path, name), data, 0o600); err != nil {
+		return fmt.Errorf("failed to write captured exchange: %w", err)
+	}
+	return nil
+}
+
+// appendToBundle rewrites the zip bundle with the new entry added
+func (w *captureWriter) appendToBundle(name string, data []byte) error {
+	if dir := filepath.Dir(w.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return fmt.Errorf("failed to create bundle directory: %w", err)
+		}
+	}
+
+	tmpPath := w.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create support bundle: %w", err)
+	}
+	defer os.Remove(tmpPath)
+
+	zw := zip.NewWriter(tmp)
+	if err := copyBundleEntries(zw, w.path); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	entry, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err == nil {
+		_, err = entry.Write(data)
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write support bundle: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, w.path); err != nil {
+		return fmt.Errorf("failed to replace support bundle: %w", err)
+	}
+	return nil
+}
+
+// copyBundleEntries copies the entries of an existing bundle, if any, into zw
+func copyBundleEntries(zw *zip.Writer, path string) error {
+	existing, err := zip.OpenReader(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open support bundle: %w", err)
+	}
+	defer existing.Close()
+
+	for _, f := range existing.File {
+		if err := zw.Copy(f); err != nil {
+			return fmt.Errorf("failed to copy support bundle entry %s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// captureFailure records a failed send attempt if capture is enabled
+func (c *Client) captureFailure(req *http.Request, body []byte, resp *http.Response, respBody []byte, duration time.Duration, requestID string, sendErr error) {
+	if c.capture == nil {
+		return
+	}
+
+	exchange := &CapturedExchange{
+		Timestamp:       time.Now(),
+		ClientRequestID: requestID,
+		Duration:        duration.String(),
+		Error:           sendErr.Error(),
+		Request: CapturedRequest{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: sanitizeHeaders(req.Header),
+			Body:    sanitizeBody(body),
+		},
+	}
+	if resp != nil {
+		exchange.Response = &CapturedResponse{
+			StatusCode: resp.StatusCode,
+			Headers:    sanitizeHeaders(resp.Header),
+			Body:       rawBody(respBody),
+		}
+	}
+
+	if err := c.capture.write(exchange); err != nil {
+		c.logger.Printf("[WARN] Failed to capture failed request: %v", err)
+	}
+}
+
+// sanitizeHeaders flattens headers and redacts credentials
+func sanitizeHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		switch strings.ToLower(name) {
+		case "authorization", "api-key", "set-cookie", "cookie":
+			value = redacted
+		}
+		headers[name] = value
+	}
+	return headers
+}
+
+// sanitizeBody masks recipient addresses and strips attachment content from a request body
+func sanitizeBody(body []byte) json.RawMessage {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return rawBody([]byte(redacted))
+	}
+
+	sanitized, err := json.Marshal(sanitizeValue("", doc))
+	if err != nil {
+		return rawBody([]byte(redacted))
+	}
+	return sanitized
+}
+
+// sanitizeValue walks a decoded JSON value and redacts sensitive fields
+func sanitizeValue(key string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = sanitizeValue(k, item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = sanitizeValue(key, item)
+		}
+		return v
+	case string:
+		switch key {
+		case "address":
+			return maskAddress(v)
+		case "contentInBase64":
+			return fmt.Sprintf("%s %d bytes", redacted, len(v))
+		}
+	}
+	return value
+}
+
+// maskAddress keeps the domain and first character of an email address
+func maskAddress(address string) string {
+	at := strings.LastIndex(address, "@")
+	if at <= 0 {
+		return redacted
+	}
+	return address[:1] + "***" + address[at:]
+}
+
+// rawBody returns body as JSON, quoting it when it is not valid JSON
+func rawBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		return body
+	}
+	quoted, _ := json.Marshal(string(body))
+	return quoted
+}
@@ -0,0 +1,148 @@
+package azemailsender
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Priority is the importance of a message shown by mail clients
+type Priority string
+
+// Message priorities
+const (
+	PriorityHigh   Priority = "high"
+	PriorityNormal Priority = "normal"
+	PriorityLow    Priority = "low"
+)
+
+// importanceHeader carries the priority of a message, next to the older X-Priority
+const importanceHeader = "Importance"
+
+// xPriorities maps priorities to the values of the X-Priority header
+var xPriorities = map[Priority]string{
+	PriorityHigh:   "1",
+	PriorityNormal: "3",
+	PriorityLow:    "5",
+}
+
+// ParsePriority parses a priority name: high, normal or low
+func ParsePriority(name string) (Priority, error) {
+	priority := Priority(strings.ToLower(strings.TrimSpace(name)))
+	if _, ok := xPriorities[priority]; !ok {
+		return "", fmt.Errorf("invalid priority %q: use high, normal or low", name)
+	}
+	return priority, nil
+}
+
+// CategoryOptions are the defaults of a message category, such as alerts,
+// reports or marketing, so one client can govern each of them differently
+type CategoryOptions struct {
+	// From is the sender of messages of the category that don't set one. It
+	// takes precedence over ClientOptions.DefaultFrom
+	From string
+
+	// ReplyTo is the reply-to address of messages of the category that don't
+	// set one. It takes precedence over ClientOptions.DefaultReplyTo
+	ReplyTo string
+
+	// DisableTracking turns off user engagement tracking for the category
+	DisableTracking bool
+
+	// Priority is the priority of messages of the category that don't set one
+	Priority Priority
+
+	// MaxRequestsPerSecond limits the send rate of the category through the
+	// client. Zero means unlimited
+	MaxRequestsPerSecond float64
+
+	// Throttle limits how many messages of the category each recipient gets
+	// from an outbox, unless OutboxOptions.Throttle has a policy for it
+	Throttle *ThrottlePolicy
+}
+
+// Category sets the category of the email, which applies the defaults of
+// ClientOptions.Categories for it when the message is built and sent
+func (b *MessageBuilder) Category(name string) *MessageBuilder {
+	if b.client.options.Debug {
+		b.client.logger.Printf("[DEBUG] Setting category: %s", name)
+	}
+
+	b.message.Category = name
+	return b
+}
+
+// Priority sets the Importance and X-Priority headers of the email
+func (b *MessageBuilder) Priority(priority Priority) *MessageBuilder {
+	if b.client.options.Debug {
+		b.client.logger.Printf("[DEBUG] Setting priority: %s", priority)
+	}
+
+	if b.message.Headers == nil {
+		b.message.Headers = make(map[string]string)
+	}
+	b.message.Headers[importanceHeader] = string(priority)
+	b.message.Headers["X-Priority"] = xPriorities[priority]
+	return b
+}
+
+// checkPriority reports a priority set with Priority that is not known
+func checkPriority(headers map[string]string) error {
+	importance, ok := headers[importanceHeader]
+	if !ok {
+		return nil
+	}
+	_, err := ParsePriority(importance)
+	return err
+}
+
+// category returns the options of a category, nil when it has none
+func (c *Client) category(name string) *CategoryOptions {
+	if name == "" {
+		return nil
+	}
+	return c.options.Categories[name]
+}
+
+// applyCategory fills in what the builder's message leaves to the defaults of its category
+func (b *MessageBuilder) applyCategory() {
+	category := b.client.category(b.message.Category)
+	if category == nil {
+		return
+	}
+	if b.client.options.Debug {
+		b.client.logger.Printf("[DEBUG] Applying defaults of category %s", b.message.Category)
+	}
+
+	if category.From != "" && !b.fromSet {
+		b.message.SenderAddress = category.From
+	}
+	if category.ReplyTo != "" && len(b.message.ReplyTo) == 0 {
+		b.message.ReplyTo = append(b.message.ReplyTo, EmailAddress{Address: category.ReplyTo})
+	}
+	if category.DisableTracking {
+		b.message.UserEngagementTrackingDisabled = true
+	}
+	if _, ok := b.message.Headers[importanceHeader]; category.Priority != "" && !ok {
+		b.Priority(category.Priority)
+	}
+}
+
+// newCategoryLimiters returns the rate limiters of the categories with a send rate limit
+func newCategoryLimiters(categories map[string]*CategoryOptions) map[string]*rateLimiter {
+	limiters := map[string]*rateLimiter{}
+	for name, category := range categories {
+		if category == nil {
+			continue
+		}
+		if limiter := newRateLimiter(category.MaxRequestsPerSecond, 0); limiter != nil {
+			limiters[name] = limiter
+		}
+	}
+	return limiters
+}
+
+// waitForCategory blocks until the send rate limit of the message's category allows another send
+func (c *Client) waitForCategory(ctx context.Context, message *EmailMessage) error {
+	return c.categoryLimiters[message.Category].wait(ctx)
+}
@@ -1,14 +1,14 @@
 package azemailsender
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
-	"time"
+	"sync"
+
+	"github.com/groovy-sky/azemailsender/hmacauth"
 )
 
 // Client represents the Azure Communication Services Email client
@@ -19,6 +19,19 @@ type Client struct {
 	options    *ClientOptions
 	httpClient *http.Client
 	logger     Logger
+	audit      *auditLog
+	failover   *failoverState
+	cloud      *CloudConfiguration
+	configErr  error
+	usage      *usageTracker
+	capture    *captureWriter
+	metrics    *clientMetrics
+
+	// categoryLimiters limit the send rate of categories, by name
+	categoryLimiters map[string]*rateLimiter
+
+	middlewareMu sync.RWMutex
+	middlewares  []Middleware
 }
 
 // NewClient creates a new email client with endpoint and access key
@@ -37,23 +50,75 @@ func NewClient(endpoint, accessKey string, options *ClientOptions) *Client {
 		options.Logger = &noOpLogger{}
 	}
 
+	normalized, configErr := NormalizeEndpoint(endpoint)
+	if configErr != nil {
+		normalized = strings.TrimSuffix(endpoint, "/")
+	}
+
 	client := &Client{
-		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		endpoint:   normalized,
 		accessKey:  accessKey,
 		authMethod: AuthMethodHMAC,
 		options:    options,
 		logger:     options.Logger,
+		usage:      newUsageTracker(),
 		httpClient: &http.Client{
-			Timeout: options.HTTPTimeout,
+			Timeout:   options.HTTPTimeout,
+			Transport: newTransport(options),
 		},
 	}
 
+	if options.OnHTTPTrace != nil {
+		client.httpClient.Transport = &tracingTransport{next: client.httpClient.Transport, onTrace: options.OnHTTPTrace}
+	}
+
+	client.cloud = options.Cloud
+	if client.cloud == nil {
+		client.cloud, _ = DetectCloud(client.endpoint)
+	}
+
+	if configErr == nil {
+		configErr = client.validateConfiguration()
+	}
+	client.configErr = configErr
+
+	if len(options.FailoverEndpoints) > 0 {
+		client.failover = newFailoverState(client.endpoint, accessKey, options)
+	}
+
+	if options.AuditLog != nil && options.AuditLog.Path != "" {
+		client.audit = newAuditLog(options.AuditLog)
+	}
+
+	if options.ArchiveBCC != "" {
+		client.Use(ArchiveBCC(options.ArchiveBCC))
+	}
+
+	client.categoryLimiters = newCategoryLimiters(options.Categories)
+
+	if options.CaptureFailures != "" {
+		client.capture = newCaptureWriter(options.CaptureFailures)
+	}
+
+	if options.MeterProvider != nil {
+		client.metrics = newClientMetrics(options.MeterProvider, client.logger)
+	}
+
 	if client.options.Debug {
 		client.logger.Printf("[DEBUG] Client initialized with endpoint: %s", client.endpoint)
+		if client.configErr != nil {
+			client.logger.Printf("[DEBUG] Invalid configuration: %v", client.configErr)
+		}
 		client.logger.Printf("[DEBUG] Authentication method: HMAC-SHA256")
+		if client.cloud != nil {
+			client.logger.Printf("[DEBUG] Cloud: %s", client.cloud.Name)
+		}
 		client.logger.Printf("[DEBUG] API Version: %s", client.options.APIVersion)
 		client.logger.Printf("[DEBUG] HTTP Timeout: %v", client.options.HTTPTimeout)
 		client.logger.Printf("[DEBUG] Max Retries: %d", client.options.MaxRetries)
+		if client.failover != nil {
+			client.logger.Printf("[DEBUG] Failover endpoints: %d", len(client.options.FailoverEndpoints))
+		}
 	}
 
 	return client
@@ -76,6 +141,9 @@ func NewClientFromConnectionString(connectionString string, options *ClientOptio
 	}
 
 	client := NewClient(parsed.Endpoint, parsed.AccessKey, options)
+	if err := client.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid connection string: %w", err)
+	}
 	client.authMethod = AuthMethodConnectionString
 
 	if client.options.Debug {
@@ -102,72 +170,91 @@ func NewClientWithAccessKey(endpoint, accessKey string, options *ClientOptions)
 	return client
 }
 
-// parseConnectionString parses an Azure Communication Services connection string
+// ConnectionStringError describes which component of a connection string is malformed
+type ConnectionStringError struct {
+	Component string
+	Reason    string
+}
+
+func (e *ConnectionStringError) Error() string {
+	if e.Component == "" {
+		return e.Reason
+	}
+	return fmt.Sprintf("%s: %s", e.Component, e.Reason)
+}
+
+// parseConnectionString parses an Azure Communication Services connection string.
+// Keys are case-insensitive, components may appear in any order and surrounding
+// whitespace and empty segments (such as a trailing semicolon) are ignored
 func parseConnectionString(connectionString string) (*ParsedConnectionString, error) {
-	parts := strings.Split(connectionString, ";")
 	parsed := &ParsedConnectionString{}
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(connectionString, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		// Split on the first '=' only, access keys end with '=' padding
+		key, value, found := strings.Cut(part, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
 
-	for _, part := range parts {
-		if strings.HasPrefix(part, "endpoint=") {
-			parsed.Endpoint = strings.TrimPrefix(part, "endpoint=")
-		} else if strings.HasPrefix(part, "accesskey=") {
-			parsed.AccessKey = strings.TrimPrefix(part, "accesskey=")
+		if !found || key == "" {
+			return nil, &ConnectionStringError{Component: part, Reason: "expected key=value"}
+		}
+
+		if seen[key] {
+			return nil, &ConnectionStringError{Component: key, Reason: "specified more than once"}
+		}
+		seen[key] = true
+
+		switch key {
+		case "endpoint":
+			if value == "" {
+				return nil, &ConnectionStringError{Component: "endpoint", Reason: "value is empty"}
+			}
+			if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+				return nil, &ConnectionStringError{Component: "endpoint", Reason: fmt.Sprintf("%q is not an absolute URL", value)}
+			}
+			parsed.Endpoint = value
+		case "accesskey":
+			if value == "" {
+				return nil, &ConnectionStringError{Component: "accesskey", Reason: "value is empty"}
+			}
+			if _, err := base64.StdEncoding.DecodeString(value); err != nil {
+				return nil, &ConnectionStringError{Component: "accesskey", Reason: "value is not valid base64"}
+			}
+			parsed.AccessKey = value
 		}
 	}
 
 	if parsed.Endpoint == "" {
-		return nil, fmt.Errorf("endpoint not found in connection string")
+		return nil, &ConnectionStringError{Component: "endpoint", Reason: "not found in connection string"}
 	}
 
 	if parsed.AccessKey == "" {
-		return nil, fmt.Errorf("access key not found in connection string")
+		return nil, &ConnectionStringError{Component: "accesskey", Reason: "not found in connection string"}
 	}
 
 	return parsed, nil
 }
 
-// generateHMACSignature generates HMAC-SHA256 signature for Azure API authentication
-func (c *Client) generateHMACSignature(method, uri, host, dateHeader, contentHash string) string {
-	if c.options.Debug {
-		c.logger.Printf("[DEBUG] Generating HMAC signature")
-		c.logger.Printf("[DEBUG] Method: %s", method)
-		c.logger.Printf("[DEBUG] URI: %s", uri)
-		c.logger.Printf("[DEBUG] Host: %s", host)
-		c.logger.Printf("[DEBUG] Date: %s", dateHeader)
-		c.logger.Printf("[DEBUG] Content hash: %s", contentHash)
+// newTransport builds the HTTP transport from the TLS and proxy options
+func newTransport(options *ClientOptions) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if options.TLSConfig != nil {
+		transport.TLSClientConfig = options.TLSConfig.Clone()
 	}
-
-	// Create string to sign according to Azure Communication Services format
-	// Format: HTTP_METHOD + "\n" + path_and_query + "\n" + date + ";" + host + ";" + content-hash
-	stringToSign := fmt.Sprintf("%s\n%s\n%s;%s;%s", method, uri, dateHeader, host, contentHash)
-
-	if c.options.Debug {
-		c.logger.Printf("[DEBUG] String to sign: %s", stringToSign)
+	if options.Proxy != nil {
+		transport.Proxy = options.Proxy
 	}
-
-	// Decode the access key
-	decodedKey, err := base64.StdEncoding.DecodeString(c.accessKey)
-	if err != nil {
-		if c.options.Debug {
-			c.logger.Printf("[DEBUG] Failed to decode access key: %v", err)
-		}
-		return ""
-	}
-
-	// Create HMAC
-	h := hmac.New(sha256.New, decodedKey)
-	h.Write([]byte(stringToSign))
-	signature := base64.StdEncoding.EncodeToString(h.Sum(nil))
-
-	if c.options.Debug {
-		c.logger.Printf("[DEBUG] Generated signature: %s", signature)
-	}
-
-	return signature
+	return transport
 }
 
 // addAuthentication adds authentication headers to the HTTP request
-func (c *Client) addAuthentication(req *http.Request, body string) error {
+func (c *Client) addAuthentication(req *http.Request, body []byte, accessKey string) error {
 	if c.options.Debug {
 		c.logger.Printf("[DEBUG] Adding authentication headers (method: %v)", c.authMethod)
 	}
@@ -175,41 +262,28 @@ func (c *Client) addAuthentication(req *http.Request, body string) error {
 	switch c.authMethod {
 	case AuthMethodAccessKey:
 		// Legacy API key authentication
-		req.Header.Set("api-key", c.accessKey)
+		req.Header.Set("api-key", accessKey)
 		if c.options.Debug {
 			c.logger.Printf("[DEBUG] Added api-key header")
 		}
 	case AuthMethodHMAC, AuthMethodConnectionString:
 		// HMAC-SHA256 authentication
-		// Azure expects RFC1123 format: "Mon, 02 Jan 2006 15:04:05 GMT"
-		dateHeader := time.Now().UTC().Format("Mon, 02 Jan 2006 15:04:05 GMT")
-		req.Header.Set("Date", dateHeader)
-
-		parsedURL, err := url.Parse(req.URL.String())
+		signer, err := hmacauth.NewSigner(accessKey)
 		if err != nil {
-			return fmt.Errorf("failed to parse URL: %w", err)
+			return err
 		}
+		signer.DateHeader = c.options.SigningDateHeader
 
-		// Calculate content hash first
-		h := sha256.New()
-		h.Write([]byte(body))
-		contentHash := base64.StdEncoding.EncodeToString(h.Sum(nil))
-		req.Header.Set("x-ms-content-sha256", contentHash)
-
-		// Generate signature with the content hash
-		pathAndQuery := parsedURL.Path
-		if parsedURL.RawQuery != "" {
-			pathAndQuery += "?" + parsedURL.RawQuery
+		signature, err := signer.Sign(req, body)
+		if err != nil {
+			return err
 		}
-		signature := c.generateHMACSignature(req.Method, pathAndQuery, parsedURL.Host, dateHeader, contentHash)
-
-		authHeader := fmt.Sprintf("HMAC-SHA256 SignedHeaders=date;host;x-ms-content-sha256&Signature=%s", signature)
-		req.Header.Set("Authorization", authHeader)
 
 		if c.options.Debug {
 			c.logger.Printf("[DEBUG] Added HMAC-SHA256 authentication headers")
-			c.logger.Printf("[DEBUG] Authorization: %s", authHeader)
-			c.logger.Printf("[DEBUG] Content hash: %s", contentHash)
+			c.logger.Printf("[DEBUG] String to sign: %q", signature.StringToSign)
+			c.logger.Printf("[DEBUG] Authorization: %s", signature.Authorization)
+			c.logger.Printf("[DEBUG] Content hash: %s", signature.ContentHash)
 		}
 	default:
 		return fmt.Errorf("unsupported authentication method: %v", c.authMethod)
@@ -218,6 +292,11 @@ func (c *Client) addAuthentication(req *http.Request, body string) error {
 	return nil
 }
 
+// Cloud returns the Azure cloud the client's endpoint belongs to, or nil if it is not a known cloud
+func (c *Client) Cloud() *CloudConfiguration {
+	return c.cloud
+}
+
 // SetDebug enables or disables debug logging at runtime
 func (c *Client) SetDebug(enabled bool) {
 	c.options.Debug = enabled
@@ -0,0 +1,94 @@
+package azemailsender
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// CloudConfiguration describes an Azure cloud environment hosting Communication Services
+type CloudConfiguration struct {
+	// Name identifies the cloud
+	Name string
+
+	// EndpointSuffix is the DNS suffix of Communication Services endpoints in this cloud
+	EndpointSuffix string
+
+	// AuthScope is the Microsoft Entra ID scope used for token authentication
+	AuthScope string
+}
+
+var (
+	// AzurePublicCloud is the global Azure cloud
+	AzurePublicCloud = CloudConfiguration{
+		Name:           "AzurePublic",
+		EndpointSuffix: "communication.azure.com",
+		AuthScope:      "https://communication.azure.com/.default",
+	}
+
+	// AzureGovernmentCloud is the Azure US Government cloud
+	AzureGovernmentCloud = CloudConfiguration{
+		Name:           "AzureGovernment",
+		EndpointSuffix: "communication.azure.us",
+		AuthScope:      "https://communication.azure.us/.default",
+	}
+
+	// AzureChinaCloud is the Azure China cloud operated by 21Vianet
+	AzureChinaCloud = CloudConfiguration{
+		Name:           "AzureChina",
+		EndpointSuffix: "communication.azure.cn",
+		AuthScope:      "https://communication.azure.cn/.default",
+	}
+)
+
+// knownClouds lists the clouds endpoints are matched against
+var knownClouds = []CloudConfiguration{
+	AzurePublicCloud,
+	AzureGovernmentCloud,
+	AzureChinaCloud,
+}
+
+// DetectCloud returns the cloud an endpoint belongs to based on its domain suffix
+func DetectCloud(endpoint string) (*CloudConfiguration, bool) {
+	host := endpointHost(endpoint)
+	for i := range knownClouds {
+		if matchesCloud(host, &knownClouds[i]) {
+			cloud := knownClouds[i]
+			return &cloud, true
+		}
+	}
+	return nil, false
+}
+
+// ValidateEndpointForCloud checks that an endpoint belongs to the given cloud
+func ValidateEndpointForCloud(endpoint string, cloud *CloudConfiguration) error {
+	if cloud == nil || cloud.EndpointSuffix == "" {
+		return nil
+	}
+
+	host := endpointHost(endpoint)
+	if host == "" {
+		return fmt.Errorf("invalid endpoint %q: missing host", endpoint)
+	}
+
+	if !matchesCloud(host, cloud) {
+		return fmt.Errorf("endpoint %q does not belong to cloud %s (expected *.%s)", endpoint, cloud.Name, cloud.EndpointSuffix)
+	}
+
+	return nil
+}
+
+// endpointHost extracts the lower-cased host name from an endpoint URL
+func endpointHost(endpoint string) string {
+	parsed, err := url.Parse(strings.TrimSpace(endpoint))
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
+
+// matchesCloud reports whether a host name ends with the cloud's endpoint suffix
+func matchesCloud(host string, cloud *CloudConfiguration) bool {
+	suffix := strings.ToLower(cloud.EndpointSuffix)
+	return host == suffix || strings.HasSuffix(host, "."+suffix)
+}
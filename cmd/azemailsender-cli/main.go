@@ -5,6 +5,8 @@ import (
 	"os"
 
 	"github.com/groovy-sky/azemailsender/internal/cli/commands"
+	"github.com/groovy-sky/azemailsender/internal/cli/output"
+	"github.com/groovy-sky/azemailsender/internal/simpleconfig"
 	"github.com/groovy-sky/azemailsender/internal/simplecli"
 )
 
@@ -12,6 +14,9 @@ var (
 	version = "dev"
 	commit  = "none"
 	date    = "unknown"
+
+	// envPrefix namespaces the environment variables, e.g. -X main.envPrefix=MYAPP_EMAIL_
+	envPrefix = "AZURE_EMAIL_"
 )
 
 func main() {
@@ -21,6 +26,12 @@ func main() {
 Supports multiple authentication methods, flexible recipient management,
 and both plain text and HTML email content.`)
 
+	app.Version = version
+	app.Commit = commit
+	app.Date = date
+	app.EnvPrefix = envPrefix
+	simpleconfig.EnvPrefix = envPrefix
+
 	// Add global flags
 	app.AddGlobalFlag(&simplecli.Flag{
 		Name:        "config",
@@ -28,12 +39,38 @@ and both plain text and HTML email content.`)
 		Description: "Configuration file path",
 		Value:       "",
 	})
+	app.AddGlobalFlag(&simplecli.Flag{
+		Name:        "profile",
+		Description: "Configuration profile to use",
+		Value:       "",
+		Complete:    commands.CompleteProfiles,
+	})
+	app.AddGlobalFlag(&simplecli.Flag{
+		Name:        "config-from",
+		Description: "Read config keys from mounted Kubernetes ConfigMap and Secret directories: k8s or k8s:DIR[,DIR...]",
+		Value:       "",
+	})
+	app.AddGlobalFlag(&simplecli.Flag{
+		Name:        "key-file",
+		Description: fmt.Sprintf("File whose contents are the key of encrypted config values (default: %s, then %s)", simpleconfig.EnvName("CONFIG_KEY_FILE"), simpleconfig.EnvName("CONFIG_PASSPHRASE")),
+		Value:       "",
+	})
 	app.AddGlobalFlag(&simplecli.Flag{
 		Name:        "debug",
 		Short:       "d",
 		Description: "Enable debug logging",
 		Value:       false,
 	})
+	app.AddGlobalFlag(&simplecli.Flag{
+		Name:        "trace",
+		Description: "Print one line per HTTP request (method, URL, status, duration, request IDs) to stderr",
+		Value:       false,
+	})
+	app.AddGlobalFlag(&simplecli.Flag{
+		Name:        "log-file",
+		Description: "Write debug and trace output to a file instead of stdout and stderr, rotated at 10 MB",
+		Value:       "",
+	})
 	app.AddGlobalFlag(&simplecli.Flag{
 		Name:        "quiet",
 		Short:       "q",
@@ -46,17 +83,49 @@ and both plain text and HTML email content.`)
 		Description: "Output in JSON format",
 		Value:       false,
 	})
+	app.AddGlobalFlag(&simplecli.Flag{
+		Name:        "no-color",
+		Description: "Disable colored output (also disabled by NO_COLOR or when not a terminal)",
+		Value:       false,
+	})
+	app.AddGlobalFlag(&simplecli.Flag{
+		Name:        "output",
+		Description: "Output format: text, json, table, yaml, go-template=TEMPLATE, go-template-file=FILE or jsonpath=EXPRESSION",
+		Value:       "",
+		Complete: func(map[string]interface{}) []string {
+			return []string{"text", "json", "table", "yaml", "go-template=", "go-template-file=", "jsonpath="}
+		},
+	})
 
 	// Add all commands
 	app.AddCommand(commands.NewVersionCommand(version, commit, date))
 	app.AddCommand(commands.NewConfigCommand())
 	app.AddCommand(commands.NewStatusCommand())
 	app.AddCommand(commands.NewSendCommand())
+	app.AddCommand(commands.NewSendBatchCommand())
+	app.AddCommand(commands.NewTemplateCommand())
+	app.AddCommand(commands.NewTemplatesCommand())
+	app.AddCommand(commands.NewValidateCommand())
+	app.AddCommand(commands.NewDoctorCommand())
+	app.AddCommand(commands.NewHistoryCommand())
+	app.AddCommand(commands.NewQueueCommand())
+	app.AddCommand(commands.NewDaemonCommand())
+	app.AddCommand(commands.NewCompletionCommand())
+	app.AddCommand(commands.NewDocsCommand())
 
 
 
 	if err := app.Run(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		flags := app.LastFlags()
+		jsonOutput, _ := flags["json"].(bool)
+		spec, _ := flags["output"].(string)
+		jsonOutput = jsonOutput || output.StructuredOutput(spec)
+		formatter := output.NewFormatter(jsonOutput, false, false)
+		if noColor, _ := flags["no-color"].(bool); noColor {
+			formatter.Color = false
+		}
+		cliErr := commands.Classify(err)
+		formatter.PrintError(cliErr)
+		os.Exit(cliErr.ExitCode())
 	}
 }
\ No newline at end of file
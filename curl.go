@@ -0,0 +1,73 @@
+package azemailsender
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// curlKeyPlaceholder replaces the access key in exported curl commands
+const curlKeyPlaceholder = "<access-key>"
+
+// CurlCommand renders the signed send request for message as a curl command.
+// HMAC signatures are included as-is (they do not reveal the key) and stay valid
+// only for a few minutes; a legacy api-key header is replaced with a placeholder
+func (c *Client) CurlCommand(ctx context.Context, message *EmailMessage) (string, error) {
+	message, err := c.prepareMessage(ctx, message)
+	if err != nil {
+		return "", err
+	}
+	buf, apiVersion, err := c.prepareSend(message)
+	if err != nil {
+		return "", err
+	}
+	defer releaseBodyBuffer(buf)
+	body := buf.Bytes()
+
+	requestID := message.ClientRequestID
+	if requestID == "" {
+		requestID = newClientRequestID()
+	}
+
+	endpoint, accessKey := c.activeEndpoint()
+	url := fmt.Sprintf("%s/emails:send?api-version=%s", endpoint, apiVersion)
+
+	req, err := c.newSendRequest(ctx, url, body, accessKey, requestID)
+	if err != nil {
+		return "", err
+	}
+
+	return curlCommand(req, body), nil
+}
+
+// curlCommand renders req and its body as a shell-quoted curl command
+func curlCommand(req *http.Request, body []byte) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "curl -X %s %s", req.Method, shellQuote(req.URL.String()))
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := strings.Join(req.Header[name], ", ")
+		if strings.EqualFold(name, "api-key") {
+			value = curlKeyPlaceholder
+		}
+		fmt.Fprintf(&b, " \\\n  -H %s", shellQuote(name+": "+value))
+	}
+
+	if len(body) > 0 {
+		fmt.Fprintf(&b, " \\\n  --data-raw %s", shellQuote(string(body)))
+	}
+	return b.String()
+}
+
+// shellQuote quotes s for POSIX shells
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,270 @@
+package azemailsender
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultDedupeWindow is how long identical messages are suppressed when
+// ClientOptions.DedupeWindow is zero
+const DefaultDedupeWindow = time.Hour
+
+// DedupeStore remembers the messages sent recently, so identical ones can be
+// suppressed
+type DedupeStore interface {
+	// Reserve records key unless it was recorded within window, in which case
+	// it reports a duplicate and when key was recorded
+	Reserve(ctx context.Context, key string, window time.Duration) (first time.Time, duplicate bool, err error)
+	// Release forgets key, so a message that failed to send can be sent again
+	Release(ctx context.Context, key string) error
+}
+
+// DuplicateError is returned when a message identical to one sent within the
+// dedupe window is suppressed
+type DuplicateError struct {
+	Key       string
+	FirstSent time.Time
+	Window    time.Duration
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("duplicate of a message sent at %s suppressed (dedupe window %s)", e.FirstSent.Format(time.RFC3339), e.Window)
+}
+
+// DedupeKey returns the key identifying message for deduplication: a hash of
+// its recipients, in any order and case, its subject and its content
+func DedupeKey(message *EmailMessage) string {
+	var recipients []string
+	for _, list := range [][]EmailAddress{message.Recipients.To, message.Recipients.Cc, message.Recipients.Bcc} {
+		for _, recipient := range list {
+			recipients = append(recipients, strings.ToLower(strings.TrimSpace(recipient.Address)))
+		}
+	}
+	sort.Strings(recipients)
+
+	hash := sha256.New()
+	for _, part := range []string{strings.Join(recipients, ","), message.Content.Subject, message.Content.Html, message.Content.PlainText} {
+		// Length prefixes keep the boundaries between the parts unambiguous
+		fmt.Fprintf(hash, "%d:%s\n", len(part), part)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// DirDedupeStore is a DedupeStore keeping one file per key in a directory,
+// so separate processes, such as one CLI run per alert, share it
+type DirDedupeStore struct {
+	dir string
+}
+
+// OpenDedupeStore opens the dedupe store in dir, creating the directory if needed
+func OpenDedupeStore(dir string) (*DirDedupeStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create dedupe store %s: %w", dir, err)
+	}
+	return &DirDedupeStore{dir: dir}, nil
+}
+
+// Dir returns the directory of the store
+func (s *DirDedupeStore) Dir() string {
+	return s.dir
+}
+
+// path returns the file of a key
+func (s *DirDedupeStore) path(key string) (string, error) {
+	if key == "" || strings.ContainsAny(key, `/\.`) {
+		return "", fmt.Errorf("invalid dedupe key %q", key)
+	}
+	return filepath.Join(s.dir, key), nil
+}
+
+// Reserve implements DedupeStore. The first process to create the file of a
+// key wins. A record older than window is replaced under a lock file, so of
+// several processes finding it expired only one sends
+func (s *DirDedupeStore) Reserve(ctx context.Context, key string, window time.Duration) (time.Time, bool, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	now := time.Now().UTC()
+	record := []byte(now.Format(time.RFC3339Nano) + "\n")
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err == nil {
+		defer file.Close()
+		if _, err := file.Write(record); err != nil {
+			return time.Time{}, false, fmt.Errorf("failed to write dedupe record: %w", err)
+		}
+		return now, false, nil
+	}
+	if !errors.Is(err, fs.ErrExist) {
+		return time.Time{}, false, fmt.Errorf("failed to write dedupe record: %w", err)
+	}
+
+	first, err := s.recorded(path)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if now.Sub(first) < window {
+		return first, true, nil
+	}
+
+	// Another process replacing the record reserves the key for itself
+	locked, since, err := s.lock(path, now)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if !locked {
+		return since, true, nil
+	}
+	defer os.Remove(path + dedupeLockSuffix)
+
+	// The record may have been replaced before the lock was taken
+	if first, err := s.recorded(path); err == nil && now.Sub(first) < window {
+		return first, true, nil
+	}
+	tmp := path + dedupeTempSuffix
+	if err := os.WriteFile(tmp, record, 0600); err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to write dedupe record: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return time.Time{}, false, fmt.Errorf("failed to write dedupe record: %w", err)
+	}
+	return now, false, nil
+}
+
+// Suffixes of the lock and temporary files next to a record; keys can't
+// contain a dot, so they never clash with a key
+const (
+	dedupeLockSuffix = ".lock"
+	dedupeTempSuffix = ".tmp"
+)
+
+// dedupeLockTimeout is the age beyond which a lock is taken to be left by a
+// process that died while replacing a record
+const dedupeLockTimeout = time.Minute
+
+// lock takes the lock file of the record at path. When another process holds
+// it, lock returns false and when that process took it
+func (s *DirDedupeStore) lock(path string, now time.Time) (bool, time.Time, error) {
+	lockPath := path + dedupeLockSuffix
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			file.Close()
+			return true, now, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return false, time.Time{}, fmt.Errorf("failed to lock dedupe record: %w", err)
+		}
+		info, err := os.Stat(lockPath)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return false, time.Time{}, fmt.Errorf("failed to lock dedupe record: %w", err)
+		}
+		if now.Sub(info.ModTime()) < dedupeLockTimeout {
+			return false, info.ModTime(), nil
+		}
+		os.Remove(lockPath)
+	}
+	return false, now, nil
+}
+
+// recorded reads the time of a record, falling back to the file's modification
+// time for records that can't be read
+func (s *DirDedupeStore) recorded(path string) (time.Time, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read dedupe record: %w", err)
+	}
+	if t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(data))); err == nil {
+		return t, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read dedupe record: %w", err)
+	}
+	return info.ModTime(), nil
+}
+
+// Release implements DedupeStore
+func (s *DirDedupeStore) Release(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove dedupe record: %w", err)
+	}
+	return nil
+}
+
+// Prune removes the records made before cutoff and returns how many it removed
+func (s *DirDedupeStore) Prune(cutoff time.Time) (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read dedupe store %s: %w", s.dir, err)
+	}
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), dedupeLockSuffix) || strings.HasSuffix(entry.Name(), dedupeTempSuffix) {
+			continue
+		}
+		path := filepath.Join(s.dir, entry.Name())
+		first, err := s.recorded(path)
+		if err != nil || !first.Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(path); err == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// reserveDedupe suppresses message when an identical one was sent within the
+// dedupe window. It returns the key to release when the send fails, empty
+// when deduplication is off
+func (c *Client) reserveDedupe(ctx context.Context, message *EmailMessage) (string, error) {
+	if c.options.Dedupe == nil {
+		return "", nil
+	}
+	window := c.options.DedupeWindow
+	if window <= 0 {
+		window = DefaultDedupeWindow
+	}
+
+	key := DedupeKey(message)
+	first, duplicate, err := c.options.Dedupe.Reserve(ctx, key, window)
+	if err != nil {
+		return "", fmt.Errorf("dedupe check failed: %w", err)
+	}
+	if duplicate {
+		if c.options.Debug {
+			c.logger.Printf("[DEBUG] Suppressed duplicate of the message sent at %s", first.Format(time.RFC3339))
+		}
+		return "", &DuplicateError{Key: key, FirstSent: first, Window: window}
+	}
+	return key, nil
+}
+
+// releaseDedupe forgets a message that failed to send
+func (c *Client) releaseDedupe(ctx context.Context, key string) {
+	if key == "" {
+		return
+	}
+	if err := c.options.Dedupe.Release(context.WithoutCancel(ctx), key); err != nil {
+		c.logger.Printf("[WARN] %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+package azemailsender
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+)
+
+// ErrMissingDigestKey is returned by AddDigest without a digest key
+var ErrMissingDigestKey = errors.New("digest key is required")
+
+// DigestFunc merges the messages collected for a recipient under a digest key
+// into the email that is sent. Messages are in the order they were added
+type DigestFunc func(key string, recipient EmailAddress, messages []*EmailMessage) (*EmailMessage, error)
+
+// AddDigest stores message for a digest instead of sending it on its own. Each
+// recipient, whether To, Cc or Bcc, gets a copy addressed to them alone. The
+// first message for a recipient and key opens a window; Deliver sends all the
+// messages added for them within it as one email once it closes, merged by
+// OutboxOptions.Digest. Chatty notifications thus reach a recipient at most
+// once per window
+func (o *Outbox) AddDigest(message *EmailMessage, key string, window time.Duration) ([]*OutboxItem, error) {
+	if key == "" {
+		return nil, ErrMissingDigestKey
+	}
+
+	var recipients []EmailAddress
+	recipients = append(recipients, message.Recipients.To...)
+	recipients = append(recipients, message.Recipients.Cc...)
+	recipients = append(recipients, message.Recipients.Bcc...)
+	if len(recipients) == 0 {
+		return nil, ErrNoRecipients
+	}
+
+	closes := time.Now().Add(window)
+	var items []*OutboxItem
+	for _, recipient := range recipients {
+		single := *message
+		single.Recipients = EmailRecipients{To: []EmailAddress{recipient}}
+		single.ClientRequestID = ""
+		item, err := newOutboxItem(&single, time.Time{})
+		if err != nil {
+			return items, err
+		}
+		item.DigestKey = key
+		item.DigestRecipient = strings.ToLower(recipient.Address)
+
+		if err := o.addToDigest(item, closes); err != nil {
+			return items, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// addToDigest stores an item in the open window of its digest, or opens one
+// that closes at closes
+func (o *Outbox) addToDigest(item *OutboxItem, closes time.Time) error {
+	unlock, err := o.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	pending, err := o.list(OutboxPending)
+	if err != nil {
+		return err
+	}
+	sendAt := closes.UTC()
+	for _, other := range pending {
+		if other.DigestKey == item.DigestKey && other.DigestRecipient == item.DigestRecipient && other.NextAttemptAt != nil && other.NextAttemptAt.After(time.Now()) {
+			sendAt = *other.NextAttemptAt
+			break
+		}
+	}
+	item.NextAttemptAt = &sendAt
+	return o.write(item)
+}
+
+// digestGroup returns the due items of the digest of item, oldest first
+func digestGroup(due []*OutboxItem, item *OutboxItem, now time.Time) []*OutboxItem {
+	var group []*OutboxItem
+	for _, other := range due {
+		if other.DigestKey != item.DigestKey || other.DigestRecipient != item.DigestRecipient {
+			continue
+		}
+		if other.NextAttemptAt != nil && other.NextAttemptAt.After(now) {
+			continue
+		}
+		group = append(group, other)
+	}
+	return group
+}
+
+// deliverDigest merges the messages of a digest, sends them as one email and
+// records the outcome on every item. It returns the items it attempted
+func (o *Outbox) deliverDigest(ctx context.Context, client *Client, options *OutboxOptions, group []*OutboxItem) ([]*OutboxItem, error) {
+	var (
+		items    []*OutboxItem
+		messages []*EmailMessage
+		loadErr  error
+	)
+	unlock, err := o.lock()
+	if err != nil {
+		return nil, err
+	}
+	for _, listed := range group {
+		item, err := o.read(listed.ID)
+		if errors.Is(err, ErrOutboxItemNotFound) {
+			continue
+		}
+		if err != nil {
+			unlock()
+			return nil, err
+		}
+		if item.State != OutboxPending && item.State != OutboxFailed {
+			continue
+		}
+		message, err := item.LoadMessage()
+		if err != nil && loadErr == nil {
+			loadErr = err
+		}
+		items = append(items, item)
+		messages = append(messages, message)
+	}
+	unlock()
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	var response *SendResponse
+	sendErr := loadErr
+	if sendErr == nil {
+		merge := options.Digest
+		if merge == nil {
+			merge = MergeDigest
+		}
+		recipient := EmailAddress{Address: items[0].DigestRecipient}
+		if to := messages[0].Recipients.To; len(to) > 0 {
+			recipient = to[0]
+		}
+		var digest *EmailMessage
+		digest, sendErr = merge(items[0].DigestKey, recipient, messages)
+		if sendErr == nil {
+			if digest.ClientRequestID == "" {
+				digest.ClientRequestID = items[0].ID
+			}
+			response, sendErr = client.SendWithContext(context.WithoutCancel(ctx), digest)
+		}
+	}
+
+	unlock, err = o.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	for _, item := range items {
+		recordAttempt(item, options, response, sendErr)
+		if err := o.write(item); err != nil {
+			return items, err
+		}
+	}
+	return items, nil
+}
+
+// digestHTML lays out the messages of a digest, one section each
+var digestHTML = template.Must(template.New("digest").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family:Segoe UI,Helvetica,Arial,sans-serif;color:#1f2328;">
+{{- range $i, $m := .}}
+{{- if $i}}
+<hr style="border:none;border-top:1px solid #e1e4e8;margin:24px 0;">
+{{- end}}
+<h2 style="font-size:18px;margin:0 0 12px;">{{$m.Subject}}</h2>
+{{- if $m.HTML}}
+<div>{{$m.HTML}}</div>
+{{- else}}
+<pre style="font-family:inherit;white-space:pre-wrap;margin:0;">{{$m.Text}}</pre>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// MergeDigest is the default DigestFunc. A single message is sent as is.
+// Several are combined into one email with a section per message, titled
+// with its subject, and the subject of the first message followed by the
+// number of others. Sender, reply-to and headers come from the first message;
+// the attachments of all messages are kept
+func MergeDigest(key string, recipient EmailAddress, messages []*EmailMessage) (*EmailMessage, error) {
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("digest %s has no messages", key)
+	}
+	first := messages[0]
+	if len(messages) == 1 {
+		single := *first
+		return &single, nil
+	}
+
+	type section struct {
+		Subject string
+		HTML    template.HTML
+		Text    string
+	}
+	sections := make([]section, 0, len(messages))
+	var text strings.Builder
+	digest := &EmailMessage{
+		SenderAddress:                  first.SenderAddress,
+		Recipients:                     EmailRecipients{To: []EmailAddress{recipient}},
+		ReplyTo:                        first.ReplyTo,
+		Headers:                        first.Headers,
+		UserEngagementTrackingDisabled: first.UserEngagementTrackingDisabled,
+		APIVersion:                     first.APIVersion,
+		SuppressionCheck:               first.SuppressionCheck,
+		Category:                       first.Category,
+	}
+	digest.Content.Subject = fmt.Sprintf("%s (+%d more)", first.Content.Subject, len(messages)-1)
+
+	for i, message := range messages {
+		// The HTML of each message is trusted as it would be sent on its own
+		sections = append(sections, section{
+			Subject: message.Content.Subject,
+			HTML:    template.HTML(message.Content.Html),
+			Text:    message.Content.PlainText,
+		})
+		if i > 0 {
+			text.WriteString("\n\n")
+		}
+		text.WriteString(message.Content.Subject + "\n" + strings.Repeat("=", len([]rune(message.Content.Subject))) + "\n")
+		if message.Content.PlainText != "" {
+			text.WriteString(strings.TrimRight(message.Content.PlainText, "\n") + "\n")
+		}
+		digest.Attachments = append(digest.Attachments, message.Attachments...)
+	}
+
+	var html bytes.Buffer
+	if err := digestHTML.Execute(&html, sections); err != nil {
+		return nil, fmt.Errorf("failed to render digest: %w", err)
+	}
+	digest.Content.Html = html.String()
+	digest.Content.PlainText = text.String()
+	return digest, nil
+}
@@ -0,0 +1,143 @@
+package azemailsender
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WriteEML writes message as an RFC 5322 email, the .eml format understood by
+// mail clients. Bcc recipients are left out of the headers, as a mail server would
+func WriteEML(w io.Writer, message *EmailMessage) error {
+	return writeEML(w, message, newMessageID(message.SenderAddress))
+}
+
+// newMessageID returns a Message-ID in the domain of the sender
+func newMessageID(sender string) string {
+	domain := "azemailsender.local"
+	if _, host, ok := strings.Cut(sender, "@"); ok && host != "" {
+		domain = host
+	}
+	return fmt.Sprintf("<%s@%s>", newClientRequestID(), domain)
+}
+
+// writeEML writes message with the given Message-ID
+func writeEML(w io.Writer, message *EmailMessage, messageID string) error {
+	message, err := materializeAttachments(message)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+		}
+	}
+	header("From", formatAddresses([]EmailAddress{{Address: message.SenderAddress}}))
+	header("To", formatAddresses(message.Recipients.To))
+	header("Cc", formatAddresses(message.Recipients.Cc))
+	header("Reply-To", formatAddresses(message.ReplyTo))
+	header("Subject", mime.QEncoding.Encode("utf-8", message.Content.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID)
+
+	names := make([]string, 0, len(message.Headers))
+	for name := range message.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		header(name, mime.QEncoding.Encode("utf-8", message.Headers[name]))
+	}
+	header("MIME-Version", "1.0")
+
+	body, contentType := emlContent(message.Content)
+	if len(message.Attachments) == 0 {
+		header("Content-Type", contentType)
+		buf.WriteString(body)
+	} else {
+		boundary := newBoundary()
+		header("Content-Type", fmt.Sprintf("multipart/mixed; boundary=%q", boundary))
+		fmt.Fprintf(&buf, "\r\n--%s\r\nContent-Type: %s\r\n%s", boundary, contentType, body)
+		for _, attachment := range message.Attachments {
+			fmt.Fprintf(&buf, "\r\n--%s\r\n", boundary)
+			writeEMLAttachment(&buf, attachment)
+		}
+		fmt.Fprintf(&buf, "\r\n--%s--\r\n", boundary)
+	}
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+// emlContent returns the body of the message content, starting with the
+// headers that follow Content-Type, and its content type
+func emlContent(content EmailContent) (string, string) {
+	switch {
+	case content.Html == "":
+		return quotedPrintable(content.PlainText), `text/plain; charset="utf-8"`
+	case content.PlainText == "":
+		return quotedPrintable(content.Html), `text/html; charset="utf-8"`
+	}
+
+	boundary := newBoundary()
+	body := fmt.Sprintf("\r\n--%[1]s\r\nContent-Type: text/plain; charset=\"utf-8\"\r\n%[2]s\r\n--%[1]s\r\nContent-Type: text/html; charset=\"utf-8\"\r\n%[3]s\r\n--%[1]s--\r\n",
+		boundary, quotedPrintable(content.PlainText), quotedPrintable(content.Html))
+	return body, fmt.Sprintf("multipart/alternative; boundary=%q", boundary)
+}
+
+// quotedPrintable encodes text, preceded by its Content-Transfer-Encoding header
+func quotedPrintable(text string) string {
+	var buf bytes.Buffer
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(&buf)
+	qp.Write([]byte(text))
+	qp.Close()
+	return buf.String()
+}
+
+// writeEMLAttachment writes an attachment part, inline when it has a content ID
+func writeEMLAttachment(buf *bytes.Buffer, attachment EmailAttachment) {
+	disposition := "attachment"
+	if attachment.ContentID != "" {
+		disposition = "inline"
+		fmt.Fprintf(buf, "Content-ID: <%s>\r\n", attachment.ContentID)
+	}
+	fmt.Fprintf(buf, "Content-Type: %s\r\n", mime.FormatMediaType(attachment.ContentType, map[string]string{"name": attachment.Name}))
+	fmt.Fprintf(buf, "Content-Disposition: %s\r\n", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Name}))
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	content := attachment.ContentInBase64
+	for len(content) > 76 {
+		buf.WriteString(content[:76] + "\r\n")
+		content = content[76:]
+	}
+	buf.WriteString(content + "\r\n")
+}
+
+// formatAddresses formats an address list for a header, encoding display names as needed
+func formatAddresses(addresses []EmailAddress) string {
+	formatted := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		formatted = append(formatted, (&mail.Address{Name: address.DisplayName, Address: address.Address}).String())
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// newBoundary returns a random MIME boundary
+func newBoundary() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return "azemailsender-" + hex.EncodeToString(b)
+}
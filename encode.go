@@ -0,0 +1,124 @@
+package azemailsender
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// maxPooledBufferSize keeps unusually large request buffers out of the pool
+const maxPooledBufferSize = 32 << 20
+
+// bodyBufferPool reuses request body buffers between sends
+var bodyBufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// encodeMessage streams the JSON encoding of message into a pooled buffer.
+// Unlike json.Marshal this does not copy the encoded body, which matters for
+// messages carrying megabytes of base64 attachment content
+func encodeMessage(message *EmailMessage) (*bytes.Buffer, error) {
+	buf := bodyBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+
+	var err error
+	if hasReaderAttachments(message) {
+		err = encodeWithReaders(buf, message)
+	} else if err = json.NewEncoder(buf).Encode(message); err == nil {
+		// Encode terminates the value with a newline that is not part of the signed body
+		buf.Truncate(buf.Len() - 1)
+	}
+	if err != nil {
+		releaseBodyBuffer(buf)
+		return nil, err
+	}
+	return buf, nil
+}
+
+// hasReaderAttachments reports whether any attachment is read lazily
+func hasReaderAttachments(message *EmailMessage) bool {
+	for _, attachment := range message.Attachments {
+		if attachment.Reader != nil && attachment.ContentInBase64 == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeWithReaders encodes message with placeholders for reader attachments, then
+// splices in their content, base64 encoding it straight from the reader into buf
+func encodeWithReaders(buf *bytes.Buffer, message *EmailMessage) error {
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	skeleton := *message
+	skeleton.Attachments = append([]EmailAttachment(nil), message.Attachments...)
+
+	var placeholders []string
+	var readers []*EmailAttachment
+	for i := range skeleton.Attachments {
+		attachment := &message.Attachments[i]
+		if attachment.Reader == nil || attachment.ContentInBase64 != "" {
+			continue
+		}
+		placeholder := fmt.Sprintf("azemailsender-attachment-%s-%d", hex.EncodeToString(nonce), i)
+		skeleton.Attachments[i].ContentInBase64 = placeholder
+		placeholders = append(placeholders, `"`+placeholder+`"`)
+		readers = append(readers, attachment)
+	}
+
+	encoded, err := json.Marshal(&skeleton)
+	if err != nil {
+		return err
+	}
+
+	rest := encoded
+	for i, placeholder := range placeholders {
+		pos := bytes.Index(rest, []byte(placeholder))
+		if pos < 0 {
+			return fmt.Errorf("attachment %s: placeholder not found in encoded message", readers[i].Name)
+		}
+		buf.Write(rest[:pos+1])
+		if err := streamAttachment(buf, readers[i]); err != nil {
+			return err
+		}
+		rest = rest[pos+len(placeholder)-1:]
+	}
+	buf.Write(rest)
+	return nil
+}
+
+// streamAttachment base64 encodes the attachment reader into buf
+func streamAttachment(buf *bytes.Buffer, attachment *EmailAttachment) error {
+	if closer, ok := attachment.Reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	encoder := base64.NewEncoder(base64.StdEncoding, buf)
+	n, err := io.Copy(encoder, attachment.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to read attachment %s: %w", attachment.Name, err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to encode attachment %s: %w", attachment.Name, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("attachment %s is empty (readers can only be sent once)", attachment.Name)
+	}
+	return nil
+}
+
+// releaseBodyBuffer returns buf to the pool once no request reads from it anymore
+func releaseBodyBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	bodyBufferPool.Put(buf)
+}
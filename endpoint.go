@@ -0,0 +1,100 @@
+package azemailsender
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// ConfigError is returned when the client configuration is invalid
+type ConfigError struct {
+	Field  string
+	Value  string
+	Reason string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid %s %q: %s", e.Field, e.Value, e.Reason)
+}
+
+// NormalizeEndpoint validates an Azure Communication Services endpoint and returns it
+// in canonical form. The endpoint must be an https URL without path, query or fragment;
+// plain http is only accepted for loopback hosts used in local testing
+func NormalizeEndpoint(endpoint string) (string, error) {
+	trimmed := strings.TrimSpace(endpoint)
+	if trimmed == "" {
+		return "", &ConfigError{Field: "endpoint", Value: endpoint, Reason: "endpoint is required"}
+	}
+
+	parsed, err := url.Parse(trimmed)
+	if err != nil {
+		return "", &ConfigError{Field: "endpoint", Value: endpoint, Reason: err.Error()}
+	}
+
+	scheme := strings.ToLower(parsed.Scheme)
+	host := strings.ToLower(parsed.Host)
+
+	if host == "" {
+		return "", &ConfigError{Field: "endpoint", Value: endpoint, Reason: "must be an absolute URL such as https://<resource>.communication.azure.com"}
+	}
+
+	switch scheme {
+	case "https":
+	case "http":
+		if !isLoopbackHost(parsed.Hostname()) {
+			return "", &ConfigError{Field: "endpoint", Value: endpoint, Reason: "scheme must be https"}
+		}
+	default:
+		return "", &ConfigError{Field: "endpoint", Value: endpoint, Reason: "scheme must be https"}
+	}
+
+	if parsed.User != nil {
+		return "", &ConfigError{Field: "endpoint", Value: endpoint, Reason: "must not contain credentials"}
+	}
+	if parsed.Path != "" && parsed.Path != "/" {
+		return "", &ConfigError{Field: "endpoint", Value: endpoint, Reason: "must not contain a path"}
+	}
+	if parsed.RawQuery != "" || parsed.Fragment != "" {
+		return "", &ConfigError{Field: "endpoint", Value: endpoint, Reason: "must not contain a query or fragment"}
+	}
+
+	return scheme + "://" + host, nil
+}
+
+// isLoopbackHost reports whether a host name refers to the local machine
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// validateConfiguration checks the endpoints the client was created with
+func (c *Client) validateConfiguration() error {
+	if err := ValidateEndpointForCloud(c.endpoint, c.options.Cloud); err != nil {
+		return &ConfigError{Field: "endpoint", Value: c.endpoint, Reason: err.Error()}
+	}
+
+	if c.options.ArchiveBCC != "" && !isValidEmail(c.options.ArchiveBCC) {
+		return &ConfigError{Field: "archive BCC", Value: c.options.ArchiveBCC, Reason: "not a valid email address"}
+	}
+
+	for _, secondary := range c.options.FailoverEndpoints {
+		if _, err := NormalizeEndpoint(secondary.URL); err != nil {
+			return err
+		}
+		if err := ValidateEndpointForCloud(secondary.URL, c.options.Cloud); err != nil {
+			return &ConfigError{Field: "endpoint", Value: secondary.URL, Reason: err.Error()}
+		}
+	}
+
+	return nil
+}
+
+// Validate returns the configuration error detected when the client was created, if any.
+// Send and status requests fail with the same error
+func (c *Client) Validate() error {
+	return c.configErr
+}
@@ -0,0 +1,29 @@
+package main
+
+// An Azure Functions custom handler sending email. host.json points the host at
+// the binary and forwards HTTP triggers as they are:
+//
+//	{"version": "2.0", "customHandler": {"description": {"defaultExecutablePath": "handler"}, "enableForwardingHttpRequest": true}}
+//
+// send/function.json is an HTTP trigger on POST /api/send, and
+// send-queued/function.json a queue trigger named "message" on the
+// "outgoing-email" queue. Both take a message written by SaveMessage.
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"github.com/groovy-sky/azemailsender"
+	"github.com/groovy-sky/azemailsender/azfunc"
+)
+
+func main() {
+	client := azemailsender.NewClient(os.Getenv("ACS_ENDPOINT"), os.Getenv("ACS_ACCESS_KEY"), nil)
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/send", azfunc.HTTPHandler(client, nil))
+	mux.Handle("/send-queued", azfunc.QueueHandler(client, nil))
+
+	log.Printf("Listening on %s", azfunc.Addr())
+	log.Fatal(http.ListenAndServe(azfunc.Addr(), mux))
+}
@@ -0,0 +1,115 @@
+package azemailsender
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// failoverState tracks which endpoint is active and how often it has failed
+type failoverState struct {
+	mu        sync.Mutex
+	endpoints []Endpoint
+	active    int
+	failures  int
+	threshold int
+}
+
+// newFailoverState creates failover state with the primary endpoint first
+func newFailoverState(endpoint, accessKey string, options *ClientOptions) *failoverState {
+	endpoints := []Endpoint{{URL: endpoint, AccessKey: accessKey}}
+	for _, secondary := range options.FailoverEndpoints {
+		normalized, err := NormalizeEndpoint(secondary.URL)
+		if err != nil {
+			normalized = strings.TrimSuffix(secondary.URL, "/")
+		}
+		endpoints = append(endpoints, Endpoint{
+			URL:       normalized,
+			AccessKey: secondary.AccessKey,
+		})
+	}
+
+	threshold := options.FailoverThreshold
+	if threshold <= 0 {
+		threshold = 1
+	}
+
+	return &failoverState{
+		endpoints: endpoints,
+		threshold: threshold,
+	}
+}
+
+// activeEndpoint returns the endpoint and access key requests should currently use
+func (c *Client) activeEndpoint() (string, string) {
+	if c.failover == nil {
+		return c.endpoint, c.accessKey
+	}
+
+	c.failover.mu.Lock()
+	defer c.failover.mu.Unlock()
+
+	active := c.failover.endpoints[c.failover.active]
+	return active.URL, active.AccessKey
+}
+
+// ActiveEndpoint returns the endpoint the client is currently sending to
+func (c *Client) ActiveEndpoint() string {
+	endpoint, _ := c.activeEndpoint()
+	return endpoint
+}
+
+// recordEndpointResult counts consecutive failures and switches to the next
+// endpoint once the failover threshold is reached
+func (c *Client) recordEndpointResult(err error) {
+	if c.failover == nil {
+		return
+	}
+
+	// Requests the service rejected as invalid would fail on every endpoint
+	if err != nil && !isEndpointFailure(err) {
+		return
+	}
+
+	c.failover.mu.Lock()
+
+	if err == nil {
+		c.failover.failures = 0
+		c.failover.mu.Unlock()
+		return
+	}
+
+	c.failover.failures++
+	if c.failover.failures < c.failover.threshold {
+		c.failover.mu.Unlock()
+		return
+	}
+
+	from := c.failover.endpoints[c.failover.active].URL
+	c.failover.active = (c.failover.active + 1) % len(c.failover.endpoints)
+	c.failover.failures = 0
+	to := c.failover.endpoints[c.failover.active].URL
+	c.failover.mu.Unlock()
+
+	if c.options.Debug {
+		c.logger.Printf("[DEBUG] Failing over from %s to %s after error: %v", from, to, err)
+	}
+
+	if c.options.OnFailover != nil {
+		c.options.OnFailover(from, to, err)
+	}
+}
+
+// isEndpointFailure reports whether an error indicates a problem with the endpoint
+// itself (transport errors, throttling, server errors) rather than with the request
+func isEndpointFailure(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return true
+	}
+
+	return apiErr.StatusCode >= 500 ||
+		apiErr.StatusCode == http.StatusRequestTimeout ||
+		apiErr.StatusCode == http.StatusTooManyRequests
+}
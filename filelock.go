@@ -0,0 +1,44 @@
+package azemailsender
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// fileLockTimeout is the age beyond which a lock file is taken to be left by a
+// process that crashed while holding it
+const fileLockTimeout = time.Minute
+
+// fileLockRetry is how often a lock file held by another process is tried again
+const fileLockRetry = 10 * time.Millisecond
+
+// lockFile takes the lock file at path, waiting while another process holds
+// it, and returns the function that releases it. Locks older than
+// fileLockTimeout are broken, so a crashed process doesn't block others for good
+func lockFile(path string) (func(), error) {
+	for {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+
+		info, err := os.Stat(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		case time.Since(info.ModTime()) >= fileLockTimeout:
+			os.Remove(path)
+			continue
+		}
+		time.Sleep(fileLockRetry)
+	}
+}
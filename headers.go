@@ -0,0 +1,52 @@
+package azemailsender
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// InvalidHeaderError reports a custom header that cannot be sent
+type InvalidHeaderError struct {
+	Name   string
+	Reason string
+}
+
+func (e *InvalidHeaderError) Error() string {
+	return fmt.Sprintf("invalid header %q: %s", e.Name, e.Reason)
+}
+
+// validateHeaders checks custom header names and values, in name order
+func validateHeaders(headers map[string]string) []error {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []error
+	for _, name := range names {
+		switch {
+		case name == "":
+			problems = append(problems, &InvalidHeaderError{Name: name, Reason: "name is empty"})
+		case !isHeaderToken(name):
+			problems = append(problems, &InvalidHeaderError{Name: name, Reason: "name may only contain letters, digits and !#$%&'*+-.^_`|~"})
+		case strings.ContainsAny(headers[name], "\r\n"):
+			problems = append(problems, &InvalidHeaderError{Name: name, Reason: "value contains a line break"})
+		}
+	}
+	return problems
+}
+
+// isHeaderToken reports whether name is a valid RFC 7230 token
+func isHeaderToken(name string) bool {
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
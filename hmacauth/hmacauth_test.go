@@ -0,0 +1,168 @@
+package hmacauth
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The key, hash and HMAC vectors are the ones the Azure SDK for JavaScript
+// (@azure/communication-common) checks its own signing primitives against.
+// The request signatures were computed from the documented string to sign
+// with an independent HMAC-SHA256 implementation
+const (
+	testKey         = "pw=="
+	testBody        = "banana"
+	testBodyHash    = "tJPUg2Sv5E0RwBZc9HCkFk0eJgmRHvmYvoaNRq3j3k4="
+	testBodyHMAC    = "88EC05aAS9iXnaimtNO78JLjiPtfWryQB/5QYEzEsu8="
+	emptyBodyHash   = "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+	testDate        = "Thu, 25 Mar 2021 10:00:00 GMT"
+	testSendURL     = "https://contoso.communication.azure.com/emails:send?api-version=2023-03-31"
+	testSendSig     = "+lVtEtCvGngNar9IUFEzW7tWGcgjUNUSsqZ7Mm40TQA="
+	testPortURL     = "https://contoso.communication.azure.com:8443/emails/operations/abc"
+	testPortSig     = "SVdQxy0HbGVpQq+TcjmP514N2h8tUsQvNqUJCMt2RZ0="
+	testSignedNames = ";host;" + ContentHashHeader
+)
+
+func testSigner(t *testing.T, dateHeader string) *Signer {
+	t.Helper()
+	signer, err := NewSigner(testKey)
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	signer.DateHeader = dateHeader
+	signer.Now = func() time.Time { return time.Date(2021, 3, 25, 10, 0, 0, 0, time.UTC) }
+	return signer
+}
+
+func TestContentHash(t *testing.T) {
+	if got := ContentHash([]byte(testBody)); got != testBodyHash {
+		t.Errorf("ContentHash(%q) = %s, want %s", testBody, got, testBodyHash)
+	}
+	if got := ContentHash(nil); got != emptyBodyHash {
+		t.Errorf("ContentHash(nil) = %s, want %s", got, emptyBodyHash)
+	}
+}
+
+func TestCompute(t *testing.T) {
+	if got := testSigner(t, "").compute(testBody); got != testBodyHMAC {
+		t.Errorf("compute(%q) = %s, want %s", testBody, got, testBodyHMAC)
+	}
+}
+
+func TestNewSignerInvalidKey(t *testing.T) {
+	if _, err := NewSigner("not base64!"); err == nil {
+		t.Error("NewSigner accepted a key that is not base64")
+	}
+}
+
+func TestPathAndQuery(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{testSendURL, "/emails:send?api-version=2023-03-31"},
+		{"https://contoso.communication.azure.com/emails/operations/abc", "/emails/operations/abc"},
+		{"https://contoso.communication.azure.com/emails/operations/abc?", "/emails/operations/abc"},
+		{"https://contoso.communication.azure.com", "/"},
+		{"https://contoso.communication.azure.com/a%20b?x=1&y=2", "/a%20b?x=1&y=2"},
+	}
+	for _, test := range tests {
+		u, err := url.Parse(test.url)
+		if err != nil {
+			t.Fatalf("url.Parse(%q): %v", test.url, err)
+		}
+		if got := PathAndQuery(u); got != test.want {
+			t.Errorf("PathAndQuery(%q) = %q, want %q", test.url, got, test.want)
+		}
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	local := time.Date(2021, 3, 25, 11, 0, 0, 0, time.FixedZone("CET", 3600))
+	if got := FormatDate(local); got != testDate {
+		t.Errorf("FormatDate = %q, want %q", got, testDate)
+	}
+}
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		body       string
+		dateHeader string
+		wantHeader string
+		wantString string
+		wantSig    string
+	}{
+		{
+			name:       "x-ms-date",
+			method:     http.MethodPost,
+			url:        testSendURL,
+			body:       testBody,
+			wantHeader: DateHeaderXMsDate,
+			wantString: "POST\n/emails:send?api-version=2023-03-31\n" + testDate + ";contoso.communication.azure.com;" + testBodyHash,
+			wantSig:    testSendSig,
+		},
+		{
+			name:       "Date",
+			method:     http.MethodPost,
+			url:        testSendURL,
+			body:       testBody,
+			dateHeader: "date",
+			wantHeader: DateHeaderDate,
+			wantString: "POST\n/emails:send?api-version=2023-03-31\n" + testDate + ";contoso.communication.azure.com;" + testBodyHash,
+			wantSig:    testSendSig,
+		},
+		{
+			name:       "empty query and host with port",
+			method:     http.MethodGet,
+			url:        testPortURL,
+			wantHeader: DateHeaderXMsDate,
+			wantString: "GET\n/emails/operations/abc\n" + testDate + ";contoso.communication.azure.com:8443;" + emptyBodyHash,
+			wantSig:    testPortSig,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(test.method, test.url, strings.NewReader(test.body))
+			if err != nil {
+				t.Fatalf("NewRequest: %v", err)
+			}
+			sig, err := testSigner(t, test.dateHeader).Sign(req, []byte(test.body))
+			if err != nil {
+				t.Fatalf("Sign: %v", err)
+			}
+
+			if sig.StringToSign != test.wantString {
+				t.Errorf("string to sign = %q, want %q", sig.StringToSign, test.wantString)
+			}
+			if sig.Value != test.wantSig {
+				t.Errorf("signature = %s, want %s", sig.Value, test.wantSig)
+			}
+			wantAuth := "HMAC-SHA256 SignedHeaders=" + strings.ToLower(test.wantHeader) + testSignedNames + "&Signature=" + test.wantSig
+			if got := req.Header.Get("Authorization"); got != wantAuth {
+				t.Errorf("Authorization = %q, want %q", got, wantAuth)
+			}
+			if got := req.Header.Get(test.wantHeader); got != testDate {
+				t.Errorf("%s header = %q, want %q", test.wantHeader, got, testDate)
+			}
+			if got := req.Header.Get(ContentHashHeader); got != sig.ContentHash {
+				t.Errorf("%s header = %q, want %q", ContentHashHeader, got, sig.ContentHash)
+			}
+		})
+	}
+}
+
+func TestSignUnsupportedDateHeader(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, testPortURL, nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	if _, err := testSigner(t, "X-Date").Sign(req, nil); err == nil {
+		t.Error("Sign accepted an unsupported date header")
+	}
+}
@@ -0,0 +1,97 @@
+package azemailsender
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+// RecipientResult is the outcome of the individual send to one recipient
+type RecipientResult struct {
+	Address  string
+	Response *SendResponse
+	Err      error
+}
+
+// IndividualSendResult aggregates the results of SendIndividually
+type IndividualSendResult struct {
+	Results []RecipientResult
+	Sent    int
+	Failed  int
+}
+
+// SendIndividually sends a separate copy of message to each To recipient, so recipients
+// cannot see each other. Cc and Bcc recipients get one copy, the first. The copies are
+// sent through SendBatch with options, which may be nil. The returned error is non-nil
+// if any send failed; per-recipient errors are in the result, in recipient order
+func (c *Client) SendIndividually(ctx context.Context, message *EmailMessage, options *BatchOptions) (*IndividualSendResult, error) {
+	if len(message.Recipients.To) == 0 {
+		return nil, ErrNoRecipients
+	}
+
+	// Reader attachments can only be read once, so load them before fanning out
+	base, err := materializeAttachments(message)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.options.Debug {
+		c.logger.Printf("[DEBUG] Sending individually to %d recipients", len(base.Recipients.To))
+	}
+
+	messages := make(chan *EmailMessage)
+	go func() {
+		defer close(messages)
+		for i, recipient := range base.Recipients.To {
+			single := cloneMessage(base)
+			single.Recipients.To = []EmailAddress{recipient}
+			single.ClientRequestID = ""
+			// Copy Cc and Bcc once rather than on every copy
+			if i > 0 {
+				single.Recipients.Cc = nil
+				single.Recipients.Bcc = nil
+			}
+			messages <- single
+		}
+	}()
+
+	result := &IndividualSendResult{Results: make([]RecipientResult, len(base.Recipients.To))}
+	for batchResult := range c.SendBatch(ctx, messages, options) {
+		result.Results[batchResult.Index] = RecipientResult{
+			Address:  base.Recipients.To[batchResult.Index].Address,
+			Response: batchResult.Response,
+			Err:      batchResult.Err,
+		}
+		if batchResult.Err != nil {
+			result.Failed++
+		} else {
+			result.Sent++
+		}
+	}
+
+	if result.Failed > 0 {
+		return result, fmt.Errorf("%d of %d individual sends failed", result.Failed, len(result.Results))
+	}
+	return result, nil
+}
+
+// materializeAttachments returns a copy of message with reader attachments read into memory
+func materializeAttachments(message *EmailMessage) (*EmailMessage, error) {
+	clone := cloneMessage(message)
+	for i, attachment := range clone.Attachments {
+		if attachment.Reader == nil || attachment.ContentInBase64 != "" {
+			continue
+		}
+		content, err := io.ReadAll(attachment.Reader)
+		if closer, ok := attachment.Reader.(io.Closer); ok {
+			closer.Close()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read attachment %s: %w", attachment.Name, err)
+		}
+		clone.Attachments[i].ContentInBase64 = base64.StdEncoding.EncodeToString(content)
+		clone.Attachments[i].Reader = nil
+	}
+	return clone, nil
+}
@@ -0,0 +1,318 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Chat formats of daemon alerts
+const (
+	alertFormatSlack = "slack"
+	alertFormatTeams = "teams"
+)
+
+// chatAlerter posts daemon alerts to a Slack or Microsoft Teams incoming webhook
+type chatAlerter struct {
+	url    string
+	format string
+	client *http.Client
+}
+
+// newChatAlerter returns an alerter for webhookURL. Format "auto" picks the
+// chat service from the host of the URL
+func newChatAlerter(webhookURL, format string) (*chatAlerter, error) {
+	u, err := url.Parse(webhookURL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, codedError(CodeUsage, "invalid --alert-webhook: use the https:// URL of a Slack or Teams incoming webhook")
+	}
+
+	switch format {
+	case alertFormatSlack, alertFormatTeams:
+	case "", "auto":
+		host := strings.ToLower(u.Hostname())
+		switch {
+		case host == "hooks.slack.com":
+			format = alertFormatSlack
+		case strings.HasSuffix(host, ".webhook.office.com"), strings.HasSuffix(host, ".logic.azure.com"), strings.HasSuffix(host, ".powerplatform.com"):
+			format = alertFormatTeams
+		default:
+			return nil, codedError(CodeUsage, "cannot tell the chat service of --alert-webhook host %s: set --alert-format slack or teams", u.Hostname())
+		}
+	default:
+		return nil, codedError(CodeUsage, "invalid --alert-format %q: use auto, slack or teams", format)
+	}
+
+	return &chatAlerter{url: webhookURL, format: format, client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+// payload returns the webhook request body for an alert
+func (a *chatAlerter) payload(title, text string) interface{} {
+	if a.format == alertFormatSlack {
+		return map[string]string{"text": fmt.Sprintf("*%s*\n%s", title, text)}
+	}
+
+	// Teams workflows and incoming webhooks both accept Adaptive Cards
+	return map[string]interface{}{
+		"type": "message",
+		"attachments": []interface{}{map[string]interface{}{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]interface{}{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body": []interface{}{
+					map[string]interface{}{"type": "TextBlock", "text": title, "weight": "Bolder", "size": "Medium", "wrap": true},
+					map[string]interface{}{"type": "TextBlock", "text": text, "wrap": true},
+				},
+			},
+		}},
+	}
+}
+
+// send posts an alert to the chat webhook
+func (a *chatAlerter) send(ctx context.Context, title, text string) error {
+	body, err := json.Marshal(a.payload(title, text))
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create alert request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("alert request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s webhook answered %s: %s", a.format, resp.Status, strings.TrimSpace(string(reply)))
+	}
+	return nil
+}
+
+// Metrics alert rules are evaluated on
+const (
+	metricFailureRate = "failure-rate"
+	metricFailures    = "failures"
+	metricThrottled   = "throttled"
+	metricDeadLetters = "dead-letters"
+)
+
+// alertMetrics lists the metrics accepted by --alert-rule
+var alertMetrics = []string{metricFailureRate, metricFailures, metricThrottled, metricDeadLetters}
+
+// alertRule fires while a metric over a sliding window is above a threshold
+type alertRule struct {
+	// name is the rule as given, e.g. failure-rate>5%/10m
+	name      string
+	metric    string
+	inclusive bool
+	threshold float64
+	window    time.Duration
+}
+
+// parseAlertRule parses METRIC>THRESHOLD/WINDOW or METRIC>=THRESHOLD/WINDOW,
+// e.g. failure-rate>5%/10m or throttled>20/5m
+func parseAlertRule(value string) (*alertRule, error) {
+	invalid := func(reason string) error {
+		return codedError(CodeUsage, "invalid --alert-rule %q: %s", value, reason)
+	}
+
+	rule := &alertRule{name: strings.ReplaceAll(value, " ", "")}
+	condition, window, ok := strings.Cut(rule.name, "/")
+	if !ok {
+		return nil, invalid("use METRIC>THRESHOLD/WINDOW, e.g. failure-rate>5%/10m")
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		return nil, invalid("the window must be a positive duration such as 10m")
+	}
+	rule.window = d
+
+	metric, threshold, ok := strings.Cut(condition, ">")
+	if !ok {
+		return nil, invalid("use METRIC>THRESHOLD/WINDOW, e.g. failure-rate>5%/10m")
+	}
+	if strings.HasPrefix(threshold, "=") {
+		rule.inclusive = true
+		threshold = threshold[1:]
+	}
+
+	rule.metric = metric
+	switch metric {
+	case metricFailureRate:
+		threshold = strings.TrimSuffix(threshold, "%")
+	case metricFailures, metricThrottled, metricDeadLetters:
+	default:
+		return nil, invalid("the metric must be one of " + strings.Join(alertMetrics, ", "))
+	}
+	rule.threshold, err = strconv.ParseFloat(threshold, 64)
+	if err != nil || rule.threshold < 0 || (metric == metricFailureRate && rule.threshold > 100) {
+		return nil, invalid("the threshold must be a non-negative number, and a percentage for failure-rate")
+	}
+	return rule, nil
+}
+
+// exceeds reports whether value breaks the rule
+func (r *alertRule) exceeds(value float64) bool {
+	if r.inclusive {
+		return value >= r.threshold
+	}
+	return value > r.threshold
+}
+
+// Events counted by the alert monitor
+const (
+	eventSucceeded = iota
+	eventFailed
+	eventThrottled
+	eventDeadLettered
+)
+
+// alertEvent is one event counted by the alert monitor
+type alertEvent struct {
+	at   time.Time
+	kind int
+}
+
+// alertChange is reported when a rule starts or stops firing
+type alertChange struct {
+	rule   *alertRule
+	firing bool
+	// value is the metric when the rule changed, with the sample it is
+	// computed from for failure rates
+	value    float64
+	failures int
+	total    int
+}
+
+// alertMonitor counts daemon events over sliding windows and evaluates the
+// alert rules against them
+type alertMonitor struct {
+	rules []*alertRule
+	// minMessages is the number of outcomes a failure rate needs to be evaluated
+	minMessages int
+
+	mu     sync.Mutex
+	events []alertEvent
+	firing map[*alertRule]bool
+}
+
+// newAlertMonitor returns a monitor evaluating rules
+func newAlertMonitor(rules []*alertRule, minMessages int) *alertMonitor {
+	return &alertMonitor{rules: rules, minMessages: minMessages, firing: map[*alertRule]bool{}}
+}
+
+// record counts an event and returns the rules it made start or stop firing
+func (m *alertMonitor) record(kinds ...int) []*alertChange {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, kind := range kinds {
+		m.events = append(m.events, alertEvent{at: now, kind: kind})
+	}
+	return m.evaluateLocked(now)
+}
+
+// evaluate returns the rules that started or stopped firing as events left
+// their windows
+func (m *alertMonitor) evaluate() []*alertChange {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.evaluateLocked(time.Now())
+}
+
+func (m *alertMonitor) evaluateLocked(now time.Time) []*alertChange {
+	var longest time.Duration
+	for _, rule := range m.rules {
+		if rule.window > longest {
+			longest = rule.window
+		}
+	}
+	kept := m.events[:0]
+	for _, event := range m.events {
+		if event.at.After(now.Add(-longest)) {
+			kept = append(kept, event)
+		}
+	}
+	m.events = kept
+
+	var changes []*alertChange
+	for _, rule := range m.rules {
+		counts := map[int]int{}
+		for _, event := range m.events {
+			if event.at.After(now.Add(-rule.window)) {
+				counts[event.kind]++
+			}
+		}
+
+		change := &alertChange{rule: rule}
+		switch rule.metric {
+		case metricFailureRate:
+			change.failures = counts[eventFailed]
+			change.total = counts[eventFailed] + counts[eventSucceeded]
+			if change.total > 0 {
+				change.value = float64(change.failures) * 100 / float64(change.total)
+			}
+			// Too few outcomes say nothing about the rate
+			change.firing = change.total >= m.minMessages && rule.exceeds(change.value)
+		case metricFailures:
+			change.value = float64(counts[eventFailed])
+		case metricThrottled:
+			change.value = float64(counts[eventThrottled])
+		case metricDeadLetters:
+			change.value = float64(counts[eventDeadLettered])
+		}
+		if rule.metric != metricFailureRate {
+			change.firing = rule.exceeds(change.value)
+		}
+
+		if change.firing != m.firing[rule] {
+			m.firing[rule] = change.firing
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// firingRules returns the names of the rules currently firing
+func (m *alertMonitor) firingRules() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var names []string
+	for _, rule := range m.rules {
+		if m.firing[rule] {
+			names = append(names, rule.name)
+		}
+	}
+	return names
+}
+
+// describe returns the alert title and text of a rule change
+func (c *alertChange) describe() (string, string) {
+	value := fmt.Sprintf("%g", c.value)
+	if c.rule.metric == metricFailureRate {
+		value = fmt.Sprintf("%.1f%% (%d of %d)", c.value, c.failures, c.total)
+	}
+	text := fmt.Sprintf("%s is %s over the last %s.", c.rule.metric, value, c.rule.window)
+	if c.firing {
+		return "Email alert firing: " + c.rule.name, text
+	}
+	return "Email alert resolved: " + c.rule.name, text
+}
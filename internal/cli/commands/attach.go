@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/groovy-sky/azemailsender"
+)
+
+// attachFlag is one --attach or --attach-inline value
+type attachFlag struct {
+	contentID string
+	path      string
+}
+
+// parseInlineAttachment parses an --attach-inline value of the form cid=<id>:<path>
+func parseInlineAttachment(value string) (attachFlag, error) {
+	spec := strings.TrimPrefix(value, "cid=")
+	contentID, path, ok := strings.Cut(spec, ":")
+	if !ok || contentID == "" || path == "" {
+		return attachFlag{}, fmt.Errorf("invalid --attach-inline %q: use cid=<content-id>:<file>, e.g. cid=logo:logo.png", value)
+	}
+	return attachFlag{contentID: contentID, path: path}, nil
+}
+
+// addAttachmentFlags reads the --attach and --attach-inline files and adds them
+// to the builder, rejecting files over limit, the client's attachment size
+// limit, unless it is zero
+func addAttachmentFlags(builder *azemailsender.MessageBuilder, limit int64, attach, attachInline []string) error {
+	var files []attachFlag
+	for _, path := range attach {
+		files = append(files, attachFlag{path: path})
+	}
+	for _, value := range attachInline {
+		file, err := parseInlineAttachment(value)
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+	}
+
+	for _, file := range files {
+		info, err := os.Stat(file.path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("attachment not found: %s", file.path)
+			}
+			return fmt.Errorf("failed to read attachment %s: %w", file.path, err)
+		}
+		if info.IsDir() {
+			return fmt.Errorf("attachment %s is a directory", file.path)
+		}
+
+		// Fail before reading files that could never be sent
+		encoded := int64(base64.StdEncoding.EncodedLen(int(info.Size())))
+		if limit > 0 && encoded > limit {
+			return fmt.Errorf("attachment %s is %d bytes (%d bytes encoded), exceeding the %d byte limit", file.path, info.Size(), encoded, limit)
+		}
+
+		content, err := os.ReadFile(file.path)
+		if err != nil {
+			return fmt.Errorf("failed to read attachment %s: %w", file.path, err)
+		}
+
+		name := filepath.Base(file.path)
+		if file.contentID != "" {
+			builder.AttachInline(file.contentID, name, "", content)
+		} else {
+			builder.Attach(name, "", content)
+		}
+	}
+	return nil
+}
+
+// attachmentHint adds advice to attachment size errors
+func attachmentHint(err error) error {
+	var limitErr *azemailsender.AttachmentLimitError
+	if errors.As(err, &limitErr) {
+		return fmt.Errorf("%w (base64 encoding adds about a third to each file; send fewer or smaller files, or link to large files instead)", err)
+	}
+	return err
+}
@@ -0,0 +1,290 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/groovy-sky/azemailsender"
+	"github.com/groovy-sky/azemailsender/internal/cli/output"
+	"github.com/groovy-sky/azemailsender/internal/simplecli"
+	"github.com/groovy-sky/azemailsender/internal/simpleconfig"
+)
+
+// maxBatchLineSize bounds a single NDJSON line, which may embed attachment content
+const maxBatchLineSize = 16 << 20
+
+// NewSendBatchCommand creates the send-batch command
+func NewSendBatchCommand() *simplecli.Command {
+	return &simplecli.Command{
+		Name:        "send-batch",
+		Aliases:     []string{"batch"},
+		Description: "Send many emails from an NDJSON file",
+		Usage:       "send-batch [flags] --ndjson <file>",
+		LongDesc: `Send one email per line of an NDJSON file. Each line is a message document
+as accepted by 'send --message-file'. One JSON result per message is written
+to stdout as it completes, with the input line number, message ID, status
+and error.
+
+Examples:
+  # Send a batch and keep the failures
+  azemailsender-cli send-batch --ndjson messages.ndjson | jq -c 'select(.error)'
+
+  # Read messages from a pipeline with 8 concurrent sends
+  generate-messages | azemailsender-cli send-batch --ndjson - --max-concurrency 8
+
+  # Stay under 5 messages per second
+  azemailsender-cli send-batch --ndjson messages.ndjson --rate 5`,
+		Run: runSendBatch,
+		Flags: joinFlags(authFlags(), networkFlags(), []*simplecli.Flag{
+			{
+				Name:        "ndjson",
+				Description: "NDJSON file with one message document per line, or - for stdin",
+				Value:       "",
+			},
+		}, pacingFlags(azemailsender.DefaultBatchConcurrency), suppressionFlags(), dedupeFlags(), confirmFlags(), receiptFlags()),
+		Constraints: joinConstraints(authConstraints(), suppressionConstraints()),
+	}
+}
+
+func runSendBatch(ctx *simplecli.Context) error {
+	path := ctx.GetString("ndjson")
+	if path == "" {
+		return codedError(CodeUsage, "input required (--ndjson)")
+	}
+
+	batchOptions, err := newBatchOptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Load configuration
+	configFile := ctx.GetString("config")
+	config, err := simpleconfig.LoadConfig(configFile, ctx.ProvidedFlags())
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	formatter, err := newFormatter(ctx)
+	if err != nil {
+		return err
+	}
+	// Results are always streamed as NDJSON
+	formatter.JSON = true
+
+	client, err := newClient(ctx, config, formatter)
+	if err != nil {
+		return err
+	}
+
+	var input io.Reader = os.Stdin
+	dir := "."
+	total := 0
+	if path == "-" && !ctx.GetBool("yes") && config.GetConfirmThreshold() > 0 {
+		// Spool stdin so the messages are counted, and confirmed, before the first is sent
+		spool, err := spoolStdin()
+		if err != nil {
+			return err
+		}
+		defer os.Remove(spool.Name())
+		defer spool.Close()
+		if total, err = countLines(spool.Name()); err != nil {
+			return err
+		}
+		if err := confirmSend(ctx, config, total, "messages"); err != nil {
+			return err
+		}
+		input = spool
+	} else if path != "-" {
+		if total, err = countLines(path); err != nil {
+			return err
+		}
+		if err := confirmSend(ctx, config, total, "messages"); err != nil {
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open batch file: %w", err)
+		}
+		defer file.Close()
+		input = file
+		dir = filepath.Dir(path)
+	}
+
+	receipts, err := openReceipts(ctx)
+	if err != nil {
+		return err
+	}
+	defer receipts.Close()
+
+	progress := formatter.NewProgress(ctx.GetBool("json"), total)
+
+	var (
+		mu         sync.Mutex
+		lines      []int
+		failed     int
+		receiptErr error
+	)
+	emit := func(result output.BatchResult, errorClass string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if result.Error != "" {
+			failed++
+		}
+		formatter.PrintBatchResult(result)
+		progress.Add(errorClass)
+	}
+
+	messages := make(chan *azemailsender.EmailMessage)
+	var readErr error
+	go func() {
+		defer close(messages)
+
+		scanner := bufio.NewScanner(input)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxBatchLineSize)
+		line := 0
+		for scanner.Scan() {
+			line++
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+
+			message, err := parseBatchLine(scanner.Bytes(), dir, client, config)
+			if err != nil {
+				emit(output.BatchResult{Line: line, Error: err.Error()}, output.ErrorClassValidation)
+				continue
+			}
+
+			mu.Lock()
+			lines = append(lines, line)
+			mu.Unlock()
+			messages <- message
+		}
+		readErr = scanner.Err()
+	}()
+
+	results := client.SendBatch(context.Background(), messages, batchOptions)
+	for result := range results {
+		mu.Lock()
+		line := lines[result.Index]
+		mu.Unlock()
+
+		batchResult := output.BatchResult{Line: line, To: recipientAddresses(result.Message)}
+		if result.Response != nil {
+			batchResult.ID = result.Response.ID
+			batchResult.Status = string(result.Response.Status)
+		}
+		errorClass := ""
+		if result.Err != nil {
+			batchResult.Error = result.Err.Error()
+			errorClass = classifyError(result.Err)
+		}
+		if err := receipts.write(result.Message, result.Response, "", result.Err); err != nil && receiptErr == nil {
+			receiptErr = err
+		}
+		emit(batchResult, errorClass)
+	}
+	progress.Finish()
+
+	if readErr != nil {
+		return fmt.Errorf("failed to read batch input: %w", readErr)
+	}
+	if receiptErr != nil {
+		return receiptErr
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d messages failed", failed)
+	}
+	return nil
+}
+
+// parseBatchLine builds the message described by one NDJSON line
+func parseBatchLine(line []byte, dir string, client *azemailsender.Client, config *simpleconfig.Config) (*azemailsender.EmailMessage, error) {
+	var doc messageDocument
+	if err := json.Unmarshal(line, &doc); err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+	doc.dir = dir
+	return doc.build(client, config)
+}
+
+// recipientAddresses returns the To addresses of a message for result output
+func recipientAddresses(message *azemailsender.EmailMessage) []string {
+	addresses := make([]string, 0, len(message.Recipients.To))
+	for _, recipient := range message.Recipients.To {
+		addresses = append(addresses, recipient.Address)
+	}
+	return addresses
+}
+
+// countLines counts the non-empty lines of a file to size the progress bar
+func countLines(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open batch file: %w", err)
+	}
+	defer file.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxBatchLineSize)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			count++
+		}
+	}
+	return count, scanner.Err()
+}
+
+// spoolStdin copies stdin to a temporary file, returned rewound to its start
+func spoolStdin() (*os.File, error) {
+	spool, err := os.CreateTemp("", "azemailsender-batch-*.ndjson")
+	if err != nil {
+		return nil, fmt.Errorf("failed to buffer stdin: %w", err)
+	}
+	if _, err := io.Copy(spool, os.Stdin); err != nil {
+		spool.Close()
+		os.Remove(spool.Name())
+		return nil, fmt.Errorf("failed to buffer stdin: %w", err)
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		spool.Close()
+		os.Remove(spool.Name())
+		return nil, fmt.Errorf("failed to buffer stdin: %w", err)
+	}
+	return spool, nil
+}
+
+// classifyError maps a send error to an error class for the batch summary
+func classifyError(err error) string {
+	var apiErr *azemailsender.APIError
+	var suppressionErr *azemailsender.SuppressionError
+	var duplicateErr *azemailsender.DuplicateError
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return output.ErrorClassCanceled
+	case errors.As(err, &suppressionErr):
+		return output.ErrorClassSuppressed
+	case errors.As(err, &duplicateErr):
+		return output.ErrorClassDuplicate
+	case errors.As(err, &apiErr):
+		switch {
+		case apiErr.StatusCode == 401 || apiErr.StatusCode == 403:
+			return output.ErrorClassAuth
+		case apiErr.StatusCode == 429:
+			return output.ErrorClassThrottled
+		case apiErr.StatusCode >= 500:
+			return output.ErrorClassServer
+		default:
+			return output.ErrorClassClient
+		}
+	default:
+		return output.ErrorClassNetwork
+	}
+}
@@ -0,0 +1,668 @@
+package commands
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/groovy-sky/azemailsender"
+	"github.com/groovy-sky/azemailsender/internal/cli/output"
+	"github.com/groovy-sky/azemailsender/internal/simpleconfig"
+	"github.com/groovy-sky/azemailsender/internal/simplecli"
+)
+
+// joinFlags concatenates flag groups into a single command flag list
+func joinFlags(groups ...[]*simplecli.Flag) []*simplecli.Flag {
+	var flags []*simplecli.Flag
+	for _, group := range groups {
+		flags = append(flags, group...)
+	}
+	return flags
+}
+
+// joinConstraints concatenates constraint groups into a single command constraint list
+func joinConstraints(groups ...[]simplecli.Constraint) []simplecli.Constraint {
+	var constraints []simplecli.Constraint
+	for _, group := range groups {
+		constraints = append(constraints, group...)
+	}
+	return constraints
+}
+
+// authFlags returns the authentication flags shared by commands that call the service
+func authFlags() []*simplecli.Flag {
+	return simplecli.InCategory("Authentication", []*simplecli.Flag{
+		{
+			Name:        "endpoint",
+			Short:       "e",
+			Description: "Azure Communication Services endpoint",
+			Value:       "",
+			EnvVar:      "ENDPOINT",
+		},
+		{
+			Name:        "access-key",
+			Short:       "k",
+			Description: "Access key for authentication",
+			Value:       "",
+			EnvVar:      "ACCESS_KEY",
+		},
+		{
+			Name:        "connection-string",
+			Description: "Connection string for authentication",
+			Value:       "",
+			EnvVar:      "CONNECTION_STRING",
+		},
+		{
+			Name:        "access-key-file",
+			Description: "File containing the access key, such as a mounted Kubernetes secret",
+			Value:       "",
+			EnvVar:      "ACCESS_KEY_FILE",
+		},
+		{
+			Name:        "connection-string-file",
+			Description: "File containing the connection string",
+			Value:       "",
+			EnvVar:      "CONNECTION_STRING_FILE",
+		},
+	})
+}
+
+// authConstraints keeps the two ways of authenticating, and each secret and
+// its file, from being mixed on the command line
+func authConstraints() []simplecli.Constraint {
+	return []simplecli.Constraint{
+		simplecli.MutuallyExclusive([]string{"connection-string", "connection-string-file"}, []string{"endpoint", "access-key", "access-key-file"}),
+		simplecli.MutuallyExclusive([]string{"connection-string"}, []string{"connection-string-file"}),
+		simplecli.MutuallyExclusive([]string{"access-key"}, []string{"access-key-file"}),
+	}
+}
+
+// networkFlags returns the TLS, proxy, timeout and retry flags shared by commands that call the service
+func networkFlags() []*simplecli.Flag {
+	return simplecli.InCategory("Network", []*simplecli.Flag{
+		{
+			Name:        "ca-file",
+			Description: "PEM file with additional CA certificates to trust",
+			Value:       "",
+			EnvVar:      "CA_FILE",
+		},
+		{
+			Name:        "client-cert",
+			Description: "PEM client certificate for mutual TLS",
+			Value:       "",
+			EnvVar:      "CLIENT_CERT",
+		},
+		{
+			Name:        "client-key",
+			Description: "PEM private key for --client-cert",
+			Value:       "",
+			EnvVar:      "CLIENT_KEY",
+		},
+		{
+			Name:        "tls-min-version",
+			Description: "Minimum TLS version (1.2 or 1.3)",
+			Value:       "",
+			EnvVar:      "TLS_MIN_VERSION",
+		},
+		{
+			Name:        "proxy",
+			Description: "HTTP(S) proxy URL (default: HTTPS_PROXY environment variable)",
+			Value:       "",
+			EnvVar:      "PROXY",
+		},
+		{
+			Name:        "transport",
+			Description: "Send through smtp://HOST:PORT, write .eml files to file://DIR or discard with null:// instead of using the API",
+			Value:       "",
+			EnvVar:      "TRANSPORT",
+		},
+		{
+			Name:        "fallback-transport",
+			Description: "Transports tried in order, separated by commas, when sending through --transport or the API fails",
+			Value:       "",
+			EnvVar:      "FALLBACK_TRANSPORT",
+		},
+		{
+			Name:        "http-timeout",
+			Description: "Timeout of each HTTP request (default: 30s)",
+			Value:       "",
+			EnvVar:      "HTTP_TIMEOUT",
+		},
+		{
+			Name:        "max-retries",
+			Description: "Retries after a failed send request (default: 3)",
+			Value:       "",
+			EnvVar:      "MAX_RETRIES",
+		},
+		{
+			Name:        "retry-delay",
+			Description: "Delay between retries (default: 1s)",
+			Value:       "",
+			EnvVar:      "RETRY_DELAY",
+		},
+	})
+}
+
+// pacingFlags returns the concurrency and pacing flags shared by bulk commands,
+// with concurrency as the default of --max-concurrency
+func pacingFlags(concurrency int) []*simplecli.Flag {
+	return simplecli.InCategory("Behavior", []*simplecli.Flag{
+		{
+			Name:        "max-concurrency",
+			Description: fmt.Sprintf("Maximum number of concurrent requests (0: every message at once with status --watch, %d otherwise)", azemailsender.DefaultBatchConcurrency),
+			Value:       concurrency,
+		},
+		{
+			Name:        "rate",
+			Description: "Maximum requests per second (0 for unlimited)",
+			Value:       0.0,
+		},
+		{
+			Name:        "pause-between",
+			Description: "Minimum delay between consecutive requests, e.g. 200ms",
+			Value:       time.Duration(0),
+		},
+	})
+}
+
+// newBatchOptions parses the pacing flags
+func newBatchOptions(ctx *simplecli.Context) (*azemailsender.BatchOptions, error) {
+	concurrency := ctx.GetInt("max-concurrency")
+	if concurrency < 0 {
+		return nil, codedError(CodeUsage, "invalid max-concurrency: %d", concurrency)
+	}
+
+	rate := ctx.GetFloat("rate")
+	if rate < 0 {
+		return nil, codedError(CodeUsage, "invalid rate: %v", rate)
+	}
+
+	pause := ctx.GetDuration("pause-between")
+	if pause < 0 {
+		return nil, codedError(CodeUsage, "invalid pause-between: %v", pause)
+	}
+
+	return &azemailsender.BatchOptions{
+		MaxConcurrency:       concurrency,
+		MaxRequestsPerSecond: rate,
+		PauseBetween:         pause,
+	}, nil
+}
+
+// waitFlags returns the flags controlling status polling
+func waitFlags() []*simplecli.Flag {
+	return simplecli.InCategory("Behavior", []*simplecli.Flag{
+		{
+			Name:        "wait",
+			Short:       "w",
+			Description: "Wait for email completion",
+			Value:       false,
+			EnvVar:      "WAIT",
+		},
+		{
+			Name:        "poll-interval",
+			Description: "Status polling interval (when --wait is used)",
+			Value:       5 * time.Second,
+			EnvVar:      "POLL_INTERVAL",
+		},
+		{
+			Name:        "max-wait-time",
+			Description: "Maximum wait time (when --wait is used)",
+			Value:       5 * time.Minute,
+			EnvVar:      "MAX_WAIT_TIME",
+		},
+		{
+			Name:        "poll-backoff",
+			Description: "Poll adaptively: start at --poll-interval (default 1s) and double the interval up to --poll-max-interval",
+			Value:       false,
+			EnvVar:      "POLL_BACKOFF",
+		},
+		{
+			Name:        "poll-max-interval",
+			Description: "Longest polling interval with --poll-backoff",
+			Value:       azemailsender.DefaultPollBackoff().MaxInterval,
+			EnvVar:      "POLL_MAX_INTERVAL",
+		},
+	})
+}
+
+// checkWaitFlags rejects polling flags given on the command line when none of
+// the flags that poll is enabled, --poll-max-interval without backoff, and a
+// poll interval that isn't positive, which would poll without pause. Wait and
+// backoff may be enabled by the environment or the config file, so this runs
+// once the configuration is loaded
+func checkWaitFlags(ctx *simplecli.Context, config *simpleconfig.Config, pollingFlags ...string) error {
+	polls := false
+	for _, name := range pollingFlags {
+		if name == "wait" {
+			polls = polls || config.Wait
+		} else {
+			polls = polls || ctx.GetBool(name)
+		}
+	}
+	for _, name := range []string{"poll-interval", "max-wait-time", "poll-backoff"} {
+		if ctx.IsGiven(name) && !polls {
+			return codedError(CodeUsage, "--%s requires --%s", name, strings.Join(pollingFlags, " or --"))
+		}
+	}
+	if ctx.IsGiven("poll-max-interval") && !config.PollBackoff {
+		return codedError(CodeUsage, "--poll-max-interval requires --poll-backoff")
+	}
+	if interval, err := time.ParseDuration(config.PollInterval); polls && err == nil && interval <= 0 {
+		return codedError(CodeUsage, "--poll-interval must be positive, not %s", config.PollInterval)
+	}
+	return nil
+}
+
+// suppressionFlags returns the flags controlling pre-send suppression checks
+func suppressionFlags() []*simplecli.Flag {
+	return simplecli.InCategory("Behavior", []*simplecli.Flag{
+		{
+			Name:        "check-suppression",
+			Description: "Fail if any recipient is on the suppression list",
+			Value:       false,
+		},
+		{
+			Name:        "drop-suppressed",
+			Description: "Remove suppressed recipients and send to the rest",
+			Value:       false,
+		},
+		{
+			Name:        "suppression-file",
+			Description: "Suppression list file (one address per line)",
+			Value:       "",
+			EnvVar:      "SUPPRESSION_FILE",
+		},
+	})
+}
+
+// dedupeFlags returns the flags suppressing repeated identical messages
+func dedupeFlags() []*simplecli.Flag {
+	return simplecli.InCategory("Behavior", []*simplecli.Flag{
+		{
+			Name:        "dedupe-window",
+			Description: "Suppress messages identical to one sent within this window (0 disables)",
+			Value:       time.Duration(0),
+			EnvVar:      "DEDUPE_WINDOW",
+		},
+		{
+			Name:        "dedupe-dir",
+			Description: "Directory recording recently sent messages (default: dedupe next to the config file)",
+			Value:       "",
+			EnvVar:      "DEDUPE_DIR",
+		},
+	})
+}
+
+// suppressionConstraints allows only one suppression mode
+func suppressionConstraints() []simplecli.Constraint {
+	return []simplecli.Constraint{
+		simplecli.MutuallyExclusive([]string{"check-suppression"}, []string{"drop-suppressed"}),
+	}
+}
+
+// confirmFlags returns the flags controlling the confirmation prompt for large sends
+func confirmFlags() []*simplecli.Flag {
+	return simplecli.InCategory("Behavior", []*simplecli.Flag{
+		{
+			Name:        "yes",
+			Short:       "y",
+			Description: "Send without asking for confirmation",
+			Value:       false,
+		},
+		{
+			Name:        "confirm-threshold",
+			Description: "Ask for confirmation when sending to more recipients than this (0 to never ask, default 10)",
+			Value:       "",
+			EnvVar:      "CONFIRM_THRESHOLD",
+		},
+	})
+}
+
+// confirmSend asks on the terminal before sending to more recipients than the
+// configured threshold. Without a terminal the send is refused unless --yes is given
+func confirmSend(ctx *simplecli.Context, config *simpleconfig.Config, count int, what string) error {
+	threshold := config.GetConfirmThreshold()
+	if ctx.GetBool("yes") || threshold == 0 || count <= threshold {
+		return nil
+	}
+
+	stat, err := os.Stdin.Stat()
+	if err != nil || stat.Mode()&os.ModeCharDevice == 0 {
+		return codedError(CodeConfirmationRequired, "refusing to send to %d %s without confirmation: rerun with --yes", count, what)
+	}
+
+	fmt.Fprintf(os.Stderr, "About to send to %d %s (confirmation threshold %d). Continue? [y/N] ", count, what, threshold)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	default:
+		return codedError(CodeCanceled, "send cancelled")
+	}
+}
+
+// readAddressFile reads one email address per line, skipping blank lines and # comments
+func readAddressFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recipient file: %w", err)
+	}
+	defer file.Close()
+
+	var addresses []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		addresses = append(addresses, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recipient file %s: %w", path, err)
+	}
+	return addresses, nil
+}
+
+// newClient creates an email client from the authentication flags, falling back to configuration values.
+// When a log file is configured, the debug output of the formatter and client and the HTTP trace go there
+func newClient(ctx *simplecli.Context, config *simpleconfig.Config, formatter *output.Formatter) (*azemailsender.Client, error) {
+	return newClientWith(ctx, config, formatter, nil)
+}
+
+// configTransports returns the transport and fallback transports of the config
+func configTransports(config *simpleconfig.Config) (azemailsender.Transport, []azemailsender.Transport, error) {
+	transport, err := azemailsender.NewTransport(config.Transport)
+	if err != nil {
+		return nil, nil, codedError(CodeConfigInvalid, "%v", err)
+	}
+	var fallbacks []azemailsender.Transport
+	for _, value := range strings.Split(config.FallbackTransport, ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		fallback, err := azemailsender.NewTransport(value)
+		if err != nil {
+			return nil, nil, codedError(CodeConfigInvalid, "%v", err)
+		}
+		fallbacks = append(fallbacks, fallback)
+	}
+	return transport, fallbacks, nil
+}
+
+// usesAPI reports whether the transport or one of the fallbacks is the API
+func usesAPI(transport azemailsender.Transport, fallbacks []azemailsender.Transport) bool {
+	usesAPI := transport == nil
+	for _, fallback := range fallbacks {
+		usesAPI = usesAPI || fallback == nil
+	}
+	return usesAPI
+}
+
+// checkAuth reports missing credentials when the config sends through the API.
+// Other transports don't use the API, so they need none
+func checkAuth(config *simpleconfig.Config) error {
+	transport, fallbacks, err := configTransports(config)
+	if err != nil {
+		return err
+	}
+	if usesAPI(transport, fallbacks) && config.ConnectionString == "" && (config.Endpoint == "" || config.AccessKey == "") {
+		return codedError(CodeAuthMissing, "authentication required: provide either --connection-string or both --endpoint and --access-key (or --access-key-file / --connection-string-file)")
+	}
+	return nil
+}
+
+// newClientWith is newClient with customize applied to the client options
+// before the client is created, when it is not nil
+func newClientWith(ctx *simplecli.Context, config *simpleconfig.Config, formatter *output.Formatter, customize func(*azemailsender.ClientOptions)) (*azemailsender.Client, error) {
+	// The configuration already holds the flags, environment and key files
+	endpoint := config.Endpoint
+	accessKey := config.AccessKey
+	connectionString := config.ConnectionString
+
+	transport, fallbacks, err := configTransports(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAuth(config); err != nil {
+		return nil, err
+	}
+
+	httpTimeout, err := time.ParseDuration(config.HTTPTimeout)
+	if err != nil {
+		return nil, codedError(CodeConfigInvalid, "invalid http-timeout %q: %w", config.HTTPTimeout, err)
+	}
+	maxRetries, err := strconv.Atoi(config.MaxRetries)
+	if err != nil || maxRetries < 0 {
+		return nil, codedError(CodeConfigInvalid, "invalid max-retries %q: use a non-negative number", config.MaxRetries)
+	}
+	retryDelay, err := time.ParseDuration(config.RetryDelay)
+	if err != nil {
+		return nil, codedError(CodeConfigInvalid, "invalid retry-delay %q: %w", config.RetryDelay, err)
+	}
+
+	clientOptions := &azemailsender.ClientOptions{
+		Debug:           ctx.GetBool("debug"),
+		CaptureFailures: ctx.GetString("capture-dir"),
+		HTTPTimeout:     httpTimeout,
+		MaxRetries:      maxRetries,
+		RetryDelay:      retryDelay,
+	}
+
+	if formatter.Log == nil && config.LogFile != "" {
+		log, err := output.OpenLogFile(config.LogFile)
+		if err != nil {
+			return nil, err
+		}
+		formatter.Log = log
+	}
+	if formatter.Log != nil {
+		clientOptions.Logger = formatter.Log
+	}
+
+	// Trace each HTTP request on stderr, apart from the debug log
+	if ctx.GetBool("trace") {
+		clientOptions.OnHTTPTrace = func(trace azemailsender.HTTPTrace) {
+			if formatter.Log != nil {
+				formatter.Log.Printf("[TRACE] %s", trace)
+				return
+			}
+			fmt.Fprintf(os.Stderr, "[TRACE] %s\n", trace)
+		}
+	}
+
+	tlsConfig, proxy, err := newNetworkSettings(config)
+	if err != nil {
+		return nil, err
+	}
+	clientOptions.TLSConfig = tlsConfig
+	clientOptions.Proxy = proxy
+
+	clientOptions.Transport = transport
+	clientOptions.FallbackTransports = fallbacks
+	for _, t := range append([]azemailsender.Transport{transport}, fallbacks...) {
+		if smtpTransport, ok := t.(*azemailsender.SMTPTransport); ok {
+			smtpTransport.TLSConfig = tlsConfig
+		}
+	}
+	if len(fallbacks) > 0 {
+		clientOptions.OnFallback = func(from, to string, err error) {
+			formatter.PrintDebug("Falling back from %s to %s: %v", from, to, err)
+		}
+	}
+
+	// Load the suppression list when a command asks for suppression checks
+	if ctx.GetBool("check-suppression") || ctx.GetBool("drop-suppressed") {
+		if config.SuppressionFile == "" {
+			return nil, codedError(CodeUsage, "suppression check requires --suppression-file")
+		}
+		list, err := azemailsender.LoadSuppressionList(config.SuppressionFile)
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.Suppression = list
+		clientOptions.SuppressionMode = azemailsender.SuppressionFail
+		if ctx.GetBool("drop-suppressed") {
+			clientOptions.SuppressionMode = azemailsender.SuppressionDrop
+		}
+	}
+
+	if clientOptions.Categories, err = categoryOptions(config); err != nil {
+		return nil, err
+	}
+
+	// Record sent messages in the local store to suppress repeats
+	if window := ctx.GetDuration("dedupe-window"); window > 0 {
+		store, err := azemailsender.OpenDedupeStore(config.GetDedupeDir())
+		if err != nil {
+			return nil, err
+		}
+		if _, err := store.Prune(time.Now().Add(-window)); err != nil {
+			formatter.PrintDebug("Failed to prune dedupe records: %v", err)
+		}
+		clientOptions.Dedupe = store
+		clientOptions.DedupeWindow = window
+	}
+
+	if customize != nil {
+		customize(clientOptions)
+	}
+
+	if connectionString != "" {
+		return azemailsender.NewClientFromConnectionString(connectionString, clientOptions)
+	}
+
+	client := azemailsender.NewClient(endpoint, accessKey, clientOptions)
+	if !usesAPI(transport, fallbacks) {
+		return client, nil
+	}
+	if err := client.Validate(); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// newNetworkSettings builds the TLS configuration and proxy function from the network settings.
+// Both are nil when the defaults apply
+func newNetworkSettings(config *simpleconfig.Config) (*tls.Config, func(*http.Request) (*url.URL, error), error) {
+	var tlsConfig *tls.Config
+	if config.CAFile != "" || config.ClientCert != "" || config.ClientKey != "" || config.TLSMinVersion != "" {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, nil, fmt.Errorf("no certificates found in CA file %s", config.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.ClientCert != "" || config.ClientKey != "" {
+		if config.ClientCert == "" || config.ClientKey == "" {
+			return nil, nil, codedError(CodeConfigInvalid, "mutual TLS requires both --client-cert and --client-key")
+		}
+		cert, err := tls.LoadX509KeyPair(config.ClientCert, config.ClientKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	switch config.TLSMinVersion {
+	case "", "1.2":
+	case "1.3":
+		tlsConfig.MinVersion = tls.VersionTLS13
+	default:
+		return nil, nil, codedError(CodeConfigInvalid, "invalid tls-min-version %q: use 1.2 or 1.3", config.TLSMinVersion)
+	}
+
+	var proxy func(*http.Request) (*url.URL, error)
+	if config.Proxy != "" {
+		proxyURL, err := url.Parse(config.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, nil, codedError(CodeConfigInvalid, "invalid proxy URL %q", config.Proxy)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	return tlsConfig, proxy, nil
+}
+
+// newWaitOptions parses the polling flags, falling back to configuration values
+func newWaitOptions(ctx *simplecli.Context, config *simpleconfig.Config, onStatusUpdate func(status *azemailsender.StatusResponse)) (*azemailsender.WaitOptions, error) {
+	// The configuration already holds the flag values
+	pollInterval, err := time.ParseDuration(config.PollInterval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid poll-interval: %w", err)
+	}
+
+	maxWaitTime, err := time.ParseDuration(config.MaxWaitTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid max-wait-time: %w", err)
+	}
+
+	waitOptions := &azemailsender.WaitOptions{
+		PollInterval:   pollInterval,
+		MaxWaitTime:    maxWaitTime,
+		OnStatusUpdate: onStatusUpdate,
+	}
+
+	// Adaptive polling starts fast unless a poll interval was chosen; the
+	// client waits longer whenever the service sends Retry-After
+	if config.PollBackoff {
+		backoff := azemailsender.DefaultPollBackoff()
+		if config.Sources["poll-interval"] != "default" {
+			backoff.InitialInterval = pollInterval
+		}
+		backoff.MaxInterval = ctx.GetDuration("poll-max-interval")
+		if backoff.MaxInterval < backoff.InitialInterval {
+			return nil, codedError(CodeUsage, "--poll-max-interval %v is shorter than the initial poll interval %v", backoff.MaxInterval, backoff.InitialInterval)
+		}
+		waitOptions.Backoff = backoff
+	}
+	return waitOptions, nil
+}
+
+// newFormatter returns the output formatter for the --json, --output, --quiet,
+// --debug and --no-color flags. Commands with an --output flag of their own, such as
+// template render, only get --json. Debug output goes to the configured log
+// file from the start, also in commands that create no client
+func newFormatter(ctx *simplecli.Context) (*output.Formatter, error) {
+	formatter := output.NewFormatter(ctx.GetBool("json"), ctx.GetBool("quiet"), ctx.GetBool("debug"))
+	if ctx.GetBool("no-color") {
+		formatter.Color = false
+	}
+	// Configuration errors are left to the command, which reports them
+	if config, err := simpleconfig.LoadConfig(ctx.GetString("config"), ctx.ProvidedFlags()); err == nil && config.LogFile != "" {
+		log, err := output.OpenLogFile(config.LogFile)
+		if err != nil {
+			return nil, err
+		}
+		formatter.Log = log
+	}
+	for _, flag := range ctx.Command.Flags {
+		if flag.Name == "output" {
+			return formatter, nil
+		}
+	}
+	if err := formatter.SetOutput(ctx.GetString("output")); err != nil {
+		return nil, err
+	}
+	return formatter, nil
+}
@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"os"
+	"strings"
+
+	"github.com/groovy-sky/azemailsender/internal/simpleconfig"
+	"github.com/groovy-sky/azemailsender/internal/simplecli"
+)
+
+// NewCompletionCommand creates the completion command
+func NewCompletionCommand() *simplecli.Command {
+	return &simplecli.Command{
+		Name:        "completion",
+		Description: "Generate shell completion scripts",
+		Usage:       "completion bash|zsh|fish|powershell",
+		LongDesc: `Generate a completion script for commands, flags and configuration profile
+names. The script asks azemailsender-cli for candidates, so it does not need to
+be regenerated after upgrades.
+
+Examples:
+  # bash (current shell, or permanently)
+  source <(azemailsender-cli completion bash)
+  azemailsender-cli completion bash > /etc/bash_completion.d/azemailsender-cli
+
+  # zsh (a directory on $fpath)
+  azemailsender-cli completion zsh > "${fpath[1]}/_azemailsender-cli"
+
+  # fish
+  azemailsender-cli completion fish > ~/.config/fish/completions/azemailsender-cli.fish
+
+  # PowerShell
+  azemailsender-cli completion powershell | Out-String | Invoke-Expression`,
+		Run: runCompletion,
+	}
+}
+
+func runCompletion(ctx *simplecli.Context) error {
+	if len(ctx.Args) != 1 {
+		return codedError(CodeUsage, "shell required: %s", strings.Join(simplecli.CompletionShells, ", "))
+	}
+	return ctx.GlobalCtx.GenerateCompletion(os.Stdout, ctx.Args[0])
+}
+
+// CompleteProfiles completes --profile with the profiles of the selected config file
+func CompleteProfiles(flags map[string]interface{}) []string {
+	configFile, _ := flags["config"].(string)
+	return simpleconfig.ProfileNames(configFile)
+}
@@ -2,8 +2,10 @@ package commands
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/groovy-sky/azemailsender/internal/cli/output"
 	"github.com/groovy-sky/azemailsender/internal/simpleconfig"
@@ -18,44 +20,61 @@ func NewConfigCommand() *simplecli.Command {
 		Usage:       "config [subcommand]",
 		LongDesc:    "Manage configuration files and environment variables for azemailsender-cli",
 		Run: func(ctx *simplecli.Context) error {
-			return fmt.Errorf("subcommand required. Use --help to see available subcommands")
+			return codedError(CodeUsage, "subcommand required. Use --help to see available subcommands")
 		},
 		Subcommands: []*simplecli.Command{
 			{
 				Name:        "init",
 				Description: "Create a default configuration file",
 				Usage:       "config init [--path <path>]",
-				LongDesc: `Create a default configuration file.
+				LongDesc: `Create a default configuration file. Without --path the file is created in
+the per-user location: %APPDATA%\azemailsender\azemailsender.json on Windows,
+otherwise $XDG_CONFIG_HOME/azemailsender/azemailsender.json or
+~/.config/azemailsender/azemailsender.json.
 
 Examples:
-  # Create config in current directory
+  # Create config in the per-user location
   azemailsender-cli config init
 
-  # Create config in specific location
-  azemailsender-cli config init --path ~/.config/azemailsender/config.json`,
+  # Create config in the current directory
+  azemailsender-cli config init --path ./azemailsender.json`,
 				Run: runConfigInit,
 				Flags: []*simplecli.Flag{
 					{
 						Name:        "path",
 						Short:       "p",
-						Description: "Path for the configuration file",
-						Value:       "./azemailsender.json",
+						Description: "Path for the configuration file (default: the per-user location)",
+						Value:       "",
 					},
 				},
 			},
 			{
 				Name:        "show",
 				Description: "Show current configuration",
-				Usage:       "config show",
+				Usage:       "config show [--sources]",
 				LongDesc: `Show the current configuration loaded from files and environment variables.
 
+With --sources, each effective setting is listed with where its value came
+from: default, file <path>, profile <name>, k8s <path>, env <VAR> or
+flag --<name>. Later sources override earlier ones in that order.
+
 Examples:
   # Show current configuration
   azemailsender-cli config show
 
   # Show configuration from specific file
-  azemailsender-cli config show --config ~/.config/azemailsender/config.json`,
+  azemailsender-cli config show --config ~/.config/azemailsender/config.json
+
+  # Find out why a setting has an unexpected value
+  azemailsender-cli config show --sources --profile marketing`,
 				Run: runConfigShow,
+				Flags: []*simplecli.Flag{
+					{
+						Name:        "sources",
+						Description: "Show where each setting came from",
+						Value:       false,
+					},
+				},
 			},
 			{
 				Name:        "env",
@@ -71,23 +90,154 @@ Examples:
   azemailsender-cli config env > .env`,
 				Run: runConfigEnv,
 			},
+			{
+				Name:        "validate",
+				Description: "Check the configuration file for mistakes",
+				Usage:       "config validate",
+				LongDesc: `Check the configuration file for unknown keys, values of the wrong type,
+invalid durations, malformed endpoints and proxies, and authentication settings
+that conflict. Each problem is reported with its line number. Exits with code 0
+when the file is valid and 2 when it is not.
+
+Examples:
+  # Check the file found in the search path
+  azemailsender-cli config validate
+
+  # Check a specific file in CI
+  azemailsender-cli config validate --config deploy/azemailsender.json --json`,
+				Run: runConfigValidate,
+			},
+			{
+				Name:        "encrypt",
+				Description: "Encrypt access keys and connection strings in the configuration file",
+				Usage:       "config encrypt [--key-file <file>]",
+				LongDesc: `Encrypt the access keys and connection strings of the configuration file in
+place, at the top level and in every profile, with AES-256-GCM. The key is
+derived from the contents of --key-file (or AZURE_EMAIL_CONFIG_KEY_FILE), or
+from AZURE_EMAIL_CONFIG_PASSPHRASE. Other commands decrypt the values
+transparently when the same key file or passphrase is given, and 'config set'
+encrypts new secrets once the file holds encrypted values.
+
+Examples:
+  # Encrypt with a random key file
+  head -c 32 /dev/urandom | base64 > ~/.config/azemailsender/key
+  azemailsender-cli config encrypt --key-file ~/.config/azemailsender/key
+
+  # Encrypt with a passphrase
+  AZURE_EMAIL_CONFIG_PASSPHRASE='correct horse battery staple' azemailsender-cli config encrypt`,
+				Run: runConfigEncrypt,
+			},
+			{
+				Name:        "decrypt",
+				Description: "Decrypt encrypted values in the configuration file",
+				Usage:       "config decrypt [--key-file <file>]",
+				LongDesc: `Write the plain text of encrypted values back to the configuration file,
+using the same key file or passphrase they were encrypted with.
+
+Examples:
+  azemailsender-cli config decrypt --key-file ~/.config/azemailsender/key`,
+				Run: runConfigDecrypt,
+			},
+			{
+				Name:        "get",
+				Description: "Print the effective value of a setting",
+				Usage:       "config get <key>",
+				LongDesc: `Print the value of a setting after the config file, the active profile,
+environment variables and flags are applied.
+
+Examples:
+  azemailsender-cli config get endpoint
+  azemailsender-cli --profile marketing config get from`,
+				Run: runConfigGet,
+			},
+			{
+				Name:        "set",
+				Description: "Set a value in the config file",
+				Usage:       "config set <key> <value>",
+				LongDesc: `Set a value in the active config file, creating ./azemailsender.json if there
+is none. When a profile is active and the key is a profile setting, the value is
+stored in that profile. Other keys, including unknown ones, are kept in order.
+
+Examples:
+  azemailsender-cli config set from sender@yourdomain.com
+  azemailsender-cli --profile marketing config set reply-to marketing@yourdomain.com
+  azemailsender-cli config set poll-interval 10s`,
+				Run: runConfigSet,
+			},
+			{
+				Name:        "unset",
+				Description: "Remove a value from the config file",
+				Usage:       "config unset <key>",
+				LongDesc: `Remove a value from the active config file, or from the active profile
+when the key is a profile setting.
+
+Examples:
+  azemailsender-cli config unset reply-to`,
+				Run: runConfigUnset,
+			},
+			{
+				Name:        "profiles",
+				Description: "List, show and select named profiles",
+				Usage:       "config profiles [subcommand]",
+				LongDesc:    "Manage the named profiles in the configuration file",
+				Run: func(ctx *simplecli.Context) error {
+					return codedError(CodeUsage, "subcommand required. Use --help to see available subcommands")
+				},
+				Subcommands: []*simplecli.Command{
+					{
+						Name:        "list",
+						Description: "List profiles, marking the active one",
+						Usage:       "config profiles list",
+						LongDesc: `List the profiles defined in the configuration file. The active profile,
+chosen by --profile, AZURE_EMAIL_PROFILE or the "profile" key, is marked with *.
+
+Examples:
+  azemailsender-cli config profiles list`,
+						Run: runConfigProfilesList,
+					},
+					{
+						Name:        "show",
+						Description: "Show the settings of a profile",
+						Usage:       "config profiles show [name]",
+						LongDesc: `Show the settings of a profile, or of the active profile when no name is
+given. Access keys and connection strings are hidden.
+
+Examples:
+  azemailsender-cli config profiles show marketing`,
+						Run: runConfigProfilesShow,
+					},
+					{
+						Name:        "use",
+						Description: "Set the default profile in the configuration file",
+						Usage:       "config profiles use <name>",
+						LongDesc: `Set the "profile" key of the configuration file, so the profile is used
+whenever --profile and AZURE_EMAIL_PROFILE are not given.
+
+Examples:
+  azemailsender-cli config profiles use transactional`,
+						Run: runConfigProfilesUse,
+					},
+				},
+			},
 		},
 	}
 }
 
 func runConfigInit(ctx *simplecli.Context) error {
 	path := ctx.GetString("path")
-	debug := ctx.GetBool("debug")
-	quiet := ctx.GetBool("quiet")
-	jsonOutput := ctx.GetBool("json")
+	if path == "" {
+		path = simpleconfig.DefaultPath()
+	}
 
-	formatter := output.NewFormatter(jsonOutput, quiet, debug)
+	formatter, err := newFormatter(ctx)
+	if err != nil {
+		return err
+	}
 
 	// Create directory if it doesn't exist
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		formatter.PrintError(fmt.Errorf("failed to create directory %s: %w", dir, err))
-		return err
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
 	}
 
 	// Check if file already exists
@@ -97,26 +247,27 @@ func runConfigInit(ctx *simplecli.Context) error {
 
 	// Create default configuration file
 	if err := simpleconfig.SaveDefaultConfig(path); err != nil {
-		formatter.PrintError(fmt.Errorf("failed to create configuration file: %w", err))
-		return err
+		return fmt.Errorf("failed to create configuration file: %w", err)
 	}
 
 	return formatter.PrintSuccess("Configuration file created at %s", path)
 }
 
 func runConfigShow(ctx *simplecli.Context) error {
-	debug := ctx.GetBool("debug")
-	quiet := ctx.GetBool("quiet")
-	jsonOutput := ctx.GetBool("json")
-
-	formatter := output.NewFormatter(jsonOutput, quiet, debug)
+	formatter, err := newFormatter(ctx)
+	if err != nil {
+		return err
+	}
 
 	// Load configuration
 	configFile := ctx.GetString("config")
-	cfg, err := simpleconfig.LoadConfig(configFile, ctx.Flags)
+	cfg, err := simpleconfig.LoadConfig(configFile, ctx.ProvidedFlags())
 	if err != nil {
-		formatter.PrintError(fmt.Errorf("failed to load configuration: %w", err))
-		return err
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if ctx.GetBool("sources") {
+		return formatter.PrintConfigSources(configSources(cfg))
 	}
 
 	// Hide sensitive data for display
@@ -127,31 +278,313 @@ func runConfigShow(ctx *simplecli.Context) error {
 	if displayConfig.ConnectionString != "" {
 		displayConfig.ConnectionString = "***HIDDEN***"
 	}
+	displayConfig.Transport = hideURLPassword(cfg.Transport)
+	if len(cfg.Profiles) > 0 {
+		displayConfig.Profiles = make(map[string]*simpleconfig.Profile, len(cfg.Profiles))
+		for name, profile := range cfg.Profiles {
+			displayConfig.Profiles[name] = hideProfileSecrets(profile)
+		}
+	}
 
-	return formatter.PrintConfig(displayConfig)
+	return formatter.PrintConfig(output.KindConfig, displayConfig)
 }
 
 func runConfigEnv(ctx *simplecli.Context) error {
-	debug := ctx.GetBool("debug")
-	quiet := ctx.GetBool("quiet")
-	jsonOutput := ctx.GetBool("json")
-
-	formatter := output.NewFormatter(jsonOutput, quiet, debug)
+	formatter, err := newFormatter(ctx)
+	if err != nil {
+		return err
+	}
 
-	if jsonOutput {
+	if formatter.JSON {
 		envConfig := map[string]string{
-			"AZURE_EMAIL_ENDPOINT":          "https://your-resource.communication.azure.com",
-			"AZURE_EMAIL_ACCESS_KEY":        "your-access-key",
-			"AZURE_EMAIL_CONNECTION_STRING": "endpoint=https://your-resource.communication.azure.com;accesskey=your-access-key",
-			"AZURE_EMAIL_FROM":              "sender@yourdomain.com",
-			"AZURE_EMAIL_REPLY_TO":          "reply@yourdomain.com",
-			"AZURE_EMAIL_DEBUG":             "false",
-			"AZURE_EMAIL_QUIET":             "false",
-			"AZURE_EMAIL_JSON":              "false",
+			simpleconfig.EnvName("ENDPOINT"):          "https://your-resource.communication.azure.com",
+			simpleconfig.EnvName("ACCESS_KEY"):        "your-access-key",
+			simpleconfig.EnvName("CONNECTION_STRING"): "endpoint=https://your-resource.communication.azure.com;accesskey=your-access-key",
+			simpleconfig.EnvName("FROM"):              "sender@yourdomain.com",
+			simpleconfig.EnvName("REPLY_TO"):          "reply@yourdomain.com",
+			simpleconfig.EnvName("DEBUG"):             "false",
+			simpleconfig.EnvName("QUIET"):             "false",
+			simpleconfig.EnvName("JSON"):              "false",
 		}
-		return formatter.PrintConfig(envConfig)
+		return formatter.PrintConfig(output.KindEnvironment, envConfig)
 	}
 
 	fmt.Print(simpleconfig.GetEnvConfigExample())
 	return nil
-}
\ No newline at end of file
+}
+
+// configSources lists the settable keys with their effective values and origin
+func configSources(cfg *simpleconfig.Config) []output.ConfigSource {
+	var settings []output.ConfigSource
+	for _, key := range simpleconfig.Keys(false) {
+		value, _ := cfg.Get(key)
+		source := cfg.Sources[key]
+		if source == "" {
+			source = "unset"
+		}
+		if value != "" && (key == "access-key" || key == "connection-string") {
+			value = "***HIDDEN***"
+		}
+		if key == "transport" {
+			value = hideURLPassword(value)
+		}
+		settings = append(settings, output.ConfigSource{Key: key, Value: value, Source: source})
+	}
+	return settings
+}
+
+// hideURLPassword hides the password of a URL such as an smtp:// transport
+func hideURLPassword(value string) string {
+	if parsed, err := url.Parse(value); err == nil && parsed.User != nil {
+		return parsed.Redacted()
+	}
+	return value
+}
+
+// hideProfileSecrets returns a copy of a profile with its credentials hidden
+func hideProfileSecrets(profile *simpleconfig.Profile) *simpleconfig.Profile {
+	displayProfile := *profile
+	if displayProfile.AccessKey != "" {
+		displayProfile.AccessKey = "***HIDDEN***"
+	}
+	if displayProfile.ConnectionString != "" {
+		displayProfile.ConnectionString = "***HIDDEN***"
+	}
+	return &displayProfile
+}
+
+// profileEndpoint returns the endpoint of a profile, including one inside its connection string
+func profileEndpoint(profile *simpleconfig.Profile) string {
+	if profile.Endpoint != "" {
+		return profile.Endpoint
+	}
+	for _, part := range strings.Split(profile.ConnectionString, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "endpoint") {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+func runConfigProfilesList(ctx *simplecli.Context) error {
+	formatter, err := newFormatter(ctx)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := simpleconfig.LoadFile(ctx.GetString("config"))
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	active := simpleconfig.SelectedProfile(cfg, ctx.ProvidedFlags())
+
+	var profiles []output.ProfileSummary
+	for _, name := range simpleconfig.ProfileNames(ctx.GetString("config")) {
+		profile := cfg.Profiles[name]
+		profiles = append(profiles, output.ProfileSummary{
+			Name:     name,
+			Active:   name == active,
+			Endpoint: profileEndpoint(profile),
+			From:     profile.From,
+		})
+	}
+	return formatter.PrintProfiles(profiles)
+}
+
+func runConfigProfilesShow(ctx *simplecli.Context) error {
+	formatter, err := newFormatter(ctx)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := simpleconfig.LoadFile(ctx.GetString("config"))
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	name := simpleconfig.SelectedProfile(cfg, ctx.ProvidedFlags())
+	if len(ctx.Args) > 0 {
+		name = ctx.Args[0]
+	}
+	if name == "" {
+		return fmt.Errorf("no active profile: give a profile name or select one with --profile")
+	}
+
+	profile, ok := cfg.Profiles[name]
+	if !ok {
+		return fmt.Errorf("profile %q not found in configuration", name)
+	}
+	return formatter.PrintConfig(output.KindProfile, map[string]interface{}{
+		"name":    name,
+		"profile": hideProfileSecrets(profile),
+	})
+}
+
+func runConfigProfilesUse(ctx *simplecli.Context) error {
+	formatter, err := newFormatter(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(ctx.Args) != 1 {
+		return codedError(CodeUsage, "profile name required")
+	}
+
+	path, err := simpleconfig.SetDefaultProfile(ctx.GetString("config"), ctx.Args[0])
+	if err != nil {
+		return err
+	}
+	return formatter.PrintSuccess("Default profile set to %s in %s", ctx.Args[0], path)
+}
+
+// editTarget returns the profile a set or unset of key applies to, or "" for the top level
+func editTarget(ctx *simplecli.Context, key string) (string, error) {
+	cfg, err := simpleconfig.LoadFile(ctx.GetString("config"))
+	if err != nil {
+		return "", fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	profile := simpleconfig.SelectedProfile(cfg, ctx.ProvidedFlags())
+	if profile == "" {
+		return "", nil
+	}
+	for _, profileKey := range simpleconfig.Keys(true) {
+		if profileKey == key {
+			return profile, nil
+		}
+	}
+	return "", nil
+}
+
+// editedScope describes where a value was edited, for messages
+func editedScope(profile, path string) string {
+	if profile != "" {
+		return fmt.Sprintf("profile %s in %s", profile, path)
+	}
+	return path
+}
+
+func runConfigValidate(ctx *simplecli.Context) error {
+	formatter, err := newFormatter(ctx)
+	if err != nil {
+		return err
+	}
+
+	path, problems, err := simpleconfig.Check(ctx.GetString("config"))
+	if err != nil {
+		return err
+	}
+
+	var report []output.ConfigProblem
+	for _, problem := range problems {
+		report = append(report, output.ConfigProblem{Line: problem.Line, Key: problem.Key, Message: problem.Message})
+	}
+	if err := formatter.PrintConfigProblems(path, report); err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return codedError(CodeConfigFileInvalid, "%s has %d problem(s)", path, len(problems))
+	}
+	return nil
+}
+
+func runConfigGet(ctx *simplecli.Context) error {
+	formatter, err := newFormatter(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(ctx.Args) != 1 {
+		return codedError(CodeUsage, "key required")
+	}
+	key := ctx.Args[0]
+
+	cfg, err := simpleconfig.LoadConfig(ctx.GetString("config"), ctx.ProvidedFlags())
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	value, ok := cfg.Get(key)
+	if !ok {
+		return fmt.Errorf("unknown configuration key %q; valid keys: %s", key, strings.Join(simpleconfig.Keys(false), ", "))
+	}
+
+	if formatter.JSON {
+		return formatter.PrintConfig(output.KindConfigValue, map[string]string{"key": key, "value": value})
+	}
+	fmt.Println(value)
+	return nil
+}
+
+func runConfigSet(ctx *simplecli.Context) error {
+	formatter, err := newFormatter(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(ctx.Args) != 2 {
+		return codedError(CodeUsage, "key and value required")
+	}
+	key, value := ctx.Args[0], ctx.Args[1]
+
+	profile, err := editTarget(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	path, err := simpleconfig.SetValue(ctx.GetString("config"), profile, key, value, ctx.GetString("key-file"))
+	if err != nil {
+		return err
+	}
+	return formatter.PrintSuccess("Set %s in %s", key, editedScope(profile, path))
+}
+
+func runConfigEncrypt(ctx *simplecli.Context) error {
+	formatter, err := newFormatter(ctx)
+	if err != nil {
+		return err
+	}
+
+	path, count, err := simpleconfig.EncryptFile(ctx.GetString("config"), ctx.GetString("key-file"))
+	if err != nil {
+		return err
+	}
+	return formatter.PrintSuccess("Encrypted %d value(s) in %s", count, path)
+}
+
+func runConfigDecrypt(ctx *simplecli.Context) error {
+	formatter, err := newFormatter(ctx)
+	if err != nil {
+		return err
+	}
+
+	path, count, err := simpleconfig.DecryptFile(ctx.GetString("config"), ctx.GetString("key-file"))
+	if err != nil {
+		return err
+	}
+	return formatter.PrintSuccess("Decrypted %d value(s) in %s", count, path)
+}
+
+func runConfigUnset(ctx *simplecli.Context) error {
+	formatter, err := newFormatter(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(ctx.Args) != 1 {
+		return codedError(CodeUsage, "key required")
+	}
+	key := ctx.Args[0]
+
+	profile, err := editTarget(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	path, err := simpleconfig.UnsetValue(ctx.GetString("config"), profile, key)
+	if err != nil {
+		return err
+	}
+	return formatter.PrintSuccess("Removed %s from %s", key, editedScope(profile, path))
+}
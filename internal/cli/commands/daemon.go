@@ -0,0 +1,722 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/groovy-sky/azemailsender"
+	"github.com/groovy-sky/azemailsender/internal/cli/output"
+	"github.com/groovy-sky/azemailsender/internal/simpleconfig"
+	"github.com/groovy-sky/azemailsender/internal/simplecli"
+)
+
+// subscriptionValidationEventType is the event Event Grid sends to validate a webhook subscription
+const subscriptionValidationEventType = "Microsoft.EventGrid.SubscriptionValidationEvent"
+
+// maxEventPayload bounds the Event Grid requests read by the daemon
+const maxEventPayload = 2 << 20
+
+// eventsKeyHeader carries the --events-key of Event Grid requests as an
+// alternative to the key query parameter
+const eventsKeyHeader = "X-Events-Key"
+
+// NewDaemonCommand creates the daemon command
+func NewDaemonCommand() *simplecli.Command {
+	return &simplecli.Command{
+		Name:        "daemon",
+		Description: "Run the queue worker, scheduler and Event Grid receiver",
+		Usage:       "daemon [flags]",
+		LongDesc: `Run in the foreground as a long-lived service:
+
+  - the queue worker sends pending and due failed messages from the outbox
+    every --interval, retrying failures and dead-lettering them after
+    --max-attempts; messages queued with 'queue add --send-at' are sent once
+    their time has come, and digests ('queue add --digest-key') as one email
+    per recipient once their window closes, merged with --digest-template
+  - --throttle limits the messages of a category each recipient gets within
+    a window; messages over the limit are delayed, dropped (dead-lettered) or
+    collected into a digest sent once the recipient is below the limit
+  - POST /events receives Event Grid delivery reports; permanent failures are
+    added to --suppression-file when set. Requests must carry --events-key as
+    the key query parameter of the subscription's endpoint URL, e.g.
+    https://host/events?key=SECRET, or in the X-Events-Key header; without
+    --events-key the endpoint refuses every request
+  - GET /healthz reports that the process is alive and GET /readyz that the
+    outbox can be read and the daemon is not shutting down
+  - with --webhook-url, dead-lettered messages and delivery reports are posted
+    to the URL as JSON, signed with --webhook-secret
+  - with --alert-webhook, a Slack or Microsoft Teams channel is alerted when a
+    message is dead-lettered and when an alert rule starts or stops firing
+  - --alert-rule METRIC>THRESHOLD/WINDOW fires while a metric over the window
+    is above the threshold, failing /readyz; metrics are failure-rate (percent
+    of failed sends and delivery reports), failures, throttled (HTTP 429
+    answers) and dead-letters. --alert-failure-rate N is short for
+    --alert-rule failure-rate>=N%/WINDOW with --alert-window
+
+SIGTERM or Ctrl+C stops taking work, finishes the send in progress and exits
+within --drain-timeout. Logs are structured: key=value lines, or JSON lines
+with --json, on stderr or in --log-file.
+
+--install-systemd writes a service unit running the daemon with the other
+flags given, and a socket unit listening on --listen, instead of starting. When
+started through the socket unit the daemon uses the socket systemd passes in.
+
+The HTTP server listens on 127.0.0.1:8080 by default; set --listen :8080 to
+reach it from other hosts, such as Event Grid or Kubernetes probes.
+
+Examples:
+  azemailsender-cli daemon --connection-string "$CONNECTION_STRING" --listen :8080 --events-key "$EVENTS_KEY"
+
+  # Record bounces reported by Event Grid
+  azemailsender-cli daemon --json --suppression-file /var/lib/azemailsender/suppressed.txt
+
+  # Alert a Slack channel on dead-letters and when 20% of deliveries fail
+  azemailsender-cli daemon --alert-webhook "$SLACK_WEBHOOK_URL" --alert-failure-rate 20
+
+  # Alert and fail readiness when more than 5% fail over 10 minutes or the
+  # service throttled more than 20 requests in 5 minutes
+  azemailsender-cli daemon --alert-rule 'failure-rate>5%/10m' --alert-rule 'throttled>20/5m'
+
+  # Install systemd units, or print them
+  sudo azemailsender-cli daemon --install-systemd --listen 127.0.0.1:8080
+  azemailsender-cli daemon --install-systemd --systemd-dir -`,
+		Run: runDaemon,
+		Flags: joinFlags(authFlags(), networkFlags(), queueFlags(), templateDirFlags(), suppressionFlags(), dedupeFlags(), simplecli.InCategory("Daemon", []*simplecli.Flag{
+			{
+				Name:        "listen",
+				Description: "Address of the HTTP server for health checks and Event Grid",
+				Value:       "127.0.0.1:8080",
+				EnvVar:      "DAEMON_LISTEN",
+			},
+			{
+				Name:        "events-key",
+				Description: "Shared secret POST /events requests must send as the key query parameter or X-Events-Key header",
+				Value:       "",
+				EnvVar:      "DAEMON_EVENTS_KEY",
+			},
+			{
+				Name:        "interval",
+				Description: "How often the outbox is checked for messages to send",
+				Value:       5 * time.Second,
+				EnvVar:      "DAEMON_INTERVAL",
+			},
+			{
+				Name:        "max-attempts",
+				Description: "Dead-letter a message after this many failed sends",
+				Value:       azemailsender.DefaultOutboxOptions().MaxAttempts,
+				EnvVar:      "DAEMON_MAX_ATTEMPTS",
+			},
+			{
+				Name:        "queue-retry-delay",
+				Description: "Wait before sending a failed message again, doubled after each failure",
+				Value:       azemailsender.DefaultOutboxOptions().RetryDelay,
+				EnvVar:      "DAEMON_QUEUE_RETRY_DELAY",
+			},
+			{
+				Name:        "drain-timeout",
+				Description: "Time allowed on shutdown for the send in progress and open HTTP requests",
+				Value:       30 * time.Second,
+				EnvVar:      "DAEMON_DRAIN_TIMEOUT",
+			},
+			{
+				Name:        "webhook-url",
+				Description: "POST a JSON notification here when a message is dead-lettered or a delivery report arrives",
+				Value:       "",
+				EnvVar:      "DAEMON_WEBHOOK_URL",
+			},
+			{
+				Name:        "webhook-secret",
+				Description: "Sign webhook notifications with HMAC-SHA256 in the X-Azemailsender-Signature header",
+				Value:       "",
+				EnvVar:      "DAEMON_WEBHOOK_SECRET",
+			},
+			{
+				Name:        "alert-webhook",
+				Description: "Slack or Microsoft Teams incoming webhook alerted on dead-letters and high failure rates",
+				Value:       "",
+				EnvVar:      "DAEMON_ALERT_WEBHOOK",
+			},
+			{
+				Name:        "alert-format",
+				Description: "Chat service of --alert-webhook: auto, slack or teams",
+				Value:       "auto",
+				EnvVar:      "DAEMON_ALERT_FORMAT",
+			},
+			{
+				Name:        "alert-rule",
+				Description: "Alert rule METRIC>THRESHOLD/WINDOW, e.g. failure-rate>5%/10m or throttled>20/5m (repeatable)",
+				Value:       []string{},
+				EnvVar:      "DAEMON_ALERT_RULES",
+			},
+			{
+				Name:        "alert-failure-rate",
+				Description: "Alert when this percentage of sends and delivery reports fails (0 to disable)",
+				Value:       0.0,
+				EnvVar:      "DAEMON_ALERT_FAILURE_RATE",
+			},
+			{
+				Name:        "alert-window",
+				Description: "Window of --alert-failure-rate",
+				Value:       15 * time.Minute,
+				EnvVar:      "DAEMON_ALERT_WINDOW",
+			},
+			{
+				Name:        "alert-min-messages",
+				Description: "Outcomes needed in the window before a failure rate is evaluated",
+				Value:       10,
+				EnvVar:      "DAEMON_ALERT_MIN_MESSAGES",
+			},
+			{
+				Name:        "digest-template",
+				Description: "Named template merging the messages of a digest (default: one section per message)",
+				Value:       "",
+				EnvVar:      "DAEMON_DIGEST_TEMPLATE",
+			},
+			{
+				Name:        "throttle",
+				Description: "Per-recipient limit [CATEGORY=]LIMIT/WINDOW[:delay|drop|digest], e.g. alerts=5/1h:digest (repeatable)",
+				Value:       []string{},
+				EnvVar:      "DAEMON_THROTTLE",
+			},
+			{
+				Name:        "install-systemd",
+				Description: "Write systemd service and socket units for the daemon instead of running it",
+				Value:       false,
+			},
+			{
+				Name:        "systemd-dir",
+				Description: "Directory for --install-systemd, or - to print the units",
+				Value:       "/etc/systemd/system",
+			},
+		})),
+		Constraints: joinConstraints(authConstraints(), suppressionConstraints(), []simplecli.Constraint{
+			simplecli.Requires("systemd-dir", "install-systemd"),
+			simplecli.Requires("webhook-secret", "webhook-url"),
+		}),
+	}
+}
+
+// daemon holds the state shared by the queue worker and the HTTP handlers
+type daemon struct {
+	outbox  *azemailsender.Outbox
+	client  *azemailsender.Client
+	options *azemailsender.OutboxOptions
+	logger  *slog.Logger
+
+	// eventsKey authenticates Event Grid requests; /events is refused without one
+	eventsKey string
+
+	// suppression records permanent delivery failures; nil without --suppression-file
+	suppression     *azemailsender.LocalSuppressionList
+	suppressionFile string
+
+	// webhook is notified of final message states; nil without --webhook-url
+	webhook       *azemailsender.Webhook
+	notifications sync.WaitGroup
+
+	// alerter posts to a chat channel; nil without --alert-webhook
+	alerter *chatAlerter
+	// monitor evaluates the alert rules; nil without any
+	monitor *alertMonitor
+
+	mu sync.Mutex
+	// notReady is why /readyz fails, empty when the daemon is ready
+	notReady string
+}
+
+func runDaemon(ctx *simplecli.Context) error {
+	interval := ctx.GetDuration("interval")
+	if interval <= 0 {
+		return codedError(CodeUsage, "--interval must be positive")
+	}
+	drainTimeout := ctx.GetDuration("drain-timeout")
+
+	config, err := simpleconfig.LoadConfig(ctx.GetString("config"), ctx.ProvidedFlags())
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	formatter, err := newFormatter(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The units only reference the credentials, so none are needed to write them
+	if ctx.GetBool("install-systemd") {
+		return installSystemd(ctx, formatter, ctx.GetString("systemd-dir"))
+	}
+
+	monitor, err := newDaemonAlertMonitor(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, err := newClientWith(ctx, config, formatter, func(options *azemailsender.ClientOptions) {
+		if monitor == nil {
+			return
+		}
+		// Count throttled requests, including those the client retries
+		trace := options.OnHTTPTrace
+		options.OnHTTPTrace = func(t azemailsender.HTTPTrace) {
+			if trace != nil {
+				trace(t)
+			}
+			if t.StatusCode == http.StatusTooManyRequests {
+				monitor.record(eventThrottled)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	outbox, err := azemailsender.OpenOutbox(config.GetQueueDir())
+	if err != nil {
+		return err
+	}
+
+	d := &daemon{
+		outbox: outbox,
+		client: client,
+		options: &azemailsender.OutboxOptions{
+			MaxAttempts: ctx.GetInt("max-attempts"),
+			RetryDelay:  ctx.GetDuration("queue-retry-delay"),
+		},
+		logger:    newDaemonLogger(config, formatter),
+		eventsKey: ctx.GetString("events-key"),
+		monitor:   monitor,
+		notReady:  "starting",
+	}
+	if d.eventsKey == "" {
+		d.logger.Warn("POST /events refuses delivery reports without --events-key")
+	}
+	d.options.OnDelivered = d.logDelivery
+	if name := ctx.GetString("digest-template"); name != "" {
+		t, err := (&templateStore{dir: config.GetTemplateDir()}).get(name)
+		if err != nil {
+			return err
+		}
+		d.options.Digest = t.digest
+	}
+	if d.options.Throttle, err = throttleFlagPolicies(ctx); err != nil {
+		return err
+	}
+	if url := ctx.GetString("webhook-url"); url != "" {
+		d.webhook = &azemailsender.Webhook{URL: url, Secret: ctx.GetString("webhook-secret")}
+	}
+	if url := ctx.GetString("alert-webhook"); url != "" {
+		alerter, err := newChatAlerter(url, ctx.GetString("alert-format"))
+		if err != nil {
+			return err
+		}
+		d.alerter = alerter
+	}
+
+	if config.SuppressionFile != "" {
+		list, err := azemailsender.LoadSuppressionList(config.SuppressionFile)
+		if errors.Is(err, fs.ErrNotExist) {
+			list = azemailsender.NewLocalSuppressionList()
+		} else if err != nil {
+			return err
+		}
+		d.suppression = list
+		d.suppressionFile = config.SuppressionFile
+	}
+
+	// Prefer the socket passed by systemd socket activation
+	listener, err := systemdListener()
+	if err != nil {
+		return err
+	}
+	if listener == nil {
+		listener, err = net.Listen("tcp", ctx.GetString("listen"))
+		if err != nil {
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+	}
+	server := &http.Server{
+		Handler:           d.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	stop, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+
+	worker := make(chan struct{})
+	go func() {
+		defer close(worker)
+		d.work(stop, interval)
+	}()
+
+	d.logger.Info("daemon started", "listen", listener.Addr().String(), "queue", outbox.Dir(), "interval", interval)
+
+	select {
+	case <-stop.Done():
+	case err := <-serveErr:
+		cancel()
+		<-worker
+		return fmt.Errorf("HTTP server failed: %w", err)
+	}
+
+	// Fail readiness first so load balancers stop sending events
+	d.setNotReady("shutting down")
+	d.logger.Info("draining", "timeout", drainTimeout)
+
+	drain, cancelDrain := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancelDrain()
+	if err := server.Shutdown(drain); err != nil {
+		d.logger.Warn("HTTP server did not shut down cleanly", "error", err)
+	}
+
+	select {
+	case <-worker:
+	case <-drain.Done():
+		d.logger.Error("drain timeout exceeded with a send in progress")
+		return codedError(CodeWaitTimeout, "drain timeout of %s exceeded with a send in progress", drainTimeout)
+	}
+
+	notified := make(chan struct{})
+	go func() {
+		d.notifications.Wait()
+		close(notified)
+	}()
+	select {
+	case <-notified:
+	case <-drain.Done():
+		d.logger.Warn("drain timeout exceeded with notifications pending")
+	}
+
+	d.logger.Info("daemon stopped")
+	return nil
+}
+
+// newDaemonAlertMonitor returns a monitor for the alert rules of the flags, or
+// nil when there are none
+func newDaemonAlertMonitor(ctx *simplecli.Context) (*alertMonitor, error) {
+	var rules []*alertRule
+	for _, value := range ctx.GetStringSlice("alert-rule") {
+		// The environment variable holds a comma-separated list
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			rule, err := parseAlertRule(part)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, rule)
+		}
+	}
+
+	if threshold := ctx.GetFloat("alert-failure-rate"); threshold != 0 {
+		window := ctx.GetDuration("alert-window")
+		if window <= 0 {
+			return nil, codedError(CodeUsage, "--alert-window must be positive")
+		}
+		rule, err := parseAlertRule(fmt.Sprintf("%s>=%g%%/%s", metricFailureRate, threshold, window))
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	return newAlertMonitor(rules, ctx.GetInt("alert-min-messages")), nil
+}
+
+// newDaemonLogger returns a structured logger writing JSON lines with --json or
+// the json config key and key=value lines otherwise, to the log file when one is set
+func newDaemonLogger(config *simpleconfig.Config, formatter *output.Formatter) *slog.Logger {
+	var w io.Writer = os.Stderr
+	if formatter.Log != nil {
+		w = formatter.Log
+	}
+
+	options := &slog.HandlerOptions{Level: slog.LevelInfo}
+	if config.Debug {
+		options.Level = slog.LevelDebug
+	}
+
+	if formatter.JSON || config.JSON {
+		return slog.New(slog.NewJSONHandler(w, options))
+	}
+	return slog.New(slog.NewTextHandler(w, options))
+}
+
+// work delivers the outbox every interval until ctx is cancelled
+func (d *daemon) work(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_, err := d.outbox.Deliver(ctx, d.client, d.options)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			d.logger.Error("outbox delivery failed", "error", err)
+			d.setNotReady("outbox: " + err.Error())
+		default:
+			d.setNotReady("")
+		}
+		if d.monitor != nil {
+			d.applyAlerts(d.monitor.evaluate())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// logDelivery logs the outcome of sending an outbox message
+func (d *daemon) logDelivery(item *azemailsender.OutboxItem) {
+	attrs := []any{"id", item.ID, "attempts", item.Attempts}
+	switch item.State {
+	case azemailsender.OutboxSent:
+		d.logger.Info("message sent", append(attrs, "message_id", item.MessageID)...)
+		d.record(eventSucceeded)
+	case azemailsender.OutboxFailed:
+		d.logger.Warn("message failed", append(attrs, "error", item.LastError, "next_attempt", item.NextAttemptAt)...)
+		d.record(eventFailed)
+	default:
+		d.logger.Error("message dead-lettered", append(attrs, "error", item.LastError)...)
+		d.record(eventFailed, eventDeadLettered)
+		d.alert("Email dead-lettered", fmt.Sprintf("Outbox message %s was dead-lettered after %d attempts: %s", item.ID, item.Attempts, item.LastError))
+		d.notify(&azemailsender.Notification{
+			Type:      azemailsender.NotificationDeadLetter,
+			OutboxID:  item.ID,
+			MessageID: item.MessageID,
+			Status:    string(item.State),
+			Error:     item.LastError,
+		})
+	}
+}
+
+// notify posts notification to the webhook in the background
+func (d *daemon) notify(notification *azemailsender.Notification) {
+	if d.webhook == nil {
+		return
+	}
+	d.notifications.Add(1)
+	go func() {
+		defer d.notifications.Done()
+		if err := d.webhook.Notify(context.Background(), notification); err != nil {
+			d.logger.Warn("webhook notification failed", "type", notification.Type, "error", err)
+		}
+	}()
+}
+
+// record counts events for the alert rules
+func (d *daemon) record(kinds ...int) {
+	if d.monitor != nil {
+		d.applyAlerts(d.monitor.record(kinds...))
+	}
+}
+
+// applyAlerts logs and notifies rules that started or stopped firing
+func (d *daemon) applyAlerts(changes []*alertChange) {
+	for _, change := range changes {
+		title, text := change.describe()
+		notification := &azemailsender.Notification{
+			Type: azemailsender.NotificationAlert,
+			Rule: change.rule.name,
+		}
+		if change.firing {
+			d.logger.Warn("alert firing", "rule", change.rule.name, "value", change.value)
+			notification.Status = "firing"
+			notification.Error = text
+		} else {
+			d.logger.Info("alert resolved", "rule", change.rule.name, "value", change.value)
+			notification.Status = "resolved"
+		}
+		d.alert(title, text)
+		d.notify(notification)
+	}
+}
+
+// alert posts to the chat channel in the background
+func (d *daemon) alert(title, text string) {
+	if d.alerter == nil {
+		return
+	}
+	d.notifications.Add(1)
+	go func() {
+		defer d.notifications.Done()
+		if err := d.alerter.send(context.Background(), title, text); err != nil {
+			d.logger.Warn("chat alert failed", "title", title, "error", err)
+		}
+	}()
+}
+
+// setNotReady records why the daemon is not ready, or that it is when reason is empty
+func (d *daemon) setNotReady(reason string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.notReady = reason
+}
+
+// handler routes the daemon's HTTP endpoints
+func (d *daemon) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", d.readyz)
+	mux.HandleFunc("/events", d.events)
+	return mux
+}
+
+// readyz reports whether the daemon is ready, with the reason when it is not
+func (d *daemon) readyz(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	reason := d.notReady
+	d.mu.Unlock()
+	if reason == "" && d.monitor != nil {
+		if firing := d.monitor.firingRules(); len(firing) > 0 {
+			reason = "alert firing: " + strings.Join(firing, ", ")
+		}
+	}
+
+	if reason != "" {
+		http.Error(w, "not ready: "+reason, http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+// events receives Event Grid delivery reports, answering the subscription
+// validation handshakes of both the Event Grid and CloudEvents schemas
+func (d *daemon) events(w http.ResponseWriter, r *http.Request) {
+	if d.eventsKey == "" {
+		http.Error(w, "events are disabled: the daemon has no --events-key", http.StatusForbidden)
+		return
+	}
+	if !d.eventsAuthorized(r) {
+		d.logger.Warn("unauthorized Event Grid request", "remote", r.RemoteAddr)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// CloudEvents webhooks are validated with an OPTIONS request
+	if r.Method == http.MethodOptions {
+		if origin := r.Header.Get("WebHook-Request-Origin"); origin != "" {
+			w.Header().Set("WebHook-Allowed-Origin", origin)
+		}
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxEventPayload))
+	if err != nil {
+		http.Error(w, "failed to read events", http.StatusBadRequest)
+		return
+	}
+
+	if code, ok := subscriptionValidationCode(body); ok {
+		d.logger.Info("Event Grid subscription validated")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"validationResponse": code})
+		return
+	}
+
+	reports, err := azemailsender.ParseDeliveryReports(bytes.NewReader(body))
+	if err != nil {
+		d.logger.Warn("invalid Event Grid request", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var store azemailsender.SuppressionStore
+	if d.suppression != nil {
+		store = d.suppression
+	}
+	suppressed := 0
+	for _, report := range reports {
+		result := azemailsender.ProcessDeliveryReport(report, store)
+		d.logger.Info("delivery report", "message_id", report.MessageID, "status", report.Status, "class", result.Class, "permanent", result.Permanent, "token", result.Token)
+		if result.Class == azemailsender.FailureNone {
+			d.record(eventSucceeded)
+		} else {
+			d.record(eventFailed)
+		}
+		d.notify(&azemailsender.Notification{
+			Type:      azemailsender.NotificationDelivery,
+			MessageID: report.MessageID,
+			Status:    report.Status,
+			Recipient: result.Recipient,
+			Error:     report.DeliveryStatusDetails.StatusMessage,
+			Timestamp: report.DeliveryAttemptTimestamp,
+			Token:     result.Token,
+		})
+		if store != nil && result.Permanent && result.Class != azemailsender.FailureSuppressed && result.Recipient != "" {
+			suppressed++
+		}
+	}
+
+	if suppressed > 0 {
+		if err := d.suppression.Save(d.suppressionFile); err != nil {
+			d.logger.Error("failed to save suppression list", "error", err)
+			http.Error(w, "failed to save suppression list", http.StatusInternalServerError)
+			return
+		}
+		d.logger.Info("recipients suppressed", "count", suppressed, "file", d.suppressionFile)
+	}
+}
+
+// eventsAuthorized reports whether r carries the --events-key, compared in
+// constant time
+func (d *daemon) eventsAuthorized(r *http.Request) bool {
+	key := r.Header.Get(eventsKeyHeader)
+	if key == "" {
+		key = r.URL.Query().Get("key")
+	}
+	return hmac.Equal([]byte(key), []byte(d.eventsKey))
+}
+
+// subscriptionValidationCode returns the code of an Event Grid subscription
+// validation event, which must be echoed back to activate the subscription
+func subscriptionValidationCode(body []byte) (string, bool) {
+	var events []struct {
+		EventType string `json:"eventType"`
+		Data      struct {
+			ValidationCode string `json:"validationCode"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &events); err != nil {
+		return "", false
+	}
+	for _, event := range events {
+		if event.EventType == subscriptionValidationEventType && event.Data.ValidationCode != "" {
+			return event.Data.ValidationCode, true
+		}
+	}
+	return "", false
+}
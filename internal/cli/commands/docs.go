@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/groovy-sky/azemailsender/internal/simplecli"
+)
+
+// NewDocsCommand creates the docs command
+func NewDocsCommand() *simplecli.Command {
+	return &simplecli.Command{
+		Name:        "docs",
+		Description: "Generate man pages or markdown reference docs",
+		Usage:       "docs [--format man|markdown] [--out <dir>]",
+		LongDesc: `Generate reference documentation with one page per command, built from the
+command and flag definitions.
+
+Examples:
+  # Man pages for packaging
+  azemailsender-cli docs --format man --out ./man/man1
+
+  # Markdown reference
+  azemailsender-cli docs --format markdown --out ./docs`,
+		Run: runDocs,
+		Flags: []*simplecli.Flag{
+			{
+				Name:        "format",
+				Description: "Output format: man or markdown",
+				Value:       "markdown",
+			},
+			{
+				Name:        "out",
+				Short:       "o",
+				Description: "Output directory",
+				Value:       "./docs",
+			},
+		},
+	}
+}
+
+func runDocs(ctx *simplecli.Context) error {
+	dir := ctx.GetString("out")
+	if err := ctx.GlobalCtx.GenerateDocs(dir, ctx.GetString("format")); err != nil {
+		return err
+	}
+	if !ctx.GetBool("quiet") {
+		fmt.Printf("Documentation written to %s\n", dir)
+	}
+	return nil
+}
@@ -0,0 +1,313 @@
+package commands
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/groovy-sky/azemailsender"
+	"github.com/groovy-sky/azemailsender/internal/cli/output"
+	"github.com/groovy-sky/azemailsender/internal/simpleconfig"
+	"github.com/groovy-sky/azemailsender/internal/simplecli"
+)
+
+// maxClockSkew is the largest clock difference HMAC authentication tolerates
+const maxClockSkew = 5 * time.Minute
+
+// NewDoctorCommand creates the doctor command
+func NewDoctorCommand() *simplecli.Command {
+	return &simplecli.Command{
+		Name:        "doctor",
+		Description: "Diagnose configuration and connectivity",
+		Usage:       "doctor [flags]",
+		LongDesc: `Check that the configured Azure Communication Services resource is usable:
+endpoint reachability, clock skew, authentication and, optionally, the sender
+domain by sending a test email.
+
+Examples:
+  # Run all checks that don't send email
+  azemailsender-cli doctor
+
+  # Also verify the sender domain by sending a test email
+  azemailsender-cli doctor --from sender@example.com --send-test me@example.com`,
+		Run: runDoctor,
+		Flags: joinFlags(authFlags(), networkFlags(), []*simplecli.Flag{
+			{
+				Name:        "from",
+				Short:       "f",
+				Description: "Sender email address to verify",
+				Value:       "",
+				EnvVar:      "FROM",
+			},
+			{
+				Name:        "send-test",
+				Description: "Send a test email to this address to verify the sender domain",
+				Value:       "",
+			},
+		}),
+		Constraints: authConstraints(),
+	}
+}
+
+func runDoctor(ctx *simplecli.Context) error {
+	formatter, err := newFormatter(ctx)
+	if err != nil {
+		return err
+	}
+
+	configFile := ctx.GetString("config")
+	config, err := simpleconfig.LoadConfig(configFile, ctx.ProvidedFlags())
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	var checks []output.Diagnostic
+	report := func() error {
+		if err := formatter.PrintDiagnostics(output.KindDoctorReport, checks); err != nil {
+			return err
+		}
+		for _, check := range checks {
+			if check.Status == output.DiagnosticFail {
+				return codedError(CodeChecksFailed, "one or more checks failed")
+			}
+		}
+		return nil
+	}
+
+	// Configuration
+	client, err := newClient(ctx, config, formatter)
+	if err != nil {
+		checks = append(checks, output.Diagnostic{
+			Name:   "configuration",
+			Status: output.DiagnosticFail,
+			Detail: err.Error(),
+			Hint:   "Set --connection-string, or --endpoint and --access-key (see 'config env')",
+		})
+		return report()
+	}
+	endpoint := client.ActiveEndpoint()
+	checks = append(checks, output.Diagnostic{
+		Name:   "configuration",
+		Status: output.DiagnosticPass,
+		Detail: fmt.Sprintf("endpoint %s", endpoint),
+	})
+
+	// Network settings were already validated by newClient
+	tlsConfig, proxy, _ := newNetworkSettings(config)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	if proxy != nil {
+		transport.Proxy = proxy
+	}
+
+	// Reachability
+	var reachability output.Diagnostic
+	if proxy != nil {
+		reachability = checkProxyReachability(endpoint, transport)
+	} else {
+		reachability = checkReachability(endpoint, tlsConfig)
+	}
+	checks = append(checks, reachability)
+	if reachability.Status == output.DiagnosticFail {
+		return report()
+	}
+
+	// Clock skew
+	checks = append(checks, checkClockSkew(endpoint, transport))
+
+	// Authentication
+	checks = append(checks, checkAuthentication(client))
+
+	// Sender domain
+	from := ctx.GetString("from")
+	if from == "" {
+		from = config.From
+	}
+	checks = append(checks, checkSender(client, from, ctx.GetString("send-test")))
+
+	return report()
+}
+
+// checkReachability resolves the endpoint host and opens a TLS connection to it
+func checkReachability(endpoint string, tlsConfig *tls.Config) output.Diagnostic {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return output.Diagnostic{Name: "reachability", Status: output.DiagnosticFail, Detail: err.Error()}
+	}
+
+	host := parsed.Hostname()
+	if _, err := net.LookupHost(host); err != nil {
+		return output.Diagnostic{
+			Name:   "reachability",
+			Status: output.DiagnosticFail,
+			Detail: fmt.Sprintf("DNS lookup failed: %v", err),
+			Hint:   "Check the resource name in the endpoint and your DNS/proxy settings",
+		}
+	}
+
+	port := parsed.Port()
+	if port == "" {
+		port = "443"
+	}
+
+	start := time.Now()
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if parsed.Scheme == "https" {
+		config := &tls.Config{}
+		if tlsConfig != nil {
+			config = tlsConfig.Clone()
+		}
+		config.ServerName = host
+		conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(host, port), config)
+		if err != nil {
+			return output.Diagnostic{
+				Name:   "reachability",
+				Status: output.DiagnosticFail,
+				Detail: fmt.Sprintf("TLS connection failed: %v", err),
+				Hint:   "Check firewall rules and whether outbound HTTPS requires a proxy",
+			}
+		}
+		conn.Close()
+	} else {
+		conn, err := dialer.Dial("tcp", net.JoinHostPort(host, port))
+		if err != nil {
+			return output.Diagnostic{Name: "reachability", Status: output.DiagnosticFail, Detail: err.Error()}
+		}
+		conn.Close()
+	}
+
+	return output.Diagnostic{
+		Name:   "reachability",
+		Status: output.DiagnosticPass,
+		Detail: fmt.Sprintf("connected to %s in %v", host, time.Since(start).Round(time.Millisecond)),
+	}
+}
+
+// checkProxyReachability sends a request to the endpoint through the configured proxy
+func checkProxyReachability(endpoint string, transport *http.Transport) output.Diagnostic {
+	start := time.Now()
+	httpClient := &http.Client{Timeout: 10 * time.Second, Transport: transport}
+	resp, err := httpClient.Head(endpoint)
+	if err != nil {
+		return output.Diagnostic{
+			Name:   "reachability",
+			Status: output.DiagnosticFail,
+			Detail: fmt.Sprintf("request through proxy failed: %v", err),
+			Hint:   "Check the proxy URL and, for inspecting proxies, pass its CA with --ca-file",
+		}
+	}
+	resp.Body.Close()
+
+	return output.Diagnostic{
+		Name:   "reachability",
+		Status: output.DiagnosticPass,
+		Detail: fmt.Sprintf("reached endpoint through proxy in %v", time.Since(start).Round(time.Millisecond)),
+	}
+}
+
+// checkClockSkew compares the local clock with the service's Date header
+func checkClockSkew(endpoint string, transport *http.Transport) output.Diagnostic {
+	httpClient := &http.Client{Timeout: 10 * time.Second, Transport: transport}
+	resp, err := httpClient.Head(endpoint)
+	if err != nil {
+		return output.Diagnostic{Name: "clock skew", Status: output.DiagnosticWarn, Detail: fmt.Sprintf("could not query server time: %v", err)}
+	}
+	resp.Body.Close()
+
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return output.Diagnostic{Name: "clock skew", Status: output.DiagnosticWarn, Detail: "server did not return a Date header"}
+	}
+
+	skew := time.Since(serverTime).Round(time.Second)
+	if skew < 0 {
+		skew = -skew
+	}
+
+	if skew > maxClockSkew {
+		return output.Diagnostic{
+			Name:   "clock skew",
+			Status: output.DiagnosticFail,
+			Detail: fmt.Sprintf("local clock differs from the service by %v", skew),
+			Hint:   "Synchronize the system clock (NTP); signed requests are rejected beyond 5 minutes of skew",
+		}
+	}
+
+	return output.Diagnostic{Name: "clock skew", Status: output.DiagnosticPass, Detail: fmt.Sprintf("%v", skew)}
+}
+
+// checkAuthentication verifies the service accepts the client's signed requests
+func checkAuthentication(client *azemailsender.Client) output.Diagnostic {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err := client.Ping(ctx)
+	switch {
+	case err == nil:
+		return output.Diagnostic{Name: "authentication", Status: output.DiagnosticPass, Detail: "signed request accepted"}
+	case errors.Is(err, azemailsender.ErrAuthenticationFailed):
+		return output.Diagnostic{
+			Name:   "authentication",
+			Status: output.DiagnosticFail,
+			Detail: err.Error(),
+			Hint:   "Check that the access key belongs to this endpoint and has not been regenerated",
+		}
+	default:
+		return output.Diagnostic{Name: "authentication", Status: output.DiagnosticWarn, Detail: err.Error()}
+	}
+}
+
+// checkSender validates the sender address and optionally sends a test email
+func checkSender(client *azemailsender.Client, from, testRecipient string) output.Diagnostic {
+	if from == "" {
+		return output.Diagnostic{
+			Name:   "sender domain",
+			Status: output.DiagnosticWarn,
+			Detail: "no sender address configured",
+			Hint:   "Set --from or the 'from' config key",
+		}
+	}
+
+	if testRecipient == "" {
+		return output.Diagnostic{
+			Name:   "sender domain",
+			Status: output.DiagnosticSkip,
+			Detail: fmt.Sprintf("%s not verified", from),
+			Hint:   "Domain linkage can only be verified by sending; use --send-test <address>",
+		}
+	}
+
+	message, err := client.NewMessage().
+		From(from).
+		To(testRecipient).
+		Subject("azemailsender-cli doctor test").
+		PlainText("This is a test email sent by 'azemailsender-cli doctor'.").
+		Build()
+	if err != nil {
+		return output.Diagnostic{Name: "sender domain", Status: output.DiagnosticFail, Detail: err.Error()}
+	}
+
+	response, err := client.Send(message)
+	if err != nil {
+		diagnostic := output.Diagnostic{Name: "sender domain", Status: output.DiagnosticFail, Detail: err.Error()}
+		lower := strings.ToLower(err.Error())
+		if strings.Contains(lower, "domain") || strings.Contains(lower, "sender") {
+			diagnostic.Hint = "Link the sender's verified domain to the Communication Services resource and use a configured MailFrom address"
+		}
+		return diagnostic
+	}
+
+	return output.Diagnostic{
+		Name:   "sender domain",
+		Status: output.DiagnosticPass,
+		Detail: fmt.Sprintf("test email accepted (message ID %s)", response.ID),
+	}
+}
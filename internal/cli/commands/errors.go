@@ -0,0 +1,225 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+
+	"github.com/groovy-sky/azemailsender"
+	"github.com/groovy-sky/azemailsender/internal/cli/output"
+	"github.com/groovy-sky/azemailsender/internal/simplecli"
+)
+
+// Error categories group error codes; each category has its own exit code
+const (
+	CategoryGeneral    = "general"
+	CategoryUsage      = "usage"
+	CategoryValidation = "validation"
+	CategoryConfig     = "config"
+	CategoryAuth       = "auth"
+	CategoryService    = "service"
+	CategoryNetwork    = "network"
+	CategoryTimeout    = "timeout"
+	CategoryDelivery   = "delivery"
+	CategoryCanceled   = "canceled"
+)
+
+// categoryExitCodes maps error categories to the exit code of the CLI
+var categoryExitCodes = map[string]int{
+	CategoryGeneral:    1,
+	CategoryUsage:      2,
+	CategoryValidation: 2,
+	CategoryConfig:     3,
+	CategoryAuth:       4,
+	CategoryService:    5,
+	CategoryNetwork:    6,
+	CategoryTimeout:    7,
+	CategoryDelivery:   8,
+	CategoryCanceled:   130,
+}
+
+// Error codes reported in the "code" field of JSON errors
+const (
+	CodeError                   = "ERROR"
+	CodeUsage                   = "USAGE"
+	CodeConfirmationRequired    = "CONFIRMATION_REQUIRED"
+	CodeFileError               = "FILE_ERROR"
+	CodeValidationFailed        = "VALIDATION_FAILED"
+	CodeSenderMissing           = "SENDER_MISSING"
+	CodeRecipientsMissing       = "RECIPIENTS_MISSING"
+	CodeSubjectMissing          = "SUBJECT_MISSING"
+	CodeContentMissing          = "CONTENT_MISSING"
+	CodeAddressInvalid          = "ADDRESS_INVALID"
+	CodeHeaderInvalid           = "HEADER_INVALID"
+	CodeAttachmentTooLarge      = "ATTACHMENT_TOO_LARGE"
+	CodeFeatureUnsupported      = "FEATURE_UNSUPPORTED"
+	CodeRecipientSuppressed     = "RECIPIENT_SUPPRESSED"
+	CodeDuplicateSuppressed     = "DUPLICATE_SUPPRESSED"
+	CodeConfigInvalid           = "CONFIG_INVALID"
+	CodeConfigFileInvalid       = "CONFIG_FILE_INVALID"
+	CodeConnectionStringInvalid = "CONNECTION_STRING_INVALID"
+	CodeAuthMissing             = "AUTH_MISSING"
+	CodeAuthFailed              = "AUTH_FAILED"
+	CodeThrottled               = "THROTTLED"
+	CodeServiceError            = "SERVICE_ERROR"
+	CodeRequestRejected         = "REQUEST_REJECTED"
+	CodeNetworkError            = "NETWORK_ERROR"
+	CodeWaitTimeout             = "WAIT_TIMEOUT"
+	CodeDeliveryFailed          = "DELIVERY_FAILED"
+	CodeChecksFailed            = "CHECKS_FAILED"
+	CodeQueueMessageNotFound    = "QUEUE_MESSAGE_NOT_FOUND"
+	CodeCanceled                = "CANCELED"
+)
+
+// codeCategories maps error codes to their category
+var codeCategories = map[string]string{
+	CodeError:                   CategoryGeneral,
+	CodeUsage:                   CategoryUsage,
+	CodeConfirmationRequired:    CategoryUsage,
+	CodeFileError:               CategoryGeneral,
+	CodeValidationFailed:        CategoryValidation,
+	CodeSenderMissing:           CategoryValidation,
+	CodeRecipientsMissing:       CategoryValidation,
+	CodeSubjectMissing:          CategoryValidation,
+	CodeContentMissing:          CategoryValidation,
+	CodeAddressInvalid:          CategoryValidation,
+	CodeHeaderInvalid:           CategoryValidation,
+	CodeAttachmentTooLarge:      CategoryValidation,
+	CodeFeatureUnsupported:      CategoryValidation,
+	CodeRecipientSuppressed:     CategoryValidation,
+	CodeDuplicateSuppressed:     CategoryValidation,
+	CodeConfigInvalid:           CategoryConfig,
+	CodeConfigFileInvalid:       CategoryValidation,
+	CodeConnectionStringInvalid: CategoryConfig,
+	CodeAuthMissing:             CategoryAuth,
+	CodeAuthFailed:              CategoryAuth,
+	CodeThrottled:               CategoryService,
+	CodeServiceError:            CategoryService,
+	CodeRequestRejected:         CategoryService,
+	CodeNetworkError:            CategoryNetwork,
+	CodeWaitTimeout:             CategoryTimeout,
+	CodeDeliveryFailed:          CategoryDelivery,
+	CodeChecksFailed:            CategoryGeneral,
+	CodeQueueMessageNotFound:    CategoryUsage,
+	CodeCanceled:                CategoryCanceled,
+}
+
+// CLIError is an error with a machine-readable code. The code's category
+// decides the exit code of the CLI
+type CLIError struct {
+	Code string
+	Err  error
+}
+
+func (e *CLIError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CLIError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode returns the code of the error, e.g. AUTH_MISSING
+func (e *CLIError) ErrorCode() string {
+	return e.Code
+}
+
+// ErrorCategory returns the category of the error's code
+func (e *CLIError) ErrorCategory() string {
+	if category, ok := codeCategories[e.Code]; ok {
+		return category
+	}
+	return CategoryGeneral
+}
+
+// ExitCode returns the exit code for the error's category
+func (e *CLIError) ExitCode() int {
+	return categoryExitCodes[e.ErrorCategory()]
+}
+
+// codedError returns a formatted error with the given code
+func codedError(code, format string, args ...interface{}) error {
+	return &CLIError{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// Classify returns err as a CLIError. Errors that carry no code are given
+// one from their type, falling back to ERROR
+func Classify(err error) *CLIError {
+	var cliErr *CLIError
+	if errors.As(err, &cliErr) {
+		return cliErr
+	}
+	return &CLIError{Code: errorCode(err), Err: err}
+}
+
+// errorCode derives the code of an error from the library and CLI error types
+func errorCode(err error) string {
+	var (
+		usageErr            *simplecli.UsageError
+		apiErr              *azemailsender.APIError
+		addressErr          *azemailsender.InvalidAddressError
+		headerErr           *azemailsender.InvalidHeaderError
+		attachmentErr       *azemailsender.AttachmentLimitError
+		featureErr          *azemailsender.UnsupportedFeatureError
+		suppressionErr      *azemailsender.SuppressionError
+		duplicateErr        *azemailsender.DuplicateError
+		configErr           *azemailsender.ConfigError
+		connectionStringErr *azemailsender.ConnectionStringError
+		pathErr             *fs.PathError
+		netErr              net.Error
+	)
+	switch {
+	case errors.As(err, &usageErr), errors.Is(err, output.ErrInvalidOutput):
+		return CodeUsage
+	case errors.Is(err, azemailsender.ErrWaitTimeout):
+		return CodeWaitTimeout
+	case errors.Is(err, context.Canceled):
+		return CodeCanceled
+	case errors.Is(err, azemailsender.ErrAuthenticationFailed):
+		return CodeAuthFailed
+	case errors.As(err, &apiErr):
+		switch {
+		case apiErr.StatusCode == 401 || apiErr.StatusCode == 403:
+			return CodeAuthFailed
+		case apiErr.StatusCode == 429:
+			return CodeThrottled
+		case apiErr.StatusCode >= 500:
+			return CodeServiceError
+		default:
+			return CodeRequestRejected
+		}
+	case errors.As(err, &suppressionErr):
+		return CodeRecipientSuppressed
+	case errors.As(err, &duplicateErr):
+		return CodeDuplicateSuppressed
+	case errors.Is(err, azemailsender.ErrMissingSender):
+		return CodeSenderMissing
+	case errors.Is(err, azemailsender.ErrNoRecipients):
+		return CodeRecipientsMissing
+	case errors.Is(err, azemailsender.ErrMissingSubject):
+		return CodeSubjectMissing
+	case errors.Is(err, azemailsender.ErrMissingContent):
+		return CodeContentMissing
+	case errors.As(err, &addressErr):
+		return CodeAddressInvalid
+	case errors.As(err, &headerErr):
+		return CodeHeaderInvalid
+	case errors.As(err, &attachmentErr), errors.Is(err, azemailsender.ErrAttachmentTooLarge):
+		return CodeAttachmentTooLarge
+	case errors.As(err, &featureErr):
+		return CodeFeatureUnsupported
+	case errors.Is(err, azemailsender.ErrOutboxItemNotFound):
+		return CodeQueueMessageNotFound
+	case errors.As(err, &connectionStringErr):
+		return CodeConnectionStringInvalid
+	case errors.As(err, &configErr):
+		return CodeConfigInvalid
+	case errors.As(err, &pathErr):
+		return CodeFileError
+	case errors.As(err, &netErr):
+		return CodeNetworkError
+	}
+	return CodeError
+}
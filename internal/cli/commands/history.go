@@ -0,0 +1,117 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/groovy-sky/azemailsender"
+	"github.com/groovy-sky/azemailsender/internal/cli/output"
+	"github.com/groovy-sky/azemailsender/internal/simpleconfig"
+	"github.com/groovy-sky/azemailsender/internal/simplecli"
+)
+
+// NewHistoryCommand creates the history command
+func NewHistoryCommand() *simplecli.Command {
+	return &simplecli.Command{
+		Name:        "history",
+		Description: "Manage local state kept by the CLI",
+		Usage:       "history [subcommand]",
+		LongDesc:    "Manage the files the CLI and library keep on disk: failed send captures, audit logs and rotated log files",
+		Run: func(ctx *simplecli.Context) error {
+			return codedError(CodeUsage, "subcommand required. Use --help to see available subcommands")
+		},
+		Subcommands: []*simplecli.Command{
+			{
+				Name:        "prune",
+				Description: "Remove local state older than a given age",
+				Usage:       "history prune [--older-than <age>] [flags]",
+				LongDesc: `Remove captured exchanges from a --capture-dir directory or .zip bundle,
+audit log entries (including rotated audit files) and rotated --log-file files
+older than --older-than. The age is a duration such as 36h, or days or weeks
+such as 30d or 2w; without --older-than the "retention" config key is used.
+
+Run it from cron so long-running senders don't grow unbounded state.
+
+Examples:
+  # Remove captures and rotated logs older than 30 days
+  azemailsender-cli history prune --older-than 30d --capture-dir /var/lib/azemailsender/captures
+
+  # Prune an audit log written by an application using the library
+  azemailsender-cli history prune --older-than 2w --audit-log /var/log/app/email-audit.jsonl`,
+				Run: runHistoryPrune,
+				Flags: []*simplecli.Flag{
+					{
+						Name:        "older-than",
+						Description: "Remove state older than this age, e.g. 30d, 2w or 36h (default: the retention config key)",
+						Value:       "",
+					},
+					{
+						Name:        "capture-dir",
+						Description: "Capture directory or .zip bundle written by send --capture-dir",
+						Value:       "",
+						EnvVar:      "CAPTURE_DIR",
+					},
+					{
+						Name:        "audit-log",
+						Description: "Audit log written through the library's AuditLogOptions",
+						Value:       "",
+						EnvVar:      "AUDIT_LOG",
+					},
+				},
+			},
+		},
+	}
+}
+
+func runHistoryPrune(ctx *simplecli.Context) error {
+	config, err := simpleconfig.LoadConfig(ctx.GetString("config"), ctx.ProvidedFlags())
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	formatter, err := newFormatter(ctx)
+	if err != nil {
+		return err
+	}
+
+	retention := ctx.GetString("older-than")
+	if retention == "" {
+		retention = config.Retention
+	}
+	if retention == "" {
+		return codedError(CodeUsage, "age required: give --older-than or set the retention config key")
+	}
+	age, err := simpleconfig.ParseRetention(retention)
+	if err != nil {
+		return codedError(CodeUsage, "%v", err)
+	}
+	cutoff := time.Now().Add(-age)
+
+	stores := []struct {
+		name  string
+		path  string
+		prune func(path string, cutoff time.Time) (int, error)
+	}{
+		{"captures", ctx.GetString("capture-dir"), azemailsender.PruneCaptures},
+		{"audit-log", ctx.GetString("audit-log"), azemailsender.PruneAuditLog},
+		{"log-file", config.LogFile, output.PruneLogFiles},
+	}
+
+	var report []output.PrunedStore
+	for _, store := range stores {
+		if store.path == "" {
+			continue
+		}
+		formatter.PrintDebug("Pruning %s at %s before %s", store.name, store.path, cutoff.Format(time.RFC3339))
+		removed, err := store.prune(store.path, cutoff)
+		if err != nil {
+			return err
+		}
+		report = append(report, output.PrunedStore{Store: store.name, Path: store.path, Removed: removed})
+	}
+	if len(report) == 0 {
+		return codedError(CodeUsage, "nothing to prune: give --capture-dir, --audit-log or --log-file")
+	}
+
+	return formatter.PrintPruneReport(cutoff, report)
+}
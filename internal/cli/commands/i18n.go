@@ -0,0 +1,278 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+// localeFields are the data fields that select the locale a record is rendered in
+var localeFields = []string{"locale", "Locale"}
+
+// templateVariant is the subject and content of a template in one locale.
+// Empty fields fall back to the template's own
+type templateVariant struct {
+	Subject string `json:"subject,omitempty"`
+	HTML    string `json:"html,omitempty"`
+	Text    string `json:"text,omitempty"`
+}
+
+// catalogMessage is a message of a template's message catalog. Like go-i18n
+// messages it is a template itself, with an optional plural form
+type catalogMessage struct {
+	Zero  string
+	One   string
+	Other string
+}
+
+// UnmarshalJSON reads a message given as a string or as plural forms
+func (m *catalogMessage) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &m.Other); err == nil {
+		return nil
+	}
+
+	var forms map[string]string
+	if err := json.Unmarshal(data, &forms); err != nil {
+		return fmt.Errorf("a message must be a string or an object of plural forms")
+	}
+	for form, text := range forms {
+		switch form {
+		case "zero":
+			m.Zero = text
+		case "one":
+			m.One = text
+		case "other":
+			m.Other = text
+		default:
+			return fmt.Errorf("unsupported plural form %q: use zero, one and other", form)
+		}
+	}
+	if m.Other == "" {
+		return fmt.Errorf("plural forms need at least \"other\"")
+	}
+	return nil
+}
+
+// MarshalJSON writes messages without plural forms as strings
+func (m catalogMessage) MarshalJSON() ([]byte, error) {
+	if m.Zero == "" && m.One == "" {
+		return json.Marshal(m.Other)
+	}
+	forms := map[string]string{"other": m.Other}
+	if m.Zero != "" {
+		forms["zero"] = m.Zero
+	}
+	if m.One != "" {
+		forms["one"] = m.One
+	}
+	return json.Marshal(forms)
+}
+
+// form returns the text of the message for count, which is nil for
+// messages looked up without one
+func (m *catalogMessage) form(count *float64) string {
+	switch {
+	case count == nil:
+	case *count == 0 && m.Zero != "":
+		return m.Zero
+	case *count == 1 && m.One != "":
+		return m.One
+	}
+	return m.Other
+}
+
+// messageCatalog holds the messages of a template by locale and message ID
+type messageCatalog map[string]map[string]*catalogMessage
+
+// readMessageCatalog reads a catalog from a JSON or YAML file mapping locales
+// to message IDs and messages
+func readMessageCatalog(path string) (messageCatalog, error) {
+	data, err := readTemplateData(path)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	// Round-trip YAML through JSON to decode the messages
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message catalog: %w", err)
+	}
+	var catalog messageCatalog
+	if err := json.Unmarshal(raw, &catalog); err != nil {
+		return nil, fmt.Errorf("invalid message catalog %s: %w", path, err)
+	}
+
+	normalized := messageCatalog{}
+	for locale, messages := range catalog {
+		normalized[normalizeLocale(locale)] = messages
+	}
+	return normalized, nil
+}
+
+// normalizeLocale returns a locale tag in the form used as key, e.g. pt-br
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// localeChain returns the locales to look for, most specific first: de-at,
+// de and then defaultLocale
+func localeChain(locale, defaultLocale string) []string {
+	var chain []string
+	add := func(l string) {
+		for _, seen := range chain {
+			if seen == l {
+				return
+			}
+		}
+		if l != "" {
+			chain = append(chain, l)
+		}
+	}
+
+	locale = normalizeLocale(locale)
+	for tag := locale; tag != ""; {
+		add(tag)
+		i := strings.LastIndex(tag, "-")
+		if i < 0 {
+			break
+		}
+		tag = tag[:i]
+	}
+	add(normalizeLocale(defaultLocale))
+	return chain
+}
+
+// localizer renders a template in the locale of one data record
+type localizer struct {
+	template *storedTemplate
+	chain    []string
+	data     interface{}
+}
+
+// newLocalizer returns the localizer of t for the data record
+func newLocalizer(t *storedTemplate, data interface{}) *localizer {
+	return &localizer{template: t, chain: localeChain(recordField(data, localeFields), t.DefaultLocale), data: data}
+}
+
+// variant returns the subject and content to render: the most specific locale
+// variant, with the template's own fields where it has none
+func (l *localizer) variant() templateVariant {
+	t := l.template
+	v := templateVariant{Subject: t.Subject, HTML: t.HTML, Text: t.Text}
+	for _, locale := range l.chain {
+		// The template's own content is in the default locale
+		if locale == normalizeLocale(t.DefaultLocale) {
+			break
+		}
+		if found, ok := t.Locales[locale]; ok {
+			if found.Subject != "" {
+				v.Subject = found.Subject
+			}
+			if found.HTML != "" {
+				v.HTML = found.HTML
+			}
+			if found.Text != "" {
+				v.Text = found.Text
+			}
+			break
+		}
+	}
+	return v
+}
+
+// funcs returns the template functions looking up catalog messages:
+// {{t "id"}} and, for plurals, {{tn "id" .count}}. Both render the message
+// with the record, or with the data given as last argument
+func (l *localizer) funcs() map[string]interface{} {
+	return map[string]interface{}{
+		"t": func(id string, data ...interface{}) (string, error) {
+			return l.message(id, nil, data)
+		},
+		"tn": func(id string, count interface{}, data ...interface{}) (string, error) {
+			n, err := pluralCount(count)
+			if err != nil {
+				return "", err
+			}
+			return l.message(id, &n, data)
+		},
+	}
+}
+
+// message renders the catalog message id in the first locale that has it
+func (l *localizer) message(id string, count *float64, data []interface{}) (string, error) {
+	var found *catalogMessage
+	for _, locale := range l.chain {
+		if m, ok := l.template.Messages[locale][id]; ok {
+			found = m
+			break
+		}
+	}
+	if found == nil {
+		if len(l.chain) == 0 {
+			return "", fmt.Errorf("no message %q: the data has no locale and the template no default locale", id)
+		}
+		return "", fmt.Errorf("no message %q in the catalog for %s", id, strings.Join(l.chain, ", "))
+	}
+
+	record := l.data
+	if len(data) > 0 {
+		record = data[len(data)-1]
+	}
+	tmpl, err := texttemplate.New(id).Option("missingkey=error").Parse(found.form(count))
+	if err != nil {
+		return "", fmt.Errorf("invalid message %q: %w", id, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, record); err != nil {
+		return "", fmt.Errorf("failed to render message %q: %w", id, err)
+	}
+	return buf.String(), nil
+}
+
+// pluralCount converts the count of a plural message to a number
+func pluralCount(count interface{}) (float64, error) {
+	switch n := count.(type) {
+	case float64:
+		return n, nil
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case string:
+		var f float64
+		if _, err := fmt.Sscan(n, &f); err == nil {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("plural count %v is not a number", count)
+}
+
+// templateFuncStubs lets templates using the catalog functions be parsed
+// before they are rendered
+var templateFuncStubs = map[string]interface{}{
+	"t":  func(string, ...interface{}) string { return "" },
+	"tn": func(string, interface{}, ...interface{}) string { return "" },
+}
+
+// locales returns the locales of a template: its default, its variants and
+// the locales of its catalog, sorted
+func (t *storedTemplate) locales() []string {
+	seen := map[string]bool{}
+	if t.DefaultLocale != "" {
+		seen[normalizeLocale(t.DefaultLocale)] = true
+	}
+	for locale := range t.Locales {
+		seen[locale] = true
+	}
+	for locale := range t.Messages {
+		seen[locale] = true
+	}
+	locales := make([]string, 0, len(seen))
+	for locale := range seen {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
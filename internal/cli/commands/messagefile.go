@@ -0,0 +1,178 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/groovy-sky/azemailsender"
+	"github.com/groovy-sky/azemailsender/internal/simpleconfig"
+)
+
+// messageDocument is the JSON message accepted by send --message-file
+type messageDocument struct {
+	From        string               `json:"from"`
+	To          []string             `json:"to"`
+	Cc          []string             `json:"cc"`
+	Bcc         []string             `json:"bcc"`
+	ReplyTo     string               `json:"replyTo"`
+	Subject     string               `json:"subject"`
+	Text        string               `json:"text"`
+	HTML        string               `json:"html"`
+	Headers     map[string]string    `json:"headers"`
+	Attachments []attachmentDocument `json:"attachments"`
+
+	// Template names a template rendered with Data for the subject and
+	// content the document does not give; a "locale" field in Data selects
+	// the locale
+	Template string                 `json:"template"`
+	Data     map[string]interface{} `json:"data"`
+	// Timezone is the recipient's time zone for 'queue add --send-at-local',
+	// instead of a "timezone" field in Data
+	Timezone string `json:"timezone"`
+	// Category applies the defaults of a category of the config file and
+	// selects the throttle policy of a queued message
+	Category string `json:"category"`
+	// Priority is high, normal or low
+	Priority string `json:"priority"`
+
+	// dir resolves relative attachment paths
+	dir string
+}
+
+// attachmentDocument references an attachment file of a message document
+type attachmentDocument struct {
+	Path        string `json:"path"`
+	Name        string `json:"name"`
+	ContentType string `json:"contentType"`
+	ContentID   string `json:"contentId"`
+}
+
+// readMessageDocument reads a message document from path, or from stdin when path is "-"
+func readMessageDocument(path string) (*messageDocument, error) {
+	var data []byte
+	var err error
+	dir := "."
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+		dir = filepath.Dir(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message file: %w", err)
+	}
+
+	var doc messageDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid message file: %w", err)
+	}
+	doc.dir = dir
+	return &doc, nil
+}
+
+// addAttachments adds the document's headers and attachment files to the builder
+func (d *messageDocument) addAttachments(builder *azemailsender.MessageBuilder) error {
+	for name, value := range d.Headers {
+		builder.Header(name, value)
+	}
+
+	for _, attachment := range d.Attachments {
+		if attachment.Path == "" {
+			return fmt.Errorf("attachment without path in message file")
+		}
+		path := attachment.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(d.dir, path)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read attachment: %w", err)
+		}
+
+		name := attachment.Name
+		if name == "" {
+			name = filepath.Base(path)
+		}
+		if attachment.ContentID != "" {
+			builder.AttachInline(attachment.ContentID, name, attachment.ContentType, content)
+		} else {
+			builder.Attach(name, attachment.ContentType, content)
+		}
+	}
+	return nil
+}
+
+// build turns a complete message document into a message, using configuration defaults
+func (d *messageDocument) build(client *azemailsender.Client, config *simpleconfig.Config) (*azemailsender.EmailMessage, error) {
+	from, replyTo := config.Sender(d.Category)
+	if d.From != "" {
+		from = d.From
+	}
+	if d.ReplyTo != "" {
+		replyTo = d.ReplyTo
+	}
+
+	subject, text, html := d.Subject, d.Text, d.HTML
+	if d.Template != "" {
+		renderedSubject, renderedHTML, renderedText, err := renderNamedTemplate(config, d.Template, d.Data)
+		if err != nil {
+			return nil, err
+		}
+		if subject == "" {
+			subject = renderedSubject
+		}
+		if text == "" && html == "" {
+			text, html = renderedText, renderedHTML
+		}
+	}
+
+	builder := client.NewMessage().
+		From(from).
+		Subject(subject)
+
+	for _, list := range []struct {
+		recipients []string
+		add        func(string, ...string) *azemailsender.MessageBuilder
+	}{
+		{d.To, builder.To},
+		{d.Cc, builder.Cc},
+		{d.Bcc, builder.Bcc},
+	} {
+		addresses, err := config.ExpandAliases(list.recipients)
+		if err != nil {
+			return nil, err
+		}
+		for _, address := range addresses {
+			list.add(address)
+		}
+	}
+
+	if replyTo != "" {
+		builder.ReplyTo(replyTo)
+	}
+	if text != "" {
+		builder.PlainText(text)
+	}
+	if html != "" {
+		builder.HTML(html)
+	}
+	if d.Category != "" {
+		builder.Category(d.Category)
+	}
+	if d.Priority != "" {
+		priority, err := azemailsender.ParsePriority(d.Priority)
+		if err != nil {
+			return nil, err
+		}
+		builder.Priority(priority)
+	}
+	if err := d.addAttachments(builder); err != nil {
+		return nil, err
+	}
+
+	return builder.Build()
+}
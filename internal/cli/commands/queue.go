@@ -0,0 +1,565 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/groovy-sky/azemailsender"
+	"github.com/groovy-sky/azemailsender/internal/cli/output"
+	"github.com/groovy-sky/azemailsender/internal/simpleconfig"
+	"github.com/groovy-sky/azemailsender/internal/simplecli"
+)
+
+// NewQueueCommand creates the queue command
+func NewQueueCommand() *simplecli.Command {
+	return &simplecli.Command{
+		Name:        "queue",
+		Description: "Manage the persistent outbox",
+		Usage:       "queue [subcommand]",
+		LongDesc: `Manage messages in the persistent outbox: a directory holding one JSON file
+per message with its delivery state (pending, sent, failed or dead-letter).
+The outbox is "queue" next to the config file unless --queue-dir or the
+queue-dir config key says otherwise.`,
+		Run: func(ctx *simplecli.Context) error {
+			return codedError(CodeUsage, "subcommand required. Use --help to see available subcommands")
+		},
+		Subcommands: []*simplecli.Command{
+			{
+				Name:        "add",
+				Description: "Add a message to the outbox",
+				Usage:       "queue add [flags]",
+				LongDesc: `Store a message described by the same flags and files as 'send' as a pending
+message, or one message per line of an --ndjson file of message documents.
+Nothing is sent; the IDs of the queued messages are printed. With --send-at
+the messages are scheduled and not sent before that time.
+
+--digest-key collects messages into digests instead: each recipient gets a
+copy, and the messages added for a recipient with the same key within
+--digest-window of the first are sent together as one email when the window
+closes. The daemon merges them with --digest-template, or lists them one
+after another.
+
+--category names the kind of message, such as alerts, so the daemon applies
+the --throttle policy of that category.
+
+--send-at-local schedules each message for the next time the recipient's
+clock shows a time such as 09:00. The time zone comes from the "timezone"
+field of the message document or of its template data, such as
+Europe/Berlin, and defaults to --timezone or the local time zone.
+
+Examples:
+  azemailsender-cli queue add --from sender@example.com --to user@example.com --subject "Report" --html-file report.html
+  azemailsender-cli queue add --message-file message.json --quiet
+
+  # Schedule a message for tomorrow morning, or in two hours
+  azemailsender-cli queue add --message-file reminder.json --send-at 2025-06-02T09:00:00+02:00
+  azemailsender-cli queue add --message-file reminder.json --send-at 2h
+
+  # Deliver a campaign at 9:00 in each recipient's time zone
+  azemailsender-cli queue add --ndjson campaign.ndjson --send-at-local 09:00 --timezone UTC
+
+  # Send build failures at most once an hour per recipient
+  azemailsender-cli queue add --digest-key ci-failures --digest-window 1h --message-file failure.json`,
+				Run: runQueueAdd,
+				Flags: joinFlags(queueFlags(), []*simplecli.Flag{
+					{
+						Name:        "send-at",
+						Description: "Do not send before this time: an RFC 3339 time or a delay such as 2h",
+						Value:       "",
+					},
+					{
+						Name:        "send-at-local",
+						Description: "Send at this time of day in each recipient's time zone, e.g. 09:00",
+						Value:       "",
+					},
+					{
+						Name:        "timezone",
+						Description: "Time zone for --send-at-local when a message has none (default: local)",
+						Value:       "",
+					},
+					{
+						Name:        "ndjson",
+						Description: "Queue one message document per line of this NDJSON file, or - for stdin",
+						Value:       "",
+					},
+					{
+						Name:        "digest-key",
+						Description: "Send the message in a digest with the other messages of this key",
+						Value:       "",
+					},
+					{
+						Name:        "digest-window",
+						Description: "How long a digest collects messages after its first",
+						Value:       time.Hour,
+					},
+				}, messageFlags()),
+				Constraints: joinConstraints(messageConstraints(), []simplecli.Constraint{
+					simplecli.MutuallyExclusive([]string{"send-at"}, []string{"send-at-local"}),
+					simplecli.Requires("timezone", "send-at-local"),
+					simplecli.MutuallyExclusive([]string{"ndjson"}, []string{"message-file"}),
+					simplecli.MutuallyExclusive([]string{"digest-key"}, []string{"send-at", "send-at-local"}),
+					simplecli.Requires("digest-window", "digest-key"),
+				}),
+			},
+			{
+				Name:        "list",
+				Aliases:     []string{"ls"},
+				Description: "List messages in the outbox",
+				Usage:       "queue list [flags]",
+				Run:         runQueueList,
+				Flags: joinFlags(queueFlags(), []*simplecli.Flag{
+					{
+						Name:        "state",
+						Description: "Only list messages in this state: pending, sent, failed or dead-letter",
+						Value:       "",
+					},
+				}),
+			},
+			{
+				Name:        "inspect",
+				Description: "Show an outbox message with its stored content",
+				Usage:       "queue inspect [flags] <id>",
+				Run:         runQueueInspect,
+				Flags:       queueFlags(),
+			},
+			{
+				Name:        "retry",
+				Description: "Send failed or dead-lettered messages again",
+				Usage:       "queue retry [flags] <id> [id...] | --all-failed",
+				LongDesc: `Make failed or dead-lettered messages pending again, resetting their attempt
+count, so the next delivery sends them right away.
+
+Examples:
+  azemailsender-cli queue retry 3f2b8c1e-5d4a-4c8e-9b1a-2e7f6d9c0a12
+  azemailsender-cli queue retry --all-failed`,
+				Run: runQueueRetry,
+				Flags: joinFlags(queueFlags(), []*simplecli.Flag{
+					{
+						Name:        "all-failed",
+						Description: "Retry every failed and dead-lettered message",
+						Value:       false,
+					},
+				}),
+			},
+			{
+				Name:        "dead-letter",
+				Description: "Stop messages from being sent",
+				Usage:       "queue dead-letter [flags] <id> [id...]",
+				LongDesc: `Move pending or failed messages to the dead-letter state so they are not
+sent until retried. List them with 'queue list --state dead-letter'.`,
+				Run: runQueueDeadLetter,
+				Flags: joinFlags(queueFlags(), []*simplecli.Flag{
+					{
+						Name:        "reason",
+						Description: "Reason recorded as the messages' last error",
+						Value:       "",
+					},
+				}),
+			},
+			{
+				Name:        "purge",
+				Description: "Remove messages from the outbox",
+				Usage:       "queue purge [flags]",
+				LongDesc: `Remove messages in a state from the outbox, by default the sent ones.
+Removing pending or failed messages drops mail that was never sent, so it
+needs --yes.
+
+Examples:
+  # Remove sent messages older than a week
+  azemailsender-cli queue purge --older-than 7d
+
+  # Drop all dead-lettered messages
+  azemailsender-cli queue purge --state dead-letter`,
+				Run: runQueuePurge,
+				Flags: joinFlags(queueFlags(), []*simplecli.Flag{
+					{
+						Name:        "state",
+						Description: "Remove messages in this state: pending, sent, failed, dead-letter or all",
+						Value:       string(azemailsender.OutboxSent),
+					},
+					{
+						Name:        "older-than",
+						Description: "Only remove messages last updated longer ago than this, e.g. 7d or 36h",
+						Value:       "",
+					},
+					{
+						Name:        "yes",
+						Short:       "y",
+						Description: "Remove unsent messages without asking",
+						Value:       false,
+					},
+				}),
+			},
+		},
+	}
+}
+
+// queueFlags returns the flags shared by the queue subcommands
+func queueFlags() []*simplecli.Flag {
+	return []*simplecli.Flag{
+		{
+			Name:        "queue-dir",
+			Description: "Outbox directory (default: queue next to the config file)",
+			Value:       "",
+			EnvVar:      "QUEUE_DIR",
+		},
+	}
+}
+
+// openQueue loads the configuration and formatter and opens the outbox
+func openQueue(ctx *simplecli.Context) (*simpleconfig.Config, *output.Formatter, *azemailsender.Outbox, error) {
+	config, err := simpleconfig.LoadConfig(ctx.GetString("config"), ctx.ProvidedFlags())
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	formatter, err := newFormatter(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	outbox, err := azemailsender.OpenOutbox(config.GetQueueDir())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	formatter.PrintDebug("Using outbox %s", outbox.Dir())
+	return config, formatter, outbox, nil
+}
+
+// parseSendAt reads a --send-at value, an RFC 3339 time or a delay from now
+func parseSendAt(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return time.Now().Add(d), nil
+	}
+	return time.Time{}, codedError(CodeUsage, "invalid --send-at %q: use an RFC 3339 time such as 2025-06-02T09:00:00Z or a delay such as 2h", value)
+}
+
+// parseOutboxState checks a --state value; "all" is accepted when allowAll is set
+// and returns no state
+func parseOutboxState(value string, allowAll bool) ([]azemailsender.OutboxState, error) {
+	if value == "" || (allowAll && value == "all") {
+		return nil, nil
+	}
+	for _, state := range azemailsender.OutboxStates {
+		if string(state) == value {
+			return []azemailsender.OutboxState{state}, nil
+		}
+	}
+	valid := "pending, sent, failed or dead-letter"
+	if allowAll {
+		valid = "pending, sent, failed, dead-letter or all"
+	}
+	return nil, codedError(CodeUsage, "invalid --state %q: use %s", value, valid)
+}
+
+func runQueueAdd(ctx *simplecli.Context) error {
+	sendAt, err := parseSendAt(ctx.GetString("send-at"))
+	if err != nil {
+		return err
+	}
+	schedule, err := parseLocalSchedule(ctx.GetString("send-at-local"), ctx.GetString("timezone"))
+	if err != nil {
+		return err
+	}
+
+	config, formatter, outbox, err := openQueue(ctx)
+	if err != nil {
+		return err
+	}
+	if path := ctx.GetString("ndjson"); path != "" {
+		return queueBatch(ctx, config, formatter, outbox, path, sendAt, schedule)
+	}
+
+	input, err := readSendInput(ctx, config)
+	if err != nil {
+		return err
+	}
+	if err := input.check(); err != nil {
+		return err
+	}
+
+	client, err := queueClient(ctx, config, formatter)
+	if err != nil {
+		return err
+	}
+
+	builder, err := input.builder(client)
+	if err != nil {
+		return err
+	}
+	message, err := builder.Build()
+	if err != nil {
+		return attachmentHint(err)
+	}
+
+	if key := ctx.GetString("digest-key"); key != "" {
+		items, err := outbox.AddDigest(message, key, ctx.GetDuration("digest-window"))
+		if err != nil {
+			return err
+		}
+		return formatter.PrintQueueList(items)
+	}
+	if schedule != nil {
+		if sendAt, err = schedule.next(input.timezone(), time.Now()); err != nil {
+			return err
+		}
+		formatter.PrintDebug("Scheduled for %s (%s local)", sendAt.Format(time.RFC3339), schedule)
+	}
+	item, err := outbox.AddAt(message, sendAt)
+	if err != nil {
+		return err
+	}
+	return formatter.PrintQueueMessage(item, false)
+}
+
+// queueClient returns the client that builds queued messages. Queueing is
+// offline, so missing credentials fall back to a client without any; other
+// configuration errors are returned
+func queueClient(ctx *simplecli.Context, config *simpleconfig.Config, formatter *output.Formatter) (*azemailsender.Client, error) {
+	client, err := newClient(ctx, config, formatter)
+	if err != nil {
+		if Classify(err).Code != CodeAuthMissing {
+			return nil, err
+		}
+		client = azemailsender.NewClient("", "", nil)
+	}
+	return client, nil
+}
+
+// queueBatch queues one message per line of an NDJSON file. Every line is
+// checked before any message is queued
+func queueBatch(ctx *simplecli.Context, config *simpleconfig.Config, formatter *output.Formatter, outbox *azemailsender.Outbox, path string, sendAt time.Time, schedule *localSchedule) error {
+	var input io.Reader = os.Stdin
+	dir := "."
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open batch file: %w", err)
+		}
+		defer file.Close()
+		input = file
+		dir = filepath.Dir(path)
+	}
+
+	client, err := queueClient(ctx, config, formatter)
+	if err != nil {
+		return err
+	}
+
+	type scheduled struct {
+		message *azemailsender.EmailMessage
+		sendAt  time.Time
+	}
+	var messages []scheduled
+	now := time.Now()
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxBatchLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var doc messageDocument
+		if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+			return fmt.Errorf("line %d: invalid message: %w", line, err)
+		}
+		doc.dir = dir
+		if ctx.IsSet("category") {
+			doc.Category = ctx.GetString("category")
+		}
+		message, err := doc.build(client, config)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, attachmentHint(err))
+		}
+
+		at := sendAt
+		if schedule != nil {
+			timezone := doc.Timezone
+			if timezone == "" {
+				timezone = recordField(doc.Data, timezoneFields)
+			}
+			if at, err = schedule.next(timezone, now); err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		messages = append(messages, scheduled{message: message, sendAt: at})
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read batch input: %w", err)
+	}
+
+	digestKey := ctx.GetString("digest-key")
+	items := make([]*azemailsender.OutboxItem, 0, len(messages))
+	for _, m := range messages {
+		if digestKey != "" {
+			added, err := outbox.AddDigest(m.message, digestKey, ctx.GetDuration("digest-window"))
+			if err != nil {
+				return err
+			}
+			items = append(items, added...)
+			continue
+		}
+		item, err := outbox.AddAt(m.message, m.sendAt)
+		if err != nil {
+			return err
+		}
+		items = append(items, item)
+	}
+	return formatter.PrintQueueList(items)
+}
+
+func runQueueList(ctx *simplecli.Context) error {
+	states, err := parseOutboxState(ctx.GetString("state"), false)
+	if err != nil {
+		return err
+	}
+
+	_, formatter, outbox, err := openQueue(ctx)
+	if err != nil {
+		return err
+	}
+
+	items, err := outbox.List(states...)
+	if err != nil {
+		return err
+	}
+	return formatter.PrintQueueList(items)
+}
+
+func runQueueInspect(ctx *simplecli.Context) error {
+	if len(ctx.Args) != 1 {
+		return codedError(CodeUsage, "exactly one message ID required")
+	}
+
+	_, formatter, outbox, err := openQueue(ctx)
+	if err != nil {
+		return err
+	}
+
+	item, err := outbox.Get(ctx.Args[0])
+	if err != nil {
+		return err
+	}
+	return formatter.PrintQueueMessage(item, true)
+}
+
+func runQueueRetry(ctx *simplecli.Context) error {
+	allFailed := ctx.GetBool("all-failed")
+	if allFailed == (len(ctx.Args) > 0) {
+		return codedError(CodeUsage, "give message IDs or --all-failed")
+	}
+
+	_, formatter, outbox, err := openQueue(ctx)
+	if err != nil {
+		return err
+	}
+
+	ids := ctx.Args
+	if allFailed {
+		items, err := outbox.List(azemailsender.OutboxFailed, azemailsender.OutboxDeadLetter)
+		if err != nil {
+			return err
+		}
+		ids = outboxIDs(items)
+	}
+
+	return updateQueue(formatter, "retry", ids, func(id string) error {
+		_, err := outbox.Retry(id)
+		return err
+	})
+}
+
+func runQueueDeadLetter(ctx *simplecli.Context) error {
+	if len(ctx.Args) == 0 {
+		return codedError(CodeUsage, "message ID required")
+	}
+
+	_, formatter, outbox, err := openQueue(ctx)
+	if err != nil {
+		return err
+	}
+
+	reason := ctx.GetString("reason")
+	return updateQueue(formatter, "dead-letter", ctx.Args, func(id string) error {
+		_, err := outbox.DeadLetter(id, reason)
+		return err
+	})
+}
+
+func runQueuePurge(ctx *simplecli.Context) error {
+	states, err := parseOutboxState(ctx.GetString("state"), true)
+	if err != nil {
+		return err
+	}
+
+	var cutoff time.Time
+	if olderThan := ctx.GetString("older-than"); olderThan != "" {
+		age, err := simpleconfig.ParseRetention(olderThan)
+		if err != nil {
+			return codedError(CodeUsage, "%v", err)
+		}
+		cutoff = time.Now().Add(-age)
+	}
+
+	_, formatter, outbox, err := openQueue(ctx)
+	if err != nil {
+		return err
+	}
+
+	items, err := outbox.List(states...)
+	if err != nil {
+		return err
+	}
+
+	var purge []*azemailsender.OutboxItem
+	unsent := 0
+	for _, item := range items {
+		if !cutoff.IsZero() && !item.UpdatedAt.Before(cutoff) {
+			continue
+		}
+		if item.State == azemailsender.OutboxPending || item.State == azemailsender.OutboxFailed {
+			unsent++
+		}
+		purge = append(purge, item)
+	}
+	if unsent > 0 && !ctx.GetBool("yes") {
+		return codedError(CodeConfirmationRequired, "refusing to remove %d unsent messages without confirmation: rerun with --yes", unsent)
+	}
+
+	return updateQueue(formatter, "purge", outboxIDs(purge), outbox.Remove)
+}
+
+// updateQueue applies change to each message and reports the ones changed. It
+// stops at the first error, after reporting what was already changed
+func updateQueue(formatter *output.Formatter, action string, ids []string, change func(id string) error) error {
+	var changed []string
+	for _, id := range ids {
+		if err := change(id); err != nil {
+			if len(changed) > 0 {
+				formatter.PrintQueueUpdate(action, changed)
+			}
+			return err
+		}
+		changed = append(changed, id)
+	}
+	return formatter.PrintQueueUpdate(action, changed)
+}
+
+// outboxIDs returns the IDs of outbox messages
+func outboxIDs(items []*azemailsender.OutboxItem) []string {
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
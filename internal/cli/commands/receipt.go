@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/groovy-sky/azemailsender"
+	"github.com/groovy-sky/azemailsender/internal/simplecli"
+)
+
+// receiptStatusError is the status of a receipt for a message that was not accepted
+const receiptStatusError = "Error"
+
+// receipt is one line of a --receipt-file
+type receipt struct {
+	Timestamp       time.Time `json:"timestamp"`
+	ID              string    `json:"id,omitempty"`
+	ClientRequestID string    `json:"clientRequestId,omitempty"`
+	Recipients      []string  `json:"recipients"`
+	SubjectHash     string    `json:"subjectHash"`
+	Status          string    `json:"status"`
+	Transport       string    `json:"transport,omitempty"`
+	Error           string    `json:"error,omitempty"`
+}
+
+// receiptFlags returns the flag of the commands that write send receipts
+func receiptFlags() []*simplecli.Flag {
+	return simplecli.InCategory("Behavior", []*simplecli.Flag{
+		{
+			Name:        "receipt-file",
+			Description: "Append a JSON receipt line per message to this file",
+			Value:       "",
+			EnvVar:      "RECEIPT_FILE",
+		},
+	})
+}
+
+// receiptWriter appends receipts to a file, one JSON line each
+type receiptWriter struct {
+	file *os.File
+}
+
+// openReceipts opens the --receipt-file for appending, or returns nil when none is set
+func openReceipts(ctx *simplecli.Context) (*receiptWriter, error) {
+	path := ctx.GetString("receipt-file")
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open receipt file: %w", err)
+	}
+	return &receiptWriter{file: file}, nil
+}
+
+// write appends the receipt of a send. status overrides the status of the
+// response, e.g. with the final status after waiting; sendErr is the error
+// of a send that failed
+func (w *receiptWriter) write(message *azemailsender.EmailMessage, response *azemailsender.SendResponse, status azemailsender.EmailStatus, sendErr error) error {
+	if w == nil {
+		return nil
+	}
+
+	r := &receipt{
+		Timestamp:   time.Now().UTC(),
+		Recipients:  []string{},
+		SubjectHash: subjectHash(message.Content.Subject),
+		Status:      receiptStatusError,
+	}
+	for _, list := range [][]azemailsender.EmailAddress{message.Recipients.To, message.Recipients.Cc, message.Recipients.Bcc} {
+		for _, recipient := range list {
+			r.Recipients = append(r.Recipients, recipient.Address)
+		}
+	}
+	if response != nil {
+		r.ID = response.ID
+		r.ClientRequestID = response.ClientRequestID
+		r.Transport = response.Transport
+		r.Status = string(response.Status)
+	}
+	if status != "" {
+		r.Status = string(status)
+	}
+	if sendErr != nil {
+		r.Error = sendErr.Error()
+	}
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to encode receipt: %w", err)
+	}
+	if _, err := w.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write receipt: %w", err)
+	}
+	return nil
+}
+
+// Close syncs the receipts to disk and closes the file
+func (w *receiptWriter) Close() error {
+	if w == nil {
+		return nil
+	}
+	if err := w.file.Sync(); err != nil {
+		w.file.Close()
+		return fmt.Errorf("failed to write receipt file: %w", err)
+	}
+	return w.file.Close()
+}
+
+// subjectHash identifies a subject in receipts without recording it
+func subjectHash(subject string) string {
+	sum := sha256.Sum256([]byte(subject))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
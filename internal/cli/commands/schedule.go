@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	// Time zones are resolved without relying on the system's zoneinfo,
+	// which minimal containers and Windows lack
+	_ "time/tzdata"
+)
+
+// timezoneFields are the data fields that hold the time zone of a record
+var timezoneFields = []string{"timezone", "Timezone", "tz"}
+
+// recordField returns the first of fields that is a non-empty string in a
+// data record
+func recordField(data interface{}, fields []string) string {
+	record, ok := data.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	for _, field := range fields {
+		if value, ok := record[field].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// localSchedule sends messages at a wall clock time in each recipient's time zone
+type localSchedule struct {
+	hour, minute int
+	// fallback is the time zone of records without one
+	fallback *time.Location
+}
+
+// parseLocalSchedule reads a --send-at-local time such as 09:00 and the
+// time zone used for records without one, the local zone when empty
+func parseLocalSchedule(clock, timezone string) (*localSchedule, error) {
+	if clock == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return nil, codedError(CodeUsage, "invalid --send-at-local %q: use a 24-hour time such as 09:00", clock)
+	}
+
+	s := &localSchedule{hour: t.Hour(), minute: t.Minute(), fallback: time.Local}
+	if timezone != "" {
+		if s.fallback, err = loadTimezone(timezone); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// loadTimezone resolves an IANA time zone name such as Europe/Berlin
+func loadTimezone(name string) (*time.Location, error) {
+	loc, err := time.LoadLocation(strings.TrimSpace(name))
+	if err != nil {
+		return nil, codedError(CodeUsage, "unknown time zone %q: use an IANA name such as Europe/Berlin", name)
+	}
+	return loc, nil
+}
+
+// next returns the first time after now that the clock shows the scheduled
+// time in timezone, or in the fallback zone when timezone is empty
+func (s *localSchedule) next(timezone string, now time.Time) (time.Time, error) {
+	loc := s.fallback
+	if timezone != "" {
+		var err error
+		if loc, err = loadTimezone(timezone); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	local := now.In(loc)
+	at := time.Date(local.Year(), local.Month(), local.Day(), s.hour, s.minute, 0, 0, loc)
+	if !at.After(now) {
+		// Build the next day's time from the date so DST changes keep the wall clock
+		at = time.Date(local.Year(), local.Month(), local.Day()+1, s.hour, s.minute, 0, 0, loc)
+	}
+	return at, nil
+}
+
+// String returns the scheduled time as given
+func (s *localSchedule) String() string {
+	return fmt.Sprintf("%02d:%02d", s.hour, s.minute)
+}
@@ -2,16 +2,16 @@ package commands
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"strings"
-	"time"
 
 	"github.com/groovy-sky/azemailsender"
 	"github.com/groovy-sky/azemailsender/internal/cli/output"
-	"github.com/groovy-sky/azemailsender/internal/simpleconfig"
 	"github.com/groovy-sky/azemailsender/internal/simplecli"
+	"github.com/groovy-sky/azemailsender/internal/simpleconfig"
 )
 
 // NewSendCommand creates the send command
@@ -36,208 +36,491 @@ Examples:
   echo "Hello from stdin" | azemailsender-cli send --from sender@example.com --to recipient@example.com --subject "Stdin Test"
 
   # Read content from file
-  azemailsender-cli send --from sender@example.com --to recipient@example.com --subject "File Test" --text-file message.txt`,
+  azemailsender-cli send --from sender@example.com --to recipient@example.com --subject "File Test" --text-file message.txt
+
+  # Attach files and reference an inline image as <img src="cid:logo">
+  azemailsender-cli send --from sender@example.com --to recipient@example.com --subject "Invoice" --attach invoice.pdf --attach-inline cid=logo:logo.png --html-file invoice.html`,
 		Run: runSend,
-		Flags: []*simplecli.Flag{
-			// Authentication flags
-			{
-				Name:        "endpoint",
-				Short:       "e",
-				Description: "Azure Communication Services endpoint",
-				Value:       "",
-				EnvVar:      "AZURE_EMAIL_ENDPOINT",
-			},
-			{
-				Name:        "access-key",
-				Short:       "k",
-				Description: "Access key for authentication",
-				Value:       "",
-				EnvVar:      "AZURE_EMAIL_ACCESS_KEY",
-			},
-			{
-				Name:        "connection-string",
-				Description: "Connection string for authentication",
-				Value:       "",
-				EnvVar:      "AZURE_EMAIL_CONNECTION_STRING",
-			},
-			// Email content flags
+		Flags: joinFlags(authFlags(), networkFlags(), messageFlags(), receiptFlags(), []*simplecli.Flag{
 			{
-				Name:        "from",
-				Short:       "f",
-				Description: "Sender email address",
+				Name:        "capture-dir",
+				Description: "Write sanitized request/response pairs of failed sends to this directory (or .zip bundle)",
 				Value:       "",
-				Required:    true,
-				EnvVar:      "AZURE_EMAIL_FROM",
-			},
-			{
-				Name:        "to",
-				Short:       "t",
-				Description: "To recipients (can be repeated)",
-				Value:       []string{},
-			},
-			{
-				Name:        "cc",
-				Description: "CC recipients (can be repeated)",
-				Value:       []string{},
+				EnvVar:      "CAPTURE_DIR",
 			},
 			{
-				Name:        "bcc",
-				Description: "BCC recipients (can be repeated)",
-				Value:       []string{},
-			},
-			{
-				Name:        "reply-to",
-				Description: "Reply-to email address",
-				Value:       "",
-				EnvVar:      "AZURE_EMAIL_REPLY_TO",
-			},
-			{
-				Name:        "subject",
-				Short:       "s",
-				Description: "Email subject",
-				Value:       "",
-				Required:    true,
-			},
-			{
-				Name:        "text",
-				Description: "Plain text email content",
-				Value:       "",
-			},
-			{
-				Name:        "html",
-				Description: "HTML email content",
-				Value:       "",
-			},
-			{
-				Name:        "text-file",
-				Description: "Read plain text content from file",
-				Value:       "",
+				Name:        "individual",
+				Description: "Send a separate email to each --to recipient",
+				Value:       false,
 			},
 			{
-				Name:        "html-file",
-				Description: "Read HTML content from file",
+				Name:        "verp-reply-to",
+				Description: "Set the reply-to of each --individual email to a VERP address under this one, e.g. bounces+anna=example.org@example.com",
 				Value:       "",
 			},
-			// Behavior flags
 			{
-				Name:        "wait",
-				Short:       "w",
-				Description: "Wait for email completion",
+				Name:        "print-curl",
+				Description: "Print the signed request as a curl command instead of sending it",
 				Value:       false,
-				EnvVar:      "AZURE_EMAIL_WAIT",
-			},
-			{
-				Name:        "poll-interval",
-				Description: "Status polling interval (when --wait is used)",
-				Value:       "5s",
-				EnvVar:      "AZURE_EMAIL_POLL_INTERVAL",
-			},
-			{
-				Name:        "max-wait-time",
-				Description: "Maximum wait time (when --wait is used)",
-				Value:       "5m",
-				EnvVar:      "AZURE_EMAIL_MAX_WAIT_TIME",
 			},
-		},
+		}, suppressionFlags(), dedupeFlags(), waitFlags(), pacingFlags(azemailsender.DefaultBatchConcurrency), confirmFlags()),
+		Constraints: joinConstraints(authConstraints(), messageConstraints(), suppressionConstraints(), []simplecli.Constraint{
+			simplecli.MutuallyExclusive([]string{"wait"}, []string{"individual"}),
+			simplecli.MutuallyExclusive([]string{"reply-to"}, []string{"verp-reply-to"}),
+			simplecli.Requires("verp-reply-to", "individual"),
+		}),
 	}
 }
 
+// messageConstraints rejects content given both inline and as a file, and
+// template data without a template
+func messageConstraints() []simplecli.Constraint {
+	return []simplecli.Constraint{
+		simplecli.MutuallyExclusive([]string{"text"}, []string{"text-file"}),
+		simplecli.MutuallyExclusive([]string{"html"}, []string{"html-file"}),
+		simplecli.Requires("data", "template-name"),
+	}
+}
+
+// messageFlags returns the flags that describe a message, shared by send and validate
+func messageFlags() []*simplecli.Flag {
+	return simplecli.InCategory("Content", joinFlags([]*simplecli.Flag{
+		// Email content flags
+		{
+			Name:        "from",
+			Short:       "f",
+			Description: "Sender email address",
+			Value:       "",
+			EnvVar:      "FROM",
+		},
+		{
+			Name:        "to",
+			Short:       "t",
+			Description: "To recipients (can be repeated)",
+			Value:       []string{},
+		},
+		{
+			Name:        "cc",
+			Description: "CC recipients (can be repeated)",
+			Value:       []string{},
+		},
+		{
+			Name:        "bcc",
+			Description: "BCC recipients (can be repeated)",
+			Value:       []string{},
+		},
+		{
+			Name:        "message-file",
+			Description: "Read the message (from, recipients, subject, content, attachments) from a JSON file, or - for stdin",
+			Value:       "",
+		},
+		{
+			Name:        "stdin-format",
+			Description: "Format of content piped to stdin: text or json (a message document)",
+			Value:       "text",
+		},
+		{
+			Name:        "to-file",
+			Description: "Read To recipients from a file (one address per line)",
+			Value:       "",
+		},
+		{
+			Name:        "cc-file",
+			Description: "Read CC recipients from a file (one address per line)",
+			Value:       "",
+		},
+		{
+			Name:        "bcc-file",
+			Description: "Read BCC recipients from a file (one address per line)",
+			Value:       "",
+		},
+		{
+			Name:        "reply-to",
+			Description: "Reply-to email address",
+			Value:       "",
+			EnvVar:      "REPLY_TO",
+		},
+		{
+			Name:        "subject",
+			Short:       "s",
+			Description: "Email subject",
+			Value:       "",
+		},
+		{
+			Name:        "text",
+			Description: "Plain text email content",
+			Value:       "",
+		},
+		{
+			Name:        "html",
+			Description: "HTML email content",
+			Value:       "",
+		},
+		{
+			Name:        "text-file",
+			Description: "Read plain text content from file",
+			Value:       "",
+		},
+		{
+			Name:        "html-file",
+			Description: "Read HTML content from file",
+			Value:       "",
+		},
+		{
+			Name:        "attach",
+			Short:       "a",
+			Description: "Attach a file (can be repeated)",
+			Value:       []string{},
+		},
+		{
+			Name:        "attach-inline",
+			Description: "Attach an inline image as cid=<content-id>:<file> (can be repeated)",
+			Value:       []string{},
+		},
+		{
+			Name:        "template-name",
+			Description: "Render the subject and content from a named template (see 'templates')",
+			Value:       "",
+		},
+		{
+			Name:        "data",
+			Description: "JSON or YAML data file for --template-name, or - for stdin",
+			Value:       "",
+		},
+		{
+			Name:        "category",
+			Description: "Message category, such as alerts, applying the defaults of the categories config key",
+			Value:       "",
+		},
+		{
+			Name:        "priority",
+			Description: "Message priority: high, normal or low",
+			Value:       "",
+		},
+	}, templateDirFlags()))
+}
+
 func runSend(ctx *simplecli.Context) error {
 	// Load configuration
 	configFile := ctx.GetString("config")
-	config, err := simpleconfig.LoadConfig(configFile, ctx.Flags)
+	config, err := simpleconfig.LoadConfig(configFile, ctx.ProvidedFlags())
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
+	if err := checkWaitFlags(ctx, config, "wait"); err != nil {
+		return err
+	}
 
 	// Create output formatter
-	debug := ctx.GetBool("debug")
-	quiet := ctx.GetBool("quiet")
-	jsonOutput := ctx.GetBool("json")
-	formatter := output.NewFormatter(jsonOutput, quiet, debug)
-
-	// Get values from flags and config
-	endpoint := ctx.GetString("endpoint")
-	accessKey := ctx.GetString("access-key")
-	connectionString := ctx.GetString("connection-string")
-	from := ctx.GetString("from")
-	to := ctx.GetStringSlice("to")
-	cc := ctx.GetStringSlice("cc")
-	bcc := ctx.GetStringSlice("bcc")
-	replyTo := ctx.GetString("reply-to")
-	subject := ctx.GetString("subject")
-	text := ctx.GetString("text")
-	html := ctx.GetString("html")
-	textFile := ctx.GetString("text-file")
-	htmlFile := ctx.GetString("html-file")
+	formatter, err := newFormatter(ctx)
+	if err != nil {
+		return err
+	}
+
 	wait := ctx.GetBool("wait")
 
-	// Use config values if not provided via flags
-	if endpoint == "" {
-		endpoint = config.Endpoint
+	input, err := readSendInput(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	// Validate authentication
+	if err := checkAuth(config); err != nil {
+		return err
+	}
+
+	if err := input.check(); err != nil {
+		return err
 	}
-	if accessKey == "" {
-		accessKey = config.AccessKey
+
+	// Address replies and bounces to each recipient's own return address
+	verp := ctx.GetString("verp-reply-to")
+	if verp != "" {
+		if _, err := azemailsender.PlusAddress(verp, "verp"); err != nil {
+			return codedError(CodeUsage, "invalid --verp-reply-to: %w", err)
+		}
 	}
-	if connectionString == "" {
-		connectionString = config.ConnectionString
+
+	// Create email client
+	client, err := newClient(ctx, config, formatter)
+	if err != nil {
+		return err
 	}
-	if from == "" {
-		from = config.From
+	if verp != "" {
+		client.Use(azemailsender.VERPReplyTo(verp))
 	}
-	if replyTo == "" {
-		replyTo = config.ReplyTo
+
+	builder, err := input.builder(client)
+	if err != nil {
+		return err
 	}
 
-	// Validate authentication
-	hasAuth := false
-	if connectionString != "" {
-		hasAuth = true
-	} else if endpoint != "" && accessKey != "" {
-		hasAuth = true
+	message, err := builder.Build()
+	if err != nil {
+		err = attachmentHint(err)
+		return err
 	}
 
-	if !hasAuth {
-		return fmt.Errorf("authentication required: provide either --connection-string or both --endpoint and --access-key")
+	// Print the signed request instead of sending it
+	if ctx.GetBool("print-curl") {
+		command, err := client.CurlCommand(context.Background(), message)
+		if err != nil {
+			return err
+		}
+		fmt.Println(command)
+		return nil
 	}
 
-	// Check recipients
-	if len(to) == 0 && len(cc) == 0 && len(bcc) == 0 {
-		return fmt.Errorf("at least one recipient required (--to, --cc, or --bcc)")
+	// Guard against accidental mass mail
+	recipients := len(message.Recipients.To) + len(message.Recipients.Cc) + len(message.Recipients.Bcc)
+	if err := confirmSend(ctx, config, recipients, "recipients"); err != nil {
+		return err
 	}
 
-	// Check sender
-	if from == "" {
-		return fmt.Errorf("sender address required (--from)")
+	receipts, err := openReceipts(ctx)
+	if err != nil {
+		return err
 	}
+	defer receipts.Close()
 
-	// Check subject
-	if subject == "" {
-		return fmt.Errorf("subject required (--subject)")
+	formatter.PrintDebug("Sending email to %s", output.FormatRecipients(input.to))
+
+	// Fan out to each To recipient
+	if ctx.GetBool("individual") {
+		if wait {
+			return codedError(CodeUsage, "--wait cannot be combined with --individual; use 'status --watch' with the printed message IDs")
+		}
+		batchOptions, err := newBatchOptions(ctx)
+		if err != nil {
+			return err
+		}
+		result, sendErr := client.SendIndividually(context.Background(), message, batchOptions)
+		if result == nil {
+			receipts.write(message, nil, "", sendErr)
+			return sendErr
+		}
+		for _, recipient := range result.Results {
+			single := *message
+			single.Recipients.To = []azemailsender.EmailAddress{{Address: recipient.Address}}
+			if err := receipts.write(&single, recipient.Response, "", recipient.Err); err != nil {
+				return err
+			}
+		}
+		if err := formatter.PrintIndividualResults(result); err != nil {
+			return err
+		}
+		return sendErr
+	}
+
+	// Send email
+	response, err := client.Send(message)
+	if err != nil {
+		if receiptErr := receipts.write(message, nil, "", err); receiptErr != nil {
+			return fmt.Errorf("%w (%v)", err, receiptErr)
+		}
+		return err
+	}
+
+	// Print send response
+	if err := formatter.PrintSendResponse(response); err != nil {
+		return err
+	}
+
+	// Wait for completion if requested. Transports other than the API deliver
+	// right away, so their messages already have a final status
+	if wait && !response.Status.IsFinal() {
+		formatter.PrintInfo("Waiting for email completion...")
+
+		waitOptions, err := newWaitOptions(ctx, config, func(status *azemailsender.StatusResponse) {
+			if !formatter.Quiet && !formatter.JSON {
+				fmt.Printf("Status: %s\n", formatter.PaintStatus(string(status.Status)))
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		finalStatus, err := client.WaitForCompletion(response.ID, waitOptions)
+		if err != nil {
+			// Record the accepted message even though its outcome is unknown
+			receipts.write(message, response, "", nil)
+			return fmt.Errorf("waiting for completion failed: %w", err)
+		}
+		if err := receipts.write(message, response, finalStatus.Status, nil); err != nil {
+			return err
+		}
+
+		return formatter.PrintStatusResponse(finalStatus)
+	}
+
+	return receipts.write(message, response, "", nil)
+}
+
+// sendInput holds the message fields gathered from send flags, files and stdin
+type sendInput struct {
+	from    string
+	to      []string
+	cc      []string
+	bcc     []string
+	replyTo string
+	subject string
+	text    string
+	html    string
+	// category and priority are empty when not given
+	category string
+	priority string
+	doc      *messageDocument
+	// data is the personalization record of the message, which a named
+	// template is rendered with
+	data interface{}
+
+	attach       []string
+	attachInline []string
+}
+
+// readSendInput collects the message described by the send flags, a message
+// document, recipient files, the configuration and stdin
+func readSendInput(ctx *simplecli.Context, config *simpleconfig.Config) (*sendInput, error) {
+	in := &sendInput{
+		from:         ctx.GetString("from"),
+		to:           ctx.GetStringSlice("to"),
+		cc:           ctx.GetStringSlice("cc"),
+		bcc:          ctx.GetStringSlice("bcc"),
+		replyTo:      ctx.GetString("reply-to"),
+		subject:      ctx.GetString("subject"),
+		text:         ctx.GetString("text"),
+		html:         ctx.GetString("html"),
+		category:     ctx.GetString("category"),
+		priority:     ctx.GetString("priority"),
+		attach:       ctx.GetStringSlice("attach"),
+		attachInline: ctx.GetStringSlice("attach-inline"),
+	}
+	textFile := ctx.GetString("text-file")
+	htmlFile := ctx.GetString("html-file")
+
+	// Read a JSON message document; flags override its values
+	messageFile := ctx.GetString("message-file")
+	switch ctx.GetString("stdin-format") {
+	case "", "text":
+	case "json":
+		if messageFile == "" {
+			messageFile = "-"
+		}
+	default:
+		return nil, codedError(CodeUsage, "invalid --stdin-format %q: use text or json", ctx.GetString("stdin-format"))
+	}
+
+	if messageFile != "" {
+		doc, err := readMessageDocument(messageFile)
+		if err != nil {
+			return nil, err
+		}
+		if in.from == "" {
+			in.from = doc.From
+		}
+		if in.replyTo == "" {
+			in.replyTo = doc.ReplyTo
+		}
+		if in.subject == "" {
+			in.subject = doc.Subject
+		}
+		if in.text == "" && in.html == "" && textFile == "" && htmlFile == "" {
+			in.text, in.html = doc.Text, doc.HTML
+		}
+		if in.category == "" {
+			in.category = doc.Category
+		}
+		if in.priority == "" {
+			in.priority = doc.Priority
+		}
+		in.to = append(doc.To, in.to...)
+		in.cc = append(doc.Cc, in.cc...)
+		in.bcc = append(doc.Bcc, in.bcc...)
+		in.doc = doc
+	}
+
+	// Render a named template with the data record; flags and the message
+	// document override it
+	templateName := ctx.GetString("template-name")
+	var data interface{}
+	if in.doc != nil {
+		if templateName == "" {
+			templateName = in.doc.Template
+		}
+		data = in.doc.Data
+	}
+	if templateName != "" {
+		if dataFile := ctx.GetString("data"); dataFile != "" {
+			var err error
+			if data, err = readTemplateData(dataFile); err != nil {
+				return nil, err
+			}
+		}
+		if err := in.applyTemplate(config, templateName, data, textFile != "" || htmlFile != ""); err != nil {
+			return nil, err
+		}
+	}
+	in.data = data
+
+	// Add recipients from files
+	for _, list := range []struct {
+		flag       string
+		recipients *[]string
+	}{
+		{"to-file", &in.to},
+		{"cc-file", &in.cc},
+		{"bcc-file", &in.bcc},
+	} {
+		path := ctx.GetString(list.flag)
+		if path == "" {
+			continue
+		}
+		addresses, err := readAddressFile(path)
+		if err != nil {
+			return nil, err
+		}
+		*list.recipients = append(*list.recipients, addresses...)
+	}
+
+	// Expand address book aliases
+	for _, recipients := range []*[]string{&in.to, &in.cc, &in.bcc} {
+		var err error
+		if *recipients, err = config.ExpandAliases(*recipients); err != nil {
+			return nil, err
+		}
+	}
+
+	// Use config values if not provided via flags, preferring the category's
+	from, replyTo := config.Sender(in.category)
+	if in.from == "" {
+		in.from = from
+	}
+	if in.replyTo == "" {
+		in.replyTo = replyTo
 	}
 
 	// Handle content from files
 	if textFile != "" {
 		content, err := os.ReadFile(textFile)
 		if err != nil {
-			return fmt.Errorf("failed to read text file %s: %w", textFile, err)
+			return nil, fmt.Errorf("failed to read text file %s: %w", textFile, err)
 		}
-		text = string(content)
+		in.text = string(content)
 	}
 
 	if htmlFile != "" {
 		content, err := os.ReadFile(htmlFile)
 		if err != nil {
-			return fmt.Errorf("failed to read HTML file %s: %w", htmlFile, err)
+			return nil, fmt.Errorf("failed to read HTML file %s: %w", htmlFile, err)
 		}
-		html = string(content)
+		in.html = string(content)
 	}
 
 	// Read from stdin if no content provided
-	if text == "" && html == "" {
+	if in.text == "" && in.html == "" && messageFile != "-" {
 		stat, err := os.Stdin.Stat()
 		if err != nil {
-			return fmt.Errorf("failed to check stdin: %w", err)
+			return nil, fmt.Errorf("failed to check stdin: %w", err)
 		}
 
 		if (stat.Mode() & os.ModeCharDevice) == 0 {
@@ -254,129 +537,121 @@ func runSend(ctx *simplecli.Context) error {
 						}
 						break
 					}
-					return fmt.Errorf("failed to read from stdin: %w", err)
+					return nil, fmt.Errorf("failed to read from stdin: %w", err)
 				}
 				content.WriteString(line)
 			}
 
-			text = content.String()
+			in.text = content.String()
 		}
 	}
 
-	// Validate content
-	if text == "" && html == "" {
-		return fmt.Errorf("email content required: provide --text, --html, --text-file, --html-file, or pipe content to stdin")
+	return in, nil
+}
+
+// applyTemplate fills the subject and content missing from the input by
+// rendering the named template with data. hasContentFiles reports content
+// given in files, which is read later
+func (in *sendInput) applyTemplate(config *simpleconfig.Config, name string, data interface{}, hasContentFiles bool) error {
+	subject, html, text, err := renderNamedTemplate(config, name, data)
+	if err != nil {
+		return err
 	}
 
-	// Create email client
-	clientOptions := &azemailsender.ClientOptions{
-		Debug: debug,
+	if in.subject == "" {
+		in.subject = subject
+	}
+	if in.text == "" && in.html == "" && !hasContentFiles {
+		in.text, in.html = text, html
 	}
+	return nil
+}
 
-	var client *azemailsender.Client
-	if connectionString != "" {
-		client, err = azemailsender.NewClientFromConnectionString(connectionString, clientOptions)
-	} else {
-		client = azemailsender.NewClient(endpoint, accessKey, clientOptions)
+// timezone returns the recipient's time zone given by the message document
+// or the template data, if any
+func (in *sendInput) timezone() string {
+	if in.doc != nil && in.doc.Timezone != "" {
+		return in.doc.Timezone
 	}
-	if err != nil {
-		formatter.PrintError(err)
-		return err
+	return recordField(in.data, timezoneFields)
+}
+
+// check reports the first missing required field with the flag that provides it
+func (in *sendInput) check() error {
+	// Check recipients
+	if len(in.to) == 0 && len(in.cc) == 0 && len(in.bcc) == 0 {
+		return codedError(CodeRecipientsMissing, "at least one recipient required (--to, --cc, or --bcc)")
+	}
+
+	// Check sender
+	if in.from == "" {
+		return codedError(CodeSenderMissing, "sender address required (--from)")
+	}
+
+	// Check subject
+	if in.subject == "" {
+		return codedError(CodeSubjectMissing, "subject required (--subject)")
+	}
+
+	// Validate content
+	if in.text == "" && in.html == "" {
+		return codedError(CodeContentMissing, "email content required: provide --text, --html, --text-file, --html-file, or pipe content to stdin")
 	}
+	return nil
+}
 
+// builder returns a message builder populated from the input
+func (in *sendInput) builder(client *azemailsender.Client) (*azemailsender.MessageBuilder, error) {
 	// Build email message
 	builder := client.NewMessage().
-		From(from).
-		Subject(subject)
+		From(in.from).
+		Subject(in.subject)
 
 	// Add recipients
-	for _, recipient := range to {
+	for _, recipient := range in.to {
 		builder = builder.To(recipient)
 	}
-	for _, recipient := range cc {
+	for _, recipient := range in.cc {
 		builder = builder.Cc(recipient)
 	}
-	for _, recipient := range bcc {
+	for _, recipient := range in.bcc {
 		builder = builder.Bcc(recipient)
 	}
 
 	// Add reply-to if specified
-	if replyTo != "" {
-		builder = builder.ReplyTo(replyTo)
+	if in.replyTo != "" {
+		builder = builder.ReplyTo(in.replyTo)
 	}
 
 	// Add content
-	if text != "" {
-		builder = builder.PlainText(text)
-	}
-	if html != "" {
-		builder = builder.HTML(html)
+	if in.text != "" {
+		builder = builder.PlainText(in.text)
 	}
-
-	message, err := builder.Build()
-	if err != nil {
-		formatter.PrintError(err)
-		return err
-	}
-
-	formatter.PrintDebug("Sending email to %s", output.FormatRecipients(to))
-
-	// Send email
-	response, err := client.Send(message)
-	if err != nil {
-		formatter.PrintError(err)
-		return err
+	if in.html != "" {
+		builder = builder.HTML(in.html)
 	}
 
-	// Print send response
-	if err := formatter.PrintSendResponse(response); err != nil {
-		return err
+	if in.category != "" {
+		builder = builder.Category(in.category)
 	}
-
-	// Wait for completion if requested
-	if wait {
-		formatter.PrintInfo("Waiting for email completion...")
-
-		// Parse duration strings
-		pollIntervalStr := ctx.GetString("poll-interval")
-		maxWaitTimeStr := ctx.GetString("max-wait-time")
-
-		// Use config values if defaults
-		if pollIntervalStr == "5s" {
-			pollIntervalStr = config.PollInterval
-		}
-		if maxWaitTimeStr == "5m" {
-			maxWaitTimeStr = config.MaxWaitTime
-		}
-
-		pollInterval, err := time.ParseDuration(pollIntervalStr)
-		if err != nil {
-			return fmt.Errorf("invalid poll-interval: %w", err)
-		}
-
-		maxWaitTime, err := time.ParseDuration(maxWaitTimeStr)
+	if in.priority != "" {
+		priority, err := azemailsender.ParsePriority(in.priority)
 		if err != nil {
-			return fmt.Errorf("invalid max-wait-time: %w", err)
+			return nil, codedError(CodeUsage, "%w", err)
 		}
+		builder = builder.Priority(priority)
+	}
 
-		waitOptions := &azemailsender.WaitOptions{
-			PollInterval: pollInterval,
-			MaxWaitTime:  maxWaitTime,
-			OnStatusUpdate: func(status *azemailsender.StatusResponse) {
-				if !quiet && !jsonOutput {
-					fmt.Printf("Status: %s\n", status.Status)
-				}
-			},
+	// Add headers and attachments from the message document
+	if in.doc != nil {
+		if err := in.doc.addAttachments(builder); err != nil {
+			return nil, err
 		}
-
-		finalStatus, err := client.WaitForCompletion(response.ID, waitOptions)
-		if err != nil {
-			formatter.PrintError(fmt.Errorf("waiting for completion failed: %w", err))
-			return err
-		}
-
-		return formatter.PrintStatusResponse(finalStatus)
 	}
 
-	return nil
-}
\ No newline at end of file
+	// Add attachment files from flags
+	if err := addAttachmentFlags(builder, client.AttachmentSizeLimit(), in.attach, in.attachInline); err != nil {
+		return nil, err
+	}
+	return builder, nil
+}
@@ -1,7 +1,11 @@
 package commands
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/groovy-sky/azemailsender"
@@ -14,172 +18,117 @@ import (
 func NewStatusCommand() *simplecli.Command {
 	return &simplecli.Command{
 		Name:        "status",
+		Aliases:     []string{"st"},
 		Description: "Check email status",
-		Usage:       "status <message-id> [flags]",
-		LongDesc: `Check the status of a previously sent email.
+		Usage:       "status [flags] <message-id|-> [message-id...]",
+		LongDesc: `Check the status of previously sent emails. Several message IDs are checked
+concurrently, printing one result per ID; "-" reads IDs from stdin, one per line.
 
 Examples:
   # Check status once
   azemailsender-cli status abc123def456
 
   # Check status and wait for completion
-  azemailsender-cli status abc123def456 --wait
+  azemailsender-cli status --wait abc123def456
 
   # Check status with custom polling interval
-  azemailsender-cli status abc123def456 --wait --poll-interval 10s --max-wait-time 2m`,
+  azemailsender-cli status --wait --poll-interval 10s --max-wait-time 2m abc123def456
+
+  # Check several messages at once
+  azemailsender-cli status abc123def456 def456abc123
+
+  # Check the IDs printed by a batch
+  azemailsender-cli send-batch --ndjson messages.ndjson | jq -r 'select(.id) | .id' | azemailsender-cli status -
+
+  # Watch several messages until they all complete, checking every 2 seconds
+  azemailsender-cli status --watch --interval 2s abc123def456 def456abc123`,
 		Run: runStatus,
-		Flags: []*simplecli.Flag{
-			// Authentication flags
+		Flags: joinFlags(authFlags(), networkFlags(), waitFlags(), []*simplecli.Flag{
 			{
-				Name:        "endpoint",
-				Short:       "e",
-				Description: "Azure Communication Services endpoint",
-				Value:       "",
-				EnvVar:      "AZURE_EMAIL_ENDPOINT",
-			},
-			{
-				Name:        "access-key",
-				Short:       "k",
-				Description: "Access key for authentication",
-				Value:       "",
-				EnvVar:      "AZURE_EMAIL_ACCESS_KEY",
-			},
-			{
-				Name:        "connection-string",
-				Description: "Connection string for authentication",
-				Value:       "",
-				EnvVar:      "AZURE_EMAIL_CONNECTION_STRING",
-			},
-			// Behavior flags
-			{
-				Name:        "wait",
-				Short:       "w",
-				Description: "Wait for email completion",
+				Name:        "watch",
+				Description: "Watch all given message IDs concurrently until they complete",
 				Value:       false,
-				EnvVar:      "AZURE_EMAIL_WAIT",
 			},
 			{
-				Name:        "poll-interval",
-				Description: "Status polling interval (when --wait is used)",
-				Value:       "5s",
-				EnvVar:      "AZURE_EMAIL_POLL_INTERVAL",
+				Name:        "interval",
+				Description: "Status polling interval for --watch (default: --poll-interval)",
+				Value:       time.Duration(0),
 			},
 			{
-				Name:        "max-wait-time",
-				Description: "Maximum wait time (when --wait is used)",
-				Value:       "5m",
-				EnvVar:      "AZURE_EMAIL_MAX_WAIT_TIME",
+				Name:        "max-rate",
+				Description: "Deprecated alias for --rate",
+				Deprecated:  "use --rate instead",
+				Value:       0.0,
 			},
-		},
+		}, pacingFlags(0)),
+		Constraints: joinConstraints(authConstraints(), []simplecli.Constraint{
+			simplecli.Requires("interval", "watch"),
+			simplecli.MutuallyExclusive([]string{"interval"}, []string{"poll-interval", "poll-backoff"}),
+		}),
 	}
 }
 
 func runStatus(ctx *simplecli.Context) error {
-	// Check if message ID is provided
-	if len(ctx.Args) == 0 {
-		return fmt.Errorf("message ID required")
+	messageIDs, err := readMessageIDs(ctx.Args)
+	if err != nil {
+		return err
+	}
+	if len(messageIDs) == 0 {
+		return codedError(CodeUsage, "message ID required")
+	}
+	if len(messageIDs) > 1 && ctx.GetBool("wait") {
+		return codedError(CodeUsage, "--wait takes a single message ID; use --watch to wait for several")
 	}
-	messageID := ctx.Args[0]
 
 	// Load configuration
 	configFile := ctx.GetString("config")
-	config, err := simpleconfig.LoadConfig(configFile, ctx.Flags)
+	config, err := simpleconfig.LoadConfig(configFile, ctx.ProvidedFlags())
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
-
-	// Create output formatter
-	debug := ctx.GetBool("debug")
-	quiet := ctx.GetBool("quiet")
-	jsonOutput := ctx.GetBool("json")
-	formatter := output.NewFormatter(jsonOutput, quiet, debug)
-
-	// Validate authentication
-	endpoint := ctx.GetString("endpoint")
-	accessKey := ctx.GetString("access-key")
-	connectionString := ctx.GetString("connection-string")
-
-	// Use config values if not provided via flags
-	if endpoint == "" {
-		endpoint = config.Endpoint
-	}
-	if accessKey == "" {
-		accessKey = config.AccessKey
-	}
-	if connectionString == "" {
-		connectionString = config.ConnectionString
-	}
-
-	hasAuth := false
-	if connectionString != "" {
-		hasAuth = true
-	} else if endpoint != "" && accessKey != "" {
-		hasAuth = true
+	if err := checkWaitFlags(ctx, config, "wait", "watch"); err != nil {
+		return err
 	}
 
-	if !hasAuth {
-		return fmt.Errorf("authentication required: provide either --connection-string or both --endpoint and --access-key")
+	// Create output formatter
+	formatter, err := newFormatter(ctx)
+	if err != nil {
+		return err
 	}
 
 	// Create email client
-	clientOptions := &azemailsender.ClientOptions{
-		Debug: debug,
+	client, err := newClient(ctx, config, formatter)
+	if err != nil {
+		return err
 	}
 
-	var client *azemailsender.Client
-	if connectionString != "" {
-		client, err = azemailsender.NewClientFromConnectionString(connectionString, clientOptions)
-	} else {
-		client = azemailsender.NewClient(endpoint, accessKey, clientOptions)
+	if ctx.GetBool("watch") {
+		return runStatusWatch(ctx, client, config, formatter, messageIDs)
 	}
-	if err != nil {
-		formatter.PrintError(err)
-		return err
+	if len(messageIDs) > 1 {
+		return runStatusMany(ctx, client, formatter, messageIDs)
 	}
 
+	messageID := messageIDs[0]
 	formatter.PrintDebug("Checking status for message ID: %s", messageID)
 
 	wait := ctx.GetBool("wait")
 	if wait {
-		// Parse duration strings
-		pollIntervalStr := ctx.GetString("poll-interval")
-		maxWaitTimeStr := ctx.GetString("max-wait-time")
-
-		// Use config values if not provided via flags
-		if pollIntervalStr == "5s" { // default value
-			pollIntervalStr = config.PollInterval
-		}
-		if maxWaitTimeStr == "5m" { // default value
-			maxWaitTimeStr = config.MaxWaitTime
-		}
-
-		pollInterval, err := time.ParseDuration(pollIntervalStr)
+		waitOptions, err := newWaitOptions(ctx, config, func(status *azemailsender.StatusResponse) {
+			if !formatter.Quiet && !formatter.JSON {
+				fmt.Printf("Status: %s\n", formatter.PaintStatus(string(status.Status)))
+			}
+		})
 		if err != nil {
-			return fmt.Errorf("invalid poll-interval: %w", err)
-		}
-
-		maxWaitTime, err := time.ParseDuration(maxWaitTimeStr)
-		if err != nil {
-			return fmt.Errorf("invalid max-wait-time: %w", err)
+			return err
 		}
 
 		// Wait for completion
 		formatter.PrintInfo("Waiting for email completion...")
 
-		waitOptions := &azemailsender.WaitOptions{
-			PollInterval: pollInterval,
-			MaxWaitTime:  maxWaitTime,
-			OnStatusUpdate: func(status *azemailsender.StatusResponse) {
-				if !quiet && !jsonOutput {
-					fmt.Printf("Status: %s\n", status.Status)
-				}
-			},
-		}
-
 		finalStatus, err := client.WaitForCompletion(messageID, waitOptions)
 		if err != nil {
-			formatter.PrintError(fmt.Errorf("waiting for completion failed: %w", err))
-			return err
+			return fmt.Errorf("waiting for completion failed: %w", err)
 		}
 
 		return formatter.PrintStatusResponse(finalStatus)
@@ -187,10 +136,123 @@ func runStatus(ctx *simplecli.Context) error {
 		// Check status once
 		status, err := client.GetStatus(messageID)
 		if err != nil {
-			formatter.PrintError(err)
 			return err
 		}
 
 		return formatter.PrintStatusResponse(status)
 	}
-}
\ No newline at end of file
+}
+
+// readMessageIDs returns the message IDs given as arguments, reading them from
+// stdin, one per line, in place of "-". Flags after the first ID are not parsed,
+// so an argument starting with - is rejected rather than taken for an ID
+func readMessageIDs(args []string) ([]string, error) {
+	var messageIDs []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") && arg != "-" {
+			return nil, codedError(CodeUsage, "%s is not a message ID: flags go before message IDs, e.g. status --wait ID", arg)
+		}
+		if arg != "-" {
+			messageIDs = append(messageIDs, arg)
+			continue
+		}
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			messageIDs = append(messageIDs, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read message IDs from stdin: %w", err)
+		}
+	}
+	return messageIDs, nil
+}
+
+// runStatusMany checks several messages concurrently and prints one result per ID
+func runStatusMany(ctx *simplecli.Context, client *azemailsender.Client, formatter *output.Formatter, messageIDs []string) error {
+	batchOptions, err := newBatchOptions(ctx)
+	if err != nil {
+		return err
+	}
+	if batchOptions.MaxRequestsPerSecond == 0 {
+		batchOptions.MaxRequestsPerSecond = ctx.GetFloat("max-rate")
+	}
+
+	var firstErr error
+	failed := 0
+	for _, result := range client.GetStatuses(context.Background(), messageIDs, batchOptions) {
+		if result.Err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = result.Err
+			}
+		}
+		if err := formatter.PrintStatusResult(result); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		// Each failure was printed with its ID; exit with the code of the first
+		return codedError(errorCode(firstErr), "%d of %d status checks failed", failed, len(messageIDs))
+	}
+	return nil
+}
+
+// runStatusWatch polls all message IDs concurrently and prints every status transition
+func runStatusWatch(ctx *simplecli.Context, client *azemailsender.Client, config *simpleconfig.Config, formatter *output.Formatter, messageIDs []string) error {
+	batchOptions, err := newBatchOptions(ctx)
+	if err != nil {
+		return err
+	}
+	if batchOptions.MaxRequestsPerSecond == 0 {
+		batchOptions.MaxRequestsPerSecond = ctx.GetFloat("max-rate")
+	}
+
+	waitOptions, err := newWaitOptions(ctx, config, nil)
+	if err != nil {
+		return err
+	}
+	if ctx.IsSet("interval") {
+		interval := ctx.GetDuration("interval")
+		if interval <= 0 {
+			return codedError(CodeUsage, "--interval must be positive")
+		}
+		waitOptions.PollInterval = interval
+	}
+
+	updates := client.WaitForAll(context.Background(), messageIDs, &azemailsender.WaitAllOptions{
+		WaitOptions:          waitOptions,
+		MaxRequestsPerSecond: batchOptions.MaxRequestsPerSecond,
+		PauseBetween:         batchOptions.PauseBetween,
+		MaxConcurrency:       batchOptions.MaxConcurrency,
+	})
+
+	lastStatus := make(map[string]azemailsender.EmailStatus)
+	failed := 0
+	for update := range updates {
+		if update.Done {
+			if update.Err != nil || !update.Result.FinalStatus.IsSuccess() {
+				failed++
+			}
+		} else if update.Status != nil && lastStatus[update.ID] == update.Status.Status {
+			// Only print transitions
+			continue
+		}
+
+		if update.Status != nil {
+			lastStatus[update.ID] = update.Status.Status
+		}
+		if err := formatter.PrintStatusUpdate(update); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return codedError(CodeDeliveryFailed, "%d of %d messages did not complete successfully", failed, len(messageIDs))
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package commands
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/groovy-sky/azemailsender/internal/cli/output"
+	"github.com/groovy-sky/azemailsender/internal/simpleconfig"
+	"github.com/groovy-sky/azemailsender/internal/simplecli"
+)
+
+// systemdUnitName is the name of the service and socket units written by daemon --install-systemd
+const systemdUnitName = "azemailsender"
+
+// systemdEnvironmentFile holds the credentials of the service, so they stay out of the unit files
+const systemdEnvironmentFile = "/etc/azemailsender/environment"
+
+// systemdStateDir is the state directory systemd creates for the service
+const systemdStateDir = "/var/lib/" + systemdUnitName
+
+// systemdDaemonFlags are the daemon flags carried over into ExecStart when given.
+// Credentials are left out and read from the environment file instead
+var systemdDaemonFlags = []string{
+	"config", "profile", "queue-dir", "interval", "max-attempts", "queue-retry-delay", "drain-timeout",
+	"template-dir", "digest-template", "throttle", "webhook-url", "alert-format", "alert-rule", "alert-failure-rate", "alert-window", "alert-min-messages",
+	"suppression-file", "check-suppression", "drop-suppressed", "dedupe-window", "dedupe-dir", "log-file", "json", "debug",
+	"ca-file", "client-cert", "client-key", "tls-min-version", "proxy", "http-timeout", "max-retries", "retry-delay",
+}
+
+// listenFDsStart is the first file descriptor passed by systemd socket activation
+const listenFDsStart = 3
+
+// systemdListener returns the socket passed by systemd socket activation, or
+// nil when the process was not socket-activated
+func systemdListener() (net.Listener, error) {
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	fds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || fds < 1 {
+		return nil, nil
+	}
+	// The variables are meant for this process only, not for its children
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	file := os.NewFile(uintptr(listenFDsStart), "systemd-socket")
+	defer file.Close()
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use systemd socket: %w", err)
+	}
+	return listener, nil
+}
+
+// installSystemd writes a service unit running the daemon with the given flags
+// and a socket unit for --listen to dir, or prints both when dir is "-"
+func installSystemd(ctx *simplecli.Context, formatter *output.Formatter, dir string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the executable: %w", err)
+	}
+
+	args := []string{executable, "daemon"}
+	if !ctx.IsSet("queue-dir") {
+		args = append(args, "--queue-dir", systemdStateDir+"/queue")
+	}
+	for _, name := range systemdDaemonFlags {
+		if !ctx.IsSet(name) {
+			continue
+		}
+		switch value := ctx.Flags[name].(type) {
+		case bool:
+			if value {
+				args = append(args, "--"+name)
+			}
+		case time.Duration:
+			args = append(args, "--"+name, value.String())
+		case []string:
+			for _, v := range value {
+				args = append(args, "--"+name, v)
+			}
+		default:
+			args = append(args, "--"+name, fmt.Sprint(value))
+		}
+	}
+
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = systemdQuote(arg)
+	}
+
+	// Leave systemd time to stop the daemon after its own drain timeout
+	stopTimeout := ctx.GetDuration("drain-timeout") + 15*time.Second
+
+	service := fmt.Sprintf(`[Unit]
+Description=Azure Communication Services email sender
+After=network-online.target
+Wants=network-online.target
+Requires=%[1]s.socket
+
+[Service]
+Type=simple
+ExecStart=%[2]s
+EnvironmentFile=-%[3]s
+DynamicUser=yes
+StateDirectory=%[1]s
+Restart=on-failure
+KillSignal=SIGTERM
+TimeoutStopSec=%[4]d
+
+[Install]
+WantedBy=multi-user.target
+`, systemdUnitName, strings.Join(quoted, " "), systemdEnvironmentFile, int(stopTimeout.Seconds()))
+
+	socket := fmt.Sprintf(`[Unit]
+Description=Azure Communication Services email sender HTTP socket
+
+[Socket]
+ListenStream=%s
+
+[Install]
+WantedBy=sockets.target
+`, systemdListenStream(ctx.GetString("listen")))
+
+	units := []struct {
+		name    string
+		content string
+	}{
+		{systemdUnitName + ".service", service},
+		{systemdUnitName + ".socket", socket},
+	}
+
+	if dir == "-" {
+		for i, unit := range units {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("# %s\n%s", unit.name, unit.content)
+		}
+		return nil
+	}
+
+	for _, unit := range units {
+		path := filepath.Join(dir, unit.name)
+		if err := os.WriteFile(path, []byte(unit.content), 0644); err != nil {
+			return fmt.Errorf("failed to write unit file: %w", err)
+		}
+		if err := formatter.PrintSuccess("Wrote %s", path); err != nil {
+			return err
+		}
+	}
+	formatter.PrintInfo("Put the credentials, such as %s=..., in %s, then run:", simpleconfig.EnvName("CONNECTION_STRING"), systemdEnvironmentFile)
+	formatter.PrintInfo("  systemctl daemon-reload && systemctl enable --now %s.socket %s.service", systemdUnitName, systemdUnitName)
+	return nil
+}
+
+// systemdListenStream turns a --listen address into a ListenStream value; a
+// bare port such as ":8080" listens on all addresses
+func systemdListenStream(listen string) string {
+	if strings.HasPrefix(listen, ":") {
+		return listen[1:]
+	}
+	return listen
+}
+
+// systemdQuote quotes an ExecStart argument, escaping the characters systemd
+// would otherwise expand
+func systemdQuote(arg string) string {
+	arg = strings.NewReplacer("%", "%%", "$", "$$").Replace(arg)
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\;") {
+		return arg
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
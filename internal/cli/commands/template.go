@@ -0,0 +1,317 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/groovy-sky/azemailsender"
+	"github.com/groovy-sky/azemailsender/internal/simpleconfig"
+	"github.com/groovy-sky/azemailsender/internal/simplecli"
+	"github.com/groovy-sky/azemailsender/internal/simpleyaml"
+)
+
+// NewTemplateCommand creates the template command
+func NewTemplateCommand() *simplecli.Command {
+	return &simplecli.Command{
+		Name:        "template",
+		Aliases:     []string{"tpl"},
+		Description: "Render email templates",
+		Usage:       "template [subcommand]",
+		LongDesc:    "Render Go templates with JSON or YAML data, preview the result and optionally send it",
+		Run: func(ctx *simplecli.Context) error {
+			return codedError(CodeUsage, "subcommand required. Use --help to see available subcommands")
+		},
+		Subcommands: []*simplecli.Command{
+			{
+				Name:        "render",
+				Description: "Render a template and preview or send it",
+				Usage:       "template render --template <file> [--data <file>] [flags]",
+				LongDesc: `Render a Go template (text/template syntax) with data from a JSON or YAML
+file. Templates ending in .html or .htm are rendered with html/template, which
+escapes data for HTML. The result is printed to stdout, written to --output,
+or sent with --send. The subject is rendered as a template with the same data.
+
+Examples:
+  # Preview a rendered template
+  azemailsender-cli template render --template welcome.html --data data.json
+
+  # Render with YAML data into a file
+  azemailsender-cli template render --template welcome.html --data data.yaml --output welcome.out.html
+
+  # Render and send
+  azemailsender-cli template render --template welcome.html --data data.json --send \
+    --to user@example.com --subject "Welcome, {{.name}}"`,
+				Run: runTemplateRender,
+				Flags: joinFlags([]*simplecli.Flag{
+					{
+						Name:        "template",
+						Description: "Template file",
+						Value:       "",
+					},
+					{
+						Name:        "data",
+						Description: "JSON or YAML data file (.yaml/.yml are read as YAML), or - for stdin",
+						Value:       "",
+					},
+					{
+						Name:        "format",
+						Description: "Content format: html or text (default: from the template extension)",
+						Value:       "",
+					},
+					{
+						Name:        "output",
+						Short:       "o",
+						Description: "Write the rendered content to a file instead of stdout",
+						Value:       "",
+					},
+					{
+						Name:        "send",
+						Description: "Send the rendered content as an email",
+						Value:       false,
+					},
+					{
+						Name:        "from",
+						Short:       "f",
+						Description: "Sender email address (with --send)",
+						Value:       "",
+						EnvVar:      "FROM",
+					},
+					{
+						Name:        "to",
+						Short:       "t",
+						Description: "To recipients (can be repeated)",
+						Value:       []string{},
+					},
+					{
+						Name:        "cc",
+						Description: "CC recipients (can be repeated)",
+						Value:       []string{},
+					},
+					{
+						Name:        "bcc",
+						Description: "BCC recipients (can be repeated)",
+						Value:       []string{},
+					},
+					{
+						Name:        "reply-to",
+						Description: "Reply-to email address",
+						Value:       "",
+						EnvVar:      "REPLY_TO",
+					},
+					{
+						Name:        "subject",
+						Short:       "s",
+						Description: "Email subject, rendered as a template with the same data",
+						Value:       "",
+					},
+				}, authFlags(), networkFlags(), confirmFlags()),
+				Constraints: authConstraints(),
+			},
+		},
+	}
+}
+
+func runTemplateRender(ctx *simplecli.Context) error {
+	templateFile := ctx.GetString("template")
+	if templateFile == "" {
+		return codedError(CodeUsage, "template file required (--template)")
+	}
+
+	isHTML, err := templateFormat(templateFile, ctx.GetString("format"))
+	if err != nil {
+		return err
+	}
+
+	data, err := readTemplateData(ctx.GetString("data"))
+	if err != nil {
+		return err
+	}
+
+	source, err := os.ReadFile(templateFile)
+	if err != nil {
+		return fmt.Errorf("failed to read template: %w", err)
+	}
+
+	content, err := renderTemplate(filepath.Base(templateFile), string(source), isHTML, data, nil)
+	if err != nil {
+		return err
+	}
+
+	// Preview
+	outputFile := ctx.GetString("output")
+	if outputFile != "" {
+		if err := os.WriteFile(outputFile, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+	} else if !ctx.GetBool("send") {
+		fmt.Print(content)
+		return nil
+	}
+
+	if !ctx.GetBool("send") {
+		return nil
+	}
+
+	subject, err := renderTemplate("subject", ctx.GetString("subject"), false, data, nil)
+	if err != nil {
+		return err
+	}
+	return sendRendered(ctx, subject, content, isHTML)
+}
+
+// templateFormat reports whether a template produces HTML
+func templateFormat(path, format string) (bool, error) {
+	switch strings.ToLower(format) {
+	case "html":
+		return true, nil
+	case "text":
+		return false, nil
+	case "":
+		ext := strings.ToLower(filepath.Ext(path))
+		return ext == ".html" || ext == ".htm", nil
+	default:
+		return false, codedError(CodeUsage, "invalid --format %q: use html or text", format)
+	}
+}
+
+// readTemplateData reads template data from a JSON or YAML file, or stdin when path is "-"
+func readTemplateData(path string) (interface{}, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	var raw []byte
+	var err error
+	if path == "-" {
+		raw, err = io.ReadAll(os.Stdin)
+	} else {
+		raw, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data file: %w", err)
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != ".yaml" && ext != ".yml" {
+		var data interface{}
+		err := json.Unmarshal(raw, &data)
+		if err == nil {
+			return data, nil
+		}
+		// Data on stdin may be either format
+		if path != "-" {
+			return nil, fmt.Errorf("invalid JSON data file: %w", err)
+		}
+	}
+
+	data, err := simpleyaml.Unmarshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid YAML data file: %w", err)
+	}
+	return data, nil
+}
+
+// renderTemplate executes a template with optional extra functions, failing
+// on keys missing from data
+func renderTemplate(name, source string, isHTML bool, data interface{}, funcs map[string]interface{}) (string, error) {
+	var buf bytes.Buffer
+	if isHTML {
+		tmpl, err := htmltemplate.New(name).Funcs(funcs).Option("missingkey=error").Parse(source)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse template: %w", err)
+		}
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("failed to render template: %w", err)
+		}
+	} else {
+		tmpl, err := texttemplate.New(name).Funcs(funcs).Option("missingkey=error").Parse(source)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse template: %w", err)
+		}
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("failed to render template: %w", err)
+		}
+	}
+	return buf.String(), nil
+}
+
+// sendRendered sends rendered content using the message flags
+func sendRendered(ctx *simplecli.Context, subject, content string, isHTML bool) error {
+	configFile := ctx.GetString("config")
+	config, err := simpleconfig.LoadConfig(configFile, ctx.ProvidedFlags())
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	formatter, err := newFormatter(ctx)
+	if err != nil {
+		return err
+	}
+
+	from := ctx.GetString("from")
+	if from == "" {
+		from = config.From
+	}
+	replyTo := ctx.GetString("reply-to")
+	if replyTo == "" {
+		replyTo = config.ReplyTo
+	}
+
+	client, err := newClient(ctx, config, formatter)
+	if err != nil {
+		return err
+	}
+
+	builder := client.NewMessage().
+		From(from).
+		Subject(subject)
+
+	for _, list := range []struct {
+		recipients []string
+		add        func(string, ...string) *azemailsender.MessageBuilder
+	}{
+		{ctx.GetStringSlice("to"), builder.To},
+		{ctx.GetStringSlice("cc"), builder.Cc},
+		{ctx.GetStringSlice("bcc"), builder.Bcc},
+	} {
+		addresses, err := config.ExpandAliases(list.recipients)
+		if err != nil {
+			return err
+		}
+		for _, address := range addresses {
+			list.add(address)
+		}
+	}
+
+	if replyTo != "" {
+		builder.ReplyTo(replyTo)
+	}
+	if isHTML {
+		builder.HTML(content)
+	} else {
+		builder.PlainText(content)
+	}
+
+	message, err := builder.Build()
+	if err != nil {
+		return err
+	}
+
+	recipients := len(message.Recipients.To) + len(message.Recipients.Cc) + len(message.Recipients.Bcc)
+	if err := confirmSend(ctx, config, recipients, "recipients"); err != nil {
+		return err
+	}
+
+	response, err := client.Send(message)
+	if err != nil {
+		return err
+	}
+	return formatter.PrintSendResponse(response)
+}
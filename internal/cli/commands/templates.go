@@ -0,0 +1,674 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/groovy-sky/azemailsender"
+	"github.com/groovy-sky/azemailsender/internal/cli/output"
+	"github.com/groovy-sky/azemailsender/internal/simpleconfig"
+	"github.com/groovy-sky/azemailsender/internal/simplecli"
+	"github.com/groovy-sky/azemailsender/templates"
+)
+
+// templateNamePattern restricts template names to safe file names
+var templateNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// storedTemplate is a named template, kept as <name>.json in the template directory
+type storedTemplate struct {
+	Name       string      `json:"name"`
+	Subject    string      `json:"subject"`
+	HTML       string      `json:"html,omitempty"`
+	Text       string      `json:"text,omitempty"`
+	SampleData interface{} `json:"sampleData,omitempty"`
+	UpdatedAt  time.Time   `json:"updatedAt"`
+
+	// DefaultLocale is the locale of the template's own content, used for
+	// records without a locale of their own
+	DefaultLocale string `json:"defaultLocale,omitempty"`
+	// Locales holds the content of other locales by normalized locale tag
+	Locales  map[string]*templateVariant `json:"locales,omitempty"`
+	Messages messageCatalog              `json:"messages,omitempty"`
+}
+
+// render renders the subject and content of the template with data, in the
+// locale named by its locale field. Missing keys are errors, as in template render
+func (t *storedTemplate) render(data interface{}) (subject, html, text string, err error) {
+	l := newLocalizer(t, data)
+	v, funcs := l.variant(), l.funcs()
+	if subject, err = renderTemplate(t.Name+" subject", v.Subject, false, data, funcs); err != nil {
+		return "", "", "", err
+	}
+	if v.HTML != "" {
+		if html, err = renderTemplate(t.Name+".html", v.HTML, true, data, funcs); err != nil {
+			return "", "", "", err
+		}
+	}
+	if v.Text != "" {
+		if text, err = renderTemplate(t.Name+".txt", v.Text, false, data, funcs); err != nil {
+			return "", "", "", err
+		}
+	}
+	return subject, html, text, nil
+}
+
+// parse checks the syntax of the template and its locale variants without rendering
+func (t *storedTemplate) parse() error {
+	variants := []*templateVariant{{Subject: t.Subject, HTML: t.HTML, Text: t.Text}}
+	for _, v := range t.Locales {
+		variants = append(variants, v)
+	}
+	for _, v := range variants {
+		if _, err := texttemplate.New(t.Name + " subject").Funcs(templateFuncStubs).Parse(v.Subject); err != nil {
+			return fmt.Errorf("failed to parse template: %w", err)
+		}
+		if _, err := htmltemplate.New(t.Name + ".html").Funcs(templateFuncStubs).Parse(v.HTML); err != nil {
+			return fmt.Errorf("failed to parse template: %w", err)
+		}
+		if _, err := texttemplate.New(t.Name + ".txt").Funcs(templateFuncStubs).Parse(v.Text); err != nil {
+			return fmt.Errorf("failed to parse template: %w", err)
+		}
+	}
+	return nil
+}
+
+// summary returns the template for output
+func (t *storedTemplate) summary() output.Template {
+	return output.Template{
+		Name:       t.Name,
+		Subject:    t.Subject,
+		HTML:       t.HTML,
+		Text:       t.Text,
+		SampleData: t.SampleData,
+		UpdatedAt:  t.UpdatedAt,
+		Locales:    t.locales(),
+	}
+}
+
+// digest merges the messages of an outbox digest with the template, as an
+// azemailsender.DigestFunc. The data has the digest key, the recipient, the
+// message count and the messages with their subject, html and text. A
+// single message is sent as is
+func (t *storedTemplate) digest(key string, recipient azemailsender.EmailAddress, messages []*azemailsender.EmailMessage) (*azemailsender.EmailMessage, error) {
+	digest, err := azemailsender.MergeDigest(key, recipient, messages)
+	if err != nil || len(messages) == 1 {
+		return digest, err
+	}
+
+	entries := make([]interface{}, 0, len(messages))
+	for _, message := range messages {
+		entries = append(entries, map[string]interface{}{
+			"subject": message.Content.Subject,
+			// Message HTML is inserted as is, as it would be sent on its own
+			"html": htmltemplate.HTML(message.Content.Html),
+			"text": message.Content.PlainText,
+		})
+	}
+	data := map[string]interface{}{
+		"key":       key,
+		"recipient": recipient.Address,
+		"count":     len(messages),
+		"messages":  entries,
+	}
+	if digest.Content.Subject, digest.Content.Html, digest.Content.PlainText, err = t.render(data); err != nil {
+		return nil, fmt.Errorf("digest template %s: %w", t.Name, err)
+	}
+	return digest, nil
+}
+
+// templateStore reads and writes named templates in a directory
+type templateStore struct {
+	dir string
+}
+
+// path returns the file of the template name, checking the name
+func (s *templateStore) path(name string) (string, error) {
+	if !templateNamePattern.MatchString(name) {
+		return "", codedError(CodeUsage, "invalid template name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	return filepath.Join(s.dir, name+".json"), nil
+}
+
+// get loads the template name
+func (s *templateStore) get(name string) (*storedTemplate, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, codedError(CodeUsage, "template %q not found in %s", name, s.dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+
+	var t storedTemplate
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("invalid template file %s: %w", path, err)
+	}
+	t.Name = name
+	return &t, nil
+}
+
+// put writes a template, refusing to replace an existing one unless replace is set
+func (s *templateStore) put(t *storedTemplate, replace bool) error {
+	path, err := s.path(t.Name)
+	if err != nil {
+		return err
+	}
+	if !replace {
+		if _, err := os.Stat(path); err == nil {
+			return codedError(CodeUsage, "template %q already exists; use --force to replace it", t.Name)
+		}
+	}
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create template directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode template: %w", err)
+	}
+	// Write a temporary file first so a failed write can't corrupt the template
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write template: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write template: %w", err)
+	}
+	return nil
+}
+
+// list loads every template, sorted by name
+func (s *templateStore) list() ([]*storedTemplate, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template directory: %w", err)
+	}
+
+	var stored []*storedTemplate
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() || !templateNamePattern.MatchString(name) {
+			continue
+		}
+		t, err := s.get(name)
+		if err != nil {
+			return nil, err
+		}
+		stored = append(stored, t)
+	}
+	sort.Slice(stored, func(i, j int) bool { return stored[i].Name < stored[j].Name })
+	return stored, nil
+}
+
+// remove deletes the template name
+func (s *templateStore) remove(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); errors.Is(err, fs.ErrNotExist) {
+		return codedError(CodeUsage, "template %q not found in %s", name, s.dir)
+	} else if err != nil {
+		return fmt.Errorf("failed to remove template: %w", err)
+	}
+	return nil
+}
+
+// renderNamedTemplate renders the named template of the configured template
+// directory with data
+func renderNamedTemplate(config *simpleconfig.Config, name string, data interface{}) (subject, html, text string, err error) {
+	store := &templateStore{dir: config.GetTemplateDir()}
+	t, err := store.get(name)
+	if err != nil {
+		return "", "", "", err
+	}
+	return t.render(data)
+}
+
+// templateDirFlags returns the flag selecting the template directory
+func templateDirFlags() []*simplecli.Flag {
+	return []*simplecli.Flag{
+		{
+			Name:        "template-dir",
+			Description: "Named template directory (default: templates next to the config file)",
+			Value:       "",
+			EnvVar:      "TEMPLATE_DIR",
+		},
+	}
+}
+
+// NewTemplatesCommand creates the templates command
+func NewTemplatesCommand() *simplecli.Command {
+	return &simplecli.Command{
+		Name:        "templates",
+		Description: "Manage named email templates",
+		Usage:       "templates [subcommand]",
+		LongDesc: `Manage a catalog of named templates, each a subject with HTML and/or plain
+text content and optional sample data, for 'send --template-name'. Templates
+use Go template syntax as in 'template render'; HTML content is rendered with
+html/template. They are stored as one JSON file each in "templates" next to
+the config file unless --template-dir or the template-dir config key says
+otherwise.`,
+		Run: func(ctx *simplecli.Context) error {
+			return codedError(CodeUsage, "subcommand required. Use --help to see available subcommands")
+		},
+		Subcommands: []*simplecli.Command{
+			{
+				Name:        "add",
+				Description: "Add or replace a named template",
+				Usage:       "templates add [flags] <name>",
+				LongDesc: `Store a template under a name. The subject and content are parsed, and
+rendered with the sample data in each locale when given, so mistakes show up
+now rather than at send time.
+
+Templates can be localized. --locale adds a variant of an existing template
+in another locale, with its own subject and/or content; data whose "locale"
+field names it (de-AT falls back to de) is rendered with it, other data with
+the template's own content in --default-locale. --messages adds a message
+catalog, as in go-i18n: {{t "id"}} renders the message id of the data's
+locale and {{tn "id" .count}} picks its "zero", "one" or "other" form.
+Messages are templates too, rendered with the same data.
+
+Examples:
+  azemailsender-cli templates add --subject "Welcome, {{.name}}" --html-file welcome.html --text-file welcome.txt --sample-data welcome.json welcome
+  azemailsender-cli send --template-name welcome --data alice.json --to alice@example.com
+
+  # Localize: messages in several locales, and a German variant of the content
+  azemailsender-cli templates add --default-locale en --messages messages.yaml --subject '{{t "subject"}}' --html-file welcome.html welcome
+  azemailsender-cli templates add --locale de --html-file welcome.de.html welcome`,
+				Run: runTemplatesAdd,
+				Flags: joinFlags(templateDirFlags(), []*simplecli.Flag{
+					{
+						Name:        "subject",
+						Short:       "s",
+						Description: "Subject template",
+						Value:       "",
+					},
+					{
+						Name:        "html-file",
+						Description: "HTML content template file",
+						Value:       "",
+					},
+					{
+						Name:        "text-file",
+						Description: "Plain text content template file",
+						Value:       "",
+					},
+					{
+						Name:        "sample-data",
+						Description: "JSON or YAML file with sample data for previews",
+						Value:       "",
+					},
+					{
+						Name:        "locale",
+						Description: "Add the content and messages as this locale of an existing template, e.g. de",
+						Value:       "",
+					},
+					{
+						Name:        "default-locale",
+						Description: "Locale of the template's own content, used for data without a locale",
+						Value:       "",
+					},
+					{
+						Name:        "messages",
+						Description: "JSON or YAML message catalog mapping locales to message IDs and messages",
+						Value:       "",
+					},
+					{
+						Name:        "force",
+						Description: "Replace an existing template of the same name",
+						Value:       false,
+					},
+				}),
+			},
+			{
+				Name:        "init",
+				Description: "Add a template from an embedded starter",
+				Usage:       "templates init [flags] <starter> [name]",
+				LongDesc: `Copy an embedded starter template, with its sample data, into the catalog
+under the starter's name or the given name, to send as is or edit. The
+starters are responsive HTML with a plain text alternative:
+
+` + starterList() + `
+
+Examples:
+  azemailsender-cli templates init alert
+  azemailsender-cli templates show --render alert
+  azemailsender-cli templates init report weekly-report`,
+				Run: runTemplatesInit,
+				Flags: joinFlags(templateDirFlags(), []*simplecli.Flag{
+					{
+						Name:        "force",
+						Description: "Replace an existing template of the same name",
+						Value:       false,
+					},
+				}),
+			},
+			{
+				Name:        "list",
+				Aliases:     []string{"ls"},
+				Description: "List named templates",
+				Usage:       "templates list [flags]",
+				Run:         runTemplatesList,
+				Flags:       templateDirFlags(),
+			},
+			{
+				Name:        "show",
+				Description: "Show a named template, or render it",
+				Usage:       "templates show [flags] <name>",
+				LongDesc: `Print a stored template. With --render, print the subject and content
+rendered with --data, or with the template's sample data.`,
+				Run: runTemplatesShow,
+				Flags: joinFlags(templateDirFlags(), []*simplecli.Flag{
+					{
+						Name:        "render",
+						Description: "Render the template instead of printing its source",
+						Value:       false,
+					},
+					{
+						Name:        "data",
+						Description: "JSON or YAML data file for --render (default: the sample data)",
+						Value:       "",
+					},
+				}),
+			},
+			{
+				Name:        "rm",
+				Aliases:     []string{"remove"},
+				Description: "Remove named templates",
+				Usage:       "templates rm [flags] <name> [name...]",
+				Run:         runTemplatesRemove,
+				Flags:       templateDirFlags(),
+			},
+		},
+	}
+}
+
+// openTemplates loads the configuration and formatter and returns the template store
+func openTemplates(ctx *simplecli.Context) (*simpleconfig.Config, *output.Formatter, *templateStore, error) {
+	config, err := simpleconfig.LoadConfig(ctx.GetString("config"), ctx.ProvidedFlags())
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	formatter, err := newFormatter(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	store := &templateStore{dir: config.GetTemplateDir()}
+	formatter.PrintDebug("Using template directory %s", store.dir)
+	return config, formatter, store, nil
+}
+
+func runTemplatesAdd(ctx *simplecli.Context) error {
+	if len(ctx.Args) != 1 {
+		return codedError(CodeUsage, "exactly one template name required")
+	}
+
+	content := &templateVariant{Subject: ctx.GetString("subject")}
+	for _, file := range []struct {
+		flag    string
+		content *string
+	}{
+		{"html-file", &content.HTML},
+		{"text-file", &content.Text},
+	} {
+		path := ctx.GetString(file.flag)
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read template: %w", err)
+		}
+		*file.content = string(data)
+	}
+
+	catalog, err := readMessageCatalog(ctx.GetString("messages"))
+	if err != nil {
+		return err
+	}
+
+	_, formatter, store, err := openTemplates(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Add a locale variant and messages to an existing template
+	if locale := normalizeLocale(ctx.GetString("locale")); locale != "" {
+		t, err := store.get(ctx.Args[0])
+		if err != nil {
+			return err
+		}
+		if err := t.addLocale(locale, content, catalog, ctx.GetBool("force")); err != nil {
+			return err
+		}
+		if ctx.IsSet("default-locale") {
+			t.DefaultLocale = ctx.GetString("default-locale")
+		}
+		t.UpdatedAt = time.Now().UTC()
+		if err := t.check(); err != nil {
+			return err
+		}
+		if err := store.put(t, true); err != nil {
+			return err
+		}
+		return formatter.PrintSuccess("Locale %s of template %s saved in %s", locale, t.Name, store.dir)
+	}
+
+	t := &storedTemplate{
+		Name:          ctx.Args[0],
+		Subject:       content.Subject,
+		HTML:          content.HTML,
+		Text:          content.Text,
+		UpdatedAt:     time.Now().UTC(),
+		DefaultLocale: ctx.GetString("default-locale"),
+		Messages:      catalog,
+	}
+	if t.Subject == "" {
+		return codedError(CodeUsage, "subject required (--subject)")
+	}
+	if t.HTML == "" && t.Text == "" {
+		return codedError(CodeUsage, "content required (--html-file and/or --text-file)")
+	}
+	if t.SampleData, err = readTemplateData(ctx.GetString("sample-data")); err != nil {
+		return err
+	}
+	if err := t.check(); err != nil {
+		return err
+	}
+
+	if err := store.put(t, ctx.GetBool("force")); err != nil {
+		return err
+	}
+	return formatter.PrintSuccess("Template %s saved in %s", t.Name, store.dir)
+}
+
+// addLocale stores the content of a locale and merges its messages into the
+// catalog, replacing messages of the same locale
+func (t *storedTemplate) addLocale(locale string, content *templateVariant, catalog messageCatalog, replace bool) error {
+	hasContent := content.Subject != "" || content.HTML != "" || content.Text != ""
+	if !hasContent && len(catalog) == 0 {
+		return codedError(CodeUsage, "--locale needs content (--subject, --html-file or --text-file) or --messages")
+	}
+
+	if hasContent {
+		if locale == normalizeLocale(t.DefaultLocale) {
+			return codedError(CodeUsage, "%s is the default locale of template %s; add the template again with --force to change its content", locale, t.Name)
+		}
+		if _, ok := t.Locales[locale]; ok && !replace {
+			return codedError(CodeUsage, "template %s already has locale %s; use --force to replace it", t.Name, locale)
+		}
+		if t.Locales == nil {
+			t.Locales = map[string]*templateVariant{}
+		}
+		t.Locales[locale] = content
+	}
+
+	if len(catalog) > 0 && t.Messages == nil {
+		t.Messages = messageCatalog{}
+	}
+	for catalogLocale, messages := range catalog {
+		t.Messages[catalogLocale] = messages
+	}
+	return nil
+}
+
+// check parses the template and, when it has sample data, renders it in each
+// of its locales, so mistakes show up before it is used
+func (t *storedTemplate) check() error {
+	if err := t.parse(); err != nil {
+		return err
+	}
+	sample, ok := t.SampleData.(map[string]interface{})
+	if !ok {
+		if t.SampleData != nil {
+			_, _, _, err := t.render(t.SampleData)
+			return err
+		}
+		return nil
+	}
+
+	if _, _, _, err := t.render(sample); err != nil {
+		return err
+	}
+	for _, locale := range t.locales() {
+		localized := make(map[string]interface{}, len(sample)+1)
+		for key, value := range sample {
+			localized[key] = value
+		}
+		localized[localeFields[0]] = locale
+		if _, _, _, err := t.render(localized); err != nil {
+			return fmt.Errorf("locale %s: %w", locale, err)
+		}
+	}
+	return nil
+}
+
+// starterList describes the embedded starter templates, one per line
+func starterList() string {
+	var lines []string
+	for _, t := range templates.List() {
+		lines = append(lines, fmt.Sprintf("  %-14s %s", t.Name, t.Description))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func runTemplatesInit(ctx *simplecli.Context) error {
+	if len(ctx.Args) == 0 || len(ctx.Args) > 2 {
+		return codedError(CodeUsage, "starter template required, optionally followed by a name: %s", strings.Join(templates.Names(), ", "))
+	}
+	starter, err := templates.Get(ctx.Args[0])
+	if err != nil {
+		return codedError(CodeUsage, "%v", err)
+	}
+
+	t := &storedTemplate{
+		Name:       starter.Name,
+		Subject:    starter.Subject,
+		HTML:       starter.HTML,
+		Text:       starter.Text,
+		SampleData: starter.SampleData,
+		UpdatedAt:  time.Now().UTC(),
+	}
+	if len(ctx.Args) == 2 {
+		t.Name = ctx.Args[1]
+	}
+
+	_, formatter, store, err := openTemplates(ctx)
+	if err != nil {
+		return err
+	}
+	if err := store.put(t, ctx.GetBool("force")); err != nil {
+		return err
+	}
+	return formatter.PrintSuccess("Template %s created from the %s starter in %s", t.Name, starter.Name, store.dir)
+}
+
+func runTemplatesList(ctx *simplecli.Context) error {
+	_, formatter, store, err := openTemplates(ctx)
+	if err != nil {
+		return err
+	}
+	stored, err := store.list()
+	if err != nil {
+		return err
+	}
+
+	summaries := make([]output.Template, 0, len(stored))
+	for _, t := range stored {
+		summaries = append(summaries, t.summary())
+	}
+	return formatter.PrintTemplateList(summaries)
+}
+
+func runTemplatesShow(ctx *simplecli.Context) error {
+	if len(ctx.Args) != 1 {
+		return codedError(CodeUsage, "exactly one template name required")
+	}
+
+	_, formatter, store, err := openTemplates(ctx)
+	if err != nil {
+		return err
+	}
+	t, err := store.get(ctx.Args[0])
+	if err != nil {
+		return err
+	}
+	if !ctx.GetBool("render") {
+		return formatter.PrintTemplate(t.summary())
+	}
+
+	data := t.SampleData
+	if path := ctx.GetString("data"); path != "" {
+		if data, err = readTemplateData(path); err != nil {
+			return err
+		}
+	}
+	subject, html, text, err := t.render(data)
+	if err != nil {
+		return err
+	}
+	rendered := output.Template{Name: t.Name, Subject: subject, HTML: html, Text: text, UpdatedAt: t.UpdatedAt}
+	return formatter.PrintTemplate(rendered)
+}
+
+func runTemplatesRemove(ctx *simplecli.Context) error {
+	if len(ctx.Args) == 0 {
+		return codedError(CodeUsage, "template name required")
+	}
+
+	_, formatter, store, err := openTemplates(ctx)
+	if err != nil {
+		return err
+	}
+	for _, name := range ctx.Args {
+		if err := store.remove(name); err != nil {
+			return err
+		}
+	}
+	noun := "templates"
+	if len(ctx.Args) == 1 {
+		noun = "template"
+	}
+	return formatter.PrintSuccess("Removed %s %s", noun, strings.Join(ctx.Args, ", "))
+}
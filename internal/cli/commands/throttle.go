@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/groovy-sky/azemailsender"
+	"github.com/groovy-sky/azemailsender/internal/simpleconfig"
+	"github.com/groovy-sky/azemailsender/internal/simplecli"
+)
+
+// parseThrottlePolicy parses a --throttle policy [CATEGORY=]LIMIT/WINDOW[:OVERFLOW],
+// e.g. alerts=5/1h:digest, and returns its category and policy
+func parseThrottlePolicy(value string) (string, *azemailsender.ThrottlePolicy, error) {
+	invalid := func(reason string) error {
+		return codedError(CodeUsage, "invalid throttle policy %q: %s", value, reason)
+	}
+
+	spec := strings.ReplaceAll(value, " ", "")
+	category, spec, ok := strings.Cut(spec, "=")
+	if !ok {
+		category, spec = "", category
+	}
+
+	policy := &azemailsender.ThrottlePolicy{Overflow: azemailsender.ThrottleDelay}
+	if rate, overflow, ok := strings.Cut(spec, ":"); ok {
+		spec = rate
+		policy.Overflow = azemailsender.ThrottleOverflow(overflow)
+	}
+	valid := false
+	names := make([]string, len(azemailsender.ThrottleOverflows))
+	for i, overflow := range azemailsender.ThrottleOverflows {
+		names[i] = string(overflow)
+		valid = valid || overflow == policy.Overflow
+	}
+	if !valid {
+		return "", nil, invalid("the overflow must be one of " + strings.Join(names, ", "))
+	}
+
+	limit, window, ok := strings.Cut(spec, "/")
+	if !ok {
+		return "", nil, invalid("use [CATEGORY=]LIMIT/WINDOW[:OVERFLOW], e.g. alerts=5/1h:digest")
+	}
+	n, err := strconv.Atoi(limit)
+	if err != nil || n < 0 {
+		return "", nil, invalid("the limit must be a non-negative number, 0 for no limit")
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		return "", nil, invalid("the window must be a positive duration such as 1h")
+	}
+	policy.Limit, policy.Window = n, d
+	return category, policy, nil
+}
+
+// categoryOptions returns the client options of the categories of the config file
+func categoryOptions(config *simpleconfig.Config) (map[string]*azemailsender.CategoryOptions, error) {
+	if len(config.Categories) == 0 {
+		return nil, nil
+	}
+	categories := make(map[string]*azemailsender.CategoryOptions, len(config.Categories))
+	for name, category := range config.Categories {
+		if category == nil {
+			continue
+		}
+		options := &azemailsender.CategoryOptions{
+			From:                 category.From,
+			ReplyTo:              category.ReplyTo,
+			DisableTracking:      category.DisableTracking,
+			MaxRequestsPerSecond: category.RateLimit,
+		}
+		if category.Priority != "" {
+			priority, err := azemailsender.ParsePriority(category.Priority)
+			if err != nil {
+				return nil, codedError(CodeConfigInvalid, "category %s: %w", name, err)
+			}
+			options.Priority = priority
+		}
+		if category.Throttle != "" {
+			_, policy, err := parseThrottlePolicy(category.Throttle)
+			if err != nil {
+				return nil, codedError(CodeConfigInvalid, "category %s: %w", name, err)
+			}
+			options.Throttle = policy
+		}
+		categories[name] = options
+	}
+	return categories, nil
+}
+
+// throttleFlagPolicies returns the throttle policies of the --throttle flags by
+// category, or nil when there are none
+func throttleFlagPolicies(ctx *simplecli.Context) (map[string]*azemailsender.ThrottlePolicy, error) {
+	var policies map[string]*azemailsender.ThrottlePolicy
+	for _, value := range ctx.GetStringSlice("throttle") {
+		// The environment variable holds a comma-separated list
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			category, policy, err := parseThrottlePolicy(part)
+			if err != nil {
+				return nil, err
+			}
+			if policies == nil {
+				policies = map[string]*azemailsender.ThrottlePolicy{}
+			}
+			policies[category] = policy
+		}
+	}
+	return policies, nil
+}
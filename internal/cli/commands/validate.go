@@ -0,0 +1,182 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/groovy-sky/azemailsender"
+	"github.com/groovy-sky/azemailsender/internal/cli/output"
+	"github.com/groovy-sky/azemailsender/internal/simpleconfig"
+	"github.com/groovy-sky/azemailsender/internal/simplecli"
+)
+
+// validationChecks lists the checks reported by validate, in output order
+var validationChecks = []string{"sender", "recipients", "addresses", "subject", "content", "template", "headers", "attachments", "api-version"}
+
+// NewValidateCommand creates the validate command
+func NewValidateCommand() *simplecli.Command {
+	return &simplecli.Command{
+		Name:        "validate",
+		Description: "Check a message without sending it",
+		Usage:       "validate [flags]",
+		LongDesc: `Run all client-side checks on a message described by the same flags and
+files as 'send': addresses, content, template syntax, headers, attachment sizes
+and API version features. Nothing is sent. Exits with code 0 when the message
+is valid and 2 when it is not; use --json for machine-readable diagnostics.
+
+Examples:
+  # Check a message document
+  azemailsender-cli validate --message-file message.json
+
+  # Check flags and attachments in a CI pipeline
+  azemailsender-cli validate --json --from sender@example.com --to user@example.com --subject "Report" --html-file report.html --attach report.pdf`,
+		Run:         runValidate,
+		Flags:       joinFlags(authFlags(), networkFlags(), messageFlags()),
+		Constraints: joinConstraints(authConstraints(), messageConstraints()),
+	}
+}
+
+func runValidate(ctx *simplecli.Context) error {
+	configFile := ctx.GetString("config")
+	config, err := simpleconfig.LoadConfig(configFile, ctx.ProvidedFlags())
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	formatter, err := newFormatter(ctx)
+	if err != nil {
+		return err
+	}
+
+	input, err := readSendInput(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	// Validation is offline, so credentials are optional
+	client, err := newClient(ctx, config, formatter)
+	if err != nil {
+		client = azemailsender.NewClient("", "", nil)
+	}
+
+	builder, err := input.builder(client)
+	if err != nil {
+		return err
+	}
+
+	problems := map[string][]error{}
+	if err := builder.Validate(); err != nil {
+		for _, problem := range validationProblems(err) {
+			name := validationCheckName(problem)
+			problems[name] = append(problems[name], problem)
+		}
+	}
+
+	var checks []output.Diagnostic
+	for _, name := range validationChecks {
+		if name == "template" {
+			checks = append(checks, checkTemplateSyntax(input))
+			continue
+		}
+		if len(problems[name]) == 0 {
+			checks = append(checks, output.Diagnostic{Name: name, Status: output.DiagnosticPass})
+			continue
+		}
+		for _, problem := range problems[name] {
+			checks = append(checks, output.Diagnostic{
+				Name:   name,
+				Status: output.DiagnosticFail,
+				Detail: problem.Error(),
+				Hint:   validationHint(problem),
+			})
+		}
+	}
+
+	if err := formatter.PrintDiagnostics(output.KindValidationReport, checks); err != nil {
+		return err
+	}
+	for _, check := range checks {
+		if check.Status == output.DiagnosticFail {
+			return codedError(CodeValidationFailed, "message is not valid")
+		}
+	}
+	return nil
+}
+
+// validationProblems splits the joined error returned by MessageBuilder.Validate
+func validationProblems(err error) []error {
+	joined := errors.Unwrap(err)
+	if multi, ok := joined.(interface{ Unwrap() []error }); ok {
+		return multi.Unwrap()
+	}
+	return []error{err}
+}
+
+// validationCheckName maps a validation problem to the check that reports it
+func validationCheckName(problem error) string {
+	var addressErr *azemailsender.InvalidAddressError
+	var headerErr *azemailsender.InvalidHeaderError
+	var featureErr *azemailsender.UnsupportedFeatureError
+	switch {
+	case errors.Is(problem, azemailsender.ErrMissingSender):
+		return "sender"
+	case errors.As(problem, &addressErr):
+		if addressErr.Field == "sender" {
+			return "sender"
+		}
+		return "addresses"
+	case errors.Is(problem, azemailsender.ErrNoRecipients):
+		return "recipients"
+	case errors.Is(problem, azemailsender.ErrMissingSubject):
+		return "subject"
+	case errors.Is(problem, azemailsender.ErrMissingContent):
+		return "content"
+	case errors.As(problem, &headerErr):
+		return "headers"
+	case errors.As(problem, &featureErr):
+		return "api-version"
+	default:
+		return "attachments"
+	}
+}
+
+// validationHint suggests the flag that fixes a validation problem
+func validationHint(problem error) string {
+	var limitErr *azemailsender.AttachmentLimitError
+	switch {
+	case errors.Is(problem, azemailsender.ErrMissingSender):
+		return "Set --from or 'from' in the config file"
+	case errors.Is(problem, azemailsender.ErrNoRecipients):
+		return "Add --to, --cc or --bcc"
+	case errors.Is(problem, azemailsender.ErrMissingSubject):
+		return "Set --subject"
+	case errors.Is(problem, azemailsender.ErrMissingContent):
+		return "Provide --text, --html, --text-file or --html-file"
+	case errors.As(problem, &limitErr):
+		return "Send fewer or smaller files, or link to large files instead"
+	}
+	return ""
+}
+
+// checkTemplateSyntax reports content that still contains Go template actions
+func checkTemplateSyntax(input *sendInput) output.Diagnostic {
+	if !strings.Contains(input.text, "{{") && !strings.Contains(input.html, "{{") {
+		return output.Diagnostic{Name: "template", Status: output.DiagnosticPass}
+	}
+
+	if _, err := texttemplate.New("text").Parse(input.text); err != nil {
+		return output.Diagnostic{Name: "template", Status: output.DiagnosticFail, Detail: err.Error()}
+	}
+	if _, err := htmltemplate.New("html").Parse(input.html); err != nil {
+		return output.Diagnostic{Name: "template", Status: output.DiagnosticFail, Detail: err.Error()}
+	}
+	return output.Diagnostic{
+		Name:   "template",
+		Status: output.DiagnosticWarn,
+		Detail: "content contains unrendered template actions",
+		Hint:   "Render it first with 'template render'",
+	}
+}
@@ -21,11 +21,10 @@ func NewVersionCommand(version, commit, date string) *simplecli.Command {
 }
 
 func runVersionCommand(ctx *simplecli.Context, version, commit, date string) error {
-	debug := ctx.GetBool("debug")
-	quiet := ctx.GetBool("quiet")
-	jsonOutput := ctx.GetBool("json")
-
-	formatter := output.NewFormatter(jsonOutput, quiet, debug)
+	formatter, err := newFormatter(ctx)
+	if err != nil {
+		return err
+	}
 
 	versionInfo := map[string]string{
 		"version": version,
@@ -33,8 +32,8 @@ func runVersionCommand(ctx *simplecli.Context, version, commit, date string) err
 		"date":    date,
 	}
 
-	if jsonOutput {
-		return formatter.PrintConfig(versionInfo)
+	if formatter.JSON {
+		return formatter.PrintConfig(output.KindVersion, versionInfo)
 	}
 
 	fmt.Printf("azemailsender-cli version %s\n", version)
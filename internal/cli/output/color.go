@@ -0,0 +1,51 @@
+package output
+
+import (
+	"os"
+
+	"github.com/groovy-sky/azemailsender"
+)
+
+// ANSI colors used for status indicators
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+)
+
+// colorEnabled reports whether human output to f may be colored: f is a
+// terminal, NO_COLOR is unset (https://no-color.org) and TERM is not dumb
+func colorEnabled(f *os.File) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	return os.Getenv("TERM") != "dumb" && isTerminal(f)
+}
+
+// paint wraps text in color when the formatter colors its output
+func (f *Formatter) paint(color, text string) string {
+	if !f.Color || text == "" {
+		return text
+	}
+	return color + text + colorReset
+}
+
+// statusColor returns the color of an email status: green once delivered,
+// red when it failed and yellow while it is pending
+func statusColor(status string) string {
+	switch status {
+	case string(azemailsender.StatusSucceeded), string(azemailsender.StatusDelivered):
+		return colorGreen
+	case string(azemailsender.StatusFailed), string(azemailsender.StatusCanceled),
+		azemailsender.DeliveryStatusBounced, azemailsender.DeliveryStatusSuppressed,
+		azemailsender.DeliveryStatusFilteredSpam, azemailsender.DeliveryStatusQuarantined:
+		return colorRed
+	}
+	return colorYellow
+}
+
+// PaintStatus colors an email status by what it means
+func (f *Formatter) PaintStatus(status string) string {
+	return f.paint(statusColor(status), status)
+}
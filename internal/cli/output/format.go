@@ -1,7 +1,9 @@
 package output
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -15,6 +17,15 @@ type Formatter struct {
 	JSON  bool
 	Quiet bool
 	Debug bool
+
+	// Color adds ANSI colors to status indicators in human output
+	Color bool
+
+	// Log receives debug messages instead of stdout when --log-file is set
+	Log *LogFile
+
+	// renderer prints JSON documents through --output go-template or jsonpath
+	renderer renderer
 }
 
 // NewFormatter creates a new output formatter
@@ -23,33 +34,118 @@ func NewFormatter(jsonOutput, quiet, debug bool) *Formatter {
 		JSON:  jsonOutput,
 		Quiet: quiet,
 		Debug: debug,
+		Color: colorEnabled(os.Stdout),
 	}
 }
 
 // PrintSendResponse formats and prints send response
 func (f *Formatter) PrintSendResponse(response *azemailsender.SendResponse) error {
 	if f.JSON {
-		return f.printJSON(map[string]interface{}{
-			"id":        response.ID,
-			"status":    response.Status,
-			"timestamp": response.Timestamp.Format(time.RFC3339),
+		result := map[string]interface{}{
+			"id":              response.ID,
+			"status":          response.Status,
+			"timestamp":       response.Timestamp.Format(time.RFC3339),
+			"clientRequestId": response.ClientRequestID,
+			"transport":       response.Transport,
+		}
+		if len(response.FailedTransports) > 0 {
+			failed := make([]map[string]interface{}, 0, len(response.FailedTransports))
+			for _, attempt := range response.FailedTransports {
+				failed = append(failed, map[string]interface{}{
+					"transport":  attempt.Transport,
+					"error":      attempt.Err.Error(),
+					"durationMs": attempt.Duration.Milliseconds(),
+				})
+			}
+			result["failedTransports"] = failed
+		}
+		return f.printJSON(KindSendResult, result)
+	}
+
+	// Quiet mode prints only the ID, so scripts can capture it
+	if f.Quiet {
+		fmt.Println(response.ID)
+		return nil
+	}
+
+	fmt.Printf("Email sent successfully!\n")
+	fmt.Printf("Message ID: %s\n", response.ID)
+	if response.Status != "" {
+		fmt.Printf("Status: %s\n", f.PaintStatus(string(response.Status)))
+	}
+	if response.ClientRequestID != "" {
+		fmt.Printf("Client Request ID: %s\n", response.ClientRequestID)
+	}
+	if len(response.FailedTransports) > 0 {
+		fmt.Printf("Transport: %s\n", response.Transport)
+		for _, attempt := range response.FailedTransports {
+			fmt.Printf("  %s failed first: %v\n", attempt.Transport, attempt.Err)
+		}
+	}
+	return nil
+}
+
+// PrintIndividualResults prints the per-recipient results of an individual send
+func (f *Formatter) PrintIndividualResults(result *azemailsender.IndividualSendResult) error {
+	if f.JSON {
+		results := make([]map[string]interface{}, 0, len(result.Results))
+		for _, r := range result.Results {
+			entry := map[string]interface{}{"address": r.Address}
+			if r.Response != nil {
+				entry["id"] = r.Response.ID
+				entry["status"] = r.Response.Status
+			}
+			if r.Err != nil {
+				entry["error"] = r.Err.Error()
+			}
+			results = append(results, entry)
+		}
+		return f.printJSON(KindIndividualSendResult, map[string]interface{}{
+			"sent":    result.Sent,
+			"failed":  result.Failed,
+			"results": results,
 		})
 	}
 
-	if !f.Quiet {
-		fmt.Printf("Email sent successfully!\n")
-		fmt.Printf("Message ID: %s\n", response.ID)
-		if response.Status != "" {
-			fmt.Printf("Status: %s\n", response.Status)
+	// Quiet mode prints only the IDs of the messages that were sent
+	if f.Quiet {
+		for _, r := range result.Results {
+			if r.Err == nil {
+				fmt.Println(r.Response.ID)
+			}
 		}
+		return nil
 	}
+
+	for _, r := range result.Results {
+		if r.Err != nil {
+			fmt.Printf("%s %s: %v\n", f.paint(colorRed, "✗"), r.Address, r.Err)
+			continue
+		}
+		fmt.Printf("%s %s: %s\n", f.paint(colorGreen, "✓"), r.Address, r.Response.ID)
+	}
+	fmt.Printf("Sent %d, failed %d\n", result.Sent, result.Failed)
 	return nil
 }
 
+// BatchResult is one line of the send-batch result stream
+type BatchResult struct {
+	Line   int      `json:"line"`
+	To     []string `json:"to,omitempty"`
+	ID     string   `json:"id,omitempty"`
+	Status string   `json:"status,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// PrintBatchResult prints a batch result as a single NDJSON line
+func (f *Formatter) PrintBatchResult(result BatchResult) error {
+	return f.printJSONLine(KindBatchResult, result)
+}
+
 // PrintStatusResponse formats and prints status response
 func (f *Formatter) PrintStatusResponse(response *azemailsender.StatusResponse) error {
 	if f.JSON {
-		return f.printJSON(map[string]interface{}{
+		return f.printJSON(KindStatus, map[string]interface{}{
 			"id":        response.ID,
 			"status":    response.Status,
 			"timestamp": response.Timestamp.Format(time.RFC3339),
@@ -59,7 +155,7 @@ func (f *Formatter) PrintStatusResponse(response *azemailsender.StatusResponse)
 
 	if !f.Quiet {
 		fmt.Printf("Message ID: %s\n", response.ID)
-		fmt.Printf("Status: %s\n", response.Status)
+		fmt.Printf("Status: %s\n", f.PaintStatus(string(response.Status)))
 		fmt.Printf("Timestamp: %s\n", response.Timestamp.Format(time.RFC3339))
 		if response.Error != nil {
 			fmt.Printf("Error: %s\n", response.Error.Message)
@@ -68,17 +164,182 @@ func (f *Formatter) PrintStatusResponse(response *azemailsender.StatusResponse)
 	return nil
 }
 
-// PrintError formats and prints error messages
+// PrintStatusUpdate prints one update of a multi-message watch as a single line
+func (f *Formatter) PrintStatusUpdate(update azemailsender.MessageUpdate) error {
+	if f.JSON {
+		entry := map[string]interface{}{
+			"id":   update.ID,
+			"done": update.Done,
+		}
+		if update.Status != nil {
+			entry["status"] = update.Status.Status
+			entry["timestamp"] = update.Status.Timestamp.Format(time.RFC3339)
+		}
+		if update.Err != nil {
+			entry["error"] = update.Err.Error()
+		}
+		return f.printJSONLine(KindStatusUpdate, entry)
+	}
+
+	if f.Quiet {
+		return nil
+	}
+
+	switch {
+	case update.Err != nil && update.Status != nil:
+		fmt.Printf("%s: %s (%v)\n", update.ID, f.PaintStatus(string(update.Status.Status)), update.Err)
+	case update.Err != nil:
+		fmt.Printf("%s: %s: %v\n", update.ID, f.paint(colorRed, "error"), update.Err)
+	default:
+		fmt.Printf("%s: %s\n", update.ID, f.PaintStatus(string(update.Status.Status)))
+	}
+	return nil
+}
+
+// PrintStatusResult prints the status of one of several checked messages as a single line
+func (f *Formatter) PrintStatusResult(result azemailsender.StatusResult) error {
+	if f.JSON {
+		entry := map[string]interface{}{
+			"id": result.ID,
+		}
+		if result.Status != nil {
+			entry["status"] = result.Status.Status
+			entry["timestamp"] = result.Status.Timestamp.Format(time.RFC3339)
+			if result.Status.Error != nil {
+				entry["error"] = result.Status.Error
+			}
+		}
+		if result.Err != nil {
+			entry["error"] = result.Err.Error()
+		}
+		return f.printJSONLine(KindStatus, entry)
+	}
+
+	if f.Quiet {
+		return nil
+	}
+
+	if result.Err != nil {
+		fmt.Printf("%s: %s: %v\n", result.ID, f.paint(colorRed, "error"), result.Err)
+		return nil
+	}
+	fmt.Printf("%s: %s\n", result.ID, f.PaintStatus(string(result.Status.Status)))
+	return nil
+}
+
+// Diagnostic statuses
+const (
+	DiagnosticPass = "pass"
+	DiagnosticWarn = "warn"
+	DiagnosticFail = "fail"
+	DiagnosticSkip = "skip"
+)
+
+// Diagnostic is the result of a single doctor check
+type Diagnostic struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Hint   string `json:"hint,omitempty"`
+}
+
+// PrintDiagnostics prints the results of doctor or validate checks, as a
+// document of the given kind in JSON mode
+func (f *Formatter) PrintDiagnostics(kind string, checks []Diagnostic) error {
+	if f.JSON {
+		return f.printJSON(kind, map[string]interface{}{
+			"checks": checks,
+		})
+	}
+
+	symbols := map[string]string{
+		DiagnosticPass: f.paint(colorGreen, "✓"),
+		DiagnosticWarn: f.paint(colorYellow, "!"),
+		DiagnosticFail: f.paint(colorRed, "✗"),
+		DiagnosticSkip: "-",
+	}
+
+	for _, check := range checks {
+		// Quiet mode only reports problems
+		if f.Quiet && check.Status != DiagnosticFail {
+			continue
+		}
+		fmt.Printf("%s %-16s %s\n", symbols[check.Status], check.Name, check.Detail)
+		if check.Hint != "" && check.Status != DiagnosticPass {
+			fmt.Printf("  %-16s → %s\n", "", check.Hint)
+		}
+	}
+	return nil
+}
+
+// ProfileSummary is one line of the profile list
+type ProfileSummary struct {
+	Name     string `json:"name"`
+	Active   bool   `json:"active"`
+	Endpoint string `json:"endpoint,omitempty"`
+	From     string `json:"from,omitempty"`
+}
+
+// PrintProfiles prints configured profiles, marking the active one
+func (f *Formatter) PrintProfiles(profiles []ProfileSummary) error {
+	if f.JSON {
+		if profiles == nil {
+			profiles = []ProfileSummary{}
+		}
+		return f.printJSON(KindProfileList, map[string]interface{}{
+			"profiles": profiles,
+		})
+	}
+
+	if len(profiles) == 0 {
+		if !f.Quiet {
+			fmt.Println("No profiles configured")
+		}
+		return nil
+	}
+
+	for _, profile := range profiles {
+		marker := " "
+		if profile.Active {
+			marker = "*"
+		}
+		fmt.Printf("%s %-16s %-48s %s\n", marker, profile.Name, profile.Endpoint, profile.From)
+	}
+	return nil
+}
+
+// codedError is an error with a machine-readable code and category
+type codedError interface {
+	ErrorCode() string
+	ErrorCategory() string
+}
+
+// PrintError prints an error to stderr, as an Error document in JSON mode so
+// stdout only ever holds results. Errors with a code report it in the "code"
+// and "category" fields
 func (f *Formatter) PrintError(err error) {
 	if f.JSON {
-		f.printJSON(map[string]interface{}{
+		document := map[string]interface{}{
 			"error":   err.Error(),
 			"success": false,
-		})
-		return
+		}
+		var coded codedError
+		if errors.As(err, &coded) {
+			document["code"] = coded.ErrorCode()
+			document["category"] = coded.ErrorCategory()
+		}
+		jsonBytes, marshalErr := envelope(KindError, document)
+		if marshalErr == nil {
+			fmt.Fprintln(os.Stderr, string(jsonBytes))
+			return
+		}
 	}
 
-	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	prefix := "Error:"
+	if colorEnabled(os.Stderr) {
+		prefix = f.paint(colorRed, prefix)
+	}
+	fmt.Fprintf(os.Stderr, "%s %v\n", prefix, err)
 }
 
 // PrintInfo prints informational messages (only if not quiet)
@@ -88,8 +349,8 @@ func (f *Formatter) PrintInfo(message string, args ...interface{}) {
 	}
 
 	if f.JSON {
-		f.printJSON(map[string]interface{}{
-			"info": fmt.Sprintf(message, args...),
+		f.printJSON(KindInfo, map[string]interface{}{
+			"message": fmt.Sprintf(message, args...),
 		})
 		return
 	}
@@ -103,9 +364,14 @@ func (f *Formatter) PrintDebug(message string, args ...interface{}) {
 		return
 	}
 
+	if f.Log != nil {
+		f.Log.Printf("[DEBUG] "+message, args...)
+		return
+	}
+
 	if f.JSON {
-		f.printJSON(map[string]interface{}{
-			"debug": fmt.Sprintf(message, args...),
+		f.printJSON(KindDebug, map[string]interface{}{
+			"message": fmt.Sprintf(message, args...),
 		})
 		return
 	}
@@ -116,22 +382,23 @@ func (f *Formatter) PrintDebug(message string, args ...interface{}) {
 // PrintSuccess prints success messages
 func (f *Formatter) PrintSuccess(message string, args ...interface{}) error {
 	if f.JSON {
-		return f.printJSON(map[string]interface{}{
+		return f.printJSON(KindSuccess, map[string]interface{}{
 			"success": true,
 			"message": fmt.Sprintf(message, args...),
 		})
 	}
 
 	if !f.Quiet {
-		fmt.Printf("✓ "+message+"\n", args...)
+		fmt.Printf(f.paint(colorGreen, "✓")+" "+message+"\n", args...)
 	}
 	return nil
 }
 
-// PrintConfig prints configuration in a formatted way
-func (f *Formatter) PrintConfig(config interface{}) error {
+// PrintConfig prints configuration in a formatted way, as a document of the
+// given kind in JSON mode
+func (f *Formatter) PrintConfig(kind string, config interface{}) error {
 	if f.JSON {
-		return f.printJSON(config)
+		return f.printJSON(kind, config)
 	}
 
 	// Pretty print configuration
@@ -144,17 +411,343 @@ func (f *Formatter) PrintConfig(config interface{}) error {
 	return nil
 }
 
-// printJSON prints data as JSON
-func (f *Formatter) printJSON(data interface{}) error {
-	jsonBytes, err := json.MarshalIndent(data, "", "  ")
+// ConfigSource is one effective setting and where its value came from
+type ConfigSource struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// PrintConfigSources prints effective settings annotated with their origin
+func (f *Formatter) PrintConfigSources(settings []ConfigSource) error {
+	if f.JSON {
+		return f.printJSON(KindConfigSources, map[string]interface{}{
+			"settings": settings,
+		})
+	}
+
+	for _, setting := range settings {
+		fmt.Printf("%-20s %-48s %s\n", setting.Key, setting.Value, setting.Source)
+	}
+	return nil
+}
+
+// PrunedStore is what history prune removed from one local store
+type PrunedStore struct {
+	Store   string `json:"store"`
+	Path    string `json:"path"`
+	Removed int    `json:"removed"`
+}
+
+// pruneUnits names what each store holds, for human output
+var pruneUnits = map[string]string{
+	"captures":  "captured exchanges",
+	"audit-log": "audit entries",
+	"log-file":  "rotated log files",
+}
+
+// PrintPruneReport prints how much history prune removed from each store
+func (f *Formatter) PrintPruneReport(cutoff time.Time, stores []PrunedStore) error {
+	if f.JSON {
+		return f.printJSON(KindPruneReport, map[string]interface{}{
+			"cutoff": cutoff.Format(time.RFC3339),
+			"stores": stores,
+		})
+	}
+
+	if f.Quiet {
+		return nil
+	}
+	for _, store := range stores {
+		fmt.Printf("Removed %d %s from %s\n", store.Removed, pruneUnits[store.Store], store.Path)
+	}
+	return nil
+}
+
+// queueEntry is the JSON form of an outbox message, with the stored message
+// included only when full is set
+func queueEntry(item *azemailsender.OutboxItem, full bool) map[string]interface{} {
+	entry := map[string]interface{}{
+		"id":         item.ID,
+		"state":      item.State,
+		"subject":    item.Subject,
+		"recipients": item.Recipients,
+		"attempts":   item.Attempts,
+		"enqueuedAt": item.EnqueuedAt.Format(time.RFC3339),
+		"updatedAt":  item.UpdatedAt.Format(time.RFC3339),
+	}
+	if item.MessageID != "" {
+		entry["messageId"] = item.MessageID
+	}
+	if item.NextAttemptAt != nil {
+		entry["nextAttemptAt"] = item.NextAttemptAt.Format(time.RFC3339)
+	}
+	if item.LastError != "" {
+		entry["lastError"] = item.LastError
+	}
+	if item.Category != "" {
+		entry["category"] = item.Category
+	}
+	if item.DigestKey != "" {
+		entry["digestKey"] = item.DigestKey
+		entry["digestRecipient"] = item.DigestRecipient
+	}
+	if full {
+		entry["message"] = item.Message
+	}
+	return entry
+}
+
+// PrintQueueMessage prints one outbox message. With full set the stored
+// message is printed as well, as queue inspect does
+func (f *Formatter) PrintQueueMessage(item *azemailsender.OutboxItem, full bool) error {
+	if f.JSON {
+		return f.printJSON(KindQueueMessage, queueEntry(item, full))
+	}
+
+	if f.Quiet {
+		fmt.Println(item.ID)
+		return nil
+	}
+	if !full {
+		if item.NextAttemptAt != nil {
+			fmt.Printf("Queued message %s for %s\n", item.ID, item.NextAttemptAt.Local().Format(time.RFC3339))
+			return nil
+		}
+		fmt.Printf("Queued message %s\n", item.ID)
+		return nil
+	}
+
+	fmt.Printf("ID: %s\n", item.ID)
+	fmt.Printf("State: %s\n", item.State)
+	fmt.Printf("Subject: %s\n", item.Subject)
+	fmt.Printf("Recipients: %d\n", item.Recipients)
+	fmt.Printf("Attempts: %d\n", item.Attempts)
+	fmt.Printf("Enqueued: %s\n", item.EnqueuedAt.Format(time.RFC3339))
+	fmt.Printf("Updated: %s\n", item.UpdatedAt.Format(time.RFC3339))
+	if item.NextAttemptAt != nil {
+		fmt.Printf("Next attempt: %s\n", item.NextAttemptAt.Format(time.RFC3339))
+	}
+	if item.MessageID != "" {
+		fmt.Printf("Message ID: %s\n", item.MessageID)
+	}
+	if item.LastError != "" {
+		fmt.Printf("Last error: %s\n", item.LastError)
+	}
+	var message bytes.Buffer
+	if err := json.Indent(&message, item.Message, "", "  "); err != nil {
+		return fmt.Errorf("invalid stored message: %w", err)
+	}
+	fmt.Printf("Message:\n%s\n", message.String())
+	return nil
+}
+
+// PrintQueueList prints outbox messages, one line each
+func (f *Formatter) PrintQueueList(items []*azemailsender.OutboxItem) error {
+	if f.JSON {
+		entries := []map[string]interface{}{}
+		for _, item := range items {
+			entries = append(entries, queueEntry(item, false))
+		}
+		return f.printJSON(KindQueueList, map[string]interface{}{
+			"messages": entries,
+		})
+	}
+
+	if len(items) == 0 {
+		if !f.Quiet {
+			fmt.Println("Queue is empty")
+		}
+		return nil
+	}
+
+	for _, item := range items {
+		if f.Quiet {
+			fmt.Println(item.ID)
+			continue
+		}
+		fmt.Printf("%-36s  %-11s  %2d  %s  %s\n", item.ID, item.State, item.Attempts, item.EnqueuedAt.Local().Format("2006-01-02 15:04"), item.Subject)
+	}
+	return nil
+}
+
+// queueActions names what each queue action did, for human output
+var queueActions = map[string]string{
+	"retry":       "Retried",
+	"dead-letter": "Dead-lettered",
+	"purge":       "Purged",
+}
+
+// PrintQueueUpdate reports the outbox messages changed by a queue action,
+// such as "Retried 2 messages"
+func (f *Formatter) PrintQueueUpdate(action string, ids []string) error {
+	if f.JSON {
+		if ids == nil {
+			ids = []string{}
+		}
+		return f.printJSON(KindQueueUpdate, map[string]interface{}{
+			"action": action,
+			"ids":    ids,
+		})
+	}
+
+	if f.Quiet {
+		return nil
+	}
+	noun := "messages"
+	if len(ids) == 1 {
+		noun = "message"
+	}
+	fmt.Printf("%s %d %s\n", queueActions[action], len(ids), noun)
+	for _, id := range ids {
+		f.PrintDebug("%s %s", queueActions[action], id)
+	}
+	return nil
+}
+
+// ConfigProblem is an issue found in a config file
+type ConfigProblem struct {
+	Line    int    `json:"line"`
+	Key     string `json:"key,omitempty"`
+	Message string `json:"message"`
+}
+
+// PrintConfigProblems prints the result of checking a config file, one
+// "file:line: message" per problem
+func (f *Formatter) PrintConfigProblems(path string, problems []ConfigProblem) error {
+	if f.JSON {
+		if problems == nil {
+			problems = []ConfigProblem{}
+		}
+		return f.printJSON(KindConfigValidation, map[string]interface{}{
+			"file":     path,
+			"valid":    len(problems) == 0,
+			"problems": problems,
+		})
+	}
+
+	if len(problems) == 0 {
+		if !f.Quiet {
+			fmt.Printf("✓ %s is valid\n", path)
+		}
+		return nil
+	}
+	for _, problem := range problems {
+		fmt.Printf("%s:%d: %s\n", path, problem.Line, problem.Message)
+	}
+	return nil
+}
+
+// printJSON prints data as an indented JSON document of the given kind
+func (f *Formatter) printJSON(kind string, data interface{}) error {
+	jsonBytes, err := envelope(kind, data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	if f.renderer != nil {
+		return f.renderJSON(kind, jsonBytes)
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, jsonBytes, "", "  "); err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	fmt.Println(indented.String())
+	return nil
+}
+
+// printJSONLine prints data as a single line JSON document of the given kind,
+// for streamed output
+func (f *Formatter) printJSONLine(kind string, data interface{}) error {
+	jsonBytes, err := envelope(kind, data)
 	if err != nil {
 		return fmt.Errorf("failed to marshal JSON: %w", err)
 	}
+	if f.renderer != nil {
+		return f.renderJSON(kind, jsonBytes)
+	}
 
 	fmt.Println(string(jsonBytes))
 	return nil
 }
 
+// Template is a named template, as stored or rendered
+type Template struct {
+	Name       string      `json:"name"`
+	Subject    string      `json:"subject"`
+	HTML       string      `json:"html,omitempty"`
+	Text       string      `json:"text,omitempty"`
+	SampleData interface{} `json:"sampleData,omitempty"`
+	UpdatedAt  time.Time   `json:"updatedAt"`
+	// Locales lists the locales the template has content or messages for
+	Locales []string `json:"locales,omitempty"`
+}
+
+// PrintTemplate prints a named template with its subject and content
+func (f *Formatter) PrintTemplate(t Template) error {
+	if f.JSON {
+		return f.printJSON(KindTemplate, t)
+	}
+
+	fmt.Printf("Name:    %s\n", t.Name)
+	fmt.Printf("Subject: %s\n", t.Subject)
+	if !f.Quiet {
+		fmt.Printf("Updated: %s\n", t.UpdatedAt.Local().Format("2006-01-02 15:04"))
+	}
+	if len(t.Locales) > 0 {
+		fmt.Printf("Locales: %s\n", strings.Join(t.Locales, ", "))
+	}
+	if t.HTML != "" {
+		fmt.Printf("\nHTML:\n%s\n", strings.TrimRight(t.HTML, "\n"))
+	}
+	if t.Text != "" {
+		fmt.Printf("\nText:\n%s\n", strings.TrimRight(t.Text, "\n"))
+	}
+	if t.SampleData != nil {
+		sample, err := json.MarshalIndent(t.SampleData, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal sample data: %w", err)
+		}
+		fmt.Printf("\nSample data:\n%s\n", sample)
+	}
+	return nil
+}
+
+// PrintTemplateList prints named templates, one line each
+func (f *Formatter) PrintTemplateList(templates []Template) error {
+	if f.JSON {
+		if templates == nil {
+			templates = []Template{}
+		}
+		return f.printJSON(KindTemplateList, map[string]interface{}{
+			"templates": templates,
+		})
+	}
+
+	if len(templates) == 0 {
+		if !f.Quiet {
+			fmt.Println("No templates")
+		}
+		return nil
+	}
+
+	for _, t := range templates {
+		if f.Quiet {
+			fmt.Println(t.Name)
+			continue
+		}
+		var formats []string
+		if t.HTML != "" {
+			formats = append(formats, "html")
+		}
+		if t.Text != "" {
+			formats = append(formats, "text")
+		}
+		fmt.Printf("%-20s  %-9s  %s  %s\n", t.Name, strings.Join(formats, "+"), t.UpdatedAt.Local().Format("2006-01-02 15:04"), t.Subject)
+	}
+	return nil
+}
+
 // FormatRecipients formats recipient list for display
 func FormatRecipients(recipients []string) string {
 	if len(recipients) == 0 {
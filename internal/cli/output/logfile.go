@@ -0,0 +1,90 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Log files are rotated once they would grow beyond LogFileMaxSize bytes,
+// keeping LogFileBackups rotated files next to the current one
+const (
+	LogFileMaxSize = 10 << 20
+	LogFileBackups = 5
+)
+
+// LogFile appends timestamped lines to a file and rotates it by size. It
+// implements azemailsender.Logger and io.Writer
+type LogFile struct {
+	mu   sync.Mutex
+	path string
+}
+
+// OpenLogFile checks that path can be written and returns a log writing to it
+func OpenLogFile(path string) (*LogFile, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	file.Close()
+	return &LogFile{path: path}, nil
+}
+
+// Printf appends a line to the log. Write errors are dropped so logging
+// never fails a command
+func (l *LogFile) Printf(format string, args ...interface{}) {
+	line := time.Now().Format(time.RFC3339) + " " + strings.TrimSuffix(fmt.Sprintf(format, args...), "\n") + "\n"
+	l.Write([]byte(line))
+}
+
+// Write appends p as is, for loggers that timestamp their own lines
+func (l *LogFile) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if info, err := os.Stat(l.path); err == nil && info.Size()+int64(len(p)) > LogFileMaxSize {
+		l.rotate()
+	}
+
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	return file.Write(p)
+}
+
+// PruneLogFiles deletes the rotated files of the log at path that were last
+// written before cutoff and returns how many were deleted. Higher numbers are
+// older, so everything from the first expired file on goes
+func PruneLogFiles(path string, cutoff time.Time) (int, error) {
+	removed := 0
+	expired := false
+	for i := 1; i <= LogFileBackups; i++ {
+		backup := fmt.Sprintf("%s.%d", path, i)
+		info, err := os.Stat(backup)
+		if err != nil {
+			continue
+		}
+		if expired = expired || info.ModTime().Before(cutoff); !expired {
+			continue
+		}
+		if err := os.Remove(backup); err != nil {
+			return removed, fmt.Errorf("failed to remove log file: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// rotate shifts path.N to path.N+1, dropping the oldest, and moves the
+// current file to path.1
+func (l *LogFile) rotate() {
+	os.Remove(fmt.Sprintf("%s.%d", l.path, LogFileBackups))
+	for i := LogFileBackups; i > 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", l.path, i-1), fmt.Sprintf("%s.%d", l.path, i))
+	}
+	os.Rename(l.path, l.path+".1")
+}
@@ -0,0 +1,138 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// progressBarWidth is the number of characters of the progress bar
+const progressBarWidth = 30
+
+// Error classes counted by Progress
+const (
+	ErrorClassValidation = "validation"
+	ErrorClassSuppressed = "suppressed"
+	ErrorClassDuplicate  = "duplicate"
+	ErrorClassAuth       = "auth"
+	ErrorClassThrottled  = "throttled"
+	ErrorClassClient     = "client"
+	ErrorClassServer     = "server"
+	ErrorClassNetwork    = "network"
+	ErrorClassCanceled   = "canceled"
+)
+
+// retrySuggestions tells users what to do about each error class
+var retrySuggestions = map[string]string{
+	ErrorClassValidation: "fix the reported input lines and resend them",
+	ErrorClassSuppressed: "remove suppressed recipients or use --drop-suppressed",
+	ErrorClassDuplicate:  "identical messages were sent within --dedupe-window; nothing to resend",
+	ErrorClassAuth:       "check the credentials with 'azemailsender-cli doctor'",
+	ErrorClassThrottled:  "lower --max-concurrency and resend the failed lines",
+	ErrorClassClient:     "inspect the error messages; resending unchanged will fail again",
+	ErrorClassServer:     "transient service error, resend the failed lines later",
+	ErrorClassNetwork:    "check connectivity or proxy settings, then resend the failed lines",
+	ErrorClassCanceled:   "the run was interrupted; resend the lines without results",
+}
+
+// Progress renders a progress bar and a final summary for bulk operations on stderr
+type Progress struct {
+	mu       sync.Mutex
+	out      io.Writer
+	enabled  bool
+	total    int
+	done     int
+	failed   int
+	classes  map[string]int
+	start    time.Time
+	rendered bool
+}
+
+// NewProgress creates a progress display. It stays silent under --quiet or --json,
+// and the bar is only drawn when stderr is a terminal
+func (f *Formatter) NewProgress(jsonOutput bool, total int) *Progress {
+	return &Progress{
+		out:     os.Stderr,
+		enabled: !f.Quiet && !jsonOutput,
+		total:   total,
+		classes: make(map[string]int),
+		start:   time.Now(),
+	}
+}
+
+// Add records one finished item; errorClass is empty on success
+func (p *Progress) Add(errorClass string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.done++
+	if errorClass != "" {
+		p.failed++
+		p.classes[errorClass]++
+	}
+	p.render()
+}
+
+// render redraws the progress line
+func (p *Progress) render() {
+	if !p.enabled || !isTerminal(os.Stderr) {
+		return
+	}
+
+	line := fmt.Sprintf("%d sent, %d failed", p.done-p.failed, p.failed)
+	if p.total > 0 {
+		filled := p.done * progressBarWidth / p.total
+		if filled > progressBarWidth {
+			filled = progressBarWidth
+		}
+		bar := strings.Repeat("█", filled) + strings.Repeat("░", progressBarWidth-filled)
+		line = fmt.Sprintf("%s %d/%d (%s)", bar, p.done, p.total, line)
+	}
+	fmt.Fprintf(p.out, "\r\033[K%s", line)
+	p.rendered = true
+}
+
+// Finish clears the progress line and prints the summary table
+func (p *Progress) Finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.enabled {
+		return
+	}
+	if p.rendered {
+		fmt.Fprint(p.out, "\r\033[K")
+	}
+
+	fmt.Fprintf(p.out, "Processed %d messages in %v: %d sent, %d failed\n",
+		p.done, time.Since(p.start).Round(time.Millisecond), p.done-p.failed, p.failed)
+	if p.failed == 0 {
+		return
+	}
+
+	classes := make([]string, 0, len(p.classes))
+	for class := range p.classes {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		if p.classes[classes[i]] != p.classes[classes[j]] {
+			return p.classes[classes[i]] > p.classes[classes[j]]
+		}
+		return classes[i] < classes[j]
+	})
+
+	fmt.Fprintf(p.out, "\n%-12s %6s  %s\n", "ERROR CLASS", "COUNT", "SUGGESTION")
+	for _, class := range classes {
+		fmt.Fprintf(p.out, "%-12s %6d  %s\n", class, p.classes[class], retrySuggestions[class])
+	}
+}
+
+// isTerminal reports whether f is a character device
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
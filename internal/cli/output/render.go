@@ -0,0 +1,492 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// OutputFormats lists the values --output accepts
+var OutputFormats = []string{"text", "json", "table", "yaml", "go-template=TEMPLATE", "go-template-file=FILE", "jsonpath=EXPRESSION"}
+
+// ErrInvalidOutput marks --output values, and templates that don't fit the
+// document they are applied to, so they are reported as usage errors
+var ErrInvalidOutput = errors.New("invalid --output")
+
+// renderer writes a JSON document in a user-chosen form
+type renderer interface {
+	render(w io.Writer, document []byte) error
+}
+
+// SetOutput applies an --output value. "json" selects JSON output; table and
+// yaml print each JSON document in that form, and the go-template and
+// jsonpath forms print the fields they select instead of the whole document
+func (f *Formatter) SetOutput(spec string) error {
+	name, arg, _ := strings.Cut(spec, "=")
+	switch name {
+	case "", "text":
+		return nil
+	case "json":
+		f.JSON = true
+		return nil
+	case "table":
+		f.JSON = true
+		f.renderer = &tableRenderer{}
+		return nil
+	case "yaml":
+		f.JSON = true
+		f.renderer = &yamlRenderer{}
+		return nil
+	case "go-template", "go-template-file":
+		if name == "go-template-file" {
+			data, err := os.ReadFile(arg)
+			if err != nil {
+				return fmt.Errorf("failed to read --output template: %w", err)
+			}
+			arg = string(data)
+		}
+		// A field the document doesn't have is a mistake, not an empty value
+		tmpl, err := template.New("output").Option("missingkey=error").Parse(arg)
+		if err != nil {
+			return fmt.Errorf("%w template: %w", ErrInvalidOutput, err)
+		}
+		f.JSON = true
+		f.renderer = goTemplate{tmpl: tmpl, fields: templateFields(tmpl)}
+		return nil
+	case "jsonpath":
+		nodes, err := parseJSONPath(arg)
+		if err != nil {
+			return fmt.Errorf("%w jsonpath: %w", ErrInvalidOutput, err)
+		}
+		f.JSON = true
+		f.renderer = jsonPath{nodes}
+		return nil
+	}
+	return fmt.Errorf("%w %q: use %s", ErrInvalidOutput, spec, strings.Join(OutputFormats, ", "))
+}
+
+// StructuredOutput reports whether an --output value asks for JSON documents,
+// whole or through a template
+func StructuredOutput(spec string) bool {
+	name, _, _ := strings.Cut(spec, "=")
+	switch name {
+	case "json", "table", "yaml", "go-template", "go-template-file", "jsonpath":
+		return true
+	}
+	return false
+}
+
+// renderJSON prints a JSON document through the chosen renderer. Info and
+// debug messages are not results, so they go to stderr as they are
+func (f *Formatter) renderJSON(kind string, jsonBytes []byte) error {
+	if kind == KindInfo || kind == KindDebug {
+		fmt.Fprintln(os.Stderr, string(jsonBytes))
+		return nil
+	}
+
+	var out bytes.Buffer
+	if err := f.renderer.render(&out, jsonBytes); err != nil {
+		return err
+	}
+	if out.Len() > 0 && !bytes.HasSuffix(out.Bytes(), []byte("\n")) {
+		out.WriteByte('\n')
+	}
+	_, err := os.Stdout.Write(out.Bytes())
+	return err
+}
+
+// goTemplate renders documents with a text/template. Fields are the JSON keys
+// of the document, such as {{.id}}, matched regardless of case, so {{.ID}}
+// works too
+type goTemplate struct {
+	tmpl *template.Template
+	// fields are the field names the template uses
+	fields map[string]bool
+}
+
+func (t goTemplate) render(w io.Writer, data []byte) error {
+	document, err := decodeJSON(data)
+	if err != nil {
+		return err
+	}
+	aliasFields(document, t.fields)
+	if err := t.tmpl.Execute(w, document); err != nil {
+		return fmt.Errorf("%w template: %w (fields are the JSON keys printed by --json, such as {{.id}})", ErrInvalidOutput, err)
+	}
+	return nil
+}
+
+// templateFields returns the field names used by the templates of tmpl, such
+// as ID and Status in {{.ID}} and {{$.Status}}
+func templateFields(tmpl *template.Template) map[string]bool {
+	fields := map[string]bool{}
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.FieldNode:
+			for _, name := range n.Ident {
+				fields[name] = true
+			}
+		case *parse.VariableNode:
+			for _, name := range n.Ident[1:] {
+				fields[name] = true
+			}
+		case *parse.ChainNode:
+			walk(n.Node)
+			for _, name := range n.Field {
+				fields[name] = true
+			}
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		}
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			walk(t.Tree.Root)
+		}
+	}
+	return fields
+}
+
+// aliasFields adds the fields a template uses to the objects of document
+// under the name the template spells them with, when the object has a key
+// equal to it but for case
+func aliasFields(document interface{}, fields map[string]bool) {
+	switch v := document.(type) {
+	case map[string]interface{}:
+		for name := range fields {
+			if _, ok := v[name]; ok {
+				continue
+			}
+			if key, ok := foldedKey(v, name); ok {
+				v[name] = v[key]
+			}
+		}
+		for _, child := range v {
+			aliasFields(child, fields)
+		}
+	case []interface{}:
+		for _, item := range v {
+			aliasFields(item, fields)
+		}
+	}
+}
+
+// foldedKey returns the key of m equal to name under case folding, picking
+// the first in order when there are several
+func foldedKey(m map[string]interface{}, name string) (string, bool) {
+	for _, key := range sortedKeys(m) {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// jsonPath renders documents with a kubectl-style JSONPath template such as
+// '{.id}' or '{range .checks[*]}{.name}={.status}{"\n"}{end}'
+type jsonPath struct {
+	nodes []jsonPathNode
+}
+
+// jsonPathNode is literal text, a path whose values are printed, or a range
+// over the values of a path
+type jsonPathNode struct {
+	text  string
+	path  []jsonPathStep
+	isRef bool
+	body  []jsonPathNode // set for range
+}
+
+// jsonPathStep selects a field, an index or, with wildcard, all children
+type jsonPathStep struct {
+	field    string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+func (p jsonPath) render(w io.Writer, data []byte) error {
+	document, err := decodeJSON(data)
+	if err != nil {
+		return err
+	}
+	return renderJSONPath(w, p.nodes, document)
+}
+
+// decodeJSON decodes a JSON document into maps, keeping numbers as written
+func decodeJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var document interface{}
+	if err := dec.Decode(&document); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	return document, nil
+}
+
+func renderJSONPath(w io.Writer, nodes []jsonPathNode, current interface{}) error {
+	for _, node := range nodes {
+		switch {
+		case node.body != nil:
+			for _, item := range selectJSONPath(node.path, current) {
+				if err := renderJSONPath(w, node.body, item); err != nil {
+					return err
+				}
+			}
+		case node.isRef:
+			var texts []string
+			for _, value := range selectJSONPath(node.path, current) {
+				texts = append(texts, jsonPathText(value))
+			}
+			io.WriteString(w, strings.Join(texts, " "))
+		default:
+			io.WriteString(w, node.text)
+		}
+	}
+	return nil
+}
+
+// selectJSONPath returns the values path selects from current. Missing fields
+// and indexes select nothing
+func selectJSONPath(path []jsonPathStep, current interface{}) []interface{} {
+	values := []interface{}{current}
+	for _, step := range path {
+		var next []interface{}
+		for _, value := range values {
+			switch v := value.(type) {
+			case map[string]interface{}:
+				if step.wildcard {
+					for _, key := range sortedKeys(v) {
+						next = append(next, v[key])
+					}
+				} else if !step.isIndex {
+					// Fields match regardless of case, like go-template ones
+					key, ok := step.field, false
+					if _, ok = v[key]; !ok {
+						key, ok = foldedKey(v, key)
+					}
+					if ok {
+						next = append(next, v[key])
+					}
+				}
+			case []interface{}:
+				switch {
+				case step.wildcard:
+					next = append(next, v...)
+				case step.isIndex:
+					i := step.index
+					if i < 0 {
+						i += len(v)
+					}
+					if i >= 0 && i < len(v) {
+						next = append(next, v[i])
+					}
+				}
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+// jsonPathText formats a selected value: strings as they are, objects and
+// lists as compact JSON
+func jsonPathText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// parseJSONPath parses a JSONPath template. An expression without braces,
+// such as '.id', is taken as a single path
+func parseJSONPath(text string) ([]jsonPathNode, error) {
+	if !strings.Contains(text, "{") {
+		text = "{" + text + "}"
+	}
+
+	root := &[]jsonPathNode{}
+	stack := []*[]jsonPathNode{root}
+	for text != "" {
+		nodes := stack[len(stack)-1]
+		start := strings.IndexByte(text, '{')
+		if start < 0 {
+			*nodes = append(*nodes, jsonPathNode{text: text})
+			break
+		}
+		if start > 0 {
+			*nodes = append(*nodes, jsonPathNode{text: text[:start]})
+		}
+		end := closingBrace(text, start)
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed { in %q", text[start:])
+		}
+		expr := strings.TrimSpace(text[start+1 : end])
+		text = text[end+1:]
+
+		switch {
+		case expr == "end":
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("{end} without {range}")
+			}
+			stack = stack[:len(stack)-1]
+		case strings.HasPrefix(expr, "range "):
+			path, err := parseJSONPathSteps(strings.TrimSpace(strings.TrimPrefix(expr, "range ")))
+			if err != nil {
+				return nil, err
+			}
+			*nodes = append(*nodes, jsonPathNode{path: path, body: []jsonPathNode{}})
+			stack = append(stack, &(*nodes)[len(*nodes)-1].body)
+		case strings.HasPrefix(expr, `"`):
+			literal, err := strconv.Unquote(expr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s", expr)
+			}
+			*nodes = append(*nodes, jsonPathNode{text: literal})
+		default:
+			path, err := parseJSONPathSteps(expr)
+			if err != nil {
+				return nil, err
+			}
+			*nodes = append(*nodes, jsonPathNode{path: path, isRef: true})
+		}
+	}
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("{range} without {end}")
+	}
+	return *root, nil
+}
+
+// closingBrace returns the index of the } closing the { at start, skipping
+// quoted strings, or -1
+func closingBrace(text string, start int) int {
+	inQuote := byte(0)
+	for i := start + 1; i < len(text); i++ {
+		switch c := text[i]; {
+		case inQuote != 0 && c == '\\':
+			i++
+		case inQuote != 0 && c == inQuote:
+			inQuote = 0
+		case inQuote != 0:
+		case c == '"' || c == '\'':
+			inQuote = c
+		case c == '}':
+			return i
+		}
+	}
+	return -1
+}
+
+// parseJSONPathSteps parses a path such as .checks[0].name, $.id or @.status
+func parseJSONPathSteps(expr string) ([]jsonPathStep, error) {
+	original := expr
+	expr = strings.TrimPrefix(strings.TrimPrefix(expr, "$"), "@")
+	if expr != "" && expr[0] != '.' && expr[0] != '[' {
+		expr = "." + expr
+	}
+
+	var steps []jsonPathStep
+	for expr != "" {
+		switch expr[0] {
+		case '.':
+			expr = expr[1:]
+			n := strings.IndexAny(expr, ".[")
+			if n < 0 {
+				n = len(expr)
+			}
+			name := expr[:n]
+			expr = expr[n:]
+			switch name {
+			case "":
+				if len(steps) > 0 || expr != "" {
+					return nil, fmt.Errorf("invalid path %q", original)
+				}
+			case "*":
+				steps = append(steps, jsonPathStep{wildcard: true})
+			default:
+				steps = append(steps, jsonPathStep{field: name})
+			}
+		case '[':
+			end := strings.IndexByte(expr, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ in path %q", original)
+			}
+			inner := strings.TrimSpace(expr[1:end])
+			expr = expr[end+1:]
+			switch {
+			case inner == "*":
+				steps = append(steps, jsonPathStep{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				steps = append(steps, jsonPathStep{field: inner[1 : len(inner)-1]})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index [%s] in path %q", inner, original)
+				}
+				steps = append(steps, jsonPathStep{index: index, isIndex: true})
+			}
+		default:
+			return nil, fmt.Errorf("invalid path %q", original)
+		}
+	}
+	return steps, nil
+}
+
+// sortedKeys returns the keys of m in order, so wildcards print stably
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,184 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testStatus = `{"apiVersion":"v1","kind":"Status","id":"abc","status":"Succeeded","error":null,"retries":2,"done":true,` +
+	`"checks":[{"name":"config","status":"pass"},{"name":"dns","status":"fail"}],"labels":{"b":"2","a":"1"}}`
+
+// render applies an --output value to a document
+func render(t *testing.T, spec, document string) (string, error) {
+	t.Helper()
+	f := &Formatter{}
+	if err := f.SetOutput(spec); err != nil {
+		t.Fatalf("SetOutput(%q): %v", spec, err)
+	}
+	var out bytes.Buffer
+	err := f.renderer.render(&out, []byte(document))
+	return out.String(), err
+}
+
+func TestParseJSONPathSteps(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []jsonPathStep
+	}{
+		{".id", []jsonPathStep{{field: "id"}}},
+		{"id", []jsonPathStep{{field: "id"}}},
+		{"$.id", []jsonPathStep{{field: "id"}}},
+		{"@.status", []jsonPathStep{{field: "status"}}},
+		{".", nil},
+		{".checks[0].name", []jsonPathStep{{field: "checks"}, {index: 0, isIndex: true}, {field: "name"}}},
+		{".checks[-1]", []jsonPathStep{{field: "checks"}, {index: -1, isIndex: true}}},
+		{".checks[*]", []jsonPathStep{{field: "checks"}, {wildcard: true}}},
+		{".labels.*", []jsonPathStep{{field: "labels"}, {wildcard: true}}},
+		{"['odd.key']", []jsonPathStep{{field: "odd.key"}}},
+		{`["odd key"].x`, []jsonPathStep{{field: "odd key"}, {field: "x"}}},
+	}
+	for _, test := range tests {
+		got, err := parseJSONPathSteps(test.expr)
+		if err != nil {
+			t.Errorf("parseJSONPathSteps(%q): %v", test.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseJSONPathSteps(%q) = %+v, want %+v", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestParseJSONPathErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"{.id", "unclosed {"},
+		{"{end}", "{end} without {range}"},
+		{"{range .checks[*]}{.name}", "{range} without {end}"},
+		{`{"unterminated}`, "unclosed {"},
+		{`{"\q"}`, "invalid string"},
+		{"{.checks[0}", "unclosed ["},
+		{"{.checks[x]}", "invalid index [x]"},
+		{"{..id}", "invalid path"},
+	}
+	for _, test := range tests {
+		_, err := parseJSONPath(test.expr)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("parseJSONPath(%q) error = %v, want it to contain %q", test.expr, err, test.want)
+		}
+	}
+}
+
+func TestJSONPath(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"{.id}", "abc"},
+		{".id", "abc"},
+		{"{.id} {.status}", "abc Succeeded"},
+		{"{.retries}/{.done}/{.error}", "2/true/"},
+		{"{.checks[0].name}", "config"},
+		{"{.checks[-1].status}", "fail"},
+		{"{.checks[5].name}", ""},
+		{"{.missing}", ""},
+		{"{.checks[*].name}", "config dns"},
+		{"{.labels.*}", "1 2"},
+		{"{.labels}", `{"a":"1","b":"2"}`},
+		{`{range .checks[*]}{.name}={.status}{"\n"}{end}`, "config=pass\ndns=fail\n"},
+		{"{['id']}", "abc"},
+		// Fields match regardless of case
+		{"{.ID}", "abc"},
+		{"{.Checks[0].Name}", "config"},
+	}
+	for _, test := range tests {
+		got, err := render(t, "jsonpath="+test.expr, testStatus)
+		if err != nil {
+			t.Errorf("jsonpath %q: %v", test.expr, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("jsonpath %q = %q, want %q", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestJSONPathExactCaseWins(t *testing.T) {
+	got, err := render(t, "jsonpath={.Id} {.id} {.ID}", `{"id":"lower","Id":"title"}`)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if want := "title lower title"; got != want {
+		t.Errorf("render = %q, want %q", got, want)
+	}
+}
+
+func TestGoTemplate(t *testing.T) {
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{"{{.id}}", "abc"},
+		{"{{.id}} {{.status}}", "abc Succeeded"},
+		{"{{.retries}}", "2"},
+		{"{{if .done}}done{{end}}", "done"},
+		{"{{range .checks}}{{.name}};{{end}}", "config;dns;"},
+		{"{{index .labels \"a\"}}", "1"},
+		// Fields match regardless of case, as in {{.ID}}
+		{"{{.ID}}", "abc"},
+		{"{{.Status}}", "Succeeded"},
+		{"{{range .Checks}}{{.Name}};{{end}}", "config;dns;"},
+		{"{{with .Labels}}{{.A}}{{end}}", "1"},
+		{"{{$.ID}}", "abc"},
+		{"{{define \"row\"}}{{.ID}}{{end}}{{template \"row\" .}}", "abc"},
+	}
+	for _, test := range tests {
+		got, err := render(t, "go-template="+test.tmpl, testStatus)
+		if err != nil {
+			t.Errorf("go-template %q: %v", test.tmpl, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("go-template %q = %q, want %q", test.tmpl, got, test.want)
+		}
+	}
+}
+
+func TestGoTemplateMissingField(t *testing.T) {
+	_, err := render(t, "go-template={{.nope}}", testStatus)
+	if !errors.Is(err, ErrInvalidOutput) {
+		t.Fatalf("error = %v, want ErrInvalidOutput", err)
+	}
+	if !strings.Contains(err.Error(), "{{.id}}") {
+		t.Errorf("error = %q, want a hint naming {{.id}}", err)
+	}
+}
+
+func TestSetOutputErrors(t *testing.T) {
+	for _, spec := range []string{"xml", "go-template={{.id", "jsonpath={range .x[*]}", "go-template-file=/nonexistent/template"} {
+		err := (&Formatter{}).SetOutput(spec)
+		if err == nil {
+			t.Errorf("SetOutput(%q) succeeded", spec)
+			continue
+		}
+		if !strings.HasPrefix(spec, "go-template-file") && !errors.Is(err, ErrInvalidOutput) {
+			t.Errorf("SetOutput(%q) = %v, want ErrInvalidOutput", spec, err)
+		}
+	}
+}
+
+func TestStructuredOutput(t *testing.T) {
+	for spec, want := range map[string]bool{
+		"": false, "text": false, "json": true, "table": true, "yaml": true,
+		"go-template={{.id}}": true, "go-template-file=x": true, "jsonpath={.id}": true,
+	} {
+		if got := StructuredOutput(spec); got != want {
+			t.Errorf("StructuredOutput(%q) = %v, want %v", spec, got, want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// APIVersion is the version of the JSON output schema. Within a version fields
+// are only ever added; renaming or removing one needs a new version
+const APIVersion = "v1"
+
+// Kinds of JSON documents, one per kind of result
+const (
+	KindSendResult           = "SendResult"
+	KindIndividualSendResult = "IndividualSendResult"
+	KindBatchResult          = "BatchResult"
+	KindStatus               = "Status"
+	KindStatusUpdate         = "StatusUpdate"
+	KindDoctorReport         = "DoctorReport"
+	KindValidationReport     = "ValidationReport"
+	KindProfileList          = "ProfileList"
+	KindProfile              = "Profile"
+	KindConfig               = "Config"
+	KindConfigValue          = "ConfigValue"
+	KindConfigSources        = "ConfigSources"
+	KindConfigValidation     = "ConfigValidation"
+	KindEnvironment          = "Environment"
+	KindVersion              = "Version"
+	KindPruneReport          = "PruneReport"
+	KindQueueMessage         = "QueueMessage"
+	KindQueueList            = "QueueList"
+	KindQueueUpdate          = "QueueUpdate"
+	KindTemplate             = "Template"
+	KindTemplateList         = "TemplateList"
+	KindSuccess              = "Success"
+	KindInfo                 = "Info"
+	KindDebug                = "Debug"
+	KindError                = "Error"
+)
+
+// envelope encodes data, which must be a JSON object, with the apiVersion and
+// kind fields in front of its own
+func envelope(kind string, data interface{}) ([]byte, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) < 2 || body[0] != '{' {
+		return nil, fmt.Errorf("%s output must be a JSON object", kind)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `{"apiVersion":%q,"kind":%q`, APIVersion, kind)
+	if fields := body[1 : len(body)-1]; len(bytes.TrimSpace(fields)) > 0 {
+		b.WriteByte(',')
+		b.Write(fields)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
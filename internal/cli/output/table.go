@@ -0,0 +1,337 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// field is one key of a JSON object
+type field struct {
+	key   string
+	value interface{}
+}
+
+// object is a decoded JSON object that keeps its keys in document order, so
+// table columns and YAML keys come out the way --json prints them
+type object []field
+
+// decodeOrdered decodes a JSON document into object, []interface{},
+// json.Number, string, bool or nil values
+func decodeOrdered(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return decodeValue(dec)
+}
+
+func decodeValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		o := object{}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			o = append(o, field{key: keyTok.(string), value: value})
+		}
+		_, err := dec.Token()
+		return o, err
+	case json.Delim('['):
+		list := []interface{}{}
+		for dec.More() {
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err := dec.Token()
+		return list, err
+	}
+	return tok, nil
+}
+
+// tableRenderer prints documents as aligned columns. A document holding a
+// list of objects, such as the checks of doctor, gives one row per object;
+// any other document is a single row. Streamed documents keep the columns
+// and widths of the previous one when they fit, so they read as one table
+type tableRenderer struct {
+	columns []string
+	widths  []int
+}
+
+// tablePadding is the space between table columns
+const tablePadding = 3
+
+func (t *tableRenderer) render(w io.Writer, data []byte) error {
+	document, err := decodeOrdered(data)
+	if err != nil {
+		return fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	doc, _ := document.(object)
+	var fields object
+	for _, f := range doc {
+		if f.key != "apiVersion" && f.key != "kind" {
+			fields = append(fields, f)
+		}
+	}
+
+	rows := []object{fields}
+	if list, ok := rowList(fields); ok {
+		rows = list
+	}
+
+	var columns []string
+	seen := map[string]bool{}
+	for _, row := range rows {
+		for _, f := range row {
+			if !seen[f.key] {
+				seen[f.key] = true
+				columns = append(columns, f.key)
+			}
+		}
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+
+	header := !subset(columns, t.columns)
+	if header {
+		t.columns = columns
+		t.widths = make([]int, len(columns))
+		for i, column := range columns {
+			t.widths[i] = len(column)
+		}
+	}
+	columns = t.columns
+
+	cells := make([][]string, len(rows))
+	for r, row := range rows {
+		cells[r] = make([]string, len(columns))
+		for i, column := range columns {
+			if value, ok := row.get(column); ok {
+				cells[r][i] = cellText(value)
+				t.widths[i] = max(t.widths[i], utf8.RuneCountInString(cells[r][i]))
+			}
+		}
+	}
+
+	if header {
+		t.writeRow(w, columns, strings.ToUpper)
+	}
+	for _, row := range cells {
+		t.writeRow(w, row, nil)
+	}
+	return nil
+}
+
+// writeRow writes cells padded to the column widths, transformed by format if set
+func (t *tableRenderer) writeRow(w io.Writer, cells []string, format func(string) string) {
+	var b strings.Builder
+	for i, cell := range cells {
+		if format != nil {
+			cell = format(cell)
+		}
+		if i == len(cells)-1 {
+			b.WriteString(cell)
+			break
+		}
+		b.WriteString(cell)
+		b.WriteString(strings.Repeat(" ", t.widths[i]-utf8.RuneCountInString(cell)+tablePadding))
+	}
+	fmt.Fprintln(w, strings.TrimRight(b.String(), " "))
+}
+
+// subset reports whether every column of columns is in of
+func subset(columns, of []string) bool {
+	for _, column := range columns {
+		found := false
+		for _, c := range of {
+			found = found || c == column
+		}
+		if !found {
+			return false
+		}
+	}
+	return len(of) > 0
+}
+
+// rowList returns the rows of a document whose only list is a list of objects
+func rowList(fields object) ([]object, bool) {
+	var rows []object
+	lists := 0
+	for _, f := range fields {
+		list, ok := f.value.([]interface{})
+		if !ok {
+			continue
+		}
+		lists++
+		rows = rows[:0]
+		for _, item := range list {
+			row, ok := item.(object)
+			if !ok {
+				return nil, false
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows, lists == 1
+}
+
+// get returns the value of key
+func (o object) get(key string) (interface{}, bool) {
+	for _, f := range o {
+		if f.key == key {
+			return f.value, true
+		}
+	}
+	return nil, false
+}
+
+// cellText formats a value for a table cell on a single line
+func cellText(value interface{}) string {
+	switch v := value.(type) {
+	case object:
+		parts := make([]string, 0, len(v))
+		for _, f := range v {
+			parts = append(parts, f.key+"="+cellText(f.value))
+		}
+		return strings.Join(parts, ",")
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, cellText(item))
+		}
+		return strings.Join(parts, ",")
+	case string:
+		return strings.NewReplacer("\n", " ", "\t", " ").Replace(v)
+	}
+	return jsonPathText(value)
+}
+
+// yamlRenderer prints documents as YAML, separating streamed documents with ---
+type yamlRenderer struct {
+	started bool
+}
+
+func (y *yamlRenderer) render(w io.Writer, data []byte) error {
+	document, err := decodeOrdered(data)
+	if err != nil {
+		return fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	if y.started {
+		io.WriteString(w, "---\n")
+	}
+	y.started = true
+	writeYAML(w, document, 0)
+	return nil
+}
+
+// writeYAML writes value as a block of YAML indented by indent spaces
+func writeYAML(w io.Writer, value interface{}, indent int) {
+	pad := strings.Repeat(" ", indent)
+	switch v := value.(type) {
+	case object:
+		if len(v) == 0 {
+			fmt.Fprintf(w, "%s{}\n", pad)
+		}
+		for _, f := range v {
+			fmt.Fprintf(w, "%s%s:", pad, yamlScalar(f.key))
+			writeYAMLChild(w, f.value, indent)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			fmt.Fprintf(w, "%s[]\n", pad)
+		}
+		for _, item := range v {
+			// Objects start on the line of their dash: "- name: value"
+			if o, ok := item.(object); ok && len(o) > 0 {
+				var block strings.Builder
+				writeYAML(&block, o, indent+2)
+				io.WriteString(w, pad+"- "+strings.TrimPrefix(block.String(), pad+"  "))
+				continue
+			}
+			fmt.Fprintf(w, "%s-", pad)
+			writeYAMLChild(w, item, indent)
+		}
+	default:
+		fmt.Fprintf(w, "%s%s\n", pad, yamlScalar(value))
+	}
+}
+
+// writeYAMLChild writes the value of a key or list item: scalars and empty
+// collections on the same line, others as an indented block
+func writeYAMLChild(w io.Writer, value interface{}, indent int) {
+	switch v := value.(type) {
+	case object:
+		if len(v) > 0 {
+			io.WriteString(w, "\n")
+			writeYAML(w, v, indent+2)
+			return
+		}
+		io.WriteString(w, " {}\n")
+	case []interface{}:
+		if len(v) > 0 {
+			io.WriteString(w, "\n")
+			writeYAML(w, v, indent+2)
+			return
+		}
+		io.WriteString(w, " []\n")
+	default:
+		fmt.Fprintf(w, " %s\n", yamlScalar(value))
+	}
+}
+
+// yamlScalar formats a scalar, quoting strings YAML would read as something else
+func yamlScalar(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	case string:
+		if yamlNeedsQuotes(v) {
+			return strconv.Quote(v)
+		}
+		return v
+	}
+	return fmt.Sprint(value)
+}
+
+// yamlNeedsQuotes reports whether a string cannot be written as a plain scalar
+func yamlNeedsQuotes(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s {
+		return true
+	}
+	switch strings.ToLower(s) {
+	case "null", "~", "true", "false", "yes", "no", "on", "off":
+		return true
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return true
+	}
+	if strings.ContainsRune("-?:,[]{}#&*!|>'\"%@`", rune(s[0])) {
+		return true
+	}
+	for _, r := range s {
+		if r < ' ' || r == 0x7f {
+			return true
+		}
+	}
+	return strings.Contains(s, ": ") || strings.Contains(s, " #")
+}
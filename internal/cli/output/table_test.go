@@ -0,0 +1,156 @@
+package output
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/groovy-sky/azemailsender/internal/simpleyaml"
+)
+
+// renderAll renders documents in turn with r, as streamed output is
+func renderAll(t *testing.T, r renderer, documents ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	for _, document := range documents {
+		if err := r.render(&out, []byte(document)); err != nil {
+			t.Fatalf("render(%s): %v", document, err)
+		}
+	}
+	return out.String()
+}
+
+func TestTableRenderer(t *testing.T) {
+	tests := []struct {
+		name      string
+		documents []string
+		want      string
+	}{
+		{
+			name:      "single row without apiVersion and kind",
+			documents: []string{`{"apiVersion":"v1","kind":"Status","id":"abc","status":"Succeeded"}`},
+			want:      "ID    STATUS\nabc   Succeeded\n",
+		},
+		{
+			name:      "one row per object of the only list",
+			documents: []string{`{"kind":"Doctor","checks":[{"name":"config","status":"pass"},{"name":"dns","status":"fail","detail":"no such host"}]}`},
+			want:      "NAME     STATUS   DETAIL\nconfig   pass\ndns      fail     no such host\n",
+		},
+		{
+			name: "streamed rows share the header",
+			documents: []string{
+				`{"id":"a","status":"Running"}`,
+				`{"id":"a","status":"Succeeded"}`,
+				`{"id":"b"}`,
+			},
+			want: "ID   STATUS\na    Running\na    Succeeded\nb\n",
+		},
+		{
+			name: "new columns print a new header",
+			documents: []string{
+				`{"id":"a"}`,
+				`{"id":"b","error":"boom"}`,
+			},
+			want: "ID\na\nID   ERROR\nb    boom\n",
+		},
+		{
+			name:      "nested values and newlines stay on one line",
+			documents: []string{`{"to":["a@example.com","b@example.com"],"headers":{"x":"1","y":"2"},"text":"two\nlines","n":1.50,"ok":true,"none":null}`},
+			want:      "TO                            HEADERS   TEXT        N      OK     NONE\na@example.com,b@example.com   x=1,y=2   two lines   1.50   true\n",
+		},
+		{
+			name:      "wide characters are counted as runes",
+			documents: []string{`{"name":"Zoë","n":"1"}`},
+			want:      "NAME   N\nZoë    1\n",
+		},
+		{
+			name:      "empty document prints nothing",
+			documents: []string{`{"apiVersion":"v1","kind":"Empty"}`},
+			want:      "",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := renderAll(t, &tableRenderer{}, test.documents...); got != test.want {
+				t.Errorf("table =\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestYAMLRenderer(t *testing.T) {
+	tests := []struct {
+		name      string
+		documents []string
+		want      string
+	}{
+		{
+			name:      "keys keep document order",
+			documents: []string{`{"kind":"Status","id":"abc","retries":2,"done":false,"error":null}`},
+			want:      "kind: Status\nid: abc\nretries: 2\ndone: false\nerror: null\n",
+		},
+		{
+			name:      "nested objects and lists",
+			documents: []string{`{"checks":[{"name":"config","tags":["a","b"]},"plain",[]],"labels":{"team":"mail"},"empty":{},"none":[]}`},
+			want: "checks:\n" +
+				"  - name: config\n" +
+				"    tags:\n" +
+				"      - a\n" +
+				"      - b\n" +
+				"  - plain\n" +
+				"  - []\n" +
+				"labels:\n" +
+				"  team: mail\n" +
+				"empty: {}\n" +
+				"none: []\n",
+		},
+		{
+			name:      "strings YAML would misread are quoted",
+			documents: []string{`{"a":"","b":"true","c":"No","d":"12","e":"1.5","f":"- x","g":"key: value","h":"a #b","i":" pad","j":"line\nbreak","k":"@me","l":"plain text","m":"a:b"}`},
+			want: "a: \"\"\nb: \"true\"\nc: \"No\"\nd: \"12\"\ne: \"1.5\"\nf: \"- x\"\ng: \"key: value\"\nh: \"a #b\"\n" +
+				"i: \" pad\"\nj: \"line\\nbreak\"\nk: \"@me\"\nl: plain text\nm: a:b\n",
+		},
+		{
+			name:      "keys are quoted like values",
+			documents: []string{`{"yes":1,"with space":2}`},
+			want:      "\"yes\": 1\nwith space: 2\n",
+		},
+		{
+			name:      "streamed documents are separated",
+			documents: []string{`{"id":"a"}`, `{"id":"b"}`},
+			want:      "id: a\n---\nid: b\n",
+		},
+		{
+			name:      "top-level list",
+			documents: []string{`[1,"two"]`},
+			want:      "- 1\n- two\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := renderAll(t, &yamlRenderer{}, test.documents...); got != test.want {
+				t.Errorf("yaml =\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestYAMLRoundTrip(t *testing.T) {
+	// The YAML printed for a document reads back as the same values
+	got := renderAll(t, &yamlRenderer{}, `{"s":"true","n":3,"f":1.5,"list":["- a","b: c","",null],"nested":{"q":"#x","e":{}},"text":"line\nbreak"}`)
+	value, err := simpleyaml.Unmarshal([]byte(got))
+	if err != nil {
+		t.Fatalf("Unmarshal(%q): %v", got, err)
+	}
+	want := map[string]interface{}{
+		"s":      "true",
+		"n":      3,
+		"f":      1.5,
+		"list":   []interface{}{"- a", "b: c", "", nil},
+		"nested": map[string]interface{}{"q": "#x", "e": map[string]interface{}{}},
+		"text":   "line\nbreak",
+	}
+	if !reflect.DeepEqual(value, want) {
+		t.Errorf("yaml %q reads back as %#v, want %#v", got, value, want)
+	}
+}
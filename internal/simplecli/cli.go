@@ -3,12 +3,16 @@ package simplecli
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"text/template"
+	"time"
 )
 
 // Command represents a CLI command
 type Command struct {
 	Name        string
+	Aliases     []string
 	Description string
 	Usage       string
 	LongDesc    string
@@ -16,9 +20,20 @@ type Command struct {
 	Run         func(*Context) error
 	Flags       []*Flag
 	Subcommands []*Command
+
+	// Hidden commands run but are left out of help, docs and completions
+	Hidden bool
+
+	// Deprecated is a hint such as "use 'status --watch' instead", printed
+	// when the command runs. Deprecated commands are also hidden
+	Deprecated string
+
+	// Constraints are checked after the flags are parsed
+	Constraints []Constraint
 }
 
-// Flag represents a command-line flag
+// Flag represents a command-line flag. The type of Value selects how the flag
+// is parsed: string, bool, []string, int, time.Duration or float64
 type Flag struct {
 	Name        string
 	Short       string
@@ -26,6 +41,20 @@ type Flag struct {
 	Value       interface{}
 	Required    bool
 	EnvVar      string
+
+	// Category groups the flag under "<Category> Flags" in help
+	Category string
+
+	// Hidden flags are accepted but left out of help, docs and completions
+	Hidden bool
+
+	// Deprecated is a hint such as "use --rate instead", printed when the flag
+	// is set. Deprecated flags are also hidden
+	Deprecated string
+
+	// Complete returns shell completion candidates for the flag's value, given
+	// the flag values typed so far
+	Complete func(flags map[string]interface{}) []string
 }
 
 // Context holds the execution context for a command
@@ -34,8 +63,20 @@ type Context struct {
 	Flags     map[string]interface{}
 	Command   *Command
 	GlobalCtx *GlobalContext
+
+	// set holds where the flags given on the command line or by their
+	// environment variable came from
+	set map[string]flagOrigin
 }
 
+// flagOrigin tells where the value of a flag that is set came from
+type flagOrigin int
+
+const (
+	fromEnv flagOrigin = iota + 1
+	fromArgs
+)
+
 // GlobalContext holds global CLI state
 type GlobalContext struct {
 	AppName     string
@@ -45,6 +86,29 @@ type GlobalContext struct {
 	Date        string
 	GlobalFlags []*Flag
 	Commands    []*Command
+
+	// EnvPrefix is prepended to the EnvVar of every flag, so an application
+	// can namespace its environment variables
+	EnvPrefix string
+
+	helpTemplate *template.Template
+
+	// flags holds the flag values parsed by the last Run
+	flags map[string]interface{}
+}
+
+// UsageError is returned by Run when the command line cannot be parsed: an
+// unknown command or flag, a bad flag value or conflicting flags
+type UsageError struct {
+	Err error
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
 }
 
 // NewGlobalContext creates a new global CLI context
@@ -76,6 +140,12 @@ func (g *GlobalContext) Run() error {
 		return nil
 	}
 
+	// Answer shell completion requests
+	if args[0] == completeCommand {
+		g.Complete(os.Stdout, args[1:])
+		return nil
+	}
+
 	// Check for global help
 	if args[0] == "help" || args[0] == "--help" || args[0] == "-h" {
 		g.printHelp()
@@ -83,10 +153,11 @@ func (g *GlobalContext) Run() error {
 	}
 
 	// Parse global flags and find command
-	globalFlags, remainingArgs, err := g.parseGlobalFlags(args)
+	globalFlags, globalSet, remainingArgs, err := g.parseGlobalFlags(args)
 	if err != nil {
-		return err
+		return &UsageError{Err: err}
 	}
+	g.flags = globalFlags
 
 	if len(remainingArgs) == 0 {
 		g.printHelp()
@@ -97,38 +168,63 @@ func (g *GlobalContext) Run() error {
 	cmdName := remainingArgs[0]
 	cmd := g.findCommand(cmdName)
 	if cmd == nil {
-		return fmt.Errorf("unknown command: %s", cmdName)
+		return &UsageError{Err: unknownCommandError("command", g.Commands, cmdName)}
 	}
 
 	// Parse command flags and arguments
-	ctx, err := g.parseCommand(cmd, globalFlags, remainingArgs[1:])
+	ctx, err := g.parseCommand(cmd, globalFlags, globalSet, remainingArgs[1:])
 	if err != nil {
-		return err
+		return &UsageError{Err: err}
 	}
+	g.flags = ctx.Flags
+
+	g.warnDeprecated(os.Stderr, ctx)
 
 	// Run command (use the command from context in case it's a subcommand)
 	return ctx.Command.Run(ctx)
 }
 
-// parseGlobalFlags parses global flags from arguments
-func (g *GlobalContext) parseGlobalFlags(args []string) (map[string]interface{}, []string, error) {
+// parseGlobalFlags parses global flags from arguments, returning their values,
+// the names of the flags given and the remaining arguments
+func (g *GlobalContext) parseGlobalFlags(args []string) (map[string]interface{}, map[string]flagOrigin, []string, error) {
 	flags := make(map[string]interface{})
+	set := make(map[string]flagOrigin)
 	var remaining []string
 	
 	// Set defaults for global flags
 	for _, flag := range g.GlobalFlags {
 		flags[flag.Name] = flag.Value
+		if envVar := g.envVar(flag); envVar != "" {
+			if envVal := os.Getenv(envVar); envVal != "" {
+				if err := g.setFlagValue(flags, set, fromEnv, flag, envVal); err != nil {
+					return nil, nil, nil, fmt.Errorf("invalid environment variable %s: %w", envVar, err)
+				}
+			}
+		}
 	}
 
 	i := 0
+	args = append([]string(nil), args...)
 	for i < len(args) {
 		arg := args[i]
 		
-		if !strings.HasPrefix(arg, "-") {
+		if !isFlagArg(arg) {
 			remaining = append(remaining, args[i:]...)
 			break
 		}
 
+		// "--" ends flag parsing
+		if arg == "--" {
+			remaining = append(remaining, args[i+1:]...)
+			break
+		}
+
+		// Split combined short flags such as -jq into separate arguments
+		if split := splitShortFlags(arg, g.GlobalFlags); split != nil {
+			args = append(args[:i], append(split, args[i+1:]...)...)
+			continue
+		}
+
 		// Find matching global flag
 		var matchedFlag *Flag
 		var value string
@@ -167,12 +263,11 @@ func (g *GlobalContext) parseGlobalFlags(args []string) (map[string]interface{},
 		if !hasValue {
 			switch matchedFlag.Value.(type) {
 			case bool:
-				flags[matchedFlag.Name] = true
+				// An empty value sets a boolean flag to true
 				i++
-				continue
 			default:
 				if i+1 >= len(args) {
-					return nil, nil, fmt.Errorf("flag --%s requires a value", matchedFlag.Name)
+					return nil, nil, nil, fmt.Errorf("flag --%s requires a value", matchedFlag.Name)
 				}
 				value = args[i+1]
 				i += 2
@@ -182,38 +277,33 @@ func (g *GlobalContext) parseGlobalFlags(args []string) (map[string]interface{},
 		}
 
 		// Set flag value
-		err := g.setFlagValue(flags, matchedFlag, value)
+		err := g.setFlagValue(flags, set, fromArgs, matchedFlag, value)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 	}
 
-	return flags, remaining, nil
+	return flags, set, remaining, nil
 }
 
 // parseCommand parses command-specific flags and arguments
-func (g *GlobalContext) parseCommand(cmd *Command, globalFlags map[string]interface{}, args []string) (*Context, error) {
+func (g *GlobalContext) parseCommand(cmd *Command, globalFlags map[string]interface{}, globalSet map[string]flagOrigin, args []string) (*Context, error) {
 	flags := make(map[string]interface{})
+	set := make(map[string]flagOrigin)
 	
 	// Copy global flags
 	for k, v := range globalFlags {
 		flags[k] = v
 	}
+	for k, origin := range globalSet {
+		set[k] = origin
+	}
 	
 	// Check for subcommands first, before processing any flags
 	if len(cmd.Subcommands) > 0 && len(args) > 0 && !strings.HasPrefix(args[0], "-") {
-		subCmdName := args[0]
-		var subCmd *Command
-		for _, sc := range cmd.Subcommands {
-			if sc.Name == subCmdName {
-				subCmd = sc
-				break
-			}
-		}
-		
-		if subCmd != nil {
+		if subCmd := findIn(cmd.Subcommands, args[0]); subCmd != nil {
 			// Parse subcommand flags
-			subCtx, err := g.parseCommand(subCmd, globalFlags, args[1:])
+			subCtx, err := g.parseCommand(subCmd, globalFlags, globalSet, args[1:])
 			if err != nil {
 				return nil, err
 			}
@@ -234,17 +324,18 @@ func (g *GlobalContext) parseCommand(cmd *Command, globalFlags map[string]interf
 		flags[flag.Name] = flag.Value
 		
 		// Check environment variable
-		if flag.EnvVar != "" {
-			if envVal := os.Getenv(flag.EnvVar); envVal != "" {
-				err := g.setFlagValue(flags, flag, envVal)
+		if envVar := g.envVar(flag); envVar != "" {
+			if envVal := os.Getenv(envVar); envVal != "" {
+				err := g.setFlagValue(flags, set, fromEnv, flag, envVal)
 				if err != nil {
-					return nil, fmt.Errorf("invalid environment variable %s: %w", flag.EnvVar, err)
+					return nil, fmt.Errorf("invalid environment variable %s: %w", envVar, err)
 				}
 			}
 		}
 	}
 
 	var cmdArgs []string
+	args = append([]string(nil), args...)
 	i := 0
 	
 	for i < len(args) {
@@ -252,20 +343,30 @@ func (g *GlobalContext) parseCommand(cmd *Command, globalFlags map[string]interf
 		
 		// If this command has subcommands, check if this arg is a subcommand name
 		if len(cmd.Subcommands) > 0 && !strings.HasPrefix(arg, "-") {
-			for _, subCmd := range cmd.Subcommands {
-				if arg == subCmd.Name {
-					// Found subcommand, add everything to cmdArgs
-					cmdArgs = append(cmdArgs, args[i:]...)
-					goto endFlagParsing
-				}
+			if findIn(cmd.Subcommands, arg) != nil {
+				// Found subcommand, add everything to cmdArgs
+				cmdArgs = append(cmdArgs, args[i:]...)
+				goto endFlagParsing
 			}
 		}
 		
-		if !strings.HasPrefix(arg, "-") {
+		if !isFlagArg(arg) {
 			cmdArgs = append(cmdArgs, args[i:]...)
 			break
 		}
 
+		// "--" ends flag parsing, so the rest are arguments even if they start with -
+		if arg == "--" {
+			cmdArgs = append(cmdArgs, args[i+1:]...)
+			break
+		}
+
+		// Split combined short flags such as -jq or -tuser@example.com
+		if split := splitShortFlags(arg, append(append([]*Flag{}, cmd.Flags...), g.GlobalFlags...)); split != nil {
+			args = append(args[:i], append(split, args[i+1:]...)...)
+			continue
+		}
+
 		// Check for help
 		if arg == "--help" || arg == "-h" {
 			g.printCommandHelp(cmd)
@@ -311,9 +412,8 @@ func (g *GlobalContext) parseCommand(cmd *Command, globalFlags map[string]interf
 		if !hasValue {
 			switch matchedFlag.Value.(type) {
 			case bool:
-				flags[matchedFlag.Name] = true
+				// An empty value sets a boolean flag to true
 				i++
-				continue
 			default:
 				if i+1 >= len(args) {
 					return nil, fmt.Errorf("flag --%s requires a value", matchedFlag.Name)
@@ -326,7 +426,7 @@ func (g *GlobalContext) parseCommand(cmd *Command, globalFlags map[string]interf
 		}
 
 		// Set flag value
-		err := g.setFlagValue(flags, matchedFlag, value)
+		err := g.setFlagValue(flags, set, fromArgs, matchedFlag, value)
 		if err != nil {
 			return nil, err
 		}
@@ -342,26 +442,73 @@ endFlagParsing:
 		}
 		
 		// If we get here, it means the first arg wasn't a recognized subcommand
-		return nil, fmt.Errorf("unknown subcommand: %s", cmdArgs[0])
+		return nil, unknownCommandError("subcommand", cmd.Subcommands, cmdArgs[0])
 	}
 
 	// Validate required flags
 	for _, flag := range cmd.Flags {
-		if flag.Required && flags[flag.Name] == flag.Value {
+		if flag.Required && set[flag.Name] == 0 {
 			return nil, fmt.Errorf("required flag --%s not provided", flag.Name)
 		}
 	}
-
-	return &Context{
+	ctx := &Context{
 		Args:      cmdArgs,
 		Flags:     flags,
 		Command:   cmd,
 		GlobalCtx: g,
-	}, nil
+		set:       set,
+	}
+	for _, constraint := range cmd.Constraints {
+		if err := constraint.check(ctx.IsGiven, ctx.IsEnabled); err != nil {
+			return nil, err
+		}
+	}
+
+	return ctx, nil
+}
+
+// isFlagArg reports whether arg is a flag rather than an argument. A lone "-"
+// is an argument, conventionally standing for stdin
+func isFlagArg(arg string) bool {
+	return strings.HasPrefix(arg, "-") && arg != "-"
 }
 
-// setFlagValue sets a flag value with type conversion
-func (g *GlobalContext) setFlagValue(flags map[string]interface{}, flag *Flag, value string) error {
+// splitShortFlags expands a cluster of single-letter flags into separate
+// arguments: -jq becomes -j -q, and a letter taking a value consumes the rest,
+// so -tuser@example.com becomes -t user@example.com. It returns nil when arg is
+// not such a cluster or a letter is not a known flag
+func splitShortFlags(arg string, flags []*Flag) []string {
+	if len(arg) < 3 || arg[0] != '-' || arg[1] == '-' {
+		return nil
+	}
+
+	var split []string
+	for i := 1; i < len(arg); i++ {
+		var matched *Flag
+		for _, flag := range flags {
+			if flag.Short == arg[i:i+1] {
+				matched = flag
+				break
+			}
+		}
+		if matched == nil {
+			return nil
+		}
+
+		split = append(split, "-"+matched.Short)
+		if _, isBool := matched.Value.(bool); !isBool {
+			if rest := arg[i+1:]; rest != "" {
+				split = append(split, strings.TrimPrefix(rest, "="))
+			}
+			break
+		}
+	}
+	return split
+}
+
+// setFlagValue sets a flag value with type conversion and records where it came from
+func (g *GlobalContext) setFlagValue(flags map[string]interface{}, set map[string]flagOrigin, origin flagOrigin, flag *Flag, value string) error {
+	set[flag.Name] = origin
 	switch flag.Value.(type) {
 	case string:
 		flags[flag.Name] = value
@@ -379,93 +526,90 @@ func (g *GlobalContext) setFlagValue(flags map[string]interface{}, flag *Flag, v
 		} else {
 			flags[flag.Name] = []string{value}
 		}
+	case int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer value for --%s: %s", flag.Name, value)
+		}
+		flags[flag.Name] = n
+	case time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration value for --%s: %s (use a duration such as 500ms, 5s or 2m)", flag.Name, value)
+		}
+		flags[flag.Name] = d
+	case float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number value for --%s: %s", flag.Name, value)
+		}
+		flags[flag.Name] = f
 	default:
 		flags[flag.Name] = value
 	}
 	return nil
 }
 
-// findCommand finds a command by name
-func (g *GlobalContext) findCommand(name string) *Command {
-	for _, cmd := range g.Commands {
-		if cmd.Name == name {
-			return cmd
-		}
-	}
-	return nil
+// LastFlags returns the flag values parsed by the last Run, so the error it
+// returned can be reported in the format the flags asked for
+func (g *GlobalContext) LastFlags() map[string]interface{} {
+	return g.flags
 }
 
-// printHelp prints the main help message
-func (g *GlobalContext) printHelp() {
-	fmt.Printf("%s\n\n", g.Description)
-	fmt.Printf("Usage:\n  %s [command]\n\n", g.AppName)
-	
-	if len(g.Commands) > 0 {
-		fmt.Println("Available Commands:")
-		for _, cmd := range g.Commands {
-			fmt.Printf("  %-12s %s\n", cmd.Name, cmd.Description)
-		}
-		fmt.Println()
+// envVar returns the full name of the environment variable of a flag, or ""
+func (g *GlobalContext) envVar(flag *Flag) string {
+	if flag.EnvVar == "" {
+		return ""
 	}
-	
-	if len(g.GlobalFlags) > 0 {
-		fmt.Println("Flags:")
-		for _, flag := range g.GlobalFlags {
-			flagStr := "--" + flag.Name
-			if flag.Short != "" {
-				flagStr = "-" + flag.Short + ", " + flagStr
-			}
-			fmt.Printf("  %-20s %s\n", flagStr, flag.Description)
-		}
-		fmt.Println()
-	}
-	
-	fmt.Printf("Use \"%s [command] --help\" for more information about a command.\n", g.AppName)
+	return g.EnvPrefix + flag.EnvVar
 }
 
-// printCommandHelp prints help for a specific command
-func (g *GlobalContext) printCommandHelp(cmd *Command) {
-	fmt.Printf("%s\n\n", cmd.LongDesc)
-	fmt.Printf("Usage:\n  %s %s\n\n", g.AppName, cmd.Usage)
-	
-	if cmd.Examples != "" {
-		fmt.Printf("Examples:\n%s\n\n", cmd.Examples)
-	}
-	
-	if len(cmd.Subcommands) > 0 {
-		fmt.Println("Available Commands:")
-		for _, subCmd := range cmd.Subcommands {
-			fmt.Printf("  %-12s %s\n", subCmd.Name, subCmd.Description)
-		}
-		fmt.Println()
-	}
-	
-	if len(cmd.Flags) > 0 {
-		fmt.Println("Flags:")
-		for _, flag := range cmd.Flags {
-			flagStr := "--" + flag.Name
-			if flag.Short != "" {
-				flagStr = "-" + flag.Short + ", " + flagStr
-			}
-			fmt.Printf("  %-20s %s\n", flagStr, flag.Description)
-		}
-		fmt.Println()
-	}
-	
-	if len(g.GlobalFlags) > 0 {
-		fmt.Println("Global Flags:")
-		for _, flag := range g.GlobalFlags {
-			flagStr := "--" + flag.Name
-			if flag.Short != "" {
-				flagStr = "-" + flag.Short + ", " + flagStr
-			}
-			fmt.Printf("  %-20s %s\n", flagStr, flag.Description)
-		}
+// flagHelp returns the description of a flag for help output, naming its
+// environment variable
+func (g *GlobalContext) flagHelp(flag *Flag) string {
+	if envVar := g.envVar(flag); envVar != "" {
+		return flag.Description + " [$" + envVar + "]"
 	}
+	return flag.Description
+}
+
+// findCommand finds a command by name or alias
+func (g *GlobalContext) findCommand(name string) *Command {
+	return findIn(g.Commands, name)
 }
 
 // Helper methods for Context
 
+// IsSet reports whether a flag was given on the command line or by its
+// environment variable, rather than left at its default
+func (c *Context) IsSet(name string) bool {
+	return c.set[name] != 0
+}
+
+// IsGiven reports whether a flag was given on the command line
+func (c *Context) IsGiven(name string) bool {
+	return c.set[name] == fromArgs
+}
+
+// IsEnabled reports whether a flag is set, on the command line or by its
+// environment variable, and for a boolean flag whether it is true
+func (c *Context) IsEnabled(name string) bool {
+	if val, ok := c.Flags[name].(bool); ok {
+		return val
+	}
+	return c.IsSet(name)
+}
+
+// ProvidedFlags returns the values of the flags that are set, leaving out
+// defaults, so they can be layered over configuration without masking it
+func (c *Context) ProvidedFlags() map[string]interface{} {
+	provided := make(map[string]interface{}, len(c.set))
+	for name := range c.set {
+		provided[name] = c.Flags[name]
+	}
+	return provided
+}
+
 // GetString gets a string flag value
 func (c *Context) GetString(name string) string {
 	if val, ok := c.Flags[name].(string); ok {
@@ -482,6 +626,30 @@ func (c *Context) GetBool(name string) bool {
 	return false
 }
 
+// GetInt gets an integer flag value
+func (c *Context) GetInt(name string) int {
+	if val, ok := c.Flags[name].(int); ok {
+		return val
+	}
+	return 0
+}
+
+// GetDuration gets a duration flag value
+func (c *Context) GetDuration(name string) time.Duration {
+	if val, ok := c.Flags[name].(time.Duration); ok {
+		return val
+	}
+	return 0
+}
+
+// GetFloat gets a floating point flag value
+func (c *Context) GetFloat(name string) float64 {
+	if val, ok := c.Flags[name].(float64); ok {
+		return val
+	}
+	return 0
+}
+
 // GetStringSlice gets a string slice flag value
 func (c *Context) GetStringSlice(name string) []string {
 	if val, ok := c.Flags[name].([]string); ok {
@@ -0,0 +1,183 @@
+package simplecli
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// completeCommand is the hidden command the generated shell scripts call to
+// ask the application for candidates
+const completeCommand = "__complete"
+
+// Complete prints completion candidates for the words typed after the
+// application name, one per line. The last word is the one being completed
+// and may be empty. Nothing is printed when a flag value has no completer, so
+// shells fall back to file names
+func (g *GlobalContext) Complete(w io.Writer, words []string) {
+	current := ""
+	if len(words) > 0 {
+		current = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+	// Older PowerShell versions cannot pass an empty argument to native commands
+	if current == `""` {
+		current = ""
+	}
+
+	var cmd *Command
+	flags := make(map[string]interface{})
+	var pending *Flag
+	for _, word := range words {
+		if pending != nil {
+			flags[pending.Name] = word
+			pending = nil
+			continue
+		}
+
+		if strings.HasPrefix(word, "-") {
+			name, value, hasValue := strings.Cut(word, "=")
+			flag := g.lookupFlag(cmd, name)
+			if flag == nil {
+				continue
+			}
+			if hasValue {
+				flags[flag.Name] = value
+			} else if _, isBool := flag.Value.(bool); !isBool {
+				pending = flag
+			}
+			continue
+		}
+
+		if next := g.lookupCommand(cmd, word); next != nil {
+			cmd = next
+		}
+	}
+
+	var candidates []string
+	switch {
+	case pending != nil:
+		if pending.Complete != nil {
+			candidates = pending.Complete(flags)
+		}
+	case strings.HasPrefix(current, "-"):
+		if cmd != nil {
+			for _, flag := range listedFlags(cmd.Flags) {
+				candidates = append(candidates, "--"+flag.Name)
+			}
+		}
+		for _, flag := range listedFlags(g.GlobalFlags) {
+			candidates = append(candidates, "--"+flag.Name)
+		}
+	default:
+		commands := g.Commands
+		if cmd != nil {
+			commands = cmd.Subcommands
+		}
+		for _, sub := range listedCommands(commands) {
+			candidates = append(candidates, sub.Name)
+		}
+	}
+
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, current) {
+			fmt.Fprintln(w, candidate)
+		}
+	}
+}
+
+// lookupFlag finds a command or global flag by its --name or -short form
+func (g *GlobalContext) lookupFlag(cmd *Command, arg string) *Flag {
+	var candidates []*Flag
+	if cmd != nil {
+		candidates = append(candidates, cmd.Flags...)
+	}
+	candidates = append(candidates, g.GlobalFlags...)
+	for _, flag := range candidates {
+		if arg == "--"+flag.Name || (flag.Short != "" && arg == "-"+flag.Short) {
+			return flag
+		}
+	}
+	return nil
+}
+
+// lookupCommand finds a top-level command, or a subcommand of cmd
+func (g *GlobalContext) lookupCommand(cmd *Command, name string) *Command {
+	if cmd == nil {
+		return g.findCommand(name)
+	}
+	return findIn(cmd.Subcommands, name)
+}
+
+// CompletionShells lists the shells GenerateCompletion supports
+var CompletionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+// GenerateCompletion writes a completion script for shell. The script calls
+// back into the application, so it stays current as commands change
+func (g *GlobalContext) GenerateCompletion(w io.Writer, shell string) error {
+	var script string
+	switch shell {
+	case "bash":
+		script = bashCompletion
+	case "zsh":
+		script = zshCompletion
+	case "fish":
+		script = fishCompletion
+	case "powershell":
+		script = powershellCompletion
+	default:
+		return fmt.Errorf("unsupported shell %q: use one of %s", shell, strings.Join(CompletionShells, ", "))
+	}
+
+	function := "_" + regexp.MustCompile(`[^A-Za-z0-9]`).ReplaceAllString(g.AppName, "_")
+	script = strings.NewReplacer(
+		"{{app}}", g.AppName,
+		"{{function}}", function,
+		"{{complete}}", completeCommand,
+	).Replace(script)
+	_, err := io.WriteString(w, script)
+	return err
+}
+
+const bashCompletion = `# bash completion for {{app}}
+{{function}}() {
+    local IFS=$'\n'
+    COMPREPLY=($({{app}} {{complete}} "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F {{function}} {{app}}
+`
+
+const zshCompletion = `#compdef {{app}}
+# zsh completion for {{app}}
+{{function}}() {
+    local -a candidates
+    candidates=("${(@f)$({{app}} {{complete}} "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    if (( ${#candidates} == 1 )) && [[ -z "${candidates[1]}" ]]; then
+        _files
+        return
+    fi
+    compadd -a candidates
+}
+compdef {{function}} {{app}}
+`
+
+const fishCompletion = `# fish completion for {{app}}
+function {{function}}
+    set -l tokens (commandline -opc) (commandline -ct)
+    {{app}} {{complete}} $tokens[2..-1] 2>/dev/null
+end
+complete -c {{app}} -f -n '{{function}} | string length -q' -a '({{function}})'
+complete -c {{app}} -F -n 'not {{function}} | string length -q'
+`
+
+const powershellCompletion = `# PowerShell completion for {{app}}
+Register-ArgumentCompleter -Native -CommandName '{{app}}' -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+    if ($wordToComplete -eq '') { $words += '""' }
+    & '{{app}}' {{complete}} @words 2>$null | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`
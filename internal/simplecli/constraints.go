@@ -0,0 +1,60 @@
+package simplecli
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Constraint is a rule about which flags of a command may be given together.
+// Constraints only check flags given on the command line, so values from
+// environment variables never conflict with each other. A required flag is met
+// by its value, though, wherever it came from: a boolean must be true
+type Constraint struct {
+	// Exclusive lists alternatives of which only one may be used; each
+	// alternative is one or more flag names
+	Exclusive [][]string
+
+	// Flag needs at least one of RequiresOneOf to be given as well
+	Flag          string
+	RequiresOneOf []string
+}
+
+// MutuallyExclusive returns a constraint allowing flags from only one of the
+// alternatives, e.g. MutuallyExclusive([]string{"a"}, []string{"b", "c"})
+func MutuallyExclusive(alternatives ...[]string) Constraint {
+	return Constraint{Exclusive: alternatives}
+}
+
+// Requires returns a constraint that flag is only given together with at
+// least one of the other flags, set from the command line or the environment
+func Requires(flag string, oneOf ...string) Constraint {
+	return Constraint{Flag: flag, RequiresOneOf: oneOf}
+}
+
+// check returns an error describing how the given flags break the constraint.
+// enabled reports whether a required flag is set to a value that meets it
+func (c Constraint) check(given, enabled func(name string) bool) error {
+	var first string
+	for _, alternative := range c.Exclusive {
+		for _, name := range alternative {
+			if !given(name) {
+				continue
+			}
+			if first == "" {
+				first = name
+				break
+			}
+			return fmt.Errorf("--%s cannot be used with --%s", first, name)
+		}
+	}
+
+	if c.Flag != "" && given(c.Flag) {
+		for _, name := range c.RequiresOneOf {
+			if enabled(name) {
+				return nil
+			}
+		}
+		return fmt.Errorf("--%s requires --%s", c.Flag, strings.Join(c.RequiresOneOf, " or --"))
+	}
+	return nil
+}
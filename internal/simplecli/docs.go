@@ -0,0 +1,320 @@
+package simplecli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// docPage is one command page of the generated reference
+type docPage struct {
+	path    []string // command names after the application name
+	command *Command // nil for the application overview
+}
+
+// GenerateDocs writes one reference page per command to dir, as "man" pages
+// (section 1) or "markdown" files
+func (g *GlobalContext) GenerateDocs(dir, format string) error {
+	var render func(docPage) string
+	var fileName func([]string) string
+	switch format {
+	case "man":
+		render = g.manPage
+		fileName = func(path []string) string {
+			return strings.Join(append([]string{g.AppName}, path...), "-") + ".1"
+		}
+	case "markdown":
+		render = g.markdownPage
+		fileName = func(path []string) string {
+			return strings.Join(append([]string{g.AppName}, path...), "_") + ".md"
+		}
+	default:
+		return fmt.Errorf("unsupported docs format %q: use man or markdown", format)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create docs directory: %w", err)
+	}
+
+	for _, page := range g.docPages() {
+		target := filepath.Join(dir, fileName(page.path))
+		if err := os.WriteFile(target, []byte(render(page)), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", target, err)
+		}
+	}
+	return nil
+}
+
+// docPages lists the overview page followed by every command and subcommand
+func (g *GlobalContext) docPages() []docPage {
+	pages := []docPage{{}}
+	var walk func(commands []*Command, parent []string)
+	walk = func(commands []*Command, parent []string) {
+		for _, cmd := range listedCommands(commands) {
+			path := append(append([]string{}, parent...), cmd.Name)
+			pages = append(pages, docPage{path: path, command: cmd})
+			walk(cmd.Subcommands, path)
+		}
+	}
+	walk(g.Commands, nil)
+	return pages
+}
+
+// docBlock is a paragraph of a long description; indented lines are preformatted
+type docBlock struct {
+	preformatted bool
+	lines        []string
+}
+
+// splitDescription groups a long description into text and preformatted blocks
+func splitDescription(text string) []docBlock {
+	var blocks []docBlock
+	var current *docBlock
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			if current != nil && current.preformatted {
+				current.lines = append(current.lines, "")
+			} else {
+				current = nil
+			}
+			continue
+		}
+
+		preformatted := strings.HasPrefix(line, "  ")
+		if current == nil || current.preformatted != preformatted {
+			blocks = append(blocks, docBlock{preformatted: preformatted})
+			current = &blocks[len(blocks)-1]
+		}
+		if preformatted {
+			line = strings.TrimPrefix(line, "  ")
+		}
+		current.lines = append(current.lines, line)
+	}
+
+	// Drop blank lines trailing a preformatted block
+	for i := range blocks {
+		lines := blocks[i].lines
+		for len(lines) > 0 && lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		blocks[i].lines = lines
+	}
+	return blocks
+}
+
+// flagSignature formats the short and long names of a flag with a value placeholder
+func flagSignature(flag *Flag) string {
+	signature := "--" + flag.Name
+	if flag.Short != "" {
+		signature = "-" + flag.Short + ", " + signature
+	}
+	switch flag.Value.(type) {
+	case bool:
+	case []string:
+		signature += " value (repeatable)"
+	default:
+		signature += " value"
+	}
+	return signature
+}
+
+// flagDetails returns the default value and environment variable notes of a flag
+func (g *GlobalContext) flagDetails(flag *Flag) []string {
+	var details []string
+	if value := defaultText(flag); value != "" {
+		details = append(details, fmt.Sprintf("Default: %s", value))
+	}
+	if envVar := g.envVar(flag); envVar != "" {
+		details = append(details, fmt.Sprintf("Environment: %s", envVar))
+	}
+	if flag.Required {
+		details = append(details, "Required")
+	}
+	return details
+}
+
+// defaultText formats the default value of a flag, or "" when it is empty or zero
+func defaultText(flag *Flag) string {
+	switch value := flag.Value.(type) {
+	case string:
+		return value
+	case int:
+		if value != 0 {
+			return strconv.Itoa(value)
+		}
+	case time.Duration:
+		if value != 0 {
+			return value.String()
+		}
+	case float64:
+		if value != 0 {
+			return strconv.FormatFloat(value, 'g', -1, 64)
+		}
+	}
+	return ""
+}
+
+// pageTitle returns the full command line of a page, e.g. "app config init"
+func (g *GlobalContext) pageTitle(path []string) string {
+	return strings.Join(append([]string{g.AppName}, path...), " ")
+}
+
+// pageText returns the summary, usage and description of a page
+func (g *GlobalContext) pageText(page docPage) (summary, usage, description string, subcommands []*Command, flags []*Flag) {
+	if page.command == nil {
+		return firstLine(g.Description), g.AppName + " [command]", g.Description, listedCommands(g.Commands), nil
+	}
+	cmd := page.command
+	description = cmd.LongDesc
+	if description == "" {
+		description = cmd.Description
+	}
+	// Usage strings already start with the parent command names
+	usage = g.AppName + " " + cmd.Usage
+	return cmd.Description, usage, description, listedCommands(cmd.Subcommands), listedFlags(cmd.Flags)
+}
+
+// firstLine returns the first line of text
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	return strings.TrimSpace(line)
+}
+
+// markdownPage renders a page as markdown
+func (g *GlobalContext) markdownPage(page docPage) string {
+	summary, usage, description, subcommands, flags := g.pageText(page)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n%s\n\n", g.pageTitle(page.path), summary)
+	fmt.Fprintf(&b, "## Usage\n\n```\n%s\n```\n\n", usage)
+
+	b.WriteString("## Description\n\n")
+	for _, block := range splitDescription(description) {
+		if block.preformatted {
+			fmt.Fprintf(&b, "```\n%s\n```\n\n", strings.Join(block.lines, "\n"))
+		} else {
+			fmt.Fprintf(&b, "%s\n\n", strings.Join(block.lines, "\n"))
+		}
+	}
+
+	if len(subcommands) > 0 {
+		b.WriteString("## Commands\n\n")
+		for _, sub := range subcommands {
+			path := append(append([]string{}, page.path...), sub.Name)
+			fmt.Fprintf(&b, "- [%s](%s.md) - %s", g.pageTitle(path), strings.Join(append([]string{g.AppName}, path...), "_"), sub.Description)
+			if len(sub.Aliases) > 0 {
+				fmt.Fprintf(&b, " (aliases: %s)", strings.Join(sub.Aliases, ", "))
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+
+	writeFlags := func(title string, flags []*Flag) {
+		if len(flags) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "## %s\n\n", title)
+		for _, flag := range flags {
+			fmt.Fprintf(&b, "- `%s` - %s", flagSignature(flag), flag.Description)
+			if details := g.flagDetails(flag); len(details) > 0 {
+				fmt.Fprintf(&b, " (%s)", strings.Join(details, "; "))
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	writeFlags("Flags", flags)
+	writeFlags("Global Flags", listedFlags(g.GlobalFlags))
+
+	if page.command != nil {
+		parent := page.path[:len(page.path)-1]
+		fmt.Fprintf(&b, "## See Also\n\n- [%s](%s.md)\n", g.pageTitle(parent), strings.Join(append([]string{g.AppName}, parent...), "_"))
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// manPage renders a page as a roff man page in section 1
+func (g *GlobalContext) manPage(page docPage) string {
+	summary, usage, description, subcommands, flags := g.pageText(page)
+	name := strings.Join(append([]string{g.AppName}, page.path...), "-")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, ".TH %q 1 \"\" %q \"User Commands\"\n", strings.ToUpper(name), strings.TrimSpace(g.AppName+" "+g.Version))
+	fmt.Fprintf(&b, ".SH NAME\n%s \\- %s\n", roffEscape(name), roffEscape(summary))
+	fmt.Fprintf(&b, ".SH SYNOPSIS\n.B %s\n", roffEscape(usage))
+
+	b.WriteString(".SH DESCRIPTION\n")
+	for i, block := range splitDescription(description) {
+		if i > 0 {
+			b.WriteString(".PP\n")
+		}
+		if block.preformatted {
+			b.WriteString(".RS\n.nf\n")
+			for _, line := range block.lines {
+				fmt.Fprintf(&b, "%s\n", roffEscape(line))
+			}
+			b.WriteString(".fi\n.RE\n")
+		} else {
+			fmt.Fprintf(&b, "%s\n", roffEscape(strings.Join(block.lines, "\n")))
+		}
+	}
+
+	if len(subcommands) > 0 {
+		b.WriteString(".SH COMMANDS\n")
+		for _, sub := range subcommands {
+			names := strings.Join(append([]string{sub.Name}, sub.Aliases...), ", ")
+			fmt.Fprintf(&b, ".TP\n.B %s\n%s\n", roffEscape(names), roffEscape(sub.Description))
+		}
+	}
+
+	writeFlags := func(title string, flags []*Flag) {
+		if len(flags) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, ".SH %s\n", title)
+		for _, flag := range flags {
+			fmt.Fprintf(&b, ".TP\n.B %s\n%s\n", roffEscape(flagSignature(flag)), roffEscape(flag.Description))
+			for _, detail := range g.flagDetails(flag) {
+				fmt.Fprintf(&b, ".br\n%s\n", roffEscape(detail))
+			}
+		}
+	}
+	writeFlags("OPTIONS", flags)
+	writeFlags("GLOBAL OPTIONS", listedFlags(g.GlobalFlags))
+
+	var related []string
+	if page.command != nil {
+		parent := page.path[:len(page.path)-1]
+		related = append(related, strings.Join(append([]string{g.AppName}, parent...), "-"))
+	}
+	for _, sub := range subcommands {
+		related = append(related, name+"-"+sub.Name)
+	}
+	if len(related) > 0 {
+		b.WriteString(".SH SEE ALSO\n")
+		for i, page := range related {
+			separator := ","
+			if i == len(related)-1 {
+				separator = ""
+			}
+			fmt.Fprintf(&b, ".BR %s (1)%s\n", roffEscape(page), separator)
+		}
+	}
+	return b.String()
+}
+
+// roffEscape escapes text for roff, so lines are never read as requests
+func roffEscape(text string) string {
+	text = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(text)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,265 @@
+package simplecli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const (
+	defaultWidth = 80
+	minWidth     = 40
+
+	// maxNameColumn is the widest the name column of help entries gets; longer
+	// names put their description on the next line
+	maxNameColumn = 28
+)
+
+// DefaultHelpTemplate renders the help of the application and of each command.
+// It is executed with HelpData and can use the wrap, entries and join functions
+const DefaultHelpTemplate = `{{if .Deprecated}}Deprecated: {{.Deprecated}}
+
+{{end}}{{wrap .Description}}
+
+Usage:
+  {{.Usage}}
+{{if .Aliases}}
+Aliases:
+  {{join .Aliases ", "}}
+{{end}}{{if .Examples}}
+Examples:
+{{.Examples}}
+{{end}}{{range .Sections}}
+{{.Title}}:
+{{entries .Entries}}{{end}}{{if .Footer}}
+{{wrap .Footer}}
+{{end}}`
+
+// HelpEntry is a command or flag listed in help
+type HelpEntry struct {
+	Name        string
+	Description string
+}
+
+// HelpSection is a titled list of commands or flags
+type HelpSection struct {
+	Title   string
+	Entries []HelpEntry
+}
+
+// HelpData is what help templates are executed with
+type HelpData struct {
+	AppName     string
+	Usage       string
+	Description string
+	Deprecated  string
+	Aliases     []string
+	Examples    string
+	Sections    []HelpSection
+	Footer      string
+
+	// Width is the number of columns help is wrapped to
+	Width int
+}
+
+// SetHelpTemplate replaces DefaultHelpTemplate with a text/template that is
+// executed with HelpData
+func (g *GlobalContext) SetHelpTemplate(text string) error {
+	tmpl, err := template.New("help").Funcs(helpFuncs(defaultWidth)).Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid help template: %w", err)
+	}
+	g.helpTemplate = tmpl
+	return nil
+}
+
+// InCategory sets the help category of flags and returns them, so flags can be
+// grouped where they are declared, e.g. InCategory("Authentication", flags)
+func InCategory(category string, flags []*Flag) []*Flag {
+	for _, flag := range flags {
+		flag.Category = category
+	}
+	return flags
+}
+
+// printHelp prints the main help message
+func (g *GlobalContext) printHelp() {
+	data := HelpData{
+		AppName:     g.AppName,
+		Usage:       g.AppName + " [command]",
+		Description: g.Description,
+		Footer:      fmt.Sprintf("Use \"%s [command] --help\" for more information about a command.", g.AppName),
+	}
+	data.Sections = append(data.Sections, commandSection("Available Commands", g.Commands)...)
+	data.Sections = append(data.Sections, g.flagSections("Flags", g.GlobalFlags)...)
+	g.renderHelp(os.Stdout, data)
+}
+
+// printCommandHelp prints help for a specific command
+func (g *GlobalContext) printCommandHelp(cmd *Command) {
+	data := HelpData{
+		AppName:     g.AppName,
+		Usage:       g.AppName + " " + cmd.Usage,
+		Description: cmd.LongDesc,
+		Deprecated:  cmd.Deprecated,
+		Examples:    cmd.Examples,
+	}
+	if len(cmd.Aliases) > 0 {
+		data.Aliases = append([]string{cmd.Name}, cmd.Aliases...)
+	}
+	data.Sections = append(data.Sections, commandSection("Available Commands", cmd.Subcommands)...)
+	data.Sections = append(data.Sections, g.flagSections("Flags", cmd.Flags)...)
+	data.Sections = append(data.Sections, g.flagSections("Global Flags", g.GlobalFlags)...)
+	g.renderHelp(os.Stdout, data)
+}
+
+// renderHelp executes the help template for the current terminal width
+func (g *GlobalContext) renderHelp(w io.Writer, data HelpData) {
+	data.Width = terminalWidth()
+	tmpl := g.helpTemplate
+	if tmpl == nil {
+		tmpl = template.Must(template.New("help").Funcs(helpFuncs(data.Width)).Parse(DefaultHelpTemplate))
+	} else {
+		tmpl = template.Must(tmpl.Clone()).Funcs(helpFuncs(data.Width))
+	}
+	if err := tmpl.Execute(w, data); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to render help: %v\n", err)
+	}
+}
+
+// commandSection lists the commands shown in help, if any
+func commandSection(title string, commands []*Command) []HelpSection {
+	var entries []HelpEntry
+	for _, cmd := range listedCommands(commands) {
+		entries = append(entries, HelpEntry{Name: cmd.Name, Description: cmd.Description})
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	return []HelpSection{{Title: title, Entries: entries}}
+}
+
+// flagSections groups the flags shown in help by category. Flags without a
+// category come first under title, the others under "<Category> Flags" in
+// the order the categories first appear
+func (g *GlobalContext) flagSections(title string, flags []*Flag) []HelpSection {
+	sections := []HelpSection{{Title: title}}
+	index := map[string]int{"": 0}
+	for _, flag := range listedFlags(flags) {
+		i, ok := index[flag.Category]
+		if !ok {
+			i = len(sections)
+			index[flag.Category] = i
+			sections = append(sections, HelpSection{Title: flag.Category + " Flags"})
+		}
+		sections[i].Entries = append(sections[i].Entries, HelpEntry{Name: flagName(flag), Description: g.flagHelp(flag)})
+	}
+
+	var shown []HelpSection
+	for _, section := range sections {
+		if len(section.Entries) > 0 {
+			shown = append(shown, section)
+		}
+	}
+	return shown
+}
+
+// flagName returns the short and long form of a flag, e.g. "-t, --to"
+func flagName(flag *Flag) string {
+	if flag.Short != "" {
+		return "-" + flag.Short + ", --" + flag.Name
+	}
+	return "--" + flag.Name
+}
+
+// helpFuncs returns the functions available to help templates
+func helpFuncs(width int) template.FuncMap {
+	return template.FuncMap{
+		"wrap": func(text string) string {
+			return wrapText(text, width)
+		},
+		"entries": func(entries []HelpEntry) string {
+			return formatEntries(entries, width)
+		},
+		"join": strings.Join,
+	}
+}
+
+// formatEntries lays entries out in two columns, wrapping descriptions to width
+func formatEntries(entries []HelpEntry, width int) string {
+	column := 0
+	for _, entry := range entries {
+		if len(entry.Name) <= maxNameColumn {
+			column = max(column, len(entry.Name))
+		}
+	}
+	indent := strings.Repeat(" ", 2+column+2)
+
+	var b strings.Builder
+	for _, entry := range entries {
+		lines := wrapLine(entry.Description, max(width-len(indent), minWidth/2))
+		if len(entry.Name) > column {
+			fmt.Fprintf(&b, "  %s\n", entry.Name)
+		} else if len(lines) > 0 {
+			fmt.Fprintf(&b, "  %-*s  %s\n", column, entry.Name, lines[0])
+			lines = lines[1:]
+		} else {
+			fmt.Fprintf(&b, "  %s\n", entry.Name)
+		}
+		for _, line := range lines {
+			fmt.Fprintf(&b, "%s%s\n", indent, line)
+		}
+	}
+	return b.String()
+}
+
+// wrapText wraps each line of text to width. Indented lines, such as
+// examples, are kept as they are
+func wrapText(text string, width int) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line == "" || line[0] == ' ' || line[0] == '\t' {
+			lines = append(lines, line)
+			continue
+		}
+		lines = append(lines, wrapLine(line, width)...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrapLine breaks text into lines of at most width columns at spaces. Words
+// longer than width get a line of their own
+func wrapLine(text string, width int) []string {
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(text) {
+		switch {
+		case line == "":
+			line = word
+		case len(line)+1+len(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// terminalWidth returns the width of the terminal from $COLUMNS or stdout,
+// or 80 columns when output is not a terminal
+func terminalWidth() int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return max(columns, minWidth)
+	}
+	if columns := stdoutColumns(); columns > 0 {
+		return max(columns, minWidth)
+	}
+	return defaultWidth
+}
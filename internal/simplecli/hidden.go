@@ -0,0 +1,53 @@
+package simplecli
+
+import (
+	"fmt"
+	"io"
+)
+
+// listed reports whether a flag is shown in help, docs and completions.
+// Hidden and deprecated flags still work when given
+func (f *Flag) listed() bool {
+	return !f.Hidden && f.Deprecated == ""
+}
+
+// listed reports whether a command is shown in help, docs, completions and
+// suggestions. Hidden and deprecated commands still run when named
+func (c *Command) listed() bool {
+	return !c.Hidden && c.Deprecated == ""
+}
+
+// listedFlags returns the flags shown in help, docs and completions
+func listedFlags(flags []*Flag) []*Flag {
+	var listed []*Flag
+	for _, flag := range flags {
+		if flag.listed() {
+			listed = append(listed, flag)
+		}
+	}
+	return listed
+}
+
+// listedCommands returns the commands shown in help, docs and completions
+func listedCommands(commands []*Command) []*Command {
+	var listed []*Command
+	for _, cmd := range commands {
+		if cmd.listed() {
+			listed = append(listed, cmd)
+		}
+	}
+	return listed
+}
+
+// warnDeprecated prints a warning for a deprecated command and for each
+// deprecated flag that is set, with the replacement hint
+func (g *GlobalContext) warnDeprecated(w io.Writer, ctx *Context) {
+	if ctx.Command.Deprecated != "" {
+		fmt.Fprintf(w, "Warning: command %q is deprecated, %s\n", ctx.Command.Name, ctx.Command.Deprecated)
+	}
+	for _, flag := range append(append([]*Flag{}, g.GlobalFlags...), ctx.Command.Flags...) {
+		if flag.Deprecated != "" && ctx.IsSet(flag.Name) {
+			fmt.Fprintf(w, "Warning: flag --%s is deprecated, %s\n", flag.Name, flag.Deprecated)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package simplecli
+
+import (
+	"fmt"
+	"strings"
+)
+
+// hasName reports whether name is the command's name or one of its aliases
+func (c *Command) hasName(name string) bool {
+	if c.Name == name {
+		return true
+	}
+	for _, alias := range c.Aliases {
+		if alias == name {
+			return true
+		}
+	}
+	return false
+}
+
+// findIn finds a command by name or alias
+func findIn(commands []*Command, name string) *Command {
+	for _, cmd := range commands {
+		if cmd.hasName(name) {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// suggestCommands returns the names of the commands that name is probably a
+// typo or abbreviation of
+func suggestCommands(commands []*Command, name string) []string {
+	var suggestions []string
+	for _, cmd := range listedCommands(commands) {
+		for _, candidate := range append([]string{cmd.Name}, cmd.Aliases...) {
+			if editDistance(name, candidate) <= maxEdits(candidate) || (len(name) > 1 && strings.HasPrefix(candidate, name)) {
+				suggestions = append(suggestions, cmd.Name)
+				break
+			}
+		}
+	}
+	return suggestions
+}
+
+// maxEdits returns how many edits a typo of name may have, so short names
+// like aliases don't match everything
+func maxEdits(name string) int {
+	return min(max(len(name)/3, 1), 2)
+}
+
+// unknownCommandError returns the error for an unknown command or subcommand,
+// suggesting similar ones
+func unknownCommandError(kind string, commands []*Command, name string) error {
+	suggestions := suggestCommands(commands, name)
+	if len(suggestions) == 0 {
+		return fmt.Errorf("unknown %s: %s", kind, name)
+	}
+	return fmt.Errorf("unknown %s: %s, did you mean %s?", kind, name, strings.Join(suggestions, " or "))
+}
+
+// editDistance returns the edit distance between a and b, counting swapped
+// adjacent letters as a single edit
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package simplecli
+
+// stdoutColumns returns 0 where the terminal size cannot be queried, so
+// $COLUMNS or the default width is used
+func stdoutColumns() int {
+	return 0
+}
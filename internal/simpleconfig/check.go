@@ -0,0 +1,275 @@
+package simpleconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/groovy-sky/azemailsender"
+)
+
+// Problem is an issue found in a config file
+type Problem struct {
+	Line    int
+	Key     string
+	Message string
+}
+
+// entry is one key of a JSON object with the line it is on
+type entry struct {
+	key   string
+	value json.RawMessage
+	line  int
+	start int64 // offset of the value in the file
+}
+
+// Check validates the config file found the same way as by LoadConfig: unknown
+// keys, value types, durations, endpoints and conflicting authentication
+// settings. It returns the path checked and the problems sorted by line
+func Check(configFile string) (string, []Problem, error) {
+	path := FilePath(configFile)
+	if path == "" {
+		return "", nil, fmt.Errorf("no configuration file found; create one with 'config init'")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	var syntaxErr *json.SyntaxError
+	var anything interface{}
+	if err := json.Unmarshal(data, &anything); errors.As(err, &syntaxErr) {
+		return path, []Problem{{Line: lineAt(data, syntaxErr.Offset), Message: syntaxErr.Error()}}, nil
+	} else if err != nil {
+		return path, []Problem{{Line: 1, Message: err.Error()}}, nil
+	}
+
+	c := &checker{data: data}
+	root, ok := c.object(0, data, "", "the configuration")
+	if ok {
+		c.checkScope(root, false)
+		c.checkRoot(root)
+	}
+
+	sort.SliceStable(c.problems, func(i, j int) bool {
+		return c.problems[i].Line < c.problems[j].Line
+	})
+	return path, c.problems, nil
+}
+
+// checker collects problems while walking a config file
+type checker struct {
+	data     []byte
+	problems []Problem
+}
+
+func (c *checker) add(line int, key, format string, args ...interface{}) {
+	c.problems = append(c.problems, Problem{Line: line, Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+// object parses the JSON object at offset start of the file, reporting a
+// problem when the value is not an object
+func (c *checker) object(start int64, raw json.RawMessage, key, what string) ([]entry, bool) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		c.add(lineAt(c.data, start), key, "%s must be an object", what)
+		return nil, false
+	}
+
+	var entries []entry
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return entries, false
+		}
+		name, _ := tok.(string)
+		line := lineAt(c.data, start+dec.InputOffset())
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return entries, false
+		}
+		valueStart := start + dec.InputOffset() - int64(len(value))
+		entries = append(entries, entry{key: name, value: value, line: line, start: valueStart})
+	}
+	return entries, true
+}
+
+// checkRoot checks the keys that only exist at the top level
+func (c *checker) checkRoot(root []entry) {
+	var profiles []entry
+	var selected *entry
+	for i, e := range root {
+		switch e.key {
+		case "profiles":
+			entries, ok := c.object(e.start, e.value, e.key, `"profiles"`)
+			if !ok {
+				continue
+			}
+			profiles = entries
+			for _, profile := range entries {
+				what := fmt.Sprintf("profile %q", profile.key)
+				if fields, ok := c.object(profile.start, profile.value, profile.key, what); ok {
+					c.checkScope(fields, true)
+				}
+			}
+		case "aliases":
+			entries, ok := c.object(e.start, e.value, e.key, `"aliases"`)
+			if !ok {
+				continue
+			}
+			for _, alias := range entries {
+				var members []string
+				if err := json.Unmarshal(alias.value, &members); err != nil {
+					c.add(alias.line, alias.key, "alias %q must be a list of addresses or aliases", alias.key)
+				}
+			}
+		case "profile":
+			selected = &root[i]
+		}
+	}
+
+	if selected != nil {
+		var name string
+		if json.Unmarshal(selected.value, &name) == nil && name != "" {
+			found := false
+			for _, profile := range profiles {
+				found = found || profile.key == name
+			}
+			if !found {
+				c.add(selected.line, "profile", "profile %q is not defined in \"profiles\"", name)
+			}
+		}
+	}
+}
+
+// checkScope checks the settings of the top level or of a profile
+func (c *checker) checkScope(entries []entry, forProfile bool) {
+	fields := settableFields(forProfile)
+	values := map[string]entry{}
+	for _, e := range entries {
+		kind, ok := fields[e.key]
+		if !ok {
+			if !forProfile && (e.key == "profiles" || e.key == "aliases") {
+				continue
+			}
+			if suggestion := closestKey(e.key, fields); suggestion != "" {
+				c.add(e.line, e.key, "unknown key %q, did you mean %q?", e.key, suggestion)
+			} else {
+				c.add(e.line, e.key, "unknown key %q", e.key)
+			}
+			continue
+		}
+
+		if kind == reflect.Bool {
+			var b bool
+			if json.Unmarshal(e.value, &b) != nil {
+				c.add(e.line, e.key, "%q must be true or false", e.key)
+			}
+			continue
+		}
+		var value string
+		if json.Unmarshal(e.value, &value) != nil {
+			c.add(e.line, e.key, "%q must be a string", e.key)
+			continue
+		}
+		values[e.key] = e
+		if value == "" || IsEncrypted(value) {
+			continue
+		}
+		if err := checkValue(e.key, value, kind); err != nil {
+			c.add(e.line, e.key, "%v", err)
+		}
+	}
+
+	// The connection string wins, so endpoint and access-key would be ignored
+	if cs, ok := values["connection-string"]; ok && string(cs.value) != `""` {
+		for _, key := range []string{"endpoint", "access-key"} {
+			if e, ok := values[key]; ok && string(e.value) != `""` {
+				c.add(cs.line, "connection-string", "connection-string conflicts with %s on line %d; only the connection string is used", key, e.line)
+			}
+		}
+	}
+}
+
+// checkValue validates a single string setting
+func checkValue(key, value string, kind reflect.Kind) error {
+	switch key {
+	case "endpoint":
+		_, err := azemailsender.NormalizeEndpoint(value)
+		return err
+	case "connection-string":
+		if _, err := azemailsender.NewClientFromConnectionString(value, nil); err != nil {
+			return err
+		}
+	case "proxy":
+		if parsed, err := url.Parse(value); err != nil || parsed.Host == "" {
+			return fmt.Errorf("invalid proxy URL %q", value)
+		}
+	case "transport":
+		if _, err := azemailsender.NewTransport(value); err != nil {
+			return err
+		}
+	case "fallback-transport":
+		for _, transport := range strings.Split(value, ",") {
+			if _, err := azemailsender.NewTransport(strings.TrimSpace(transport)); err != nil {
+				return err
+			}
+		}
+	case "retention":
+		if _, err := ParseRetention(value); err != nil {
+			return err
+		}
+	case "tls-min-version":
+		if value != "1.2" && value != "1.3" {
+			return fmt.Errorf("invalid tls-min-version %q: use 1.2 or 1.3", value)
+		}
+	}
+	_, err := encodeValue(key, value, kind)
+	return err
+}
+
+// closestKey returns the known key within two edits of key, if any
+func closestKey(key string, fields map[string]reflect.Kind) string {
+	best, bestDistance := "", 3
+	for candidate := range fields {
+		if d := editDistance(key, candidate); d < bestDistance || (d == bestDistance && candidate < best) {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+// lineAt returns the 1-based line of offset in data
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
@@ -4,10 +4,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// EnvPrefix starts the names of the environment variables read by LoadConfig.
+// Builds can change it to namespace the variables of an organization
+var EnvPrefix = "AZURE_EMAIL_"
+
+// EnvName returns the full name of an environment variable, e.g. EnvName("FROM")
+func EnvName(name string) string {
+	return EnvPrefix + name
+}
+
 // Config represents the CLI configuration
 type Config struct {
 	// Authentication
@@ -15,6 +28,10 @@ type Config struct {
 	AccessKey        string `json:"access-key"`
 	ConnectionString string `json:"connection-string"`
 
+	// Files holding the secrets, such as mounted Kubernetes secrets
+	AccessKeyFile        string `json:"access-key-file,omitempty"`
+	ConnectionStringFile string `json:"connection-string-file,omitempty"`
+
 	// Email settings
 	From    string `json:"from"`
 	ReplyTo string `json:"reply-to"`
@@ -24,28 +41,146 @@ type Config struct {
 	Quiet bool `json:"quiet"`
 	JSON  bool `json:"json"`
 
+	// Log settings
+	LogFile string `json:"log-file,omitempty"`
+
+	// Retention is the default age, such as 30d, beyond which history prune
+	// removes captures, audit entries and rotated log files
+	Retention string `json:"retention,omitempty"`
+
+	// QueueDir is the outbox directory used by the queue commands
+	QueueDir string `json:"queue-dir,omitempty"`
+
+	// TemplateDir holds the named templates managed by the templates commands
+	TemplateDir string `json:"template-dir,omitempty"`
+
+	// DedupeDir records recently sent messages for --dedupe-window
+	DedupeDir string `json:"dedupe-dir,omitempty"`
+
+	// Suppression settings
+	SuppressionFile string `json:"suppression-file"`
+
+	// Network settings
+	CAFile        string `json:"ca-file,omitempty"`
+	ClientCert    string `json:"client-cert,omitempty"`
+	ClientKey     string `json:"client-key,omitempty"`
+	TLSMinVersion string `json:"tls-min-version,omitempty"`
+	Proxy         string `json:"proxy,omitempty"`
+
+	// Transport sends through SMTP, writes .eml files or discards messages
+	// instead of using the Azure Communication Services API
+	Transport string `json:"transport,omitempty"`
+
+	// FallbackTransport lists transport URLs, separated by commas, tried in
+	// order when sending through the transport fails
+	FallbackTransport string `json:"fallback-transport,omitempty"`
+
+	// Request settings
+	HTTPTimeout string `json:"http-timeout,omitempty"`
+	MaxRetries  string `json:"max-retries,omitempty"`
+	RetryDelay  string `json:"retry-delay,omitempty"`
+
 	// Wait settings
 	Wait         bool   `json:"wait"`
 	PollInterval string `json:"poll-interval"`
 	MaxWaitTime  string `json:"max-wait-time"`
+	PollBackoff  bool   `json:"poll-backoff,omitempty"`
+
+	// Sends to more recipients than this ask for confirmation; 0 disables the prompt
+	ConfirmThreshold string `json:"confirm-threshold,omitempty"`
+
+	// Address book: an alias expands to one or more addresses or other aliases
+	Aliases map[string][]string `json:"aliases,omitempty"`
+
+	// Categories holds the defaults of message categories such as alerts or
+	// reports, by name
+	Categories map[string]*Category `json:"categories,omitempty"`
+
+	// Profiles
+	Profile  string              `json:"profile,omitempty"`
+	Profiles map[string]*Profile `json:"profiles,omitempty"`
+
+	// Sources records where each effective setting came from, by key: "default",
+	// "file <path>", "profile <name>", "k8s <path>", "env <VAR>" or "flag --<name>"
+	Sources map[string]string `json:"-"`
 }
 
-// LoadConfig loads configuration with priority: defaults -> config file -> env vars -> CLI flags
+// Profile holds the settings of a named sender profile
+type Profile struct {
+	Endpoint         string `json:"endpoint,omitempty"`
+	AccessKey        string `json:"access-key,omitempty"`
+	ConnectionString string `json:"connection-string,omitempty"`
+	From             string `json:"from,omitempty"`
+	ReplyTo          string `json:"reply-to,omitempty"`
+
+	AccessKeyFile        string `json:"access-key-file,omitempty"`
+	ConnectionStringFile string `json:"connection-string-file,omitempty"`
+
+	// Defaults applied when the profile is selected
+	SuppressionFile  string `json:"suppression-file,omitempty"`
+	PollInterval     string `json:"poll-interval,omitempty"`
+	MaxWaitTime      string `json:"max-wait-time,omitempty"`
+	ConfirmThreshold string `json:"confirm-threshold,omitempty"`
+	Proxy            string `json:"proxy,omitempty"`
+}
+
+// Category holds the defaults of the messages of a category
+type Category struct {
+	From            string `json:"from,omitempty"`
+	ReplyTo         string `json:"reply-to,omitempty"`
+	DisableTracking bool   `json:"disable-tracking,omitempty"`
+	// Priority is high, normal or low
+	Priority string `json:"priority,omitempty"`
+	// RateLimit caps the sends of the category per second
+	RateLimit float64 `json:"rate-limit,omitempty"`
+	// Throttle limits the messages each recipient gets from the daemon, as
+	// LIMIT/WINDOW[:OVERFLOW] such as 5/1h:digest
+	Throttle string `json:"throttle,omitempty"`
+}
+
+// LoadConfig loads configuration with priority: defaults -> config file -> profile -> mounted k8s directories -> env vars -> CLI flags
 func LoadConfig(configFile string, cliFlags map[string]interface{}) (*Config, error) {
 	// Start with defaults
 	config := &Config{
-		Debug:        false,
-		Quiet:        false,
-		JSON:         false,
-		Wait:         false,
-		PollInterval: "5s",
-		MaxWaitTime:  "5m",
+		Debug:            false,
+		Quiet:            false,
+		JSON:             false,
+		Wait:             false,
+		PollInterval:     "5s",
+		MaxWaitTime:      "5m",
+		ConfirmThreshold: "10",
+		HTTPTimeout:      "30s",
+		MaxRetries:       "3",
+		RetryDelay:       "1s",
+		Sources:          map[string]string{},
+	}
+	for _, key := range []string{"debug", "quiet", "json", "wait", "poll-interval", "max-wait-time", "poll-backoff", "confirm-threshold", "http-timeout", "max-retries", "retry-delay"} {
+		config.Sources[key] = "default"
 	}
 
 	// Load from config file (if exists)
 	if err := loadFromFile(config, configFile); err != nil {
 		return nil, err
 	}
+	if err := decryptSecrets(config, KeyFile(cliFlags)); err != nil {
+		return nil, err
+	}
+
+	// Apply the selected profile on top of the file settings
+	if err := applyProfile(config, SelectedProfile(config, cliFlags)); err != nil {
+		return nil, err
+	}
+
+	// Apply mounted Kubernetes ConfigMap and Secret directories
+	if from := ConfigFrom(cliFlags); from != "" {
+		dirs, err := ParseConfigFrom(from)
+		if err != nil {
+			return nil, err
+		}
+		if err := loadFromDirs(config, dirs); err != nil {
+			return nil, err
+		}
+	}
 
 	// Override with environment variables
 	loadFromEnv(config)
@@ -53,31 +188,112 @@ func LoadConfig(configFile string, cliFlags map[string]interface{}) (*Config, er
 	// Override with CLI flags
 	loadFromFlags(config, cliFlags)
 
+	if err := readSecretFiles(config); err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
 
-// loadFromFile loads configuration from JSON file
-func loadFromFile(config *Config, configFile string) error {
-	var filePath string
-
+// FilePath returns the config file LoadConfig reads: configFile when given,
+// otherwise the first existing file in the search path, or "" if there is none
+func FilePath(configFile string) string {
 	if configFile != "" {
-		filePath = configFile
-	} else {
-		// Look for config file in common locations
-		searchPaths := []string{
-			"./azemailsender.json",
-			os.ExpandEnv("$HOME/.config/azemailsender/azemailsender.json"),
-			"/etc/azemailsender/azemailsender.json",
-		}
-
-		for _, path := range searchPaths {
-			if _, err := os.Stat(path); err == nil {
-				filePath = path
-				break
-			}
+		return configFile
+	}
+
+	for _, path := range SearchPaths() {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// SearchPaths lists the config file locations in the order they are tried:
+// the current directory, %APPDATA% on Windows, $XDG_CONFIG_HOME, $HOME/.config
+// and /etc on other systems
+func SearchPaths() []string {
+	paths := []string{"./azemailsender.json"}
+	for _, dir := range userConfigDirs() {
+		paths = append(paths, filepath.Join(dir, "azemailsender", "azemailsender.json"))
+	}
+	if runtime.GOOS != "windows" {
+		paths = append(paths, "/etc/azemailsender/azemailsender.json")
+	}
+	return paths
+}
+
+// DefaultPath returns the per-user config file location for this platform:
+// %APPDATA%\azemailsender on Windows, otherwise $XDG_CONFIG_HOME/azemailsender
+// or $HOME/.config/azemailsender
+func DefaultPath() string {
+	dirs := userConfigDirs()
+	if len(dirs) == 0 {
+		return "./azemailsender.json"
+	}
+	return filepath.Join(dirs[0], "azemailsender", "azemailsender.json")
+}
+
+// userConfigDirs returns the per-user configuration directories that are set,
+// most specific first and without duplicates
+func userConfigDirs() []string {
+	var candidates []string
+	if runtime.GOOS == "windows" {
+		candidates = append(candidates, os.Getenv("APPDATA"))
+	}
+	// Only absolute XDG paths are valid, per the base directory specification
+	if dir := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(dir) {
+		candidates = append(candidates, dir)
+	}
+	if home := os.Getenv("HOME"); home != "" {
+		candidates = append(candidates, filepath.Join(home, ".config"))
+	}
+
+	var dirs []string
+	seen := map[string]bool{}
+	for _, dir := range candidates {
+		if dir != "" && !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
 		}
 	}
+	return dirs
+}
+
+// LoadFile reads only the config file, without the selected profile,
+// environment variables or flags applied
+func LoadFile(configFile string) (*Config, error) {
+	config := &Config{Sources: map[string]string{}}
+	if err := loadFromFile(config, configFile); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// SetDefaultProfile makes name the profile used when no --profile flag or
+// AZURE_EMAIL_PROFILE is given, by setting the "profile" key of the config file.
+// Other keys are kept. It returns the path of the updated file
+func SetDefaultProfile(configFile, name string) (string, error) {
+	return editFile(configFile, false, func(root *object) error {
+		rawProfiles, _ := root.get("profiles")
+		profiles, err := parseObject(rawProfiles)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal profiles: %w", err)
+		}
+		if _, ok := profiles.get(name); !ok {
+			return fmt.Errorf("profile %q not found in configuration", name)
+		}
+
+		raw, _ := json.Marshal(name)
+		root.set("profile", raw)
+		return nil
+	})
+}
 
+// loadFromFile loads configuration from JSON file
+func loadFromFile(config *Config, configFile string) error {
+	filePath := FilePath(configFile)
 	if filePath == "" {
 		return nil // No config file found, that's OK
 	}
@@ -97,82 +313,290 @@ func loadFromFile(config *Config, configFile string) error {
 		return fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	if root, err := parseObject(data); err == nil {
+		for _, f := range root {
+			config.setSource(f.key, "file "+filePath)
+		}
+	}
+
 	return nil
 }
 
-// loadFromEnv loads configuration from environment variables
-func loadFromEnv(config *Config) {
-	envMap := map[string]*string{
-		"AZURE_EMAIL_ENDPOINT":          &config.Endpoint,
-		"AZURE_EMAIL_ACCESS_KEY":        &config.AccessKey,
-		"AZURE_EMAIL_CONNECTION_STRING": &config.ConnectionString,
-		"AZURE_EMAIL_FROM":              &config.From,
-		"AZURE_EMAIL_REPLY_TO":          &config.ReplyTo,
-	}
-
-	for envVar, field := range envMap {
-		if value := os.Getenv(envVar); value != "" {
-			*field = value
-		}
+// setSource records where the value of key came from
+func (c *Config) setSource(key, source string) {
+	if c.Sources == nil {
+		c.Sources = map[string]string{}
 	}
+	c.Sources[key] = source
+}
 
-	boolEnvMap := map[string]*bool{
-		"AZURE_EMAIL_DEBUG": &config.Debug,
-		"AZURE_EMAIL_QUIET": &config.Quiet,
-		"AZURE_EMAIL_JSON":  &config.JSON,
-		"AZURE_EMAIL_WAIT":  &config.Wait,
+// ProfileNames returns the sorted profile names defined in the config file,
+// found the same way as by LoadConfig
+func ProfileNames(configFile string) []string {
+	config, err := LoadFile(configFile)
+	if err != nil {
+		return nil
 	}
 
-	for envVar, field := range boolEnvMap {
-		if value := os.Getenv(envVar); value != "" {
-			*field = parseBool(value)
-		}
+	names := make([]string, 0, len(config.Profiles))
+	for name := range config.Profiles {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
+}
 
-	// Duration environment variables
-	if value := os.Getenv("AZURE_EMAIL_POLL_INTERVAL"); value != "" {
-		config.PollInterval = value
+// SelectedProfile returns the profile chosen by flag, environment variable or config file
+func SelectedProfile(config *Config, flags map[string]interface{}) string {
+	if val, ok := flags["profile"].(string); ok && val != "" {
+		return val
 	}
-	if value := os.Getenv("AZURE_EMAIL_MAX_WAIT_TIME"); value != "" {
-		config.MaxWaitTime = value
+	if value := os.Getenv(EnvName("PROFILE")); value != "" {
+		return value
 	}
+	return config.Profile
 }
 
-// loadFromFlags loads configuration from CLI flags
-func loadFromFlags(config *Config, flags map[string]interface{}) {
-	if val, ok := flags["endpoint"].(string); ok && val != "" {
-		config.Endpoint = val
+// applyProfile overrides the top-level settings with the values of the named profile
+func applyProfile(config *Config, name string) error {
+	if name == "" {
+		return nil
+	}
+
+	profile, ok := config.Profiles[name]
+	if !ok {
+		return fmt.Errorf("profile %q not found in configuration", name)
 	}
-	if val, ok := flags["access-key"].(string); ok && val != "" {
-		config.AccessKey = val
+
+	config.Profile = name
+	source := "profile " + name
+
+	// A profile authenticates on its own, so don't mix it with top-level credentials
+	if profile.ConnectionString != "" || profile.ConnectionStringFile != "" || profile.Endpoint != "" {
+		config.Endpoint = profile.Endpoint
+		config.AccessKey = profile.AccessKey
+		config.ConnectionString = profile.ConnectionString
+		config.AccessKeyFile = profile.AccessKeyFile
+		config.ConnectionStringFile = profile.ConnectionStringFile
+		for _, key := range []string{"endpoint", "access-key", "connection-string", "access-key-file", "connection-string-file"} {
+			config.setSource(key, source)
+		}
 	}
-	if val, ok := flags["connection-string"].(string); ok && val != "" {
-		config.ConnectionString = val
+	for _, setting := range []struct {
+		key    string
+		value  string
+		target *string
+	}{
+		{"from", profile.From, &config.From},
+		{"reply-to", profile.ReplyTo, &config.ReplyTo},
+		{"suppression-file", profile.SuppressionFile, &config.SuppressionFile},
+		{"poll-interval", profile.PollInterval, &config.PollInterval},
+		{"max-wait-time", profile.MaxWaitTime, &config.MaxWaitTime},
+		{"confirm-threshold", profile.ConfirmThreshold, &config.ConfirmThreshold},
+		{"proxy", profile.Proxy, &config.Proxy},
+	} {
+		if setting.value != "" {
+			*setting.target = setting.value
+			config.setSource(setting.key, source)
+		}
 	}
-	if val, ok := flags["from"].(string); ok && val != "" {
-		config.From = val
+
+	return nil
+}
+
+// loadFromEnv loads configuration from environment variables
+func loadFromEnv(config *Config) {
+	for _, setting := range []struct {
+		envVar string
+		key    string
+		target *string
+	}{
+		{"ENDPOINT", "endpoint", &config.Endpoint},
+		{"ACCESS_KEY", "access-key", &config.AccessKey},
+		{"CONNECTION_STRING", "connection-string", &config.ConnectionString},
+		{"ACCESS_KEY_FILE", "access-key-file", &config.AccessKeyFile},
+		{"CONNECTION_STRING_FILE", "connection-string-file", &config.ConnectionStringFile},
+		{"FROM", "from", &config.From},
+		{"REPLY_TO", "reply-to", &config.ReplyTo},
+		{"SUPPRESSION_FILE", "suppression-file", &config.SuppressionFile},
+		{"LOG_FILE", "log-file", &config.LogFile},
+		{"RETENTION", "retention", &config.Retention},
+		{"QUEUE_DIR", "queue-dir", &config.QueueDir},
+		{"TEMPLATE_DIR", "template-dir", &config.TemplateDir},
+		{"DEDUPE_DIR", "dedupe-dir", &config.DedupeDir},
+		{"CA_FILE", "ca-file", &config.CAFile},
+		{"CLIENT_CERT", "client-cert", &config.ClientCert},
+		{"CLIENT_KEY", "client-key", &config.ClientKey},
+		{"TLS_MIN_VERSION", "tls-min-version", &config.TLSMinVersion},
+		{"PROXY", "proxy", &config.Proxy},
+		{"TRANSPORT", "transport", &config.Transport},
+		{"FALLBACK_TRANSPORT", "fallback-transport", &config.FallbackTransport},
+		{"POLL_INTERVAL", "poll-interval", &config.PollInterval},
+		{"MAX_WAIT_TIME", "max-wait-time", &config.MaxWaitTime},
+		{"CONFIRM_THRESHOLD", "confirm-threshold", &config.ConfirmThreshold},
+		{"HTTP_TIMEOUT", "http-timeout", &config.HTTPTimeout},
+		{"MAX_RETRIES", "max-retries", &config.MaxRetries},
+		{"RETRY_DELAY", "retry-delay", &config.RetryDelay},
+		{"PROFILE", "profile", &config.Profile},
+	} {
+		if value := os.Getenv(EnvName(setting.envVar)); value != "" {
+			*setting.target = value
+			config.setSource(setting.key, "env "+EnvName(setting.envVar))
+		}
 	}
-	if val, ok := flags["reply-to"].(string); ok && val != "" {
-		config.ReplyTo = val
+
+	for _, setting := range []struct {
+		envVar string
+		key    string
+		target *bool
+	}{
+		{"DEBUG", "debug", &config.Debug},
+		{"QUIET", "quiet", &config.Quiet},
+		{"JSON", "json", &config.JSON},
+		{"WAIT", "wait", &config.Wait},
+		{"POLL_BACKOFF", "poll-backoff", &config.PollBackoff},
+	} {
+		if value := os.Getenv(EnvName(setting.envVar)); value != "" {
+			*setting.target = parseBool(value)
+			config.setSource(setting.key, "env "+EnvName(setting.envVar))
+		}
 	}
-	if val, ok := flags["debug"].(bool); ok {
-		config.Debug = val
+}
+
+// loadFromFlags loads configuration from CLI flags
+func loadFromFlags(config *Config, flags map[string]interface{}) {
+	for _, setting := range []struct {
+		key    string
+		target *string
+	}{
+		{"endpoint", &config.Endpoint},
+		{"access-key", &config.AccessKey},
+		{"connection-string", &config.ConnectionString},
+		{"access-key-file", &config.AccessKeyFile},
+		{"connection-string-file", &config.ConnectionStringFile},
+		{"from", &config.From},
+		{"reply-to", &config.ReplyTo},
+		{"suppression-file", &config.SuppressionFile},
+		{"log-file", &config.LogFile},
+		{"queue-dir", &config.QueueDir},
+		{"template-dir", &config.TemplateDir},
+		{"dedupe-dir", &config.DedupeDir},
+		{"ca-file", &config.CAFile},
+		{"client-cert", &config.ClientCert},
+		{"client-key", &config.ClientKey},
+		{"tls-min-version", &config.TLSMinVersion},
+		{"proxy", &config.Proxy},
+		{"transport", &config.Transport},
+		{"fallback-transport", &config.FallbackTransport},
+		{"poll-interval", &config.PollInterval},
+		{"max-wait-time", &config.MaxWaitTime},
+		{"confirm-threshold", &config.ConfirmThreshold},
+		{"http-timeout", &config.HTTPTimeout},
+		{"max-retries", &config.MaxRetries},
+		{"retry-delay", &config.RetryDelay},
+		{"profile", &config.Profile},
+	} {
+		var val string
+		switch flag := flags[setting.key].(type) {
+		case string:
+			val = flag
+		case time.Duration:
+			val = flag.String()
+			if current, err := time.ParseDuration(*setting.target); err == nil && current == flag {
+				val = *setting.target
+			}
+		}
+		if val == "" {
+			continue
+		}
+		// Flags backed by an environment variable hold its value when not given
+		if val != *setting.target || !strings.HasPrefix(config.Sources[setting.key], "env ") {
+			config.setSource(setting.key, "flag --"+setting.key)
+		}
+		*setting.target = val
 	}
-	if val, ok := flags["quiet"].(bool); ok {
-		config.Quiet = val
+
+	for _, setting := range []struct {
+		key    string
+		target *bool
+	}{
+		{"debug", &config.Debug},
+		{"quiet", &config.Quiet},
+		{"json", &config.JSON},
+		{"wait", &config.Wait},
+		{"poll-backoff", &config.PollBackoff},
+	} {
+		val, ok := flags[setting.key].(bool)
+		if !ok {
+			continue
+		}
+		if val != *setting.target || (val && !strings.HasPrefix(config.Sources[setting.key], "env ")) {
+			config.setSource(setting.key, "flag --"+setting.key)
+		}
+		*setting.target = val
 	}
-	if val, ok := flags["json"].(bool); ok {
-		config.JSON = val
+}
+
+// sourceRank orders sources by precedence, matching the order LoadConfig applies them
+func sourceRank(source string) int {
+	for rank, prefix := range []string{"default", "file ", "profile ", "k8s ", "env ", "flag "} {
+		if strings.HasPrefix(source, prefix) {
+			return rank
+		}
 	}
-	if val, ok := flags["wait"].(bool); ok {
-		config.Wait = val
+	return -1
+}
+
+// readSecretFiles loads the access key and connection string from their files.
+// A file set with the same or higher precedence than the value itself wins
+func readSecretFiles(config *Config) error {
+	for _, setting := range []struct {
+		key    string
+		path   string
+		target *string
+	}{
+		{"access-key", config.AccessKeyFile, &config.AccessKey},
+		{"connection-string", config.ConnectionStringFile, &config.ConnectionString},
+	} {
+		fileKey := setting.key + "-file"
+		if setting.path == "" {
+			continue
+		}
+		if *setting.target != "" && sourceRank(config.Sources[fileKey]) < sourceRank(config.Sources[setting.key]) {
+			continue
+		}
+
+		data, err := os.ReadFile(setting.path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", fileKey, err)
+		}
+		value := strings.TrimSpace(string(data))
+		if value == "" {
+			return fmt.Errorf("%s %s is empty", fileKey, setting.path)
+		}
+		*setting.target = value
+		config.setSource(setting.key, config.Sources[fileKey])
 	}
-	if val, ok := flags["poll-interval"].(string); ok && val != "" {
-		config.PollInterval = val
+	return nil
+}
+
+// ParseRetention parses a retention age: a Go duration such as 36h, or a number
+// of days or weeks such as 30d or 2w
+func ParseRetention(value string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if number, ok := strings.CutSuffix(value, suffix); ok {
+			n, err := strconv.Atoi(number)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid retention %q: use a duration such as 30d, 2w or 36h", value)
+			}
+			return time.Duration(n) * unit, nil
+		}
 	}
-	if val, ok := flags["max-wait-time"].(string); ok && val != "" {
-		config.MaxWaitTime = val
+	age, err := time.ParseDuration(value)
+	if err != nil || age <= 0 {
+		return 0, fmt.Errorf("invalid retention %q: use a duration such as 30d, 2w or 36h", value)
 	}
+	return age, nil
 }
 
 // parseBool parses boolean from string
@@ -201,19 +625,74 @@ func SaveDefaultConfig(path string) error {
 		return fmt.Errorf("failed to marshal default config: %w", err)
 	}
 
-	return os.WriteFile(path, data, 0644)
+	// The file will hold the access key
+	return os.WriteFile(path, data, 0600)
 }
 
 // GetEnvConfigExample returns example environment variable configuration
 func GetEnvConfigExample() string {
-	return `# Azure Communication Services Email Environment Variables
+	return strings.NewReplacer("AZURE_EMAIL_", EnvPrefix).Replace(`# Azure Communication Services Email Environment Variables
 export AZURE_EMAIL_ENDPOINT="https://your-resource.communication.azure.com"
 export AZURE_EMAIL_ACCESS_KEY="your-access-key"
 export AZURE_EMAIL_FROM="sender@yourdomain.com"
 export AZURE_EMAIL_REPLY_TO="reply@yourdomain.com"
 export AZURE_EMAIL_DEBUG="false"
 export AZURE_EMAIL_QUIET="false" 
-export AZURE_EMAIL_JSON="false"`
+export AZURE_EMAIL_JSON="false"`)
+}
+
+// Sender returns the sender and reply-to of a message of category: those of
+// the category, falling back to the top-level settings
+func (c *Config) Sender(category string) (from, replyTo string) {
+	from, replyTo = c.From, c.ReplyTo
+	if defaults := c.Categories[category]; defaults != nil {
+		if defaults.From != "" {
+			from = defaults.From
+		}
+		if defaults.ReplyTo != "" {
+			replyTo = defaults.ReplyTo
+		}
+	}
+	return from, replyTo
+}
+
+// ExpandAliases replaces alias names in recipients with the addresses they stand for.
+// Entries containing '@' are kept as they are; aliases may refer to other aliases
+func (c *Config) ExpandAliases(recipients []string) ([]string, error) {
+	var expanded []string
+	for _, recipient := range recipients {
+		addresses, err := c.expandAlias(recipient, nil)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, addresses...)
+	}
+	return expanded, nil
+}
+
+// expandAlias resolves a single entry, tracking the aliases being expanded to detect cycles
+func (c *Config) expandAlias(name string, visiting []string) ([]string, error) {
+	members, ok := c.Aliases[name]
+	if strings.Contains(name, "@") || !ok {
+		return []string{name}, nil
+	}
+
+	for _, seen := range visiting {
+		if seen == name {
+			return nil, fmt.Errorf("alias %q refers to itself", name)
+		}
+	}
+	visiting = append(visiting, name)
+
+	var addresses []string
+	for _, member := range members {
+		expanded, err := c.expandAlias(member, visiting)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, expanded...)
+	}
+	return addresses, nil
 }
 
 // GetPollInterval returns the poll interval as a time.Duration
@@ -230,4 +709,39 @@ func (c *Config) GetMaxWaitTime() time.Duration {
 		return d
 	}
 	return 5 * time.Minute // default
+}
+
+// GetQueueDir returns the outbox directory, by default "queue" next to the
+// per-user config file
+func (c *Config) GetQueueDir() string {
+	if c.QueueDir != "" {
+		return c.QueueDir
+	}
+	return filepath.Join(filepath.Dir(DefaultPath()), "queue")
+}
+
+// GetTemplateDir returns the named template directory, by default "templates"
+// next to the per-user config file
+func (c *Config) GetTemplateDir() string {
+	if c.TemplateDir != "" {
+		return c.TemplateDir
+	}
+	return filepath.Join(filepath.Dir(DefaultPath()), "templates")
+}
+
+// GetDedupeDir returns the directory recording recently sent messages, by
+// default "dedupe" next to the per-user config file
+func (c *Config) GetDedupeDir() string {
+	if c.DedupeDir != "" {
+		return c.DedupeDir
+	}
+	return filepath.Join(filepath.Dir(DefaultPath()), "dedupe")
+}
+
+// GetConfirmThreshold returns the recipient count above which sends need confirmation
+func (c *Config) GetConfirmThreshold() int {
+	if n, err := strconv.Atoi(c.ConfirmThreshold); err == nil && n >= 0 {
+		return n
+	}
+	return 10 // default
 }
\ No newline at end of file
@@ -0,0 +1,328 @@
+package simpleconfig
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// encryptedPrefix marks a config value encrypted with AES-256-GCM. The rest is
+// base64 of salt, nonce and sealed value
+const encryptedPrefix = "enc:v1:"
+
+const (
+	saltSize   = 16
+	kdfRounds  = 600000
+	encKeySize = 32
+)
+
+// secretKeys are the config keys encrypted at rest, at the top level and in profiles
+var secretKeys = []string{"access-key", "connection-string"}
+
+// IsEncrypted reports whether a config value is encrypted
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// keyring derives encryption keys from a passphrase or key file, caching one key per salt
+type keyring struct {
+	secret []byte
+	keys   map[string][]byte
+}
+
+// newKeyring reads the secret from keyFile, or from AZURE_EMAIL_CONFIG_KEY_FILE or
+// AZURE_EMAIL_CONFIG_PASSPHRASE when keyFile is empty
+func newKeyring(keyFile string) (*keyring, error) {
+	if keyFile == "" {
+		keyFile = os.Getenv(EnvName("CONFIG_KEY_FILE"))
+	}
+
+	var secret []byte
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+		secret = bytes.TrimSpace(data)
+	} else {
+		secret = []byte(os.Getenv(EnvName("CONFIG_PASSPHRASE")))
+	}
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("no encryption key: use --key-file or set %s or %s", EnvName("CONFIG_KEY_FILE"), EnvName("CONFIG_PASSPHRASE"))
+	}
+	return &keyring{secret: secret, keys: map[string][]byte{}}, nil
+}
+
+// key returns the AES key for salt
+func (k *keyring) key(salt []byte) []byte {
+	if key, ok := k.keys[string(salt)]; ok {
+		return key
+	}
+	key := pbkdf2SHA256(k.secret, salt, kdfRounds, encKeySize)
+	k.keys[string(salt)] = key
+	return key
+}
+
+// encrypt seals value with a key derived using salt
+func (k *keyring) encrypt(value string, salt []byte) (string, error) {
+	gcm, err := newGCM(k.key(salt))
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := append(append([]byte{}, salt...), nonce...)
+	sealed = gcm.Seal(sealed, nonce, []byte(value), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt opens a value written by encrypt
+func (k *keyring) decrypt(value string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil || len(sealed) < saltSize {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	salt, rest := sealed[:saltSize], sealed[saltSize:]
+
+	gcm, err := newGCM(k.key(salt))
+	if err != nil {
+		return "", err
+	}
+	if len(rest) < gcm.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	nonce, ciphertext := rest[:gcm.NonceSize()], rest[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("wrong passphrase or key file")
+	}
+	return string(plain), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2SHA256 derives a key from a secret as specified in RFC 8018
+func pbkdf2SHA256(secret, salt []byte, rounds, size int) []byte {
+	prf := hmac.New(sha256.New, secret)
+	var key []byte
+	u := make([]byte, 0, prf.Size())
+	for block := uint32(1); len(key) < size; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.Write(prf, binary.BigEndian, block)
+		key = prf.Sum(key)
+		t := key[len(key)-prf.Size():]
+
+		u = append(u[:0], t...)
+		for n := 1; n < rounds; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for i := range t {
+				t[i] ^= u[i]
+			}
+		}
+	}
+	return key[:size]
+}
+
+// KeyFile returns the --key-file flag among flags, or "" to fall back to the environment
+func KeyFile(flags map[string]interface{}) string {
+	val, _ := flags["key-file"].(string)
+	return val
+}
+
+// isSecretKey reports whether key is encrypted at rest
+func isSecretKey(key string) bool {
+	for _, secret := range secretKeys {
+		if key == secret {
+			return true
+		}
+	}
+	return false
+}
+
+// hasEncryptedSecrets reports whether the config file holds encrypted values,
+// at the top level or in a profile
+func hasEncryptedSecrets(root object) bool {
+	scopes := []object{root}
+	rawProfiles, _ := root.get("profiles")
+	profiles, _ := parseObject(rawProfiles)
+	for _, profile := range profiles {
+		if scope, err := parseObject(profile.value); err == nil {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	for _, scope := range scopes {
+		for _, key := range secretKeys {
+			raw, ok := scope.get(key)
+			var value string
+			if ok && json.Unmarshal(raw, &value) == nil && IsEncrypted(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decryptSecrets replaces encrypted values loaded from the config file with
+// their plain text, using keyFile or the environment. A key is only needed
+// when the file holds encrypted values
+func decryptSecrets(config *Config, keyFile string) error {
+	targets := map[string]*string{
+		"access-key":        &config.AccessKey,
+		"connection-string": &config.ConnectionString,
+	}
+	for name, profile := range config.Profiles {
+		targets["profiles."+name+".access-key"] = &profile.AccessKey
+		targets["profiles."+name+".connection-string"] = &profile.ConnectionString
+	}
+
+	var names []string
+	for name, target := range targets {
+		if IsEncrypted(*target) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+
+	keys, err := newKeyring(keyFile)
+	if err != nil {
+		return fmt.Errorf("config file has encrypted values: %w", err)
+	}
+	for _, name := range names {
+		plain, err := keys.decrypt(*targets[name])
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s: %w", name, err)
+		}
+		*targets[name] = plain
+	}
+	return nil
+}
+
+// EncryptFile encrypts the access keys and connection strings of the config
+// file in place, at the top level and in every profile. It returns the path
+// written and the number of values encrypted; values already encrypted are kept
+func EncryptFile(configFile, keyFile string) (string, int, error) {
+	keys, err := newKeyring(keyFile)
+	if err != nil {
+		return "", 0, err
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", 0, err
+	}
+
+	return transformSecrets(configFile, func(name, value string) (string, bool, error) {
+		if value == "" || IsEncrypted(value) {
+			return value, false, nil
+		}
+		encrypted, err := keys.encrypt(value, salt)
+		return encrypted, true, err
+	})
+}
+
+// encryptSecret encrypts value with a key from keyFile or the environment
+func encryptSecret(value, keyFile string) (string, error) {
+	keys, err := newKeyring(keyFile)
+	if err != nil {
+		return "", err
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return keys.encrypt(value, salt)
+}
+
+// DecryptFile writes the plain text of encrypted values back to the config
+// file. It returns the path written and the number of values decrypted
+func DecryptFile(configFile, keyFile string) (string, int, error) {
+	keys, err := newKeyring(keyFile)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return transformSecrets(configFile, func(name, value string) (string, bool, error) {
+		if !IsEncrypted(value) {
+			return value, false, nil
+		}
+		plain, err := keys.decrypt(value)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to decrypt %s: %w", name, err)
+		}
+		return plain, true, nil
+	})
+}
+
+// transformSecrets rewrites the secret values of the config file with fn,
+// which reports whether it changed the value
+func transformSecrets(configFile string, fn func(name, value string) (string, bool, error)) (string, int, error) {
+	changed := 0
+	apply := func(scope *object, prefix string) error {
+		for _, key := range secretKeys {
+			raw, ok := scope.get(key)
+			if !ok {
+				continue
+			}
+			var value string
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return fmt.Errorf("%s%s must be a string", prefix, key)
+			}
+			updated, ok, err := fn(prefix+key, value)
+			if err != nil {
+				return err
+			}
+			if ok {
+				encoded, _ := json.Marshal(updated)
+				scope.set(key, encoded)
+				changed++
+			}
+		}
+		return nil
+	}
+
+	path, err := editFile(configFile, false, func(root *object) error {
+		if err := apply(root, ""); err != nil {
+			return err
+		}
+		rawProfiles, _ := root.get("profiles")
+		profiles, err := parseObject(rawProfiles)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal profiles: %w", err)
+		}
+		for _, profile := range profiles {
+			name := profile.key
+			if err := editScope(root, name, func(scope *object) error {
+				return apply(scope, "profiles."+name+".")
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return path, changed, err
+}
@@ -0,0 +1,368 @@
+package simpleconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultFilePath is where set creates a config file when none exists
+const defaultFilePath = "./azemailsender.json"
+
+// field is one key of a JSON object
+type field struct {
+	key   string
+	value json.RawMessage
+}
+
+// object is a JSON object that keeps its keys in file order, so edits
+// leave the rest of the file as the user wrote it
+type object []field
+
+// parseObject decodes a JSON object, keeping key order and unknown keys
+func parseObject(data []byte) (object, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return object{}, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+
+	var o object
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected an object key")
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		o = append(o, field{key: key, value: value})
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// get returns the raw value of key
+func (o object) get(key string) (json.RawMessage, bool) {
+	for _, f := range o {
+		if f.key == key {
+			return f.value, true
+		}
+	}
+	return nil, false
+}
+
+// set replaces the value of key, or appends the key
+func (o *object) set(key string, value json.RawMessage) {
+	for i := range *o {
+		if (*o)[i].key == key {
+			(*o)[i].value = value
+			return
+		}
+	}
+	*o = append(*o, field{key: key, value: value})
+}
+
+// unset removes key and reports whether it was present
+func (o *object) unset(key string) bool {
+	for i := range *o {
+		if (*o)[i].key == key {
+			*o = append((*o)[:i], (*o)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// marshal encodes the object with two-space indentation
+func (o object) marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		if err := json.Compact(&buf, f.value); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// Keys returns the settable top-level keys, or the keys of a profile when
+// forProfile is true
+func Keys(forProfile bool) []string {
+	var keys []string
+	for key := range settableFields(forProfile) {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// settableFields maps the JSON keys of scalar settings to their Go kind
+func settableFields(forProfile bool) map[string]reflect.Kind {
+	t := reflect.TypeOf(Config{})
+	if forProfile {
+		t = reflect.TypeOf(Profile{})
+	}
+
+	fields := make(map[string]reflect.Kind)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		if kind := f.Type.Kind(); kind == reflect.String || kind == reflect.Bool {
+			fields[key] = kind
+		}
+	}
+	return fields
+}
+
+// encodeValue checks value against the type of key and returns it as JSON
+func encodeValue(key, value string, kind reflect.Kind) (json.RawMessage, error) {
+	switch key {
+	case "poll-interval", "max-wait-time", "http-timeout", "retry-delay":
+		if _, err := time.ParseDuration(value); err != nil {
+			return nil, fmt.Errorf("invalid %s %q: use a duration such as 5s or 2m", key, value)
+		}
+	case "confirm-threshold", "max-retries":
+		if n, err := strconv.Atoi(value); err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid %s %q: use a non-negative number", key, value)
+		}
+	}
+
+	if kind == reflect.Bool {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: use true or false", key, value)
+		}
+		return json.Marshal(b)
+	}
+	return json.Marshal(value)
+}
+
+// checkKey returns an error naming the valid keys when key cannot be set
+func checkKey(key, profile string) (reflect.Kind, error) {
+	kind, ok := settableFields(profile != "")[key]
+	if !ok {
+		scope := "configuration"
+		if profile != "" {
+			scope = "profile"
+		}
+		return 0, fmt.Errorf("unknown %s key %q; valid keys: %s", scope, key, strings.Join(Keys(profile != ""), ", "))
+	}
+	return kind, nil
+}
+
+// SetValue sets key in the config file, or in the named profile when profile is
+// not empty. Other keys and their order are kept. A secret is encrypted with
+// keyFile, or the key in the environment, when the file holds encrypted values.
+// It returns the path written, creating ./azemailsender.json when no config
+// file exists
+func SetValue(configFile, profile, key, value, keyFile string) (string, error) {
+	kind, err := checkKey(key, profile)
+	if err != nil {
+		return "", err
+	}
+	raw, err := encodeValue(key, value, kind)
+	if err != nil {
+		return "", err
+	}
+
+	return editFile(configFile, true, func(root *object) error {
+		// Keep a file that holds encrypted values free of plain-text secrets
+		if isSecretKey(key) && value != "" && hasEncryptedSecrets(*root) {
+			encrypted, err := encryptSecret(value, keyFile)
+			if err != nil {
+				return fmt.Errorf("config file has encrypted values: %w", err)
+			}
+			raw, _ = json.Marshal(encrypted)
+		}
+		return editScope(root, profile, func(scope *object) error {
+			scope.set(key, raw)
+			return nil
+		})
+	})
+}
+
+// UnsetValue removes key from the config file, or from the named profile when
+// profile is not empty. It returns the path written
+func UnsetValue(configFile, profile, key string) (string, error) {
+	if _, err := checkKey(key, profile); err != nil {
+		return "", err
+	}
+
+	return editFile(configFile, false, func(root *object) error {
+		return editScope(root, profile, func(scope *object) error {
+			if !scope.unset(key) {
+				return fmt.Errorf("%s is not set", key)
+			}
+			return nil
+		})
+	})
+}
+
+// editScope applies edit to the root object, or to a profile inside it
+func editScope(root *object, profile string, edit func(*object) error) error {
+	if profile == "" {
+		return edit(root)
+	}
+
+	rawProfiles, _ := root.get("profiles")
+	profiles, err := parseObject(rawProfiles)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal profiles: %w", err)
+	}
+	rawProfile, ok := profiles.get(profile)
+	if !ok {
+		return fmt.Errorf("profile %q not found in configuration", profile)
+	}
+	scope, err := parseObject(rawProfile)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal profile %q: %w", profile, err)
+	}
+
+	if err := edit(&scope); err != nil {
+		return err
+	}
+
+	encoded, err := scope.marshal()
+	if err != nil {
+		return err
+	}
+	profiles.set(profile, encoded)
+	encoded, err = profiles.marshal()
+	if err != nil {
+		return err
+	}
+	root.set("profiles", encoded)
+	return nil
+}
+
+// editFile reads the config file, applies edit and replaces the file atomically
+func editFile(configFile string, create bool, edit func(*object) error) (string, error) {
+	path := FilePath(configFile)
+	if path == "" {
+		if !create {
+			return "", fmt.Errorf("no configuration file found; create one with 'config init'")
+		}
+		path = defaultFilePath
+	}
+
+	// New files may hold credentials, so only the owner can read them
+	mode := os.FileMode(0600)
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if info, statErr := os.Stat(path); statErr == nil {
+			mode = info.Mode().Perm()
+		}
+	case errors.Is(err, os.ErrNotExist) && create:
+	default:
+		return "", fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	root, err := parseObject(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	if err := edit(&root); err != nil {
+		return "", err
+	}
+	encoded, err := root.marshal()
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	// Write next to the target and rename, so a failure never leaves a partial file
+	temp, err := os.CreateTemp(filepath.Dir(path), ".azemailsender-*.json")
+	if err != nil {
+		return "", fmt.Errorf("failed to write config file %s: %w", path, err)
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(encoded); err != nil {
+		temp.Close()
+		return "", fmt.Errorf("failed to write config file %s: %w", path, err)
+	}
+	if err := temp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write config file %s: %w", path, err)
+	}
+	if err := os.Chmod(temp.Name(), mode); err != nil {
+		return "", fmt.Errorf("failed to write config file %s: %w", path, err)
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to write config file %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// Get returns the value of a top-level key as text
+func (c *Config) Get(key string) (string, bool) {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != key {
+			continue
+		}
+		switch value := v.Field(i).Interface().(type) {
+		case string:
+			return value, true
+		case bool:
+			return strconv.FormatBool(value), true
+		}
+	}
+	return "", false
+}
+
+// set sets a string or boolean setting by its JSON key, reporting whether the key exists
+func (c *Config) set(key, value string) bool {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != key {
+			continue
+		}
+		switch v.Field(i).Kind() {
+		case reflect.String:
+			v.Field(i).SetString(value)
+			return true
+		case reflect.Bool:
+			v.Field(i).SetBool(parseBool(value))
+			return true
+		}
+	}
+	return false
+}
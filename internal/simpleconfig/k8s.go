@@ -0,0 +1,103 @@
+package simpleconfig
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultK8sDirs are the directories read by --config-from k8s: a mounted
+// ConfigMap followed by a mounted Secret, so secrets win over plain settings
+var DefaultK8sDirs = []string{"/etc/azemailsender/config", "/var/run/secrets/azemailsender"}
+
+// ConfigFrom returns the --config-from value from flags or the environment
+func ConfigFrom(flags map[string]interface{}) string {
+	if val, ok := flags["config-from"].(string); ok && val != "" {
+		return val
+	}
+	return os.Getenv(EnvName("CONFIG_FROM"))
+}
+
+// ParseConfigFrom parses a --config-from value, "k8s" or "k8s:DIR[,DIR...]",
+// into the directories to read
+func ParseConfigFrom(value string) ([]string, error) {
+	kind, dirs, hasDirs := strings.Cut(value, ":")
+	if kind != "k8s" {
+		return nil, fmt.Errorf("invalid config source %q: use k8s or k8s:DIR[,DIR...]", value)
+	}
+	if !hasDirs {
+		return DefaultK8sDirs, nil
+	}
+
+	var list []string
+	for _, dir := range strings.Split(dirs, ",") {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			list = append(list, dir)
+		}
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("invalid config source %q: no directory given", value)
+	}
+	return list, nil
+}
+
+// k8sKey turns a mounted file name into a config key: both "access-key" and
+// environment-style names such as ACCESS_KEY or AZURE_EMAIL_ACCESS_KEY are accepted
+func k8sKey(name string) string {
+	name = strings.TrimPrefix(name, EnvPrefix)
+	return strings.ReplaceAll(strings.ToLower(name), "_", "-")
+}
+
+// loadFromDirs sets config keys from the files of mounted ConfigMap and Secret
+// directories, one file per key holding its value. Later directories win, files
+// that are not config keys are ignored and missing directories are skipped,
+// unless none of them exists
+func loadFromDirs(config *Config, dirs []string) error {
+	fields := settableFields(false)
+	found := false
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read config directory: %w", err)
+		}
+		found = true
+
+		for _, entry := range entries {
+			// Kubernetes keeps its own bookkeeping in ..data and similar entries
+			if strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			key := k8sKey(entry.Name())
+			if _, ok := fields[key]; !ok || key == "profile" {
+				continue
+			}
+
+			path := filepath.Join(dir, entry.Name())
+			// Keys are symlinks into the ..data directory, so follow them
+			info, err := os.Stat(path)
+			if err != nil {
+				return fmt.Errorf("failed to read config key %s: %w", key, err)
+			}
+			if !info.Mode().IsRegular() {
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read config key %s: %w", key, err)
+			}
+
+			config.set(key, strings.TrimSpace(string(data)))
+			config.setSource(key, "k8s "+path)
+		}
+	}
+	if !found {
+		return fmt.Errorf("no config directory found: %s", strings.Join(dirs, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,430 @@
+// Package simpleyaml parses the subset of YAML used for data files: block
+// mappings and sequences, plain and quoted scalars, literal (|) and folded (>)
+// block scalars, comments, and flow sequences of scalars. Anchors, tags and
+// multiple documents are not supported.
+package simpleyaml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// line is a non-empty, comment-stripped input line
+type line struct {
+	number int
+	indent int
+	text   string
+}
+
+// parser walks the lines of one document
+type parser struct {
+	lines []line
+	pos   int
+	raw   []string
+}
+
+// Unmarshal parses a YAML document into map[string]interface{},
+// []interface{}, string, int, float64, bool or nil values
+func Unmarshal(data []byte) (interface{}, error) {
+	p := &parser{raw: strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")}
+	for i, text := range p.raw {
+		trimmed := strings.TrimLeft(text, " ")
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("yaml: line %d: tabs are not allowed for indentation", i+1)
+		}
+		content := strings.TrimRight(stripComment(trimmed), " \t")
+		if content == "" || content == "---" || content == "..." {
+			continue
+		}
+		p.lines = append(p.lines, line{number: i + 1, indent: len(text) - len(trimmed), text: content})
+	}
+
+	if len(p.lines) == 0 {
+		return nil, nil
+	}
+	value, err := p.parseBlock(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, p.errorf(p.lines[p.pos], "unexpected indentation")
+	}
+	return value, nil
+}
+
+// parseBlock parses the mapping or sequence starting at the current line
+func (p *parser) parseBlock(indent int) (interface{}, error) {
+	if isSequenceItem(p.lines[p.pos].text) {
+		return p.parseSequence(indent)
+	}
+	return p.parseMapping(indent)
+}
+
+// parseSequence parses "- item" lines at indent
+func (p *parser) parseSequence(indent int) (interface{}, error) {
+	items := []interface{}{}
+	for p.pos < len(p.lines) {
+		current := p.lines[p.pos]
+		if current.indent < indent {
+			break
+		}
+		if current.indent > indent {
+			return nil, p.errorf(current, "unexpected indentation")
+		}
+		if !isSequenceItem(current.text) {
+			break
+		}
+
+		rest := strings.TrimLeft(current.text[1:], " ")
+		if rest == "" {
+			p.pos++
+			value, err := p.parseNested(indent)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+			continue
+		}
+
+		if isSequenceItem(rest) || isMappingEntry(rest) {
+			// The item is a block that starts on the same line as the dash
+			offset := len(current.text) - len(rest)
+			p.lines[p.pos].indent += offset
+			p.lines[p.pos].text = rest
+			value, err := p.parseBlock(indent + offset)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+			continue
+		}
+
+		value, err := p.parseValue(current, rest, indent)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, value)
+	}
+	return items, nil
+}
+
+// parseMapping parses "key: value" lines at indent
+func (p *parser) parseMapping(indent int) (interface{}, error) {
+	values := map[string]interface{}{}
+	for p.pos < len(p.lines) {
+		current := p.lines[p.pos]
+		if current.indent < indent {
+			break
+		}
+		if current.indent > indent {
+			return nil, p.errorf(current, "unexpected indentation")
+		}
+		if isSequenceItem(current.text) {
+			break
+		}
+
+		key, rest, ok := splitMappingEntry(current.text)
+		if !ok {
+			return nil, p.errorf(current, "expected \"key: value\"")
+		}
+		name, err := parseKey(key)
+		if err != nil {
+			return nil, p.errorf(current, "%v", err)
+		}
+		if _, exists := values[name]; exists {
+			return nil, p.errorf(current, "duplicate key %q", name)
+		}
+
+		if rest == "" {
+			p.pos++
+			value, err := p.parseNested(indent)
+			if err != nil {
+				return nil, err
+			}
+			values[name] = value
+			continue
+		}
+
+		value, err := p.parseValue(current, rest, indent)
+		if err != nil {
+			return nil, err
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// parseNested parses the block below a key or dash with no inline value.
+// A sequence may sit at the same indentation as its mapping key
+func (p *parser) parseNested(indent int) (interface{}, error) {
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	next := p.lines[p.pos]
+	if next.indent > indent || (next.indent == indent && isSequenceItem(next.text)) {
+		return p.parseBlock(next.indent)
+	}
+	return nil, nil
+}
+
+// parseValue parses an inline value, consuming following lines for block scalars
+func (p *parser) parseValue(current line, text string, indent int) (interface{}, error) {
+	p.pos++
+	if text == "|" || text == ">" || text == "|-" || text == ">-" {
+		return p.parseBlockScalar(current, text, indent), nil
+	}
+	if strings.HasPrefix(text, "[") {
+		return parseFlowSequence(text, func(format string, args ...interface{}) error {
+			return p.errorf(current, format, args...)
+		})
+	}
+	if text == "{}" {
+		return map[string]interface{}{}, nil
+	}
+	value, err := parseScalar(text)
+	if err != nil {
+		return nil, p.errorf(current, "%v", err)
+	}
+	return value, nil
+}
+
+// parseBlockScalar collects the raw lines indented below a | or > indicator
+func (p *parser) parseBlockScalar(current line, indicator string, indent int) string {
+	// Work on raw lines so blank lines and # characters are kept
+	var body []string
+	contentIndent := -1
+	number := current.number
+	for number < len(p.raw) {
+		text := p.raw[number]
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" {
+			body = append(body, "")
+			number++
+			continue
+		}
+		lineIndent := len(text) - len(trimmed)
+		if lineIndent <= indent {
+			break
+		}
+		if contentIndent < 0 {
+			contentIndent = lineIndent
+		}
+		if lineIndent < contentIndent {
+			break
+		}
+		body = append(body, text[contentIndent:])
+		number++
+	}
+
+	// Skip the parsed lines consumed by the block
+	for p.pos < len(p.lines) && p.lines[p.pos].number <= number {
+		p.pos++
+	}
+
+	for len(body) > 0 && body[len(body)-1] == "" {
+		body = body[:len(body)-1]
+	}
+
+	var value string
+	if strings.HasPrefix(indicator, ">") {
+		value = foldLines(body)
+	} else {
+		value = strings.Join(body, "\n")
+	}
+	if !strings.HasSuffix(indicator, "-") && value != "" {
+		value += "\n"
+	}
+	return value
+}
+
+// errorf returns an error that names the input line
+func (p *parser) errorf(l line, format string, args ...interface{}) error {
+	return fmt.Errorf("yaml: line %d: %s", l.number, fmt.Sprintf(format, args...))
+}
+
+// foldLines joins lines with spaces, keeping blank lines as line breaks
+func foldLines(lines []string) string {
+	var b strings.Builder
+	for i, text := range lines {
+		switch {
+		case text == "":
+			b.WriteString("\n")
+		case i > 0 && lines[i-1] != "":
+			b.WriteString(" ")
+			b.WriteString(text)
+		default:
+			b.WriteString(text)
+		}
+	}
+	return b.String()
+}
+
+// isSequenceItem reports whether text starts a sequence item
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// isMappingEntry reports whether text is a "key: value" pair
+func isMappingEntry(text string) bool {
+	_, _, ok := splitMappingEntry(text)
+	return ok
+}
+
+// splitMappingEntry splits "key: value" at the first colon outside quotes
+func splitMappingEntry(text string) (string, string, bool) {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\'' && quote == '\'' && i+1 < len(text) && text[i+1] == '\'' {
+				i++
+			} else if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				i++
+			}
+		case (c == '"' || c == '\'') && i == 0:
+			quote = c
+		case c == ':' && (i == len(text)-1 || text[i+1] == ' '):
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// stripComment removes a trailing # comment outside quotes
+func stripComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\'' && quote == '\'' && i+1 < len(text) && text[i+1] == '\'' {
+				i++
+			} else if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				i++
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || text[i-1] == ' ' || text[i-1] == '[' || text[i-1] == ',' {
+				quote = c
+			}
+		case c == '#' && (i == 0 || text[i-1] == ' '):
+			return text[:i]
+		}
+	}
+	return text
+}
+
+// parseKey returns a mapping key, unquoting it if needed
+func parseKey(text string) (string, error) {
+	if text == "" {
+		return "", fmt.Errorf("empty key")
+	}
+	if text[0] == '"' || text[0] == '\'' {
+		value, err := parseQuoted(text)
+		if err != nil {
+			return "", err
+		}
+		return value, nil
+	}
+	return text, nil
+}
+
+// parseFlowSequence parses a one-line [a, b, c] sequence of scalars
+func parseFlowSequence(text string, errorf func(string, ...interface{}) error) (interface{}, error) {
+	if !strings.HasSuffix(text, "]") {
+		return nil, errorf("unterminated flow sequence")
+	}
+	inner := strings.TrimSpace(text[1 : len(text)-1])
+	items := []interface{}{}
+	if inner == "" {
+		return items, nil
+	}
+
+	var quote byte
+	start := 0
+	for i := 0; i <= len(inner); i++ {
+		if i < len(inner) {
+			c := inner[i]
+			if quote != 0 {
+				// Escapes never skip past the end, so the last item is always parsed
+				if c == '\'' && quote == '\'' && i+1 < len(inner) && inner[i+1] == '\'' {
+					i++
+				} else if c == quote {
+					quote = 0
+				} else if c == '\\' && quote == '"' && i+1 < len(inner) {
+					i++
+				}
+				continue
+			}
+			if c == '"' || c == '\'' {
+				quote = c
+				continue
+			}
+			if c == '[' || c == '{' {
+				return nil, errorf("nested flow collections are not supported")
+			}
+			if c != ',' {
+				continue
+			}
+		}
+		value, err := parseScalar(strings.TrimSpace(inner[start:i]))
+		if err != nil {
+			return nil, errorf("%v", err)
+		}
+		items = append(items, value)
+		start = i + 1
+	}
+	return items, nil
+}
+
+// parseScalar converts a plain or quoted scalar
+func parseScalar(text string) (interface{}, error) {
+	if text == "" {
+		return nil, nil
+	}
+	if text[0] == '"' || text[0] == '\'' {
+		return parseQuoted(text)
+	}
+	if text[0] == '&' || text[0] == '*' || text[0] == '!' {
+		return nil, fmt.Errorf("anchors, aliases and tags are not supported")
+	}
+
+	switch text {
+	case "null", "Null", "NULL", "~":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	if n, err := strconv.Atoi(text); err == nil {
+		return n, nil
+	}
+	if strings.ContainsAny(text, ".eE") {
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return f, nil
+		}
+	}
+	return text, nil
+}
+
+// parseQuoted unquotes a single- or double-quoted scalar
+func parseQuoted(text string) (string, error) {
+	quote := text[0]
+	if len(text) < 2 || text[len(text)-1] != quote {
+		return "", fmt.Errorf("unterminated quoted string %s", text)
+	}
+	if quote == '\'' {
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	}
+	value, err := strconv.Unquote(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid quoted string %s", text)
+	}
+	return value, nil
+}
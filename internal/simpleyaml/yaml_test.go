@@ -0,0 +1,128 @@
+package simpleyaml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want interface{}
+	}{
+		{"empty", "", nil},
+		{"only comments", "# nothing\n---\n", nil},
+		{
+			name: "plain scalars",
+			yaml: "s: hello world\ni: 42\nneg: -7\nf: 1.5\nexp: 2e3\nt: true\nF: False\nn: null\ntilde: ~\nempty:\nversion: 1.2.3\n",
+			want: map[string]interface{}{
+				"s": "hello world", "i": 42, "neg": -7, "f": 1.5, "exp": 2000.0, "t": true, "F": false,
+				"n": nil, "tilde": nil, "empty": nil, "version": "1.2.3",
+			},
+		},
+		{
+			name: "quoted scalars",
+			yaml: `double: "a \"b\" \\ c\n\t\u00e9"` + "\n" + `single: 'it''s # not a comment'` + "\n" + `num: "42"` + "\n" + `"quoted key": 'x: y'` + "\n",
+			want: map[string]interface{}{
+				"double":     "a \"b\" \\ c\n\t\u00e9",
+				"single":     "it's # not a comment",
+				"num":        "42",
+				"quoted key": "x: y",
+			},
+		},
+		{
+			name: "comments",
+			yaml: "# header\nkey: value # trailing\nurl: http://example.com/#anchor\nhash: a#b\n  # indented comment\nother: 1\n",
+			want: map[string]interface{}{"key": "value", "url": "http://example.com/#anchor", "hash": "a#b", "other": 1},
+		},
+		{
+			name: "nested maps",
+			yaml: "outer:\n  inner:\n    leaf: 1\n  sibling: two\ntop: 3\n",
+			want: map[string]interface{}{
+				"outer": map[string]interface{}{
+					"inner":   map[string]interface{}{"leaf": 1},
+					"sibling": "two",
+				},
+				"top": 3,
+			},
+		},
+		{"empty flow map", "m: {}\n", map[string]interface{}{"m": map[string]interface{}{}}},
+		{
+			name: "block sequences",
+			yaml: "- a\n- 2\n-\n  - nested\n- key: value\n  other: x\n",
+			want: []interface{}{"a", 2, []interface{}{"nested"}, map[string]interface{}{"key": "value", "other": "x"}},
+		},
+		{
+			name: "sequence at the indentation of its key",
+			yaml: "to:\n- a@example.com\n- b@example.com\ncc:\n  - c@example.com\n",
+			want: map[string]interface{}{
+				"to": []interface{}{"a@example.com", "b@example.com"},
+				"cc": []interface{}{"c@example.com"},
+			},
+		},
+		{
+			name: "flow sequences",
+			yaml: "plain: [a, b, 3]\nempty: []\nquoted: [\"x, y\", 'z''s', \"q\\\"\"]\nbackslash: [\"a\\\\\", b]\n",
+			want: map[string]interface{}{
+				"plain":     []interface{}{"a", "b", 3},
+				"empty":     []interface{}{},
+				"quoted":    []interface{}{"x, y", "z's", "q\""},
+				"backslash": []interface{}{"a\\", "b"},
+			},
+		},
+		{
+			name: "literal and folded block scalars",
+			yaml: "literal: |\n  line one\n\n  # kept\nfolded: >\n  one\n  two\n\n  three\nstrip: |-\n  x\n",
+			want: map[string]interface{}{
+				"literal": "line one\n\n# kept\n",
+				"folded":  "one two\nthree\n",
+				"strip":   "x",
+			},
+		},
+		{"windows line endings", "a: 1\r\nb: 2\r\n", map[string]interface{}{"a": 1, "b": 2}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Unmarshal([]byte(test.yaml))
+			if err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Unmarshal = %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"tab indentation", "a:\n\tb: 1\n", "line 2: tabs are not allowed"},
+		{"bad indentation", "a: 1\n  b: 2\n", "line 2: unexpected indentation"},
+		{"duplicate key", "a: 1\na: 2\n", `line 2: duplicate key "a"`},
+		{"not a mapping", "a: 1\njust text\n", `line 2: expected "key: value"`},
+		{"unterminated quote", `a: "open` + "\n", "unterminated quoted string"},
+		{"invalid escape", `a: "\q"` + "\n", "invalid quoted string"},
+		{"unterminated flow sequence", "a: [1, 2\n", "unterminated flow sequence"},
+		{"nested flow collection", "a: [[1], 2]\n", "nested flow collections are not supported"},
+		{"trailing backslash in flow sequence", "a: [x, \"y\\]\n", "unterminated quoted string"},
+		{"unterminated quote in flow sequence", "a: [x, \"y]\n", "unterminated quoted string"},
+		{"anchor", "a: &ref 1\n", "anchors, aliases and tags are not supported"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Unmarshal([]byte(test.yaml))
+			if err == nil {
+				t.Fatalf("Unmarshal = %#v, want an error containing %q", got, test.want)
+			}
+			if !strings.Contains(err.Error(), test.want) {
+				t.Errorf("error = %q, want it to contain %q", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package azemailsender
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// MessageSchemaVersion is the version of the envelope written by SaveMessage
+const MessageSchemaVersion = 1
+
+// savedMessage is the on-disk envelope of a message. It keeps the per-message
+// options that are not part of the API payload next to the payload itself
+type savedMessage struct {
+	SchemaVersion    int             `json:"schemaVersion"`
+	Message          *EmailMessage   `json:"message"`
+	APIVersion       string          `json:"apiVersion,omitempty"`
+	SuppressionCheck SuppressionMode `json:"suppressionCheck,omitempty"`
+	ClientRequestID  string          `json:"clientRequestId,omitempty"`
+	Category         string          `json:"category,omitempty"`
+}
+
+// SaveMessage writes message as versioned JSON that LoadMessage can read back, e.g. to hand
+// it to another process through a file or queue. Reader attachments are read and embedded
+func SaveMessage(w io.Writer, message *EmailMessage) error {
+	payload, err := materializeAttachments(message)
+	if err != nil {
+		return err
+	}
+
+	envelope := savedMessage{
+		SchemaVersion:    MessageSchemaVersion,
+		Message:          payload,
+		APIVersion:       message.APIVersion,
+		SuppressionCheck: message.SuppressionCheck,
+		ClientRequestID:  message.ClientRequestID,
+		Category:         message.Category,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(&envelope); err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	return nil
+}
+
+// LoadMessage reads a message written by SaveMessage. A bare API payload without
+// an envelope is accepted as well
+func LoadMessage(r io.Reader) (*EmailMessage, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+
+	var probe struct {
+		SchemaVersion *int `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("failed to decode message: %w", err)
+	}
+
+	if probe.SchemaVersion == nil {
+		var message EmailMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			return nil, fmt.Errorf("failed to decode message: %w", err)
+		}
+		return &message, nil
+	}
+
+	if *probe.SchemaVersion < 1 || *probe.SchemaVersion > MessageSchemaVersion {
+		return nil, fmt.Errorf("unsupported message schema version %d (supported up to %d)", *probe.SchemaVersion, MessageSchemaVersion)
+	}
+
+	var envelope savedMessage
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode message: %w", err)
+	}
+	if envelope.Message == nil {
+		return nil, fmt.Errorf("failed to decode message: no message in envelope")
+	}
+
+	message := envelope.Message
+	message.APIVersion = envelope.APIVersion
+	message.SuppressionCheck = envelope.SuppressionCheck
+	message.ClientRequestID = envelope.ClientRequestID
+	message.Category = envelope.Category
+	return message, nil
+}
@@ -0,0 +1,118 @@
+package azemailsender
+
+import (
+	"context"
+	"time"
+)
+
+// MetricsMeterName is the name of the meter the client creates its instruments with
+const MetricsMeterName = "github.com/groovy-sky/azemailsender"
+
+// Instruments recorded by the client
+const (
+	// MetricSendDuration is a histogram of send durations in seconds, including
+	// retries and fallbacks, with the outcome and, for successful sends, the
+	// transport as attributes
+	MetricSendDuration = "azemailsender.send.duration"
+	// MetricSendRetries counts requests retried after a failed attempt
+	MetricSendRetries = "azemailsender.send.retries"
+	// MetricSendInflight is the number of sends in progress
+	MetricSendInflight = "azemailsender.send.inflight"
+)
+
+// MeterProvider supplies the meter the client records metrics with. It mirrors
+// the OpenTelemetry metric API, so a metric.MeterProvider can be adapted in a few
+// lines without the library depending on OpenTelemetry
+type MeterProvider interface {
+	Meter(name string) Meter
+}
+
+// Meter creates instruments, like an OpenTelemetry metric.Meter
+type Meter interface {
+	Float64Histogram(name, description, unit string) (Float64Histogram, error)
+	Int64Counter(name, description, unit string) (Int64Counter, error)
+	Int64UpDownCounter(name, description, unit string) (Int64UpDownCounter, error)
+}
+
+// Float64Histogram records a distribution of values
+type Float64Histogram interface {
+	Record(ctx context.Context, value float64, attributes ...MetricAttribute)
+}
+
+// Int64Counter records a monotonically increasing count
+type Int64Counter interface {
+	Add(ctx context.Context, incr int64, attributes ...MetricAttribute)
+}
+
+// Int64UpDownCounter records a count that goes up and down, such as a gauge of work in progress
+type Int64UpDownCounter interface {
+	Add(ctx context.Context, incr int64, attributes ...MetricAttribute)
+}
+
+// MetricAttribute is a key and value attached to a measurement
+type MetricAttribute struct {
+	Key   string
+	Value string
+}
+
+// clientMetrics holds the instruments of a client. Instruments that could not
+// be created are nil and not recorded
+type clientMetrics struct {
+	duration Float64Histogram
+	retries  Int64Counter
+	inflight Int64UpDownCounter
+}
+
+// newClientMetrics creates the instruments of the client from provider
+func newClientMetrics(provider MeterProvider, logger Logger) *clientMetrics {
+	meter := provider.Meter(MetricsMeterName)
+	metrics := &clientMetrics{}
+	var err error
+
+	if metrics.duration, err = meter.Float64Histogram(MetricSendDuration, "Duration of email sends, including retries", "s"); err != nil {
+		logger.Printf("[WARN] Failed to create %s instrument: %v", MetricSendDuration, err)
+	}
+	if metrics.retries, err = meter.Int64Counter(MetricSendRetries, "Send requests retried after a failed attempt", "{retry}"); err != nil {
+		logger.Printf("[WARN] Failed to create %s instrument: %v", MetricSendRetries, err)
+	}
+	if metrics.inflight, err = meter.Int64UpDownCounter(MetricSendInflight, "Email sends in progress", "{send}"); err != nil {
+		logger.Printf("[WARN] Failed to create %s instrument: %v", MetricSendInflight, err)
+	}
+	return metrics
+}
+
+// startSend records a send in progress and returns a function recording its
+// end, with the transport that sent it, if any, and whether it failed
+func (m *clientMetrics) startSend(ctx context.Context) func(transport string, err error) {
+	if m == nil {
+		return func(string, error) {}
+	}
+
+	start := time.Now()
+	if m.inflight != nil {
+		m.inflight.Add(ctx, 1)
+	}
+	return func(transport string, err error) {
+		if m.inflight != nil {
+			m.inflight.Add(ctx, -1)
+		}
+		if m.duration == nil {
+			return
+		}
+		attributes := []MetricAttribute{{Key: "outcome", Value: "success"}}
+		if err != nil {
+			attributes[0].Value = "failure"
+		}
+		if transport != "" {
+			attributes = append(attributes, MetricAttribute{Key: "transport", Value: transport})
+		}
+		m.duration.Record(ctx, time.Since(start).Seconds(), attributes...)
+	}
+}
+
+// recordRetry counts a retried send request
+func (m *clientMetrics) recordRetry(ctx context.Context) {
+	if m != nil && m.retries != nil {
+		m.retries.Add(ctx, 1)
+	}
+}
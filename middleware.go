@@ -0,0 +1,85 @@
+package azemailsender
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Middleware inspects or modifies a message before it is sent. Returning an error aborts the send
+type Middleware func(message *EmailMessage) error
+
+// Use registers middlewares that run, in registration order, before every send.
+// They receive a copy of the message, so the caller's message is never modified
+func (c *Client) Use(middlewares ...Middleware) {
+	c.middlewareMu.Lock()
+	defer c.middlewareMu.Unlock()
+
+	c.middlewares = append(c.middlewares, middlewares...)
+}
+
+// applyMiddlewares runs the registered middlewares on a copy of message
+func (c *Client) applyMiddlewares(message *EmailMessage) (*EmailMessage, error) {
+	c.middlewareMu.RLock()
+	middlewares := c.middlewares
+	c.middlewareMu.RUnlock()
+
+	if len(middlewares) == 0 {
+		return message, nil
+	}
+
+	clone := cloneMessage(message)
+	for i, middleware := range middlewares {
+		if err := middleware(clone); err != nil {
+			return nil, fmt.Errorf("middleware %d rejected message: %w", i+1, err)
+		}
+	}
+
+	if c.options.Debug {
+		c.logger.Printf("[DEBUG] Applied %d middlewares", len(middlewares))
+	}
+	return clone, nil
+}
+
+// cloneMessage returns a copy of message that shares no slices or maps with it
+func cloneMessage(message *EmailMessage) *EmailMessage {
+	clone := *message
+	clone.Recipients.To = cloneAddresses(message.Recipients.To)
+	clone.Recipients.Cc = cloneAddresses(message.Recipients.Cc)
+	clone.Recipients.Bcc = cloneAddresses(message.Recipients.Bcc)
+	clone.ReplyTo = cloneAddresses(message.ReplyTo)
+	if message.Attachments != nil {
+		clone.Attachments = append(make([]EmailAttachment, 0, len(message.Attachments)), message.Attachments...)
+	}
+	if message.Headers != nil {
+		clone.Headers = make(map[string]string, len(message.Headers))
+		for name, value := range message.Headers {
+			clone.Headers[name] = value
+		}
+	}
+	return &clone
+}
+
+// cloneAddresses copies an address list, keeping nil lists nil
+func cloneAddresses(addresses []EmailAddress) []EmailAddress {
+	if addresses == nil {
+		return nil
+	}
+	return append(make([]EmailAddress, 0, len(addresses)), addresses...)
+}
+
+// ArchiveBCC returns a middleware that blind-copies address on every message,
+// unless it already is a recipient
+func ArchiveBCC(address string) Middleware {
+	return func(message *EmailMessage) error {
+		lists := [][]EmailAddress{message.Recipients.To, message.Recipients.Cc, message.Recipients.Bcc}
+		for _, list := range lists {
+			for _, recipient := range list {
+				if strings.EqualFold(recipient.Address, address) {
+					return nil
+				}
+			}
+		}
+		message.Recipients.Bcc = append(message.Recipients.Bcc, EmailAddress{Address: address})
+		return nil
+	}
+}
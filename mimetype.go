@@ -0,0 +1,72 @@
+package azemailsender
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// defaultContentType is used when neither the name nor the content identify the type
+const defaultContentType = "application/octet-stream"
+
+// sniffLen is the number of bytes content sniffing looks at
+const sniffLen = 512
+
+// DetectContentType guesses an attachment's MIME type from its file extension,
+// falling back to sniffing the first bytes of content
+func DetectContentType(name string, content []byte) string {
+	if contentType := contentTypeByExtension(name); contentType != "" {
+		return contentType
+	}
+	if len(content) == 0 {
+		return defaultContentType
+	}
+	if len(content) > sniffLen {
+		content = content[:sniffLen]
+	}
+	return mediaType(http.DetectContentType(content))
+}
+
+// contentTypeByExtension looks up the MIME type registered for the name's extension
+func contentTypeByExtension(name string) string {
+	ext := filepath.Ext(name)
+	if ext == "" {
+		return ""
+	}
+	return mediaType(mime.TypeByExtension(strings.ToLower(ext)))
+}
+
+// mediaType strips parameters such as charset from a content type
+func mediaType(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+	parsed, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	return parsed
+}
+
+// sniffReader detects the content type of a reader attachment without consuming it.
+// It returns the detected type and a reader that still yields the full content
+func sniffReader(name string, r io.Reader) (string, io.Reader) {
+	if contentType := contentTypeByExtension(name); contentType != "" {
+		return contentType, r
+	}
+
+	buffered := bufio.NewReaderSize(r, sniffLen)
+	head, _ := buffered.Peek(sniffLen)
+	contentType := DetectContentType("", head)
+
+	if closer, ok := r.(io.Closer); ok {
+		return contentType, struct {
+			io.Reader
+			io.Closer
+		}{buffered, closer}
+	}
+	return contentType, buffered
+}
@@ -0,0 +1,558 @@
+package azemailsender
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OutboxState is the delivery state of a message in an outbox
+type OutboxState string
+
+// Outbox states
+const (
+	// OutboxPending messages are waiting to be sent
+	OutboxPending OutboxState = "pending"
+	// OutboxSent messages were accepted by the service
+	OutboxSent OutboxState = "sent"
+	// OutboxFailed messages failed and are retried after NextAttemptAt
+	OutboxFailed OutboxState = "failed"
+	// OutboxDeadLetter messages are not retried until an operator retries them
+	OutboxDeadLetter OutboxState = "dead-letter"
+)
+
+// OutboxStates lists every outbox state
+var OutboxStates = []OutboxState{OutboxPending, OutboxSent, OutboxFailed, OutboxDeadLetter}
+
+// ErrOutboxItemNotFound is returned for outbox IDs that don't exist
+var ErrOutboxItemNotFound = errors.New("message not found in outbox")
+
+// OutboxItem is a message stored in an outbox together with its delivery state
+type OutboxItem struct {
+	ID    string      `json:"id"`
+	State OutboxState `json:"state"`
+
+	// Subject and Recipients summarize the message for listings
+	Subject    string `json:"subject"`
+	Recipients int    `json:"recipients"`
+
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"lastError,omitempty"`
+	// MessageID is the operation ID returned by the service once sent
+	MessageID string `json:"messageId,omitempty"`
+
+	EnqueuedAt time.Time `json:"enqueuedAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	// NextAttemptAt is when a scheduled or failed message is sent next
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+
+	// Category is the category of the message, which selects its throttle policy
+	Category string `json:"category,omitempty"`
+	// Addresses are the addresses of every recipient, in lower case
+	Addresses []string `json:"addresses,omitempty"`
+
+	// DigestKey and DigestRecipient group the messages added with AddDigest
+	// that are sent together as one digest
+	DigestKey       string `json:"digestKey,omitempty"`
+	DigestRecipient string `json:"digestRecipient,omitempty"`
+
+	// Message is the message as written by SaveMessage
+	Message json.RawMessage `json:"message"`
+}
+
+// LoadMessage decodes the stored message
+func (item *OutboxItem) LoadMessage() (*EmailMessage, error) {
+	return LoadMessage(bytes.NewReader(item.Message))
+}
+
+// OutboxOptions configures how Deliver retries failed messages
+type OutboxOptions struct {
+	// MaxAttempts moves a message to the dead letter state after this many failed
+	// sends. Zero uses the default of 5
+	MaxAttempts int
+
+	// RetryDelay is the wait after the first failure; it doubles with every further failure
+	RetryDelay time.Duration
+
+	// OnDelivered is called for every message Deliver sent or failed to send
+	OnDelivered func(item *OutboxItem)
+
+	// Digest merges the messages of a digest into the email sent; nil uses MergeDigest
+	Digest DigestFunc
+
+	// Throttle limits how many messages of a category each recipient gets, by
+	// category, ahead of the Throttle of the client's CategoryOptions. The
+	// policy of "" applies to messages without a category and to categories
+	// without a policy of their own. Nil sends without limits
+	Throttle map[string]*ThrottlePolicy
+}
+
+// DefaultOutboxOptions returns default outbox options
+func DefaultOutboxOptions() *OutboxOptions {
+	return &OutboxOptions{
+		MaxAttempts: 5,
+		RetryDelay:  time.Minute,
+	}
+}
+
+// Outbox is a persistent queue of messages kept as one JSON file per message in
+// a directory, so messages survive restarts until they are sent. Changes are
+// serialized by a lock file in the directory, so several processes can share
+// an outbox
+type Outbox struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// outboxLockFile is the name of the outbox's lock file; IDs can't start with a
+// dot, so it never clashes with a message
+const outboxLockFile = ".lock"
+
+// OpenOutbox opens the outbox in dir, creating the directory if needed
+func OpenOutbox(dir string) (*Outbox, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create outbox: %w", err)
+	}
+	return &Outbox{dir: dir}, nil
+}
+
+// Dir returns the directory of the outbox
+func (o *Outbox) Dir() string {
+	return o.dir
+}
+
+// Add stores message as a pending message. Messages without a ClientRequestID
+// use the outbox ID, so every attempt can be found in the service logs
+func (o *Outbox) Add(message *EmailMessage) (*OutboxItem, error) {
+	return o.AddAt(message, time.Time{})
+}
+
+// AddAt stores message as a pending message that Deliver sends once sendAt has
+// passed. A zero sendAt sends it with the next delivery
+func (o *Outbox) AddAt(message *EmailMessage, sendAt time.Time) (*OutboxItem, error) {
+	item, err := newOutboxItem(message, sendAt)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := o.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if err := o.write(item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// newOutboxItem returns a pending item holding message
+func newOutboxItem(message *EmailMessage, sendAt time.Time) (*OutboxItem, error) {
+	id := newClientRequestID()
+	stored := *message
+	if stored.ClientRequestID == "" {
+		stored.ClientRequestID = id
+	}
+
+	var saved, buf bytes.Buffer
+	if err := SaveMessage(&saved, &stored); err != nil {
+		return nil, err
+	}
+	if err := json.Compact(&buf, saved.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	now := time.Now().UTC()
+	item := &OutboxItem{
+		ID:         id,
+		State:      OutboxPending,
+		Subject:    message.Content.Subject,
+		Recipients: len(message.Recipients.To) + len(message.Recipients.Cc) + len(message.Recipients.Bcc),
+		Category:   message.Category,
+		Addresses:  messageAddresses(message),
+		EnqueuedAt: now,
+		UpdatedAt:  now,
+		Message:    buf.Bytes(),
+	}
+	if !sendAt.IsZero() {
+		sendAt = sendAt.UTC()
+		item.NextAttemptAt = &sendAt
+	}
+	return item, nil
+}
+
+// Get returns the message with the given ID
+func (o *Outbox) Get(id string) (*OutboxItem, error) {
+	unlock, err := o.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return o.read(id)
+}
+
+// List returns the messages in the given states, or all messages when no state
+// is given, oldest first
+func (o *Outbox) List(states ...OutboxState) ([]*OutboxItem, error) {
+	unlock, err := o.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return o.list(states...)
+}
+
+// Retry makes a failed or dead-lettered message pending again, resetting its attempts
+func (o *Outbox) Retry(id string) (*OutboxItem, error) {
+	return o.transition(id, func(item *OutboxItem) error {
+		if item.State != OutboxFailed && item.State != OutboxDeadLetter {
+			return fmt.Errorf("message %s is %s; only failed and dead-letter messages can be retried", id, item.State)
+		}
+		item.State = OutboxPending
+		item.Attempts = 0
+		item.NextAttemptAt = nil
+		return nil
+	})
+}
+
+// DeadLetter stops a pending or failed message from being sent. Reason is kept
+// as the last error when given
+func (o *Outbox) DeadLetter(id, reason string) (*OutboxItem, error) {
+	return o.transition(id, func(item *OutboxItem) error {
+		if item.State == OutboxSent {
+			return fmt.Errorf("message %s was already sent", id)
+		}
+		item.State = OutboxDeadLetter
+		item.NextAttemptAt = nil
+		if reason != "" {
+			item.LastError = reason
+		}
+		return nil
+	})
+}
+
+// Remove deletes a message from the outbox
+func (o *Outbox) Remove(id string) error {
+	unlock, err := o.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	path, err := o.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrOutboxItemNotFound, id)
+		}
+		return fmt.Errorf("failed to remove outbox message: %w", err)
+	}
+	return nil
+}
+
+// Deliver sends every pending message and every failed message that is due, one
+// at a time, and returns the messages it attempted. A failed message is retried
+// with a doubling delay until MaxAttempts, then dead-lettered. Messages the
+// service can never accept, such as invalid ones, are dead-lettered right away.
+// The due messages of a digest are merged and sent as one email, and share its
+// outcome. Messages over the throttle limit of their category are delayed,
+// dead-lettered or moved into a digest as the policy says. Cancelling ctx
+// stops Deliver before the next message; a send in progress is completed so
+// its outcome is recorded
+func (o *Outbox) Deliver(ctx context.Context, client *Client, options *OutboxOptions) ([]*OutboxItem, error) {
+	if options == nil {
+		options = DefaultOutboxOptions()
+	}
+
+	unlock, err := o.lock()
+	if err != nil {
+		return nil, err
+	}
+	due, err := o.list(OutboxPending, OutboxFailed)
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var delivered []*OutboxItem
+	now := time.Now()
+	throttle, err := o.newThrottle(options, client, now)
+	if err != nil {
+		return nil, err
+	}
+	digested := map[string]bool{}
+	for _, item := range due {
+		if (item.NextAttemptAt != nil && item.NextAttemptAt.After(now)) || digested[item.ID] {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return delivered, err
+		}
+
+		var items []*OutboxItem
+		group := []*OutboxItem{item}
+		if item.DigestKey != "" {
+			group = digestGroup(due, item, now)
+			for _, member := range group {
+				digested[member.ID] = true
+			}
+		}
+		if until, over := throttle.over(item, now); over {
+			items, err = o.overflow(throttle, group, until, now)
+		} else if item.DigestKey != "" {
+			items, err = o.deliverDigest(ctx, client, options, group)
+		} else {
+			item, err = o.deliver(ctx, client, options, item.ID)
+			if item != nil {
+				items = append(items, item)
+			}
+		}
+		if err != nil {
+			return delivered, err
+		}
+		if len(items) > 0 && items[0].State == OutboxSent {
+			// A digest is one email to its recipient
+			throttle.record(items[0], items[0].UpdatedAt)
+		}
+		for _, item := range items {
+			delivered = append(delivered, item)
+			if options.OnDelivered != nil {
+				options.OnDelivered(item)
+			}
+		}
+	}
+	return delivered, nil
+}
+
+// deliver sends one message and records the outcome. It returns nil when the
+// message changed state or disappeared since it was listed
+func (o *Outbox) deliver(ctx context.Context, client *Client, options *OutboxOptions, id string) (*OutboxItem, error) {
+	unlock, err := o.lock()
+	if err != nil {
+		return nil, err
+	}
+	item, err := o.read(id)
+	unlock()
+	if errors.Is(err, ErrOutboxItemNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if item.State != OutboxPending && item.State != OutboxFailed {
+		return nil, nil
+	}
+
+	var response *SendResponse
+	message, sendErr := item.LoadMessage()
+	if sendErr == nil {
+		response, sendErr = client.SendWithContext(context.WithoutCancel(ctx), message)
+	}
+	recordAttempt(item, options, response, sendErr)
+
+	unlock, err = o.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if err := o.write(item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// recordAttempt updates the state of an item after a send
+func recordAttempt(item *OutboxItem, options *OutboxOptions, response *SendResponse, sendErr error) {
+	maxAttempts := options.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultOutboxOptions().MaxAttempts
+	}
+	item.Attempts++
+	item.UpdatedAt = time.Now().UTC()
+	item.NextAttemptAt = nil
+	switch {
+	case sendErr == nil:
+		item.State = OutboxSent
+		item.MessageID = response.ID
+		item.LastError = ""
+	case item.Attempts >= maxAttempts || !IsRetryable(sendErr):
+		item.State = OutboxDeadLetter
+		item.LastError = sendErr.Error()
+	default:
+		item.State = OutboxFailed
+		item.LastError = sendErr.Error()
+		next := item.UpdatedAt.Add(options.RetryDelay << (item.Attempts - 1))
+		item.NextAttemptAt = &next
+	}
+}
+
+// IsRetryable reports whether sending a message again after err may succeed:
+// service and network errors may, invalid messages and rejected requests won't
+func IsRetryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == 408 || apiErr.StatusCode == 429 || apiErr.StatusCode >= 500
+	}
+	// SMTP replies in the 5xx range are permanent, 4xx ones temporary
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) {
+		return smtpErr.Code < 500
+	}
+	var configErr *ConfigError
+	return !isMessageError(err) && !errors.As(err, &configErr)
+}
+
+// isMessageError reports whether err is a problem with the message itself, so
+// sending it again, or through another transport, cannot succeed
+func isMessageError(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusBadRequest
+	}
+	var (
+		addressErr     *InvalidAddressError
+		headerErr      *InvalidHeaderError
+		attachmentErr  *AttachmentLimitError
+		featureErr     *UnsupportedFeatureError
+		suppressionErr *SuppressionError
+		duplicateErr   *DuplicateError
+	)
+	return errors.Is(err, ErrMissingSender) || errors.Is(err, ErrMissingSubject) ||
+		errors.Is(err, ErrMissingContent) || errors.Is(err, ErrNoRecipients) ||
+		errors.As(err, &addressErr) || errors.As(err, &headerErr) ||
+		errors.As(err, &attachmentErr) || errors.As(err, &featureErr) ||
+		errors.As(err, &suppressionErr) || errors.As(err, &duplicateErr)
+}
+
+// transition applies change to a stored message and saves it
+func (o *Outbox) transition(id string, change func(item *OutboxItem) error) (*OutboxItem, error) {
+	unlock, err := o.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	item, err := o.read(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := change(item); err != nil {
+		return nil, err
+	}
+	item.UpdatedAt = time.Now().UTC()
+	if err := o.write(item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// lock takes the outbox's mutex and its lock file, and returns the function
+// that releases both
+func (o *Outbox) lock() (func(), error) {
+	o.mu.Lock()
+	unlock, err := lockFile(filepath.Join(o.dir, outboxLockFile))
+	if err != nil {
+		o.mu.Unlock()
+		return nil, err
+	}
+	return func() {
+		unlock()
+		o.mu.Unlock()
+	}, nil
+}
+
+// list reads the messages in the given states, oldest first
+func (o *Outbox) list(states ...OutboxState) ([]*OutboxItem, error) {
+	entries, err := os.ReadDir(o.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+
+	var items []*OutboxItem
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		item, err := o.read(strings.TrimSuffix(entry.Name(), ".json"))
+		if errors.Is(err, ErrOutboxItemNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(states) > 0 && !containsState(states, item.State) {
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].EnqueuedAt.Before(items[j].EnqueuedAt)
+	})
+	return items, nil
+}
+
+// read loads the message with the given ID
+func (o *Outbox) read(id string) (*OutboxItem, error) {
+	path, err := o.path(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrOutboxItemNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox message: %w", err)
+	}
+
+	var item OutboxItem
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, fmt.Errorf("invalid outbox message %s: %w", id, err)
+	}
+	return &item, nil
+}
+
+// write saves a message atomically
+func (o *Outbox) write(item *OutboxItem) error {
+	path, err := o.path(item.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox message: %w", err)
+	}
+	if err := replaceFile(path, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write outbox message: %w", err)
+	}
+	return nil
+}
+
+// path returns the file of a message, rejecting IDs that would leave the outbox
+func (o *Outbox) path(id string) (string, error) {
+	if id == "" || id != filepath.Base(id) || strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("%w: %q", ErrOutboxItemNotFound, id)
+	}
+	return filepath.Join(o.dir, id+".json"), nil
+}
+
+// containsState reports whether states includes state
+func containsState(states []OutboxState, state OutboxState) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package azemailsender
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrAuthenticationFailed is returned by Ping when the service rejects the client's credentials
+var ErrAuthenticationFailed = errors.New("authentication failed")
+
+// Ping verifies that the client can reach the service and that its credentials are accepted,
+// without sending email. It checks the access key locally, then requests the status of a
+// random operation ID: a "not found" answer proves the signed request was authenticated.
+// Use it at startup or in readiness probes
+func (c *Client) Ping(ctx context.Context) error {
+	if c.configErr != nil {
+		return c.configErr
+	}
+
+	_, accessKey := c.activeEndpoint()
+	if c.authMethod != AuthMethodAccessKey {
+		if _, err := base64.StdEncoding.DecodeString(accessKey); err != nil {
+			return &ConfigError{Field: "access key", Value: "***", Reason: "not valid base64"}
+		}
+	}
+
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return fmt.Errorf("failed to generate probe ID: %w", err)
+	}
+
+	if c.options.Debug {
+		c.logger.Printf("[DEBUG] Pinging %s", c.ActiveEndpoint())
+	}
+
+	_, err := c.GetStatusWithContext(ctx, hex.EncodeToString(idBytes))
+	if err == nil {
+		return nil
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusNotFound:
+			return nil
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return fmt.Errorf("%w: service responded with status %d", ErrAuthenticationFailed, apiErr.StatusCode)
+		}
+	}
+
+	return fmt.Errorf("ping failed: %w", err)
+}
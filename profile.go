@@ -0,0 +1,103 @@
+package azemailsender
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Profile describes a named Azure Communication Services resource and its sending defaults
+type Profile struct {
+	Name             string
+	Endpoint         string
+	AccessKey        string
+	ConnectionString string
+	From             string
+	ReplyTo          string
+}
+
+// NewClientFromProfile creates a new email client for a profile. The profile's
+// From and ReplyTo become the client defaults unless options already set them
+func NewClientFromProfile(profile *Profile, options *ClientOptions) (*Client, error) {
+	if profile == nil {
+		return nil, fmt.Errorf("profile is required")
+	}
+
+	if options == nil {
+		options = DefaultClientOptions()
+	}
+
+	// Copy options so profiles sharing them don't overwrite each other's defaults
+	profileOptions := *options
+	if profileOptions.DefaultFrom == "" {
+		profileOptions.DefaultFrom = profile.From
+	}
+	if profileOptions.DefaultReplyTo == "" {
+		profileOptions.DefaultReplyTo = profile.ReplyTo
+	}
+
+	if profile.ConnectionString != "" {
+		client, err := NewClientFromConnectionString(profile.ConnectionString, &profileOptions)
+		if err != nil {
+			return nil, fmt.Errorf("profile %q: %w", profile.Name, err)
+		}
+		return client, nil
+	}
+
+	if profile.Endpoint == "" || profile.AccessKey == "" {
+		return nil, fmt.Errorf("profile %q: either a connection string or both endpoint and access key are required", profile.Name)
+	}
+
+	client := NewClient(profile.Endpoint, profile.AccessKey, &profileOptions)
+	if err := client.Validate(); err != nil {
+		return nil, fmt.Errorf("profile %q: %w", profile.Name, err)
+	}
+	return client, nil
+}
+
+// ClientSet holds one client per named profile
+type ClientSet struct {
+	clients map[string]*Client
+}
+
+// NewClientSet creates clients for all profiles using shared options
+func NewClientSet(profiles []*Profile, options *ClientOptions) (*ClientSet, error) {
+	set := &ClientSet{
+		clients: make(map[string]*Client, len(profiles)),
+	}
+
+	for _, profile := range profiles {
+		if profile.Name == "" {
+			return nil, fmt.Errorf("profile name is required")
+		}
+		if _, exists := set.clients[profile.Name]; exists {
+			return nil, fmt.Errorf("duplicate profile %q", profile.Name)
+		}
+
+		client, err := NewClientFromProfile(profile, options)
+		if err != nil {
+			return nil, err
+		}
+		set.clients[profile.Name] = client
+	}
+
+	return set, nil
+}
+
+// Client returns the client for the named profile
+func (s *ClientSet) Client(name string) (*Client, error) {
+	client, ok := s.clients[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q", name)
+	}
+	return client, nil
+}
+
+// Names returns the profile names in sorted order
+func (s *ClientSet) Names() []string {
+	names := make([]string, 0, len(s.clients))
+	for name := range s.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,60 @@
+package azemailsender
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter spaces out requests shared across goroutines
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newRateLimiter creates a limiter allowing perSecond requests per second and keeping at
+// least pause between consecutive requests. It returns nil, which never blocks, when
+// neither limit is set
+func newRateLimiter(perSecond float64, pause time.Duration) *rateLimiter {
+	var interval time.Duration
+	if perSecond > 0 {
+		interval = time.Duration(float64(time.Second) / perSecond)
+	}
+	if pause > interval {
+		interval = pause
+	}
+	if interval <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		interval: interval,
+	}
+}
+
+// wait blocks until the next request slot or until ctx is done
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
@@ -0,0 +1,23 @@
+package azemailsender
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// Correlation headers used by Azure services
+const (
+	clientRequestIDHeader = "x-ms-client-request-id"
+	requestIDHeader       = "x-ms-request-id"
+)
+
+// newClientRequestID returns a random UUID v4 for the x-ms-client-request-id header
+func newClientRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,217 @@
+package azemailsender
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// captureTimeLayout is the timestamp that starts the name of every captured exchange
+const captureTimeLayout = "20060102T150405.000000000Z"
+
+// PruneAuditLog removes entries older than cutoff from the audit log at path and from
+// its rotated files. Rotated files left without entries are deleted. It returns the
+// number of entries removed. Lines that are not audit entries are kept. The log's
+// lock file is held throughout, so clients appending to it in the meantime wait
+// rather than write to a file about to be replaced
+func PruneAuditLog(path string, cutoff time.Time) (int, error) {
+	unlock, err := lockFile(path + auditLockSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		// No directory, so no log to prune
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune audit log %s: %w", path, err)
+	}
+	defer unlock()
+
+	removed := 0
+	for i := 0; ; i++ {
+		file := path
+		if i > 0 {
+			file = fmt.Sprintf("%s.%d", path, i)
+		}
+		n, err := pruneAuditFile(file, cutoff, i > 0)
+		if errors.Is(err, fs.ErrNotExist) {
+			if i == 0 {
+				continue
+			}
+			return removed, nil
+		}
+		if err != nil {
+			return removed, err
+		}
+		removed += n
+	}
+}
+
+// pruneAuditFile rewrites one audit log file without the entries older than cutoff,
+// deleting it instead when it is a rotated file and nothing is left
+func pruneAuditFile(path string, cutoff time.Time, rotated bool) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var kept bytes.Buffer
+	removed := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err == nil && !entry.Timestamp.IsZero() && entry.Timestamp.Before(cutoff) {
+			removed++
+			continue
+		}
+		kept.Write(scanner.Bytes())
+		kept.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read audit log %s: %w", path, err)
+	}
+
+	if removed == 0 {
+		return 0, nil
+	}
+	if rotated && kept.Len() == 0 {
+		if err := os.Remove(path); err != nil {
+			return 0, fmt.Errorf("failed to remove audit log %s: %w", path, err)
+		}
+		return removed, nil
+	}
+	if err := replaceFile(path, kept.Bytes()); err != nil {
+		return 0, fmt.Errorf("failed to rewrite audit log %s: %w", path, err)
+	}
+	return removed, nil
+}
+
+// PruneCaptures removes exchanges captured before cutoff from a capture directory or
+// .zip support bundle and returns how many were removed. A bundle left empty is deleted
+func PruneCaptures(path string, cutoff time.Time) (int, error) {
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		return pruneBundle(path, cutoff)
+	}
+
+	entries, err := os.ReadDir(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read capture directory: %w", err)
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if !captureTime(entry.Name(), info.ModTime()).Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(path, entry.Name())); err != nil {
+			return removed, fmt.Errorf("failed to remove captured exchange: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// pruneBundle rewrites a support bundle without the exchanges captured before cutoff
+func pruneBundle(path string, cutoff time.Time) (int, error) {
+	existing, err := zip.OpenReader(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to open support bundle: %w", err)
+	}
+	defer existing.Close()
+
+	var kept []*zip.File
+	for _, file := range existing.File {
+		if captureTime(file.Name, file.Modified).Before(cutoff) {
+			continue
+		}
+		kept = append(kept, file)
+	}
+	removed := len(existing.File) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+	if len(kept) == 0 {
+		existing.Close()
+		if err := os.Remove(path); err != nil {
+			return 0, fmt.Errorf("failed to remove support bundle: %w", err)
+		}
+		return removed, nil
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range kept {
+		if err := copyZipEntry(zw, file); err != nil {
+			return 0, fmt.Errorf("failed to rewrite support bundle: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return 0, fmt.Errorf("failed to rewrite support bundle: %w", err)
+	}
+	existing.Close()
+	if err := replaceFile(path, buf.Bytes()); err != nil {
+		return 0, fmt.Errorf("failed to replace support bundle: %w", err)
+	}
+	return removed, nil
+}
+
+// copyZipEntry copies a compressed entry into zw without recompressing it
+func copyZipEntry(zw *zip.Writer, file *zip.File) error {
+	reader, err := file.OpenRaw()
+	if err != nil {
+		return err
+	}
+	header := file.FileHeader
+	writer, err := zw.CreateRaw(&header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(writer, reader)
+	return err
+}
+
+// captureTime returns when an exchange was captured, from the timestamp starting its
+// name, falling back to the file's modification time
+func captureTime(name string, modified time.Time) time.Time {
+	name = filepath.Base(name)
+	if len(name) >= len(captureTimeLayout) {
+		if t, err := time.Parse(captureTimeLayout, name[:len(captureTimeLayout)]); err == nil {
+			return t
+		}
+	}
+	return modified
+}
+
+// replaceFile atomically replaces the contents of path, keeping it private
+func replaceFile(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
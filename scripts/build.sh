@@ -11,7 +11,7 @@ COMMIT="${COMMIT:-$(git rev-parse --short HEAD 2>/dev/null || echo "unknown")}"
 DATE="${DATE:-$(date -u +"%Y-%m-%dT%H:%M:%SZ")}"
 
 # Build flags
-LDFLAGS="-s -w -X main.version=${VERSION} -X main.commit=${COMMIT} -X main.date=${DATE}"
+LDFLAGS="-s -w -X main.version=${VERSION} -X main.commit=${COMMIT} -X main.date=${DATE} -X main.envPrefix=${ENV_PREFIX:-AZURE_EMAIL_}"
 
 # Platforms to build for
 PLATFORMS=(
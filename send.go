@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -17,41 +19,83 @@ func (c *Client) Send(message *EmailMessage) (*SendResponse, error) {
 
 // SendWithContext sends an email message with context support
 func (c *Client) SendWithContext(ctx context.Context, message *EmailMessage) (*SendResponse, error) {
-	if c.options.Debug {
-		c.logger.Printf("[DEBUG] Starting email send process")
-		c.logger.Printf("[DEBUG] From: %s", message.SenderAddress)
-		c.logger.Printf("[DEBUG] Subject: %s", message.Content.Subject)
+	requestID := message.ClientRequestID
+	if requestID == "" {
+		requestID = newClientRequestID()
 	}
 	
-	startTime := time.Now()
-	
-	// Serialize the message
-	body, err := json.Marshal(message)
+	dedupeKey, err := c.reserveDedupe(ctx, message)
 	if err != nil {
-		if c.options.Debug {
-			c.logger.Printf("[DEBUG] Failed to marshal message: %v", err)
-		}
-		return nil, fmt.Errorf("failed to marshal email message: %w", err)
+		return nil, err
+	}
+	if err := c.waitForCategory(ctx, message); err != nil {
+		c.releaseDedupe(ctx, dedupeKey)
+		return nil, err
 	}
 	
+	done := c.metrics.startSend(ctx)
+	// Middlewares and suppression checks run once, ahead of every transport, so
+	// the audit log records the recipients the message was sent to
+	var response *SendResponse
+	prepared, err := c.prepareMessage(ctx, message)
+	if err == nil {
+		response, err = c.sendWithFallback(ctx, prepared, requestID)
+	} else {
+		prepared = message
+	}
+	if err != nil {
+		c.releaseDedupe(ctx, dedupeKey)
+		done("", err)
+	} else {
+		done(response.Transport, nil)
+	}
+	c.recordSendResult(err)
+	c.recordAudit(prepared, requestID, response, err)
+	if err == nil && response.Status.IsFinal() {
+		c.notifyFinalStatus(ctx, &StatusResponse{ID: response.ID, Status: response.Status, Error: response.Error})
+	}
+	return response, err
+}
+
+// send performs the send with retries
+func (c *Client) send(ctx context.Context, message *EmailMessage, requestID string) (*SendResponse, error) {
 	if c.options.Debug {
-		c.logger.Printf("[DEBUG] Message serialized (%d bytes)", len(body))
+		c.logger.Printf("[DEBUG] Starting email send process")
+		c.logger.Printf("[DEBUG] Client request ID: %s", requestID)
+		c.logger.Printf("[DEBUG] From: %s", message.SenderAddress)
+		c.logger.Printf("[DEBUG] Subject: %s", message.Content.Subject)
 	}
 	
-	// Build the URL
-	url := fmt.Sprintf("%s/emails:send?api-version=%s", c.endpoint, c.options.APIVersion)
+	startTime := time.Now()
 	
-	if c.options.Debug {
-		c.logger.Printf("[DEBUG] API URL: %s", url)
+	buf, apiVersion, err := c.prepareSend(message)
+	if err != nil {
+		return nil, err
 	}
+	defer releaseBodyBuffer(buf)
+	body := buf.Bytes()
 	
 	// Attempt to send with retries
 	var lastErr error
+	var lastAttempt time.Duration
 	for attempt := 0; attempt <= c.options.MaxRetries; attempt++ {
 		if attempt > 0 {
+			// Don't sleep through the caller's budget for a retry that can't finish in time
+			if deadline, ok := ctx.Deadline(); ok {
+				if remaining := time.Until(deadline); remaining < c.options.RetryDelay+lastAttempt {
+					if c.options.Debug {
+						c.logger.Printf("[DEBUG] Skipping retry: %v left before the deadline, retry needs about %v", remaining, c.options.RetryDelay+lastAttempt)
+					}
+					return nil, fmt.Errorf("%w after %d attempts (%v left): %w", ErrDeadlineWouldExceed, attempt, remaining.Round(time.Millisecond), lastErr)
+				}
+			}
 			if c.options.Debug {
 				c.logger.Printf("[DEBUG] Retry attempt %d/%d", attempt, c.options.MaxRetries)
 			}
+			c.metrics.recordRetry(ctx)
+			if c.options.OnRetry != nil {
+				c.options.OnRetry(attempt, lastErr, c.options.RetryDelay)
+			}
 			
 			select {
 			case <-ctx.Done():
@@ -61,7 +105,21 @@ func (c *Client) SendWithContext(ctx context.Context, message *EmailMessage) (*S
 			}
 		}
 		
-		response, err := c.sendSingleAttempt(ctx, url, body)
+		// Build the URL for the currently active endpoint
+		endpoint, accessKey := c.activeEndpoint()
+		url := fmt.Sprintf("%s/emails:send?api-version=%s", endpoint, apiVersion)
+		
+		if c.options.Debug {
+			c.logger.Printf("[DEBUG] API URL: %s", url)
+		}
+		
+		attemptStart := time.Now()
+		response, err := c.sendSingleAttempt(ctx, url, body, accessKey, requestID)
+		lastAttempt = time.Since(attemptStart)
+		c.recordEndpointResult(err)
+		if c.options.OnRequestComplete != nil {
+			c.options.OnRequestComplete(newRequestInfo(attempt+1, endpoint, requestID, lastAttempt, response, err))
+		}
 		if err == nil {
 			duration := time.Since(startTime)
 			if c.options.Debug {
@@ -84,8 +142,46 @@ func (c *Client) SendWithContext(ctx context.Context, message *EmailMessage) (*S
 	return nil, fmt.Errorf("failed to send email after %d attempts: %w", c.options.MaxRetries+1, lastErr)
 }
 
-// sendSingleAttempt performs a single send attempt
-func (c *Client) sendSingleAttempt(ctx context.Context, url string, body []byte) (*SendResponse, error) {
+// prepareSend checks a message prepared by prepareMessage against the client
+// configuration and serializes it. The returned buffer comes from a pool and
+// must be released with releaseBodyBuffer
+func (c *Client) prepareSend(message *EmailMessage) (*bytes.Buffer, string, error) {
+	if c.configErr != nil {
+		return nil, "", c.configErr
+	}
+	
+	apiVersion := c.messageAPIVersion(message)
+	if err := checkFeatures(message, apiVersion); err != nil {
+		return nil, "", err
+	}
+	
+	// Serialize the message
+	buf, err := encodeMessage(message)
+	if err != nil {
+		if c.options.Debug {
+			c.logger.Printf("[DEBUG] Failed to marshal message: %v", err)
+		}
+		return nil, "", fmt.Errorf("failed to marshal email message: %w", err)
+	}
+	
+	if c.options.Debug {
+		c.logger.Printf("[DEBUG] Message serialized (%d bytes)", buf.Len())
+	}
+	
+	return buf, apiVersion, nil
+}
+
+// prepareMessage applies the middlewares and suppression checks to message
+func (c *Client) prepareMessage(ctx context.Context, message *EmailMessage) (*EmailMessage, error) {
+	message, err := c.applyMiddlewares(message)
+	if err != nil {
+		return nil, err
+	}
+	return c.applySuppression(ctx, message)
+}
+
+// newSendRequest builds and signs the HTTP request for a send attempt
+func (c *Client) newSendRequest(ctx context.Context, url string, body []byte, accessKey, requestID string) (*http.Request, error) {
 	// Create HTTP request
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
@@ -95,35 +191,51 @@ func (c *Client) sendSingleAttempt(ctx context.Context, url string, body []byte)
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "azemailsender-go/1.0")
+	req.Header.Set(clientRequestIDHeader, requestID)
 	
 	if c.options.Debug {
 		c.logger.Printf("[DEBUG] HTTP Request:")
 		c.logger.Printf("[DEBUG]   Method: %s", req.Method)
 		c.logger.Printf("[DEBUG]   URL: %s", req.URL.String())
 		c.logger.Printf("[DEBUG]   Content-Type: %s", req.Header.Get("Content-Type"))
+		c.logger.Printf("[DEBUG]   Client request ID: %s", requestID)
 		c.logger.Printf("[DEBUG]   Body size: %d bytes", len(body))
 	}
 	
 	// Add authentication
-	if err := c.addAuthentication(req, string(body)); err != nil {
+	if err := c.addAuthentication(req, body, accessKey); err != nil {
 		return nil, fmt.Errorf("failed to add authentication: %w", err)
 	}
 	
+	return req, nil
+}
+
+// sendSingleAttempt performs a single send attempt
+func (c *Client) sendSingleAttempt(ctx context.Context, url string, body []byte, accessKey, requestID string) (*SendResponse, error) {
+	req, err := c.newSendRequest(ctx, url, body, accessKey, requestID)
+	if err != nil {
+		return nil, err
+	}
+	
 	// Send request
 	reqStartTime := time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
+		err = fmt.Errorf("HTTP request failed: %w", err)
+		c.captureFailure(req, body, nil, nil, time.Since(reqStartTime), requestID, err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 	
 	requestDuration := time.Since(reqStartTime)
+	c.observeResponse(resp)
 	
 	if c.options.Debug {
 		c.logger.Printf("[DEBUG] HTTP Response:")
 		c.logger.Printf("[DEBUG]   Status: %s (%d)", resp.Status, resp.StatusCode)
 		c.logger.Printf("[DEBUG]   Request duration: %v", requestDuration)
 		c.logger.Printf("[DEBUG]   Content-Length: %s", resp.Header.Get("Content-Length"))
+		c.logger.Printf("[DEBUG]   Request ID: %s", resp.Header.Get(requestIDHeader))
 	}
 	
 	// Read response body
@@ -138,13 +250,18 @@ func (c *Client) sendSingleAttempt(ctx context.Context, url string, body []byte)
 	
 	// Check for success
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		sendErr := &APIError{StatusCode: resp.StatusCode, Message: string(respBody), RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")), ClientRequestID: requestID, RequestID: resp.Header.Get(requestIDHeader)}
+		
 		var apiError Error
-		if err := json.Unmarshal(respBody, &apiError); err != nil {
-			// If we can't parse the error, return the raw response
-			return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(respBody))
+		if err := json.Unmarshal(respBody, &apiError); err == nil {
+			sendErr.Message = apiError.Message
 		}
 		
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, apiError.Message)
+		c.captureFailure(req, body, resp, respBody, requestDuration, requestID, sendErr)
+		if c.options.Debug {
+			c.logger.Printf("[DEBUG] Reproduce with: %s", curlCommand(req, body))
+		}
+		return nil, sendErr
 	}
 	
 	// Parse response
@@ -152,6 +269,8 @@ func (c *Client) sendSingleAttempt(ctx context.Context, url string, body []byte)
 	if err := json.Unmarshal(respBody, &sendResponse); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
+	sendResponse.ClientRequestID = requestID
+	sendResponse.RequestID = resp.Header.Get(requestIDHeader)
 	
 	return &sendResponse, nil
 }
@@ -167,7 +286,20 @@ func (c *Client) GetStatusWithContext(ctx context.Context, messageID string) (*S
 		c.logger.Printf("[DEBUG] Checking status for message ID: %s", messageID)
 	}
 	
-	url := fmt.Sprintf("%s/emails/operations/%s?api-version=%s", c.endpoint, messageID, c.options.APIVersion)
+	if c.options.Transport != nil {
+		transport, ok := c.options.Transport.(StatusTransport)
+		if !ok {
+			return nil, ErrStatusUnsupported
+		}
+		return transport.Status(ctx, messageID)
+	}
+	
+	if c.configErr != nil {
+		return nil, c.configErr
+	}
+	
+	endpoint, accessKey := c.activeEndpoint()
+	url := fmt.Sprintf("%s/emails/operations/%s?api-version=%s", endpoint, messageID, c.options.APIVersion)
 	
 	if c.options.Debug {
 		c.logger.Printf("[DEBUG] Status check URL: %s", url)
@@ -181,7 +313,7 @@ func (c *Client) GetStatusWithContext(ctx context.Context, messageID string) (*S
 	req.Header.Set("User-Agent", "azemailsender-go/1.0")
 	
 	// Add authentication
-	if err := c.addAuthentication(req, ""); err != nil {
+	if err := c.addAuthentication(req, nil, accessKey); err != nil {
 		return nil, fmt.Errorf("failed to add authentication: %w", err)
 	}
 	
@@ -207,7 +339,11 @@ func (c *Client) GetStatusWithContext(ctx context.Context, messageID string) (*S
 		if c.options.Debug {
 			c.logger.Printf("[DEBUG] Status check failed: %s", string(respBody))
 		}
-		return nil, fmt.Errorf("status check failed with status %d: %s", resp.StatusCode, string(respBody))
+		return nil, fmt.Errorf("status check failed: %w", &APIError{
+			StatusCode: resp.StatusCode,
+			Message:    string(respBody),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		})
 	}
 	
 	var statusResponse StatusResponse
@@ -216,6 +352,7 @@ func (c *Client) GetStatusWithContext(ctx context.Context, messageID string) (*S
 	}
 	
 	statusResponse.Timestamp = time.Now()
+	statusResponse.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
 	
 	if c.options.Debug {
 		c.logger.Printf("[DEBUG] Current status: %s", statusResponse.Status)
@@ -231,28 +368,65 @@ func (c *Client) WaitForCompletion(messageID string, options *WaitOptions) (*Sta
 
 // WaitForCompletionWithContext waits for an email to reach a final status with context support
 func (c *Client) WaitForCompletionWithContext(ctx context.Context, messageID string, options *WaitOptions) (*StatusResponse, error) {
+	result, err := c.Wait(ctx, messageID, options)
+	return result.Final, err
+}
+
+// Wait waits for an email to reach a final status and returns every status observed
+// on the way. It returns ErrWaitTimeout when MaxWaitTime elapses and the context's
+// error when ctx is canceled or its own deadline passes
+func (c *Client) Wait(ctx context.Context, messageID string, options *WaitOptions) (*WaitResult, error) {
+	return c.wait(ctx, messageID, options, nil)
+}
+
+// wait implements Wait, pacing status checks through limiter when it is not nil
+func (c *Client) wait(ctx context.Context, messageID string, options *WaitOptions, limiter *rateLimiter) (*WaitResult, error) {
 	if options == nil {
 		options = DefaultWaitOptions()
 	}
 	
 	if c.options.Debug {
 		c.logger.Printf("[DEBUG] Starting status polling for message ID: %s", messageID)
-		c.logger.Printf("[DEBUG] Poll interval: %v", options.PollInterval)
+		if options.Backoff != nil {
+			c.logger.Printf("[DEBUG] Poll backoff: %v up to %v (x%.1f)", options.Backoff.InitialInterval, options.Backoff.MaxInterval, options.Backoff.Multiplier)
+		} else {
+			c.logger.Printf("[DEBUG] Poll interval: %v", options.PollInterval)
+		}
 		c.logger.Printf("[DEBUG] Max wait time: %v", options.MaxWaitTime)
 	}
 	
+	result := &WaitResult{ID: messageID}
+	startTime := time.Now()
+	
+	parentCtx := ctx
 	ctx, cancel := context.WithTimeout(ctx, options.MaxWaitTime)
 	defer cancel()
 	
-	ticker := time.NewTicker(options.PollInterval)
-	defer ticker.Stop()
+	// done reports why polling stopped, telling our own timeout apart from the caller's context
+	done := func() (*WaitResult, error) {
+		result.Duration = time.Since(startTime)
+		if parentCtx.Err() != nil {
+			return result, parentCtx.Err()
+		}
+		return result, fmt.Errorf("%w after %v: %w", ErrWaitTimeout, options.MaxWaitTime, ctx.Err())
+	}
 	
-	attempt := 0
+	interval := options.PollInterval
+	if options.Backoff != nil {
+		interval = options.Backoff.InitialInterval
+	}
 	
 	for {
-		attempt++
+		result.Attempts++
 		if c.options.Debug {
-			c.logger.Printf("[DEBUG] Status polling attempt %d", attempt)
+			c.logger.Printf("[DEBUG] Status polling attempt %d", result.Attempts)
+		}
+		
+		delay := interval
+		interval = nextPollInterval(interval, options.Backoff)
+		
+		if err := limiter.wait(ctx); err != nil {
+			return done()
 		}
 		
 		status, err := c.GetStatusWithContext(ctx, messageID)
@@ -264,56 +438,119 @@ func (c *Client) WaitForCompletionWithContext(ctx context.Context, messageID str
 				options.OnError(err)
 			}
 			
+			// Honor the service's requested delay when throttled
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && apiErr.RetryAfter > delay {
+				delay = apiErr.RetryAfter
+			}
+			
 			// Don't fail immediately on status check errors, continue polling
 			select {
 			case <-ctx.Done():
-				return nil, ctx.Err()
-			case <-ticker.C:
+				return done()
+			case <-time.After(delay):
 				continue
 			}
 		}
 		
+		result.Final = status
+		result.observe(status)
+		
 		if options.OnStatusUpdate != nil {
 			options.OnStatusUpdate(status)
 		}
 		
 		// Check if we've reached a final status
-		if isFinalStatus(status.Status) {
+		if status.Status.IsFinal() {
 			if c.options.Debug {
-				c.logger.Printf("[DEBUG] Final status reached: %s (after %d attempts)", status.Status, attempt)
+				c.logger.Printf("[DEBUG] Final status reached: %s (after %d attempts)", status.Status, result.Attempts)
 			}
-			return status, nil
+			result.FinalStatus = status.Status
+			result.Duration = time.Since(startTime)
+			c.notifyFinalStatus(parentCtx, status)
+			return result, nil
+		}
+		
+		if status.RetryAfter > delay {
+			delay = status.RetryAfter
 		}
 		
 		if c.options.Debug {
-			c.logger.Printf("[DEBUG] Status still pending: %s", status.Status)
+			c.logger.Printf("[DEBUG] Status still pending: %s (next check in %v)", status.Status, delay)
 		}
 		
 		select {
 		case <-ctx.Done():
 			if c.options.Debug {
-				c.logger.Printf("[DEBUG] Polling timed out after %d attempts", attempt)
+				c.logger.Printf("[DEBUG] Polling stopped after %d attempts", result.Attempts)
 			}
-			return status, ctx.Err()
-		case <-ticker.C:
+			return done()
+		case <-time.After(delay):
 			// Continue polling
 		}
 	}
 }
 
-// isFinalStatus checks if the given status is a final status
-func isFinalStatus(status string) bool {
-	finalStatuses := []EmailStatus{
-		StatusDelivered,
-		StatusFailed,
-		StatusCanceled,
+// observe records a status in the history when it differs from the previous one
+func (r *WaitResult) observe(status *StatusResponse) {
+	if n := len(r.History); n > 0 && r.History[n-1].Status == status.Status {
+		return
+	}
+	r.History = append(r.History, StatusObservation{
+		Status:    status.Status,
+		Timestamp: status.Timestamp,
+	})
+}
+
+// nextPollInterval returns the delay to use after the current one
+func nextPollInterval(current time.Duration, backoff *PollBackoff) time.Duration {
+	if backoff == nil {
+		return current
 	}
 	
-	for _, finalStatus := range finalStatuses {
-		if status == string(finalStatus) {
-			return true
+	multiplier := backoff.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	
+	next := time.Duration(float64(current) * multiplier)
+	if backoff.MaxInterval > 0 && next > backoff.MaxInterval {
+		next = backoff.MaxInterval
+	}
+	return next
+}
+
+// newRequestInfo describes a send attempt from its outcome
+func newRequestInfo(attempt int, endpoint, requestID string, duration time.Duration, response *SendResponse, err error) RequestInfo {
+	info := RequestInfo{Attempt: attempt, Endpoint: endpoint, Duration: duration, ClientRequestID: requestID, Err: err}
+	var apiErr *APIError
+	switch {
+	case err == nil:
+		// The service accepts send requests with 202
+		info.StatusCode = http.StatusAccepted
+		info.RequestID = response.RequestID
+	case errors.As(err, &apiErr):
+		info.StatusCode = apiErr.StatusCode
+		info.RequestID = apiErr.RequestID
+	}
+	return info
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
 		}
 	}
 	
-	return false
-}
\ No newline at end of file
+	return 0
+}
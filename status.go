@@ -0,0 +1,61 @@
+package azemailsender
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// knownStatuses lists every status reported by the Email API
+var knownStatuses = []EmailStatus{
+	StatusNotStarted,
+	StatusRunning,
+	StatusSucceeded,
+	StatusQueued,
+	StatusOutForDelivery,
+	StatusDelivered,
+	StatusFailed,
+	StatusCanceled,
+}
+
+// ParseStatus converts a status string to its EmailStatus constant, ignoring case
+func ParseStatus(s string) (EmailStatus, error) {
+	trimmed := strings.TrimSpace(s)
+	for _, status := range knownStatuses {
+		if strings.EqualFold(trimmed, string(status)) {
+			return status, nil
+		}
+	}
+	return EmailStatus(s), fmt.Errorf("unknown email status: %q", s)
+}
+
+// IsFinal reports whether the status will no longer change
+func (s EmailStatus) IsFinal() bool {
+	switch s {
+	case StatusSucceeded, StatusDelivered, StatusFailed, StatusCanceled:
+		return true
+	}
+	return false
+}
+
+// IsSuccess reports whether the status means the email was delivered to the service's satisfaction
+func (s EmailStatus) IsSuccess() bool {
+	return s == StatusSucceeded || s == StatusDelivered
+}
+
+// String returns the status as reported by the service
+func (s EmailStatus) String() string {
+	return string(s)
+}
+
+// UnmarshalJSON normalizes known statuses to their canonical spelling
+func (s *EmailStatus) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	// Keep unknown values as-is so newer service statuses are not rejected
+	*s, _ = ParseStatus(raw)
+	return nil
+}
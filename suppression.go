@@ -0,0 +1,221 @@
+package azemailsender
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SuppressionList reports which addresses must not receive email
+type SuppressionList interface {
+	// IsSuppressed reports whether an address is suppressed and why
+	IsSuppressed(ctx context.Context, address string) (bool, string, error)
+}
+
+// SuppressionMode controls what happens to suppressed recipients before sending
+type SuppressionMode int
+
+const (
+	// SuppressionInherit uses the client's mode for a message, and means off for the client
+	SuppressionInherit SuppressionMode = iota
+	// SuppressionOff sends without consulting the suppression list
+	SuppressionOff
+	// SuppressionDrop removes suppressed recipients and sends to the rest
+	SuppressionDrop
+	// SuppressionFail refuses to send when any recipient is suppressed
+	SuppressionFail
+)
+
+// SuppressedRecipient is a recipient found on the suppression list
+type SuppressedRecipient struct {
+	Address string
+	Field   string
+	Reason  string
+}
+
+// SuppressionError is returned when suppressed recipients prevent a send
+type SuppressionError struct {
+	Recipients []SuppressedRecipient
+
+	// AllSuppressed is set when dropping suppressed recipients left none to send to
+	AllSuppressed bool
+}
+
+func (e *SuppressionError) Error() string {
+	details := make([]string, 0, len(e.Recipients))
+	for _, r := range e.Recipients {
+		if r.Reason != "" {
+			details = append(details, fmt.Sprintf("%s (%s: %s)", r.Address, r.Field, r.Reason))
+		} else {
+			details = append(details, fmt.Sprintf("%s (%s)", r.Address, r.Field))
+		}
+	}
+
+	if e.AllSuppressed {
+		return fmt.Sprintf("all recipients are suppressed: %s", strings.Join(details, ", "))
+	}
+	return fmt.Sprintf("%d suppressed recipients: %s", len(e.Recipients), strings.Join(details, ", "))
+}
+
+// LocalSuppressionList is an in-memory suppression list that can be loaded from and saved to a file
+type LocalSuppressionList struct {
+	mu      sync.RWMutex
+	entries map[string]string
+}
+
+// NewLocalSuppressionList creates an empty local suppression list
+func NewLocalSuppressionList() *LocalSuppressionList {
+	return &LocalSuppressionList{
+		entries: make(map[string]string),
+	}
+}
+
+// LoadSuppressionList reads a suppression list file with one address per line,
+// optionally followed by a comma and a reason. Blank lines and lines starting with # are ignored
+func LoadSuppressionList(path string) (*LocalSuppressionList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open suppression list %s: %w", path, err)
+	}
+	defer file.Close()
+
+	list := NewLocalSuppressionList()
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		address, reason, _ := strings.Cut(line, ",")
+		address = strings.TrimSpace(address)
+		if !isValidEmail(address) {
+			return nil, fmt.Errorf("%s:%d: invalid email address: %s", path, lineNumber, address)
+		}
+		list.Add(address, strings.TrimSpace(reason))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read suppression list %s: %w", path, err)
+	}
+	return list, nil
+}
+
+// Add suppresses an address
+func (l *LocalSuppressionList) Add(address, reason string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[strings.ToLower(strings.TrimSpace(address))] = reason
+}
+
+// Remove lifts the suppression of an address
+func (l *LocalSuppressionList) Remove(address string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, strings.ToLower(strings.TrimSpace(address)))
+}
+
+// IsSuppressed implements SuppressionList
+func (l *LocalSuppressionList) IsSuppressed(ctx context.Context, address string) (bool, string, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	reason, ok := l.entries[strings.ToLower(strings.TrimSpace(address))]
+	return ok, reason, nil
+}
+
+// Save writes the list to a file in the format read by LoadSuppressionList
+func (l *LocalSuppressionList) Save(path string) error {
+	l.mu.RLock()
+	addresses := make([]string, 0, len(l.entries))
+	for address := range l.entries {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	var content strings.Builder
+	for _, address := range addresses {
+		content.WriteString(address)
+		if reason := l.entries[address]; reason != "" {
+			content.WriteString("," + reason)
+		}
+		content.WriteString("\n")
+	}
+	l.mu.RUnlock()
+
+	if err := os.WriteFile(path, []byte(content.String()), 0600); err != nil {
+		return fmt.Errorf("failed to write suppression list %s: %w", path, err)
+	}
+	return nil
+}
+
+// applySuppression checks the recipients against the suppression list and returns the
+// message to send, which is a copy without suppressed recipients in drop mode
+func (c *Client) applySuppression(ctx context.Context, message *EmailMessage) (*EmailMessage, error) {
+	mode := message.SuppressionCheck
+	if mode == SuppressionInherit {
+		mode = c.options.SuppressionMode
+	}
+	if mode == SuppressionInherit || mode == SuppressionOff {
+		return message, nil
+	}
+
+	if c.options.Suppression == nil {
+		return nil, fmt.Errorf("suppression check requested but no suppression list is configured")
+	}
+
+	var suppressed []SuppressedRecipient
+	filter := func(field string, recipients []EmailAddress) ([]EmailAddress, error) {
+		kept := make([]EmailAddress, 0, len(recipients))
+		for _, recipient := range recipients {
+			isSuppressed, reason, err := c.options.Suppression.IsSuppressed(ctx, recipient.Address)
+			if err != nil {
+				return nil, fmt.Errorf("suppression check failed for %s: %w", recipient.Address, err)
+			}
+			if isSuppressed {
+				suppressed = append(suppressed, SuppressedRecipient{Address: recipient.Address, Field: field, Reason: reason})
+				continue
+			}
+			kept = append(kept, recipient)
+		}
+		return kept, nil
+	}
+
+	filtered := *message
+	var err error
+	if filtered.Recipients.To, err = filter("to", message.Recipients.To); err != nil {
+		return nil, err
+	}
+	if filtered.Recipients.Cc, err = filter("cc", message.Recipients.Cc); err != nil {
+		return nil, err
+	}
+	if filtered.Recipients.Bcc, err = filter("bcc", message.Recipients.Bcc); err != nil {
+		return nil, err
+	}
+
+	if len(suppressed) == 0 {
+		return message, nil
+	}
+
+	if c.options.Debug {
+		c.logger.Printf("[DEBUG] %d suppressed recipients found", len(suppressed))
+	}
+
+	if mode == SuppressionFail {
+		return nil, &SuppressionError{Recipients: suppressed}
+	}
+
+	if len(filtered.Recipients.To)+len(filtered.Recipients.Cc)+len(filtered.Recipients.Bcc) == 0 {
+		return nil, &SuppressionError{Recipients: suppressed, AllSuppressed: true}
+	}
+
+	for _, r := range suppressed {
+		c.logger.Printf("[WARN] Dropped suppressed recipient %s (%s)", r.Address, r.Field)
+	}
+	return &filtered, nil
+}
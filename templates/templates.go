@@ -0,0 +1,159 @@
+// Package templates provides embedded starter email templates: responsive
+// HTML with a plain text alternative and a subject, rendered with Go
+// template syntax.
+//
+// Each starter comes with sample data naming the fields it uses:
+//
+//	t, err := templates.Get("alert")
+//	content, err := t.Render(map[string]interface{}{"severity": "Warning", ...})
+//	message, err := content.Apply(client.NewMessage()).From(from).To(to).Build()
+//
+// The HTML is rendered with html/template, which escapes the data. A field
+// missing from the data is an error rather than an empty value.
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/groovy-sky/azemailsender"
+)
+
+// starters holds <name>.json with the description, subject and sample data of
+// each starter, and its <name>.html and <name>.txt content
+//
+//go:embed starters
+var starters embed.FS
+
+// ErrNotFound is returned for a starter template that does not exist
+var ErrNotFound = errors.New("starter template not found")
+
+// Template is a starter template
+type Template struct {
+	Name        string
+	Description string
+	Subject     string
+	HTML        string
+	Text        string
+	// SampleData holds an example value of every field the template uses
+	SampleData map[string]interface{}
+}
+
+// Content is a rendered template
+type Content struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Names returns the names of the starter templates, sorted
+func Names() []string {
+	entries, _ := fs.ReadDir(starters, "starters")
+	var names []string
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), ".json"); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// List returns every starter template, sorted by name
+func List() []*Template {
+	var list []*Template
+	for _, name := range Names() {
+		if t, err := Get(name); err == nil {
+			list = append(list, t)
+		}
+	}
+	return list
+}
+
+// Get returns the starter template name. The template is a copy that may be
+// changed freely
+func Get(name string) (*Template, error) {
+	var meta []byte
+	err := ErrNotFound
+	if !strings.ContainsAny(name, "/.") {
+		meta, err = starters.ReadFile("starters/" + name + ".json")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q (available: %s)", ErrNotFound, name, strings.Join(Names(), ", "))
+	}
+
+	t := &Template{Name: name}
+	var fields struct {
+		Description string                 `json:"description"`
+		Subject     string                 `json:"subject"`
+		SampleData  map[string]interface{} `json:"sampleData"`
+	}
+	if err := json.Unmarshal(meta, &fields); err != nil {
+		return nil, fmt.Errorf("invalid starter template %s: %w", name, err)
+	}
+	t.Description, t.Subject, t.SampleData = fields.Description, fields.Subject, fields.SampleData
+
+	html, err := starters.ReadFile("starters/" + name + ".html")
+	if err != nil {
+		return nil, fmt.Errorf("invalid starter template %s: %w", name, err)
+	}
+	text, err := starters.ReadFile("starters/" + name + ".txt")
+	if err != nil {
+		return nil, fmt.Errorf("invalid starter template %s: %w", name, err)
+	}
+	t.HTML, t.Text = string(html), string(text)
+	return t, nil
+}
+
+// Render renders the subject, HTML and text of the template with data,
+// usually a map with the fields of SampleData
+func (t *Template) Render(data interface{}) (*Content, error) {
+	content := &Content{}
+	var err error
+	if content.Subject, err = renderText(t.Name+" subject", t.Subject, data); err != nil {
+		return nil, err
+	}
+	// Subjects are a single line
+	content.Subject = strings.Join(strings.Fields(content.Subject), " ")
+
+	html, err := htmltemplate.New(t.Name + ".html").Option("missingkey=error").Parse(t.HTML)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := html.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+	content.HTML = buf.String()
+
+	if content.Text, err = renderText(t.Name+".txt", t.Text, data); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// renderText renders a text/template
+func renderText(name, source string, data interface{}) (string, error) {
+	tmpl, err := texttemplate.New(name).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// Apply sets the subject and content of a message builder
+func (c *Content) Apply(b *azemailsender.MessageBuilder) *azemailsender.MessageBuilder {
+	return b.Subject(c.Subject).HTML(c.HTML).PlainText(c.Text)
+}
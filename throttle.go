@@ -0,0 +1,258 @@
+package azemailsender
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ThrottleOverflow selects what Deliver does with messages over a throttle limit
+type ThrottleOverflow string
+
+// Throttle overflow handling
+const (
+	// ThrottleDelay keeps messages pending until every recipient is below the limit again
+	ThrottleDelay ThrottleOverflow = "delay"
+	// ThrottleDrop dead-letters messages over the limit
+	ThrottleDrop ThrottleOverflow = "drop"
+	// ThrottleDigest collects messages over the limit into one digest per
+	// recipient, sent once the recipient is below the limit again
+	ThrottleDigest ThrottleOverflow = "digest"
+)
+
+// ThrottleOverflows lists every overflow handling
+var ThrottleOverflows = []ThrottleOverflow{ThrottleDelay, ThrottleDrop, ThrottleDigest}
+
+// ThrottlePolicy limits how many messages each recipient gets within a sliding window
+type ThrottlePolicy struct {
+	// Limit is the number of messages per recipient within Window; zero or less is unlimited
+	Limit  int
+	Window time.Duration
+	// Overflow handles messages over the limit; empty means ThrottleDelay
+	Overflow ThrottleOverflow
+}
+
+// String returns the policy as LIMIT/WINDOW:OVERFLOW, e.g. 5/1h0m0s:digest
+func (p *ThrottlePolicy) String() string {
+	overflow := p.Overflow
+	if overflow == "" {
+		overflow = ThrottleDelay
+	}
+	return fmt.Sprintf("%d/%s:%s", p.Limit, p.Window, overflow)
+}
+
+// throttleDigestKey is the digest key of messages a ThrottleDigest policy held back
+const throttleDigestKey = "throttled"
+
+// throttle counts the messages sent to each recipient within the windows of
+// the throttle policies during a Deliver
+type throttle struct {
+	policies map[string]*ThrottlePolicy
+	// sent holds the send times by category and address
+	sent map[string]map[string][]time.Time
+}
+
+// newThrottle returns the throttle of options, and of the categories of the
+// client they don't have a policy for, with the messages sent within the
+// windows of its policies. It returns nil when nothing is throttled
+func (o *Outbox) newThrottle(options *OutboxOptions, client *Client, now time.Time) (*throttle, error) {
+	policies := map[string]*ThrottlePolicy{}
+	for name, category := range client.options.Categories {
+		if category != nil && category.Throttle != nil {
+			policies[name] = category.Throttle
+		}
+	}
+	for category, policy := range options.Throttle {
+		policies[category] = policy
+	}
+	if len(policies) == 0 {
+		return nil, nil
+	}
+	t := &throttle{policies: policies, sent: map[string]map[string][]time.Time{}}
+
+	unlock, err := o.lock()
+	if err != nil {
+		return nil, err
+	}
+	sent, err := o.list(OutboxSent)
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range sent {
+		if policy := t.policy(item.Category); policy != nil && now.Sub(item.UpdatedAt) < policy.Window {
+			t.record(item, item.UpdatedAt)
+		}
+	}
+	return t, nil
+}
+
+// policy returns the policy limiting category, nil when it is unlimited
+func (t *throttle) policy(category string) *ThrottlePolicy {
+	policy, ok := t.policies[category]
+	if !ok {
+		policy = t.policies[""]
+	}
+	if policy == nil || policy.Limit <= 0 {
+		return nil
+	}
+	return policy
+}
+
+// record counts a message sent at the given time against its recipients
+func (t *throttle) record(item *OutboxItem, at time.Time) {
+	if t == nil || t.policy(item.Category) == nil {
+		return
+	}
+	byAddress := t.sent[item.Category]
+	if byAddress == nil {
+		byAddress = map[string][]time.Time{}
+		t.sent[item.Category] = byAddress
+	}
+	for _, address := range itemAddresses(item) {
+		byAddress[address] = append(byAddress[address], at)
+	}
+}
+
+// free returns when address may get another message of category, the zero
+// time when it may now
+func (t *throttle) free(category, address string, now time.Time) time.Time {
+	policy := t.policy(category)
+	if policy == nil {
+		return time.Time{}
+	}
+	var recent []time.Time
+	for _, at := range t.sent[category][address] {
+		if now.Sub(at) < policy.Window {
+			recent = append(recent, at)
+		}
+	}
+	if len(recent) < policy.Limit {
+		return time.Time{}
+	}
+	sort.Slice(recent, func(i, j int) bool { return recent[i].Before(recent[j]) })
+	return recent[len(recent)-policy.Limit].Add(policy.Window)
+}
+
+// over reports whether item would take a recipient over the limit, and when
+// the last of them is below it again
+func (t *throttle) over(item *OutboxItem, now time.Time) (time.Time, bool) {
+	if t == nil {
+		return time.Time{}, false
+	}
+	var until time.Time
+	for _, address := range itemAddresses(item) {
+		if free := t.free(item.Category, address, now); free.After(until) {
+			until = free
+		}
+	}
+	return until, !until.IsZero()
+}
+
+// overflow applies the overflow handling of the policy of items, which are
+// one message or the messages of a digest, and returns those it dead-lettered
+func (o *Outbox) overflow(t *throttle, items []*OutboxItem, until, now time.Time) ([]*OutboxItem, error) {
+	policy := t.policy(items[0].Category)
+	switch {
+	case policy.Overflow == ThrottleDrop && items[0].DigestKey == "":
+		item, err := o.transition(items[0].ID, func(item *OutboxItem) error {
+			item.State = OutboxDeadLetter
+			item.NextAttemptAt = nil
+			item.LastError = fmt.Sprintf("throttled: limit of %d messages per recipient within %s reached", policy.Limit, policy.Window)
+			if item.Category != "" {
+				item.LastError += " for category " + item.Category
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return []*OutboxItem{item}, nil
+	case policy.Overflow == ThrottleDigest && items[0].DigestKey == "":
+		return nil, o.throttleToDigest(t, items[0], now)
+	}
+
+	// Digests are always delayed: their messages were held back already
+	for _, item := range items {
+		if _, err := o.transition(item.ID, func(item *OutboxItem) error {
+			item.NextAttemptAt = &until
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// throttleToDigest replaces a message over the limit with a copy per recipient
+// in the throttle digest of its category, sent when the recipient is below
+// the limit again
+func (o *Outbox) throttleToDigest(t *throttle, item *OutboxItem, now time.Time) error {
+	message, err := item.LoadMessage()
+	if err != nil {
+		return err
+	}
+	key := throttleDigestKey
+	if item.Category != "" {
+		key += ":" + item.Category
+	}
+
+	var recipients []EmailAddress
+	recipients = append(recipients, message.Recipients.To...)
+	recipients = append(recipients, message.Recipients.Cc...)
+	recipients = append(recipients, message.Recipients.Bcc...)
+	for _, recipient := range recipients {
+		single := *message
+		single.Recipients = EmailRecipients{To: []EmailAddress{recipient}}
+		single.ClientRequestID = ""
+		copied, err := newOutboxItem(&single, time.Time{})
+		if err != nil {
+			return err
+		}
+		copied.DigestKey = key
+		copied.DigestRecipient = strings.ToLower(recipient.Address)
+
+		closes := t.free(item.Category, copied.DigestRecipient, now)
+		if closes.IsZero() {
+			closes = now
+		}
+		if err := o.addToDigest(copied, closes); err != nil {
+			return err
+		}
+	}
+	return o.Remove(item.ID)
+}
+
+// itemAddresses returns the recipients of an item, reading them from the
+// message for items stored before they were recorded
+func itemAddresses(item *OutboxItem) []string {
+	if len(item.Addresses) > 0 {
+		return item.Addresses
+	}
+	if item.DigestRecipient != "" {
+		return []string{item.DigestRecipient}
+	}
+	message, err := item.LoadMessage()
+	if err != nil {
+		return nil
+	}
+	return messageAddresses(message)
+}
+
+// messageAddresses returns the addresses of every recipient of message, in
+// lower case and without duplicates
+func messageAddresses(message *EmailMessage) []string {
+	var addresses []string
+	seen := map[string]bool{}
+	for _, list := range [][]EmailAddress{message.Recipients.To, message.Recipients.Cc, message.Recipients.Bcc} {
+		for _, recipient := range list {
+			address := strings.ToLower(strings.TrimSpace(recipient.Address))
+			if !seen[address] {
+				seen[address] = true
+				addresses = append(addresses, address)
+			}
+		}
+	}
+	return addresses
+}
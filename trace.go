@@ -0,0 +1,74 @@
+package azemailsender
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTPTrace describes one HTTP request made by the client. It holds no headers
+// or bodies, so it never contains credentials or message content
+type HTTPTrace struct {
+	Method string
+	// URL is the request URL without user information
+	URL        string
+	StatusCode int
+	Duration   time.Duration
+
+	// ClientRequestID is the x-ms-client-request-id sent with the request, if any
+	ClientRequestID string
+	// RequestID is the x-ms-request-id returned by the service, if any
+	RequestID string
+
+	// Err is set when no response was received
+	Err error
+}
+
+// String formats the trace as a single line such as
+// "POST https://.../emails:send?api-version=2024-07-01-preview -> 202 (153ms) request-id=..."
+func (t HTTPTrace) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s -> ", t.Method, t.URL)
+	if t.Err != nil {
+		fmt.Fprintf(&b, "error: %v", t.Err)
+	} else {
+		fmt.Fprintf(&b, "%d", t.StatusCode)
+	}
+	fmt.Fprintf(&b, " (%s)", t.Duration.Round(time.Millisecond))
+	if t.ClientRequestID != "" {
+		fmt.Fprintf(&b, " client-request-id=%s", t.ClientRequestID)
+	}
+	if t.RequestID != "" {
+		fmt.Fprintf(&b, " request-id=%s", t.RequestID)
+	}
+	return b.String()
+}
+
+// tracingTransport reports every request of the client to onTrace
+type tracingTransport struct {
+	next    http.RoundTripper
+	onTrace func(trace HTTPTrace)
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	traceURL := *req.URL
+	traceURL.User = nil
+	trace := HTTPTrace{
+		Method:          req.Method,
+		URL:             traceURL.String(),
+		ClientRequestID: req.Header.Get(clientRequestIDHeader),
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	trace.Duration = time.Since(start)
+	if err != nil {
+		trace.Err = err
+	} else {
+		trace.StatusCode = resp.StatusCode
+		trace.RequestID = resp.Header.Get(requestIDHeader)
+	}
+	t.onTrace(trace)
+	return resp, err
+}
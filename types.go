@@ -1,12 +1,24 @@
 package azemailsender
 
 import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"net/url"
 	"time"
 )
 
+// Supported Azure Communication Services Email API versions
+const (
+	APIVersion20230331        = "2023-03-31"
+	APIVersion20240701Preview = "2024-07-01-preview"
+)
+
 // DefaultAPIVersion is the default Azure Communication Services API version
-const DefaultAPIVersion = "2024-07-01-preview"
+const DefaultAPIVersion = APIVersion20240701Preview
 
 // Logger interface for custom logging implementations
 type Logger interface {
@@ -32,6 +44,148 @@ type ClientOptions struct {
 
 	// RetryDelay sets the delay between retry attempts
 	RetryDelay time.Duration
+
+	// TLSConfig customizes TLS, e.g. a private CA pool, client certificates or a minimum version
+	TLSConfig *tls.Config
+
+	// Proxy selects the proxy for each request. Nil uses HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	Proxy func(*http.Request) (*url.URL, error)
+
+	// Cloud restricts endpoints to an Azure cloud such as AzureGovernmentCloud.
+	// If nil, the cloud is detected from the endpoint and not enforced
+	Cloud *CloudConfiguration
+
+	// FailoverEndpoints are secondary endpoints tried in order when the primary keeps failing
+	FailoverEndpoints []Endpoint
+
+	// FailoverThreshold is the number of consecutive failed attempts before switching endpoints
+	FailoverThreshold int
+
+	// OnFailover is called when the client switches to another endpoint
+	OnFailover func(from, to string, err error)
+
+	// DefaultFrom is the sender address used when a message does not set one
+	DefaultFrom string
+
+	// DefaultReplyTo is the reply-to address used when a message does not set one
+	DefaultReplyTo string
+
+	// Categories holds the defaults of message categories by name: the sender,
+	// reply-to, tracking, priority and rate limits of messages built with Category
+	Categories map[string]*CategoryOptions
+
+	// ArchiveBCC is a compliance archive mailbox blind-copied on every message the client sends
+	ArchiveBCC string
+
+	// Suppression is consulted before sending when suppression checks are enabled
+	Suppression SuppressionList
+
+	// SuppressionMode is the default suppression check for messages that don't set one
+	SuppressionMode SuppressionMode
+
+	// Dedupe suppresses messages identical to one sent within DedupeWindow, with
+	// a DuplicateError. Nil disables deduplication
+	Dedupe DedupeStore
+
+	// DedupeWindow is how long identical messages are suppressed. Defaults to DefaultDedupeWindow
+	DedupeWindow time.Duration
+
+	// OnQuotaWarning is called when the service throttles the client or the remaining
+	// rate limit reported in response headers falls below QuotaWarningThreshold
+	OnQuotaWarning func(stats UsageStats)
+
+	// QuotaWarningThreshold is the fraction of the rate limit remaining that triggers
+	// OnQuotaWarning. Defaults to DefaultQuotaWarningThreshold
+	QuotaWarningThreshold float64
+
+	// AuditLog enables an append-only JSON lines log of every send. Nil disables auditing
+	AuditLog *AuditLogOptions
+
+	// CaptureFailures writes sanitized request/response pairs of failed sends to this directory,
+	// or into a zip support bundle when the path ends in .zip. Empty disables capture
+	CaptureFailures string
+
+	// AttachmentSizeLimit caps the total encoded attachment size checked by Validate.
+	// Zero uses DefaultAttachmentSizeLimit; a negative value disables the check
+	AttachmentSizeLimit int64
+
+	// SigningDateHeader selects the header carrying the signed date: hmacauth.DateHeaderXMsDate
+	// (the default) or hmacauth.DateHeaderDate
+	SigningDateHeader string
+
+	// OnHTTPTrace is called after every HTTP request with its method, URL, status,
+	// duration and request IDs. Nil disables tracing
+	OnHTTPTrace func(trace HTTPTrace)
+
+	// OnRetry is called before a failed send request is retried, with the number
+	// of the retry, starting at 1, the error of the failed attempt and the delay
+	// before the retry
+	OnRetry func(attempt int, err error, delay time.Duration)
+
+	// OnRequestComplete is called after every send request, including retried ones
+	OnRequestComplete func(info RequestInfo)
+
+	// MeterProvider records send duration, retry and in-flight metrics, e.g. an
+	// OpenTelemetry MeterProvider behind an adapter. Nil disables metrics
+	MeterProvider MeterProvider
+
+	// Transport delivers messages instead of the Azure Communication Services REST
+	// API, e.g. an SMTPTransport, FileTransport or NullTransport. Nil uses the API
+	Transport Transport
+
+	// FallbackTransports are tried in order when the transport fails for a reason
+	// other than the message itself, such as bad credentials or throttling. A nil
+	// entry is the API, so it can back up another transport. Reader attachments
+	// are read into memory before the first attempt, since a reader is read once
+	FallbackTransports []Transport
+
+	// OnFallback is called before a message is sent through the next fallback transport
+	OnFallback func(from, to string, err error)
+
+	// Webhook is notified when Wait sees a message reach its final status, or a
+	// transport answers with one. Nil disables notifications
+	Webhook *Webhook
+}
+
+// RequestInfo describes a completed send request
+type RequestInfo struct {
+	// Attempt is the number of the attempt, starting at 1
+	Attempt int
+	// Endpoint is the endpoint the request was sent to
+	Endpoint string
+	// StatusCode is the HTTP status of the response, 0 when none was received
+	StatusCode int
+	Duration   time.Duration
+
+	// ClientRequestID is the x-ms-client-request-id sent with the request
+	ClientRequestID string
+	// RequestID is the x-ms-request-id returned by the service, if any
+	RequestID string
+
+	// Err is why the request failed, nil when the message was accepted
+	Err error
+}
+
+// AuditLogOptions configures the audit log written by the client
+type AuditLogOptions struct {
+	// Path is the file entries are appended to
+	Path string
+
+	// MaxSize rotates the file once it would grow beyond this many bytes. Zero disables rotation
+	MaxSize int64
+
+	// MaxBackups limits how many rotated files are kept. Zero keeps all of them
+	MaxBackups int
+
+	// MaxAge deletes rotated files last written longer ago than this when the log
+	// rotates. Zero keeps them regardless of age
+	MaxAge time.Duration
+}
+
+// Endpoint is an Azure Communication Services endpoint and its access key
+type Endpoint struct {
+	URL       string
+	AccessKey string
 }
 
 // DefaultClientOptions returns default client options
@@ -43,6 +197,8 @@ func DefaultClientOptions() *ClientOptions {
 		APIVersion:  DefaultAPIVersion,
 		MaxRetries:  3,
 		RetryDelay:  time.Second,
+
+		FailoverThreshold: 3,
 	}
 }
 
@@ -66,21 +222,57 @@ type EmailRecipients struct {
 	Bcc []EmailAddress `json:"bcc,omitempty"`
 }
 
+// EmailAttachment represents a file attached to an email
+type EmailAttachment struct {
+	Name            string `json:"name"`
+	ContentType     string `json:"contentType"`
+	ContentInBase64 string `json:"contentInBase64"`
+	ContentID       string `json:"contentId,omitempty"`
+
+	// Reader supplies the content at send time when ContentInBase64 is empty.
+	// It is read once, and closed afterwards if it implements io.Closer
+	Reader io.Reader `json:"-"`
+}
+
 // EmailMessage represents a complete email message ready to be sent
 type EmailMessage struct {
-	SenderAddress string          `json:"senderAddress"`
-	Content       EmailContent    `json:"content"`
-	Recipients    EmailRecipients `json:"recipients"`
-	ReplyTo       []EmailAddress  `json:"replyTo,omitempty"`
+	SenderAddress                  string            `json:"senderAddress"`
+	Content                        EmailContent      `json:"content"`
+	Recipients                     EmailRecipients   `json:"recipients"`
+	ReplyTo                        []EmailAddress    `json:"replyTo,omitempty"`
+	Attachments                    []EmailAttachment `json:"attachments,omitempty"`
+	Headers                        map[string]string `json:"headers,omitempty"`
+	UserEngagementTrackingDisabled bool              `json:"userEngagementTrackingDisabled,omitempty"`
+
+	// APIVersion overrides the client API version for this message
+	APIVersion string `json:"-"`
+
+	// SuppressionCheck overrides the client suppression mode for this message
+	SuppressionCheck SuppressionMode `json:"-"`
+	// ClientRequestID sets the x-ms-client-request-id header; one is generated when empty
+	ClientRequestID string `json:"-"`
+	// Category names the kind of message, such as alerts or reports, for
+	// per-category policies like outbox throttling
+	Category string `json:"-"`
 }
 
 // SendResponse represents the response from sending an email
 type SendResponse struct {
-	ID        string `json:"id"`
-	Status    string `json:"status,omitempty"`
-	Error     *Error `json:"error,omitempty"`
+	ID        string      `json:"id"`
+	Status    EmailStatus `json:"status,omitempty"`
+	Error     *Error      `json:"error,omitempty"`
 	Timestamp time.Time
 	MessageID string // Legacy field for backward compatibility
+
+	// ClientRequestID is the x-ms-client-request-id sent with the request
+	ClientRequestID string `json:"-"`
+	// RequestID is the x-ms-request-id assigned by the service
+	RequestID string `json:"-"`
+
+	// Transport names the transport that sent the message, see TransportName
+	Transport string `json:"-"`
+	// FailedTransports lists the transports that failed before a fallback sent the message
+	FailedTransports []TransportAttempt `json:"-"`
 }
 
 // Error represents an error response from the Azure API
@@ -91,12 +283,55 @@ type Error struct {
 	Details []Error `json:"details,omitempty"`
 }
 
+// Validation errors reported by MessageBuilder.Validate
+var (
+	ErrMissingSender  = errors.New("sender address is required")
+	ErrMissingSubject = errors.New("subject is required")
+	ErrMissingContent = errors.New("either plain text or HTML content is required")
+	ErrNoRecipients   = errors.New("at least one recipient is required")
+)
+
+// InvalidAddressError reports a malformed email address
+type InvalidAddressError struct {
+	// Field is the message field holding the address: sender, to, cc, bcc or reply-to
+	Field   string
+	Address string
+}
+
+func (e *InvalidAddressError) Error() string {
+	if e.Field == "sender" {
+		return fmt.Sprintf("invalid sender email address: %s", e.Address)
+	}
+	return fmt.Sprintf("invalid email address: %s", e.Address)
+}
+
+// APIError is returned when the service responds to a send with a non-success status code
+type APIError struct {
+	StatusCode int
+	Message    string
+
+	// RetryAfter is the delay requested by the service through the Retry-After header
+	RetryAfter time.Duration
+
+	// ClientRequestID is the x-ms-client-request-id sent with the failed request
+	ClientRequestID string
+	// RequestID is the x-ms-request-id returned by the service, if any
+	RequestID string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API request failed with status %d: %s", e.StatusCode, e.Message)
+}
+
 // StatusResponse represents the status of a sent email
 type StatusResponse struct {
-	ID        string    `json:"id"`
-	Status    string    `json:"status"`
-	Error     *Error    `json:"error,omitempty"`
-	Timestamp time.Time `json:"timestamp"`
+	ID        string      `json:"id"`
+	Status    EmailStatus `json:"status"`
+	Error     *Error      `json:"error,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+
+	// RetryAfter is the polling delay requested by the service through the Retry-After header
+	RetryAfter time.Duration `json:"-"`
 }
 
 // WaitOptions provides configuration for waiting for email completion
@@ -107,6 +342,10 @@ type WaitOptions struct {
 	// MaxWaitTime sets the maximum time to wait for completion
 	MaxWaitTime time.Duration
 
+	// Backoff enables adaptive polling: checks start fast and back off exponentially.
+	// If nil, the status is checked every PollInterval
+	Backoff *PollBackoff
+
 	// OnStatusUpdate is called each time the status is checked
 	OnStatusUpdate func(status *StatusResponse)
 
@@ -128,10 +367,69 @@ func DefaultWaitOptions() *WaitOptions {
 	}
 }
 
+// ErrDeadlineWouldExceed is returned when a send fails and the context deadline
+// leaves too little time to wait RetryDelay and retry. It wraps the last error
+var ErrDeadlineWouldExceed = errors.New("retry would exceed the context deadline")
+
+// ErrWaitTimeout is returned when MaxWaitTime elapses before the email reaches a final status.
+// It is wrapped together with context.DeadlineExceeded
+var ErrWaitTimeout = errors.New("timed out waiting for email completion")
+
+// StatusObservation is a status seen while waiting for completion
+type StatusObservation struct {
+	Status    EmailStatus `json:"status"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// WaitResult describes the outcome of waiting for an email to complete
+type WaitResult struct {
+	// ID is the message ID that was polled
+	ID string
+
+	// FinalStatus is the final status reached, or empty if waiting stopped early
+	FinalStatus EmailStatus
+
+	// Final is the last status response received, if any
+	Final *StatusResponse
+
+	// History lists each distinct status in the order it was first observed
+	History []StatusObservation
+
+	// Attempts is the number of status checks made
+	Attempts int
+
+	// Duration is how long waiting took
+	Duration time.Duration
+}
+
+// PollBackoff configures adaptive status polling
+type PollBackoff struct {
+	// InitialInterval is the delay after the first status check
+	InitialInterval time.Duration
+
+	// MaxInterval caps the delay between status checks
+	MaxInterval time.Duration
+
+	// Multiplier grows the delay after each pending status
+	Multiplier float64
+}
+
+// DefaultPollBackoff returns a backoff starting at one second and growing to 30 seconds
+func DefaultPollBackoff() *PollBackoff {
+	return &PollBackoff{
+		InitialInterval: time.Second,
+		MaxInterval:     30 * time.Second,
+		Multiplier:      2,
+	}
+}
+
 // EmailStatus represents the possible statuses of an email
 type EmailStatus string
 
 const (
+	StatusNotStarted     EmailStatus = "NotStarted"
+	StatusRunning        EmailStatus = "Running"
+	StatusSucceeded      EmailStatus = "Succeeded"
 	StatusQueued         EmailStatus = "Queued"
 	StatusOutForDelivery EmailStatus = "OutForDelivery"
 	StatusDelivered      EmailStatus = "Delivered"
@@ -0,0 +1,143 @@
+package azemailsender
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultQuotaWarningThreshold is the fraction of remaining quota that triggers OnQuotaWarning
+const DefaultQuotaWarningThreshold = 0.1
+
+// rateLimitHeaders lists the header names rate limits are read from, in order of preference
+var rateLimitHeaders = struct {
+	limit     []string
+	remaining []string
+	reset     []string
+}{
+	limit:     []string{"x-ratelimit-limit", "ratelimit-limit", "x-ms-ratelimit-limit"},
+	remaining: []string{"x-ratelimit-remaining", "ratelimit-remaining", "x-ms-ratelimit-remaining-requests"},
+	reset:     []string{"x-ratelimit-reset", "ratelimit-reset"},
+}
+
+// UsageStats is a snapshot of send activity and the rate limits observed by a client
+type UsageStats struct {
+	// Sent and Failed count send calls by outcome
+	Sent   int64
+	Failed int64
+
+	// Throttled counts responses with status 429
+	Throttled int64
+
+	// LastThrottled is when the service last throttled the client
+	LastThrottled time.Time
+
+	// LastRetryAfter is the delay requested by the last throttled response
+	LastRetryAfter time.Duration
+
+	// RateLimit and RateLimitRemaining are taken from response headers, -1 when never reported
+	RateLimit          int
+	RateLimitRemaining int
+
+	// RateLimitReset is when the current rate limit window resets, if reported
+	RateLimitReset time.Time
+
+	// ObservedAt is when rate limit headers were last seen
+	ObservedAt time.Time
+}
+
+// usageTracker accumulates usage statistics across requests
+type usageTracker struct {
+	mu    sync.Mutex
+	stats UsageStats
+}
+
+// newUsageTracker creates a tracker with unknown rate limits
+func newUsageTracker() *usageTracker {
+	return &usageTracker{
+		stats: UsageStats{RateLimit: -1, RateLimitRemaining: -1},
+	}
+}
+
+// Stats returns a snapshot of the client's send counters and observed rate limits
+func (c *Client) Stats() UsageStats {
+	c.usage.mu.Lock()
+	defer c.usage.mu.Unlock()
+	return c.usage.stats
+}
+
+// observeResponse records rate limit headers and throttling from a send response
+func (c *Client) observeResponse(resp *http.Response) {
+	c.usage.mu.Lock()
+
+	warn := false
+	if resp.StatusCode == http.StatusTooManyRequests {
+		c.usage.stats.Throttled++
+		c.usage.stats.LastThrottled = time.Now()
+		c.usage.stats.LastRetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+		warn = true
+	}
+
+	limit, hasLimit := headerInt(resp.Header, rateLimitHeaders.limit)
+	remaining, hasRemaining := headerInt(resp.Header, rateLimitHeaders.remaining)
+	if hasLimit {
+		c.usage.stats.RateLimit = limit
+	}
+	if hasRemaining {
+		c.usage.stats.RateLimitRemaining = remaining
+	}
+	if reset, ok := headerInt(resp.Header, rateLimitHeaders.reset); ok {
+		c.usage.stats.RateLimitReset = time.Now().Add(time.Duration(reset) * time.Second)
+	}
+	if hasLimit || hasRemaining {
+		c.usage.stats.ObservedAt = time.Now()
+	}
+
+	threshold := c.options.QuotaWarningThreshold
+	if threshold <= 0 {
+		threshold = DefaultQuotaWarningThreshold
+	}
+	if stats := c.usage.stats; stats.RateLimit > 0 && stats.RateLimitRemaining >= 0 &&
+		float64(stats.RateLimitRemaining) <= float64(stats.RateLimit)*threshold {
+		warn = true
+	}
+
+	snapshot := c.usage.stats
+	c.usage.mu.Unlock()
+
+	if warn {
+		if c.options.Debug {
+			c.logger.Printf("[DEBUG] Nearing rate limit: %d of %d remaining, %d throttled responses", snapshot.RateLimitRemaining, snapshot.RateLimit, snapshot.Throttled)
+		}
+		if c.options.OnQuotaWarning != nil {
+			c.options.OnQuotaWarning(snapshot)
+		}
+	}
+}
+
+// recordSendResult counts a completed send call
+func (c *Client) recordSendResult(err error) {
+	c.usage.mu.Lock()
+	defer c.usage.mu.Unlock()
+	if err != nil {
+		c.usage.stats.Failed++
+	} else {
+		c.usage.stats.Sent++
+	}
+}
+
+// headerInt returns the first of the named headers that holds an integer
+func headerInt(header http.Header, names []string) (int, bool) {
+	for _, name := range names {
+		value := strings.TrimSpace(header.Get(name))
+		if value == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(value); err == nil {
+			return n, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,144 @@
+package azemailsender
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// verpSeparator stands for the @ of the recipient encoded in a VERP address
+const verpSeparator = "="
+
+// PlusAddress returns address with token added to its local part, e.g.
+// bounces+token@example.com for bounces@example.com. The token may contain
+// letters, digits and the characters . - _ = +
+func PlusAddress(address, token string) (string, error) {
+	local, domain, ok := strings.Cut(address, "@")
+	if !ok || !isValidEmail(address) {
+		return "", &InvalidAddressError{Address: address}
+	}
+	if token == "" {
+		return "", fmt.Errorf("plus-address token is required")
+	}
+	for _, r := range token {
+		if !isPlusTokenRune(r) {
+			return "", fmt.Errorf("invalid plus-address token %q: %q is not allowed", token, r)
+		}
+	}
+	// An address that carries a token already gets a new one
+	local, _, _ = strings.Cut(local, "+")
+	return local + "+" + token + "@" + domain, nil
+}
+
+// isPlusTokenRune reports whether r may appear in a plus-address token
+func isPlusTokenRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune(".-_=+", r)
+}
+
+// PlusToken returns the token of a plus address such as bounces+token@example.com,
+// and whether it has one
+func PlusToken(address string) (string, bool) {
+	local, _, ok := strings.Cut(strings.TrimSpace(address), "@")
+	if !ok {
+		return "", false
+	}
+	_, token, ok := strings.Cut(local, "+")
+	return token, ok && token != ""
+}
+
+// VERPAddress returns the variable envelope return path of recipient under
+// address: bounces+anna=example.org@example.com for anna@example.org and
+// bounces@example.com. Characters of the recipient's local part that can't be
+// in a token are written as +XX in hex, so o'brien becomes o+27brien
+func VERPAddress(address, recipient string) (string, error) {
+	if !isValidEmail(recipient) {
+		return "", &InvalidAddressError{Address: recipient}
+	}
+	local, domain, _ := strings.Cut(recipient, "@")
+	return PlusAddress(address, encodeVERPLocal(local)+verpSeparator+strings.ToLower(domain))
+}
+
+// encodeVERPLocal escapes the bytes of a local part other than letters, digits
+// and . - _ as +XX, including + and the separator
+func encodeVERPLocal(local string) string {
+	var b strings.Builder
+	for i := 0; i < len(local); i++ {
+		c := local[i]
+		if c < utf8.RuneSelf && c != '+' && c != verpSeparator[0] && isPlusTokenRune(rune(c)) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "+%02X", c)
+	}
+	return b.String()
+}
+
+// decodeVERPLocal reverses encodeVERPLocal
+func decodeVERPLocal(encoded string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(encoded); i++ {
+		if encoded[i] != '+' {
+			b.WriteByte(encoded[i])
+			continue
+		}
+		if i+2 >= len(encoded) {
+			return "", false
+		}
+		c, err := strconv.ParseUint(encoded[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", false
+		}
+		b.WriteByte(byte(c))
+		i += 2
+	}
+	return b.String(), true
+}
+
+// ParseVERP returns the recipient encoded in a VERP address made by
+// VERPAddress, and whether address is one
+func ParseVERP(address string) (string, bool) {
+	token, ok := PlusToken(address)
+	if !ok {
+		return "", false
+	}
+	// The separator is escaped in the local part, so the last one stands for the @
+	i := strings.LastIndex(token, verpSeparator)
+	if i < 0 {
+		return "", false
+	}
+	local, ok := decodeVERPLocal(token[:i])
+	if !ok {
+		return "", false
+	}
+	recipient := local + "@" + token[i+1:]
+	if !isValidEmail(recipient) {
+		return "", false
+	}
+	return recipient, true
+}
+
+// VERPReplyTo returns a middleware that sets the reply-to of messages with a
+// single To recipient to the VERPAddress of that recipient under address, so
+// replies and bounces can be attributed to the recipient. The sender is left
+// alone, as the service only sends from its configured addresses. Use it with
+// SendIndividually; messages to several To recipients are left unchanged
+func VERPReplyTo(address string) Middleware {
+	return func(message *EmailMessage) error {
+		if len(message.Recipients.To) != 1 {
+			return nil
+		}
+		replyTo, err := VERPAddress(address, message.Recipients.To[0].Address)
+		if err != nil {
+			return err
+		}
+		message.ReplyTo = []EmailAddress{{Address: replyTo}}
+		return nil
+	}
+}
+
+// ReturnToken returns the plus-address token of the sender of a delivery
+// report, and whether it has one
+func (r *DeliveryReport) ReturnToken() (string, bool) {
+	return PlusToken(r.Sender)
+}
@@ -0,0 +1,103 @@
+package azemailsender
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Feature is an optional capability of the Email API that depends on the API version
+type Feature string
+
+const (
+	FeatureAttachments       Feature = "attachments"
+	FeatureInlineAttachments Feature = "inline attachments"
+	FeatureCustomHeaders     Feature = "custom headers"
+	FeatureTrackingControl   Feature = "user engagement tracking control"
+)
+
+// apiVersionFeatures lists the features available in each known API version
+var apiVersionFeatures = map[string][]Feature{
+	APIVersion20230331: {
+		FeatureAttachments,
+		FeatureCustomHeaders,
+		FeatureTrackingControl,
+	},
+	APIVersion20240701Preview: {
+		FeatureAttachments,
+		FeatureInlineAttachments,
+		FeatureCustomHeaders,
+		FeatureTrackingControl,
+	},
+}
+
+// UnsupportedFeatureError is returned when a message uses a feature its API version lacks
+type UnsupportedFeatureError struct {
+	Feature    Feature
+	APIVersion string
+}
+
+func (e *UnsupportedFeatureError) Error() string {
+	return fmt.Sprintf("%s not supported by API version %s", e.Feature, e.APIVersion)
+}
+
+// SupportedAPIVersions returns the API versions known to this library
+func SupportedAPIVersions() []string {
+	versions := make([]string, 0, len(apiVersionFeatures))
+	for version := range apiVersionFeatures {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// SupportsFeature reports whether an API version supports a feature.
+// Versions unknown to this library are assumed to support everything
+func SupportsFeature(apiVersion string, feature Feature) bool {
+	features, known := apiVersionFeatures[apiVersion]
+	if !known {
+		return true
+	}
+
+	for _, f := range features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFeatures verifies that the message only uses features of the given API version
+func checkFeatures(message *EmailMessage, apiVersion string) error {
+	var used []Feature
+
+	if len(message.Attachments) > 0 {
+		used = append(used, FeatureAttachments)
+	}
+	for _, attachment := range message.Attachments {
+		if attachment.ContentID != "" {
+			used = append(used, FeatureInlineAttachments)
+			break
+		}
+	}
+	if len(message.Headers) > 0 {
+		used = append(used, FeatureCustomHeaders)
+	}
+	if message.UserEngagementTrackingDisabled {
+		used = append(used, FeatureTrackingControl)
+	}
+
+	for _, feature := range used {
+		if !SupportsFeature(apiVersion, feature) {
+			return &UnsupportedFeatureError{Feature: feature, APIVersion: apiVersion}
+		}
+	}
+	return nil
+}
+
+// messageAPIVersion returns the API version a message is sent with
+func (c *Client) messageAPIVersion(message *EmailMessage) string {
+	if message.APIVersion != "" {
+		return message.APIVersion
+	}
+	return c.options.APIVersion
+}
@@ -0,0 +1,179 @@
+package azemailsender
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WaitAllOptions configures waiting for several messages at once
+type WaitAllOptions struct {
+	// WaitOptions control polling of each message. OnStatusUpdate and OnError are
+	// still called, from the goroutine polling the message
+	WaitOptions *WaitOptions
+
+	// MaxRequestsPerSecond limits status checks across all messages. Zero means unlimited
+	MaxRequestsPerSecond float64
+
+	// PauseBetween is the minimum delay between consecutive status checks
+	PauseBetween time.Duration
+
+	// MaxConcurrency limits how many messages are polled at the same time. Zero means all of them
+	MaxConcurrency int
+}
+
+// MessageUpdate is a status update for one of several watched messages
+type MessageUpdate struct {
+	// ID is the message the update belongs to
+	ID string
+
+	// Status is the status just observed, nil when the check failed
+	Status *StatusResponse
+
+	// Err is the status check error, or the reason waiting stopped when Done is set
+	Err error
+
+	// Done is set on the last update for the message
+	Done bool
+
+	// Result is the outcome of waiting, set when Done is true
+	Result *WaitResult
+}
+
+// WaitForAll polls several messages concurrently until each reaches a final status,
+// streaming every observed status over the returned channel. The channel is closed
+// once all messages are done
+func (c *Client) WaitForAll(ctx context.Context, messageIDs []string, options *WaitAllOptions) <-chan MessageUpdate {
+	if options == nil {
+		options = &WaitAllOptions{}
+	}
+
+	waitOptions := options.WaitOptions
+	if waitOptions == nil {
+		waitOptions = DefaultWaitOptions()
+	}
+
+	if c.options.Debug {
+		c.logger.Printf("[DEBUG] Waiting for %d messages (max %.1f requests/s)", len(messageIDs), options.MaxRequestsPerSecond)
+	}
+
+	limiter := newRateLimiter(options.MaxRequestsPerSecond, options.PauseBetween)
+	updates := make(chan MessageUpdate)
+
+	var slots chan struct{}
+	if options.MaxConcurrency > 0 {
+		slots = make(chan struct{}, options.MaxConcurrency)
+	}
+
+	var wg sync.WaitGroup
+	for _, messageID := range messageIDs {
+		wg.Add(1)
+		go func(messageID string) {
+			defer wg.Done()
+
+			if slots != nil {
+				select {
+				case slots <- struct{}{}:
+					defer func() { <-slots }()
+				case <-ctx.Done():
+					deliverUpdate(ctx, updates, MessageUpdate{ID: messageID, Err: ctx.Err(), Done: true, Result: &WaitResult{ID: messageID}})
+					return
+				}
+			}
+
+			// Copy options so each message streams its own updates
+			perMessage := *waitOptions
+			perMessage.OnStatusUpdate = func(status *StatusResponse) {
+				if waitOptions.OnStatusUpdate != nil {
+					waitOptions.OnStatusUpdate(status)
+				}
+				if !status.Status.IsFinal() {
+					deliverUpdate(ctx, updates, MessageUpdate{ID: messageID, Status: status})
+				}
+			}
+			perMessage.OnError = func(err error) {
+				if waitOptions.OnError != nil {
+					waitOptions.OnError(err)
+				}
+				deliverUpdate(ctx, updates, MessageUpdate{ID: messageID, Err: err})
+			}
+
+			result, err := c.wait(ctx, messageID, &perMessage, limiter)
+			deliverUpdate(ctx, updates, MessageUpdate{
+				ID:     messageID,
+				Status: result.Final,
+				Err:    err,
+				Done:   true,
+				Result: result,
+			})
+		}(messageID)
+	}
+
+	go func() {
+		wg.Wait()
+		close(updates)
+	}()
+
+	return updates
+}
+
+// deliverUpdate delivers an update unless the caller has stopped listening
+func deliverUpdate(ctx context.Context, updates chan<- MessageUpdate, update MessageUpdate) {
+	select {
+	case updates <- update:
+	case <-ctx.Done():
+	}
+}
+
+// StatusResult is the status of one of several messages checked by GetStatuses
+type StatusResult struct {
+	ID     string
+	Status *StatusResponse
+	Err    error
+}
+
+// GetStatuses checks the status of several messages once, concurrently and paced by
+// options, which may be nil. Results are returned in the order of messageIDs
+func (c *Client) GetStatuses(ctx context.Context, messageIDs []string, options *BatchOptions) []StatusResult {
+	if options == nil {
+		options = &BatchOptions{}
+	}
+
+	concurrency := options.MaxConcurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+
+	if c.options.Debug {
+		c.logger.Printf("[DEBUG] Checking status of %d messages with %d workers", len(messageIDs), concurrency)
+	}
+
+	limiter := newRateLimiter(options.MaxRequestsPerSecond, options.PauseBetween)
+	results := make([]StatusResult, len(messageIDs))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				result := StatusResult{ID: messageIDs[index]}
+				if err := limiter.wait(ctx); err != nil {
+					result.Err = err
+				} else {
+					result.Status, result.Err = c.GetStatusWithContext(ctx, result.ID)
+				}
+				results[index] = result
+			}
+		}()
+	}
+
+	for index := range messageIDs {
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,136 @@
+package azemailsender
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WebhookSignatureHeader carries the HMAC-SHA256 signature of a webhook
+// payload as "sha256=<hex>", when the webhook has a secret
+const WebhookSignatureHeader = "X-Azemailsender-Signature"
+
+// Notification types posted by a Webhook
+const (
+	// NotificationStatus reports the final status of a send, reached while waiting
+	NotificationStatus = "status"
+	// NotificationDelivery reports a delivery report received for one recipient
+	NotificationDelivery = "delivery"
+	// NotificationDeadLetter reports an outbox message that will not be sent again
+	NotificationDeadLetter = "dead-letter"
+	// NotificationAlert reports an alert rule that started ("firing") or stopped
+	// ("resolved") firing
+	NotificationAlert = "alert"
+)
+
+// Notification is the JSON payload a Webhook posts when a message reaches a final state
+type Notification struct {
+	Type      string    `json:"type"`
+	MessageID string    `json:"messageId,omitempty"`
+	Status    string    `json:"status"`
+	Recipient string    `json:"recipient,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+
+	// OutboxID is the outbox message of a dead-letter notification
+	OutboxID string `json:"outboxId,omitempty"`
+
+	// Rule is the alert rule of an alert notification
+	Rule string `json:"rule,omitempty"`
+
+	// Token is the plus-address token of the sender of a delivery notification
+	Token string `json:"token,omitempty"`
+}
+
+// Webhook posts notifications to a URL, so other systems can react to
+// deliveries without polling
+type Webhook struct {
+	// URL receives the notifications as JSON POST requests
+	URL string
+
+	// Secret signs each payload in the WebhookSignatureHeader. Empty disables signing
+	Secret string
+
+	// HTTPClient sends the requests; nil uses a client with a 10 second timeout
+	HTTPClient *http.Client
+}
+
+// Notify posts notification to the webhook URL. Any 2xx answer is a success
+func (w *Webhook) Notify(ctx context.Context, notification *Notification) error {
+	if notification.Timestamp.IsZero() {
+		notification.Timestamp = time.Now().UTC()
+	}
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "azemailsender-go/1.0")
+	if w.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(w.Secret, body))
+	}
+
+	client := w.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook request failed: %w", &APIError{StatusCode: resp.StatusCode, Message: resp.Status})
+	}
+	return nil
+}
+
+// SignWebhookPayload returns the WebhookSignatureHeader value for body
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature reports whether signature, the WebhookSignatureHeader
+// of a received notification, matches body. Receivers use it to reject forged requests
+func VerifyWebhookSignature(secret string, body []byte, signature string) bool {
+	expected := SignWebhookPayload(secret, body)
+	return hmac.Equal([]byte(expected), []byte(strings.TrimSpace(signature)))
+}
+
+// notifyFinalStatus posts the final status of a message to the client webhook
+func (c *Client) notifyFinalStatus(ctx context.Context, status *StatusResponse) {
+	if c.options.Webhook == nil {
+		return
+	}
+
+	notification := &Notification{
+		Type:      NotificationStatus,
+		MessageID: status.ID,
+		Status:    string(status.Status),
+	}
+	if status.Error != nil {
+		notification.Error = status.Error.Message
+	}
+
+	// The status is final whether or not the caller is still waiting
+	if err := c.options.Webhook.Notify(context.WithoutCancel(ctx), notification); err != nil {
+		c.logger.Printf("[WARN] Failed to notify webhook of message %s: %v", status.ID, err)
+	}
+}